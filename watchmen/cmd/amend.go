@@ -5,17 +5,21 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"watchmen/internal/model"
+	"watchmen/internal/period"
 )
 
 var amendCmd = &cobra.Command{
 	Use:   "amend [index]",
-	Short: "Amend the note on a completed time entry",
-	Long: `Amend the note on a completed time entry.
+	Short: "Amend a completed time entry",
+	Long: `Amend the note, hourly rate, category, billable status, project or times of
+a completed time entry.
 
 With no arguments, displays an interactive list of recent entries.
 With an index (1=most recent), directly edits that entry.
@@ -30,11 +34,35 @@ Examples:
   watchmen amend --last -n "x"  # Explicit: update most recent entry
   watchmen amend 2 -n "note"    # Update second most recent entry
   watchmen amend --clear        # Clear note from most recent entry
-  echo "note" | watchmen amend  # Read note from stdin`,
+  watchmen amend 2 --rate 75    # Bill second most recent entry at 75/hour
+  watchmen amend --clear-rate   # Bill most recent entry at the project rate
+  watchmen amend 3 --category meetings --non-billable
+  echo "note" | watchmen amend  # Read note from stdin
+  watchmen amend 2 --project acme          # Move to another project
+  watchmen amend --start 8:30 --end 17:15  # Change when the entry started and ended
+  watchmen amend 3 --shift -30m            # Move the whole entry 30 minutes earlier
+  watchmen amend --segments "9:00-12:00,13:00-17:30"
+  watchmen amend --split 12:00             # Split in two at noon
+  watchmen amend 1 --merge 2               # Merge the two most recent entries
+
+Times are taken on the day the entry started, or --date, and an end earlier
+than its start is on the next day. Edits are refused if a segment would end
+before it starts or overlap another entry.
+
+Entries already on an invoice are only amended with --force.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		note, _ := cmd.Flags().GetString("note")
 		clear, _ := cmd.Flags().GetBool("clear")
 		last, _ := cmd.Flags().GetBool("last")
+		force, _ := cmd.Flags().GetBool("force")
+		detailsSet := false
+		for _, name := range []string{"rate", "clear-rate", "category", "clear-category", "billable", "non-billable"} {
+			detailsSet = detailsSet || cmd.Flags().Changed(name)
+		}
+		timesSet := false
+		for _, name := range []string{"project", "start", "end", "shift", "segments", "split", "merge"} {
+			timesSet = timesSet || cmd.Flags().Changed(name)
+		}
 
 		if clear && note != "" {
 			return fmt.Errorf("cannot use both --note and --clear")
@@ -55,9 +83,10 @@ Examples:
 		}
 
 		// Determine if we're in non-interactive mode
-		// Non-interactive when: --note, --clear, --last, or stdin is not a TTY
+		// Non-interactive when: --note, --clear, --last, a rate, category,
+		// billable, project or time flag, or stdin is not a TTY
 		stdinIsTerminal := isTerminal(os.Stdin)
-		nonInteractive := note != "" || clear || last || !stdinIsTerminal
+		nonInteractive := note != "" || clear || last || detailsSet || timesSet || !stdinIsTerminal
 
 		// Determine the index
 		var index int
@@ -73,7 +102,7 @@ Examples:
 			index = 1
 		} else {
 			// Interactive mode - no args, no flags
-			return runAmendInteractive(entries, completed)
+			return runAmendInteractive(entries, completed, force)
 		}
 
 		if index > len(completed) {
@@ -84,12 +113,40 @@ Examples:
 		// Get the entry details
 		entryIdx := completed[index-1]
 		entry := entries[entryIdx]
+		if entry.IsBilled() && !force {
+			return fmt.Errorf("entry #%d is billed on invoice %s, use --force to amend it anyway", index, entry.InvoiceID)
+		}
 		project, _ := store.GetProject(entry.ProjectID)
 		projectName := entry.ProjectID
 		if project != nil {
 			projectName = project.Name
 		}
 
+		// Every change is worked out before any is saved, so a rejected
+		// edit leaves the entry as it was
+		billingProject := project
+		if billingProject == nil {
+			billingProject = &model.Project{ID: entry.ProjectID}
+		}
+		var details func(*model.Entry)
+		if detailsSet {
+			var err error
+			if details, err = entryUpdates(cmd, billingProject); err != nil {
+				return err
+			}
+		}
+		// Changing details or times on their own leaves the note alone
+		if (detailsSet || timesSet) && note == "" && !clear {
+			updated, err := amendEntry(cmd, &entry, index, entries, completed, force, details)
+			if err != nil {
+				return err
+			}
+			if detailsSet {
+				fmt.Printf("amended entry #%d: %s\n", index, describeEntryDetails(updated, billingProject))
+			}
+			return nil
+		}
+
 		// Determine the new note
 		var newNote string
 		if clear {
@@ -133,10 +190,19 @@ Examples:
 		}
 
 		// Update the entry
-		_, err := store.AmendEntry(index, newNote)
+		updates := func(e *model.Entry) {
+			if details != nil {
+				details(e)
+			}
+			e.Note = newNote
+		}
+		updated, err := amendEntry(cmd, &entry, index, entries, completed, force, updates)
 		if err != nil {
 			return err
 		}
+		if detailsSet {
+			fmt.Printf("amended entry #%d: %s\n", index, describeEntryDetails(updated, billingProject))
+		}
 
 		// Output: minimal in non-interactive mode, verbose otherwise
 		if nonInteractive && stdinIsTerminal {
@@ -165,6 +231,184 @@ Examples:
 	},
 }
 
+// amendEntry applies updates and the project, time, split and merge flags
+// to entry, number index in completed, and returns the entry updated. A
+// project or time change is saved with updates in one write; a split or
+// merge is saved first and updates then go to the entry it leaves.
+func amendEntry(cmd *cobra.Command, entry *model.Entry, index int, entries []model.Entry, completed []int, force bool, updates func(*model.Entry)) (*model.Entry, error) {
+	flags := cmd.Flags()
+	edits := 0
+	for _, names := range [][]string{{"start", "end"}, {"shift"}, {"segments"}, {"split"}, {"merge"}} {
+		for _, name := range names {
+			if flags.Changed(name) {
+				edits++
+				break
+			}
+		}
+	}
+	if edits > 1 {
+		return nil, fmt.Errorf("use only one of --start/--end, --shift, --segments, --split and --merge at a time")
+	}
+	if flags.Changed("project") && (flags.Changed("split") || flags.Changed("merge")) {
+		return nil, fmt.Errorf("--project cannot be used with --split or --merge")
+	}
+
+	// Times are read in the timezone the project's days are counted in
+	loc := home
+	if project, err := store.GetProject(entry.ProjectID); err == nil {
+		loc = projectLocation(project)
+	}
+	baseDate := entry.StartTime().In(loc)
+	if dateStr, _ := flags.GetString("date"); dateStr != "" {
+		var err error
+		baseDate, err = period.ParseDay(dateStr, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
+		}
+	}
+
+	var project *model.Project
+	if flags.Changed("project") {
+		name, _ := flags.GetString("project")
+		var err error
+		if project, err = store.GetProject(name); err != nil {
+			return nil, fmt.Errorf("project %q not found", name)
+		}
+	}
+
+	var segments []model.TimeSegment
+	switch {
+	case flags.Changed("start") || flags.Changed("end"):
+		segments = slices.Clone(entry.Segments)
+		first, last := &segments[0], &segments[len(segments)-1]
+		if flags.Changed("start") {
+			startStr, _ := flags.GetString("start")
+			start, err := parseTime(startStr, baseDate)
+			if err != nil {
+				return nil, err
+			}
+			first.Start = start
+		}
+		if flags.Changed("end") {
+			endStr, _ := flags.GetString("end")
+			end, err := parseTime(endStr, baseDate)
+			if err != nil {
+				return nil, err
+			}
+			if end.Before(last.Start) {
+				end = end.AddDate(0, 0, 1)
+			}
+			last.End = &end
+		}
+	case flags.Changed("shift"):
+		shift, _ := flags.GetDuration("shift")
+		for _, seg := range entry.Segments {
+			if seg.End == nil {
+				return nil, fmt.Errorf("entry #%d is still running or paused", index)
+			}
+			end := seg.End.Add(shift)
+			segments = append(segments, model.TimeSegment{Start: seg.Start.Add(shift), End: &end})
+		}
+	case flags.Changed("segments"):
+		ranges, _ := flags.GetString("segments")
+		var err error
+		if segments, err = parseSegments(ranges, baseDate); err != nil {
+			return nil, err
+		}
+	case flags.Changed("split"):
+		atStr, _ := flags.GetString("split")
+		at, err := parseTime(atStr, baseDate)
+		if err != nil {
+			return nil, err
+		}
+		if at.Before(entry.StartTime()) {
+			at = at.AddDate(0, 0, 1)
+		}
+		first, rest, err := store.SplitEntry(entry.ID, at, updates)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("split entry #%d at %s:\n", index, at.Format("3:04 PM"))
+		fmt.Printf("  %s\n", describeSegments(first))
+		fmt.Printf("  %s (new entry #1)\n", describeSegments(rest))
+		return first, nil
+	case flags.Changed("merge"):
+		other, _ := flags.GetInt("merge")
+		if other <= 0 || other > len(completed) {
+			return nil, fmt.Errorf("entry #%d not found (only %d completed entries)", other, len(completed))
+		}
+		otherEntry := entries[completed[other-1]]
+		if otherEntry.IsBilled() && !force {
+			return nil, fmt.Errorf("entry #%d is billed on invoice %s, use --force to amend it anyway", other, otherEntry.InvoiceID)
+		}
+		merged, err := store.MergeEntries(entry.ID, otherEntry.ID, updates)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("merged entries #%d and #%d: %s\n", index, other, describeSegments(merged))
+		return merged, nil
+	}
+
+	projectID := ""
+	if project != nil {
+		projectID = project.ID
+	}
+	updated, err := store.EditEntry(entry.ID, projectID, segments, updates)
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		fmt.Printf("amended entry #%d: moved to %s\n", index, project.Name)
+	}
+	if segments != nil {
+		fmt.Printf("amended entry #%d: %s\n", index, describeSegments(updated))
+	}
+	return updated, nil
+}
+
+// parseSegments parses comma-separated time ranges such as
+// "9:00-12:00,13:00-17:30" on date. A range ending before it starts ends
+// on the next day.
+func parseSegments(s string, date time.Time) ([]model.TimeSegment, error) {
+	var segments []model.TimeSegment
+	for _, r := range strings.Split(s, ",") {
+		startStr, endStr, ok := strings.Cut(r, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid range %q, use start-end such as 9:00-12:00", strings.TrimSpace(r))
+		}
+		start, err := parseTime(startStr, date)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTime(endStr, date)
+		if err != nil {
+			return nil, err
+		}
+		if end.Equal(start) {
+			return nil, fmt.Errorf("range %q is empty", strings.TrimSpace(r))
+		}
+		if end.Before(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		segments = append(segments, model.TimeSegment{Start: start, End: &end})
+	}
+	return segments, nil
+}
+
+// describeSegments summarises when an entry ran, such as
+// "Mon Jan 5 9:00 AM - 12:00 PM, 1:00 PM - 5:30 PM (7.50h)"
+func describeSegments(e *model.Entry) string {
+	var ranges []string
+	for _, seg := range e.Segments {
+		end := "now"
+		if seg.End != nil {
+			end = seg.End.Format("3:04 PM")
+		}
+		ranges = append(ranges, seg.Start.Format("3:04 PM")+" - "+end)
+	}
+	return fmt.Sprintf("%s %s (%.2fh)", e.StartTime().Format("Mon Jan 2"), strings.Join(ranges, ", "), e.Duration().Hours())
+}
+
 // isTerminal returns true if the file is a terminal
 func isTerminal(f *os.File) bool {
 	stat, err := f.Stat()
@@ -174,7 +418,7 @@ func isTerminal(f *os.File) bool {
 	return (stat.Mode() & os.ModeCharDevice) != 0
 }
 
-func runAmendInteractive(entries []model.Entry, completed []int) error {
+func runAmendInteractive(entries []model.Entry, completed []int, force bool) error {
 	fmt.Println("\nRecent time entries:")
 
 	// Show up to 20 entries
@@ -227,6 +471,9 @@ func runAmendInteractive(entries []model.Entry, completed []int) error {
 	// Get the entry
 	entryIdx := completed[index-1]
 	entry := entries[entryIdx]
+	if entry.IsBilled() && !force {
+		return fmt.Errorf("entry #%d is billed on invoice %s, use --force to amend it anyway", index, entry.InvoiceID)
+	}
 
 	project, _ := store.GetProject(entry.ProjectID)
 	projectName := entry.ProjectID
@@ -255,8 +502,8 @@ func runAmendInteractive(entries []model.Entry, completed []int) error {
 		return nil
 	}
 
-	// Update the entry
-	updated, err := store.AmendEntry(index, newNote)
+	// Update the entry by ID, as the list may have changed while prompting
+	updated, err := store.EditEntry(entry.ID, "", nil, func(e *model.Entry) { e.Note = newNote })
 	if err != nil {
 		return err
 	}
@@ -274,4 +521,17 @@ func init() {
 	amendCmd.Flags().StringP("note", "n", "", "New note text (skips prompt)")
 	amendCmd.Flags().Bool("clear", false, "Clear the note (set to empty)")
 	amendCmd.Flags().BoolP("last", "1", false, "Amend the most recent entry (index 1)")
+	addEntryFlags(amendCmd)
+	amendCmd.Flags().Bool("clear-rate", false, "Bill the entry at the project rate again")
+	amendCmd.Flags().Bool("clear-category", false, "Remove the entry's category")
+	amendCmd.Flags().Bool("billable", false, "Mark the entry as billable again")
+	amendCmd.Flags().Bool("force", false, "Amend the entry even if it has been invoiced")
+	amendCmd.Flags().StringP("project", "p", "", "Move the entry to another project")
+	amendCmd.Flags().String("start", "", "New start time (e.g., 9:00AM, 14:30)")
+	amendCmd.Flags().String("end", "", "New end time (e.g., 5:00PM, 17:00)")
+	amendCmd.Flags().Duration("shift", 0, "Move the whole entry by a duration (e.g., 30m, -1h)")
+	amendCmd.Flags().String("segments", "", "Replace the segments with time ranges (e.g., 9:00-12:00,13:00-17:30)")
+	amendCmd.Flags().String("split", "", "Split the entry in two at a time")
+	amendCmd.Flags().Int("merge", 0, "Merge with another entry, by index")
+	amendCmd.Flags().String("date", "", "Date for the times given (YYYY-MM-DD, default: the day the entry started)")
 }
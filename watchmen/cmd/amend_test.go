@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"watchmen/internal/storage"
 )
@@ -39,10 +40,10 @@ func TestAmendNonInteractiveBehavior(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Create two entries
-	store.StartEntry(project.ID, "first")
+	store.StartEntry(project.ID, "first", nil)
 	store.StopEntry("")
 
-	store.StartEntry(project.ID, "second")
+	store.StartEntry(project.ID, "second", nil)
 	store.StopEntry("")
 
 	// Index 1 should be the most recent (second entry)
@@ -86,7 +87,7 @@ func TestAmendClearNoteNonInteractive(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Create entry with note
-	store.StartEntry(project.ID, "has a note")
+	store.StartEntry(project.ID, "has a note", nil)
 	store.StopEntry("")
 
 	// Clear it (empty string)
@@ -112,7 +113,7 @@ func TestAmendNoCompletedEntries(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Start but don't stop an entry
-	store.StartEntry(project.ID, "running")
+	store.StartEntry(project.ID, "running", nil)
 
 	// Try to amend - should fail (no completed entries)
 	_, err = store.AmendEntry(1, "test")
@@ -133,7 +134,7 @@ func TestAmendIndexOutOfRange(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Create one entry
-	store.StartEntry(project.ID, "only one")
+	store.StartEntry(project.ID, "only one", nil)
 	store.StopEntry("")
 
 	// Try to amend index 2 (doesn't exist)
@@ -154,3 +155,31 @@ func TestAmendIndexOutOfRange(t *testing.T) {
 		t.Error("Expected error for negative index")
 	}
 }
+
+func TestParseSegments(t *testing.T) {
+	date := time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local)
+
+	segments, err := parseSegments("9:00-12:00, 1pm-5:30pm,23:00-1:00", date)
+	if err != nil {
+		t.Fatalf("parseSegments failed: %v", err)
+	}
+	want := []struct{ start, end time.Duration }{
+		{9 * time.Hour, 12 * time.Hour},
+		{13 * time.Hour, 17*time.Hour + 30*time.Minute},
+		{23 * time.Hour, 25 * time.Hour}, // ends the next day
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("Expected %d segments, got %d", len(want), len(segments))
+	}
+	for i, w := range want {
+		if !segments[i].Start.Equal(date.Add(w.start)) || !segments[i].End.Equal(date.Add(w.end)) {
+			t.Errorf("Segment %d = %v - %v", i+1, segments[i].Start, *segments[i].End)
+		}
+	}
+
+	for _, bad := range []string{"9:00", "9:00-noon", "9:00-9:00", ""} {
+		if _, err := parseSegments(bad, date); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/model"
+	"watchmen/internal/money"
+)
+
+var clientCmd = &cobra.Command{
+	Use:   "client",
+	Short: "Manage clients",
+	Long: `Manage the clients your projects belong to. A client holds the billing
+contact shown on its projects' invoices, the payment terms for projects
+that set none of their own, and the currency given to its new projects.
+
+Every project of a client can be billed on one invoice with
+'watchmen invoice --client'.`,
+}
+
+var clientAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a new client",
+	Long: `Add a new client, optionally with its billing contact, terms, currency and
+projects.
+
+Examples:
+  watchmen client add acme --company "Acme Inc" --email billing@acme.com
+  watchmen client add acme --terms 30 --currency EUR
+  watchmen client add acme --project website --project app`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		currencyCode, _ := cmd.Flags().GetString("currency")
+		terms, _ := cmd.Flags().GetInt("terms")
+		projectNames, _ := cmd.Flags().GetStringArray("project")
+
+		if _, err := store.GetClient(args[0]); err == nil {
+			return fmt.Errorf("client %q already exists", args[0])
+		}
+		if terms < 0 {
+			return fmt.Errorf("--terms must be zero or more days")
+		}
+		currency, err := money.Lookup(currencyCode)
+		if err != nil {
+			return err
+		}
+		var projects []*model.Project
+		for _, name := range projectNames {
+			p, err := store.GetProject(name)
+			if err != nil {
+				return fmt.Errorf("project %q not found", name)
+			}
+			projects = append(projects, p)
+		}
+		contact := &model.ContactInfo{}
+		if !applyContactFlags(cmd, contact) {
+			contact = nil
+		}
+
+		client, err := store.AddClient(args[0])
+		if err != nil {
+			return err
+		}
+		err = store.UpdateClient(client.ID, func(c *model.Client) {
+			c.Contact = contact
+			c.PaymentTerms = terms
+			if currency.Code != money.DefaultCurrency {
+				c.Currency = currency.Code
+			}
+			*client = *c
+		})
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			if err := store.UpdateProject(p.ID, func(p *model.Project) { p.ClientID = client.ID }); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Created client: %s (ID: %s)\n", client.Name, client.ID)
+		printClient(client)
+		return nil
+	},
+}
+
+var clientListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List all clients",
+	Aliases: []string{"ls"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clients := store.ListClients()
+		if len(clients) == 0 {
+			fmt.Println("No clients yet. Create one with: watchmen client add <name>")
+			return nil
+		}
+		fmt.Printf("%-16s %-20s %-8s %-7s  %s\n", "ID", "NAME", "CURRENCY", "TERMS", "PROJECTS")
+		fmt.Println("-------------------------------------------------------------------------------")
+		for _, c := range clients {
+			terms := "-"
+			if c.PaymentTerms > 0 {
+				terms = fmt.Sprintf("Net %d", c.PaymentTerms)
+			}
+			fmt.Printf("%-16s %-20s %-8s %-7s  %s\n", c.ID, c.Name, c.CurrencyCode(), terms, strings.Join(clientProjectNames(c.ID), ", "))
+		}
+		return nil
+	},
+}
+
+var clientSetCmd = &cobra.Command{
+	Use:   "set <client>",
+	Short: "Show or change a client",
+	Long: `Show a client, or change its name, billing contact, terms, currency or
+projects. Only the flags given change. A project belongs to one client, so
+adding it here moves it from any other.
+
+The currency applies to projects added to the client later; existing
+projects keep theirs, see 'watchmen project currency'.
+
+Examples:
+  watchmen client set acme                                  # Show the client
+  watchmen client set acme --name "Jane Doe" --address "1 Main St"
+  watchmen client set acme --terms 14
+  watchmen client set acme --add-project app --remove-project legacy
+  watchmen client set acme --rename "Acme Corp"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rename, _ := cmd.Flags().GetString("rename")
+		currencyCode, _ := cmd.Flags().GetString("currency")
+		terms, _ := cmd.Flags().GetInt("terms")
+		addProjects, _ := cmd.Flags().GetStringArray("add-project")
+		removeProjects, _ := cmd.Flags().GetStringArray("remove-project")
+
+		client, err := store.GetClient(args[0])
+		if err != nil {
+			return fmt.Errorf("client %q not found", args[0])
+		}
+
+		contact := &model.ContactInfo{}
+		if client.Contact != nil {
+			*contact = *client.Contact
+		}
+		contactSet := applyContactFlags(cmd, contact)
+		if !contactSet && rename == "" && currencyCode == "" && !cmd.Flags().Changed("terms") &&
+			len(addProjects) == 0 && len(removeProjects) == 0 {
+			fmt.Printf("Client: %s\n", client.Name)
+			fmt.Printf("  ID:   %s\n", client.ID)
+			printClient(client)
+			return nil
+		}
+
+		if rename != "" && rename != client.Name {
+			if _, err := store.GetClient(rename); err == nil {
+				return fmt.Errorf("client %q already exists", rename)
+			}
+		}
+		if terms < 0 {
+			return fmt.Errorf("--terms must be zero or more days")
+		}
+		var currency string
+		if currencyCode != "" {
+			c, err := money.Lookup(currencyCode)
+			if err != nil {
+				return err
+			}
+			currency = c.Code
+		}
+		var add, remove []*model.Project
+		for _, name := range addProjects {
+			p, err := store.GetProject(name)
+			if err != nil {
+				return fmt.Errorf("project %q not found", name)
+			}
+			add = append(add, p)
+		}
+		for _, name := range removeProjects {
+			p, err := store.GetProject(name)
+			if err != nil {
+				return fmt.Errorf("project %q not found", name)
+			}
+			if p.ClientID != client.ID {
+				return fmt.Errorf("project %s does not belong to %s", p.Name, client.Name)
+			}
+			remove = append(remove, p)
+		}
+
+		err = store.UpdateClient(client.ID, func(c *model.Client) {
+			if rename != "" {
+				c.Name = rename
+			}
+			if contactSet {
+				c.Contact = contact
+			}
+			if cmd.Flags().Changed("terms") {
+				c.PaymentTerms = terms
+			}
+			if currency != "" {
+				c.Currency = currency
+			}
+			*client = *c
+		})
+		if err != nil {
+			return err
+		}
+		for _, p := range add {
+			if err := store.UpdateProject(p.ID, func(p *model.Project) { p.ClientID = client.ID }); err != nil {
+				return err
+			}
+		}
+		for _, p := range remove {
+			if err := store.UpdateProject(p.ID, func(p *model.Project) { p.ClientID = "" }); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Client %s updated:\n", client.Name)
+		printClient(client)
+		return nil
+	},
+}
+
+// printClient prints a client's terms, currency, projects and contact
+func printClient(c *model.Client) {
+	if c.PaymentTerms > 0 {
+		fmt.Printf("  Terms: Net %d\n", c.PaymentTerms)
+	}
+	fmt.Printf("  Currency: %s\n", c.CurrencyCode())
+	if names := clientProjectNames(c.ID); len(names) > 0 {
+		fmt.Printf("  Projects: %s\n", strings.Join(names, ", "))
+	}
+	if c.Contact != nil {
+		printContactInfo(c.Contact)
+	}
+}
+
+// clientProjects returns the projects belonging to a client
+func clientProjects(clientID string) []model.Project {
+	var projects []model.Project
+	for _, p := range store.ListProjects() {
+		if p.ClientID == clientID {
+			projects = append(projects, p)
+		}
+	}
+	return projects
+}
+
+func clientProjectNames(clientID string) []string {
+	var names []string
+	for _, p := range clientProjects(clientID) {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// projectClient returns the client a project belongs to, or nil
+func projectClient(p *model.Project) *model.Client {
+	if p.ClientID == "" {
+		return nil
+	}
+	client, err := store.GetClient(p.ClientID)
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
+// addContactFlags adds the flags setting a client's billing contact
+func addContactFlags(c *cobra.Command) {
+	c.Flags().String("name", "", "Contact name")
+	c.Flags().String("title", "", "Contact's title")
+	c.Flags().String("company", "", "Company name")
+	c.Flags().String("address", "", "Address")
+	c.Flags().String("phone", "", "Phone number")
+	c.Flags().String("email", "", "Email address")
+	c.Flags().String("tax-id", "", "Client's VAT/GST registration number")
+}
+
+// applyContactFlags copies the contact flags given onto contact and
+// reports whether there were any
+func applyContactFlags(cmd *cobra.Command, contact *model.ContactInfo) bool {
+	set := false
+	for flag, field := range map[string]*string{
+		"name":    &contact.Name,
+		"title":   &contact.Title,
+		"company": &contact.Company,
+		"address": &contact.Address,
+		"phone":   &contact.Phone,
+		"email":   &contact.Email,
+		"tax-id":  &contact.TaxID,
+	} {
+		if value, _ := cmd.Flags().GetString(flag); value != "" {
+			*field = value
+			set = true
+		}
+	}
+	return set
+}
+
+func init() {
+	clientAddCmd.Flags().String("currency", money.DefaultCurrency, "Currency for the client's new projects (e.g. USD, EUR, GBP)")
+	clientAddCmd.Flags().Int("terms", 0, "Days to pay invoices for projects without terms of their own")
+	clientAddCmd.Flags().StringArray("project", nil, "Project belonging to the client (repeatable)")
+	addContactFlags(clientAddCmd)
+
+	clientSetCmd.Flags().String("rename", "", "New name for the client")
+	clientSetCmd.Flags().String("currency", "", "Currency for the client's new projects")
+	clientSetCmd.Flags().Int("terms", 0, "Days to pay invoices for projects without terms of their own, 0 for none")
+	clientSetCmd.Flags().StringArray("add-project", nil, "Move a project to the client (repeatable)")
+	clientSetCmd.Flags().StringArray("remove-project", nil, "Take a project from the client (repeatable)")
+	addContactFlags(clientSetCmd)
+
+	clientCmd.AddCommand(clientAddCmd)
+	clientCmd.AddCommand(clientListCmd)
+	clientCmd.AddCommand(clientSetCmd)
+}
@@ -2,9 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/invoice"
 	"watchmen/internal/model"
+	"watchmen/internal/period"
 )
 
 var configCmd = &cobra.Command{
@@ -20,7 +27,8 @@ var configSetCmd = &cobra.Command{
 
 Examples:
   watchmen config set --name "John Doe" --email "john@example.com"
-  watchmen config set --name "Jane Smith" --title "Software Engineer" --company "Smith LLC" --address "123 Main St" --phone "555-1234" --email "jane@smith.com"`,
+  watchmen config set --name "Jane Smith" --title "Software Engineer" --company "Smith LLC" --address "123 Main St" --phone "555-1234" --email "jane@smith.com"
+  watchmen config set --tax-id "GB123456789"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name, _ := cmd.Flags().GetString("name")
 		title, _ := cmd.Flags().GetString("title")
@@ -28,8 +36,9 @@ Examples:
 		address, _ := cmd.Flags().GetString("address")
 		phone, _ := cmd.Flags().GetString("phone")
 		email, _ := cmd.Flags().GetString("email")
+		taxID, _ := cmd.Flags().GetString("tax-id")
 
-		if name == "" && title == "" && company == "" && address == "" && phone == "" && email == "" {
+		if name == "" && title == "" && company == "" && address == "" && phone == "" && email == "" && taxID == "" {
 			return fmt.Errorf("provide at least one field to set")
 		}
 
@@ -59,6 +68,9 @@ Examples:
 		if email != "" {
 			contact.Email = email
 		}
+		if taxID != "" {
+			contact.TaxID = taxID
+		}
 
 		if err := store.SetUserContact(contact); err != nil {
 			return err
@@ -85,6 +97,538 @@ var configShowCmd = &cobra.Command{
 	},
 }
 
+var configNumberingCmd = &cobra.Command{
+	Use:   "numbering",
+	Short: "Set how invoices are numbered",
+	Long: `Set the scheme used to number invoices saved without --number.
+
+The template may use {year}, {seq} and {project}, and must include {seq}.
+The sequence counts up across all invoices, or restarts each year with
+--yearly. With no flags, shows the current scheme and the next number.
+
+Examples:
+  watchmen config numbering --template "INV-{year}-{seq}" --yearly --digits 4
+  watchmen config numbering --template "{project}/{seq}"
+  watchmen config numbering --next 100   # Continue an existing sequence
+  watchmen config numbering --clear      # Back to date-based numbers`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		template, _ := cmd.Flags().GetString("template")
+		yearly, _ := cmd.Flags().GetBool("yearly")
+		digits, _ := cmd.Flags().GetInt("digits")
+		next, _ := cmd.Flags().GetInt("next")
+		clear, _ := cmd.Flags().GetBool("clear")
+		changed := false
+		for _, name := range []string{"template", "yearly", "digits", "next"} {
+			changed = changed || cmd.Flags().Changed(name)
+		}
+
+		if clear {
+			if changed {
+				return fmt.Errorf("cannot use --clear with other flags")
+			}
+			if err := store.UpdateSettings(func(s *model.Settings) { s.Numbering = nil }); err != nil {
+				return err
+			}
+			fmt.Println("Invoice numbering cleared")
+			return nil
+		}
+
+		now := time.Now()
+		if !changed {
+			settings := store.GetSettings()
+			if settings.Numbering == nil {
+				fmt.Println("No invoice numbering configured. Use 'watchmen config numbering --template' to set one.")
+				return nil
+			}
+			printNumbering(settings, now)
+			return nil
+		}
+
+		current := store.GetSettings().Numbering
+		numbering := model.Numbering{Template: template, Yearly: yearly, Digits: digits}
+		if current != nil {
+			if !cmd.Flags().Changed("template") {
+				numbering.Template = current.Template
+			}
+			if !cmd.Flags().Changed("yearly") {
+				numbering.Yearly = current.Yearly
+			}
+			if !cmd.Flags().Changed("digits") {
+				numbering.Digits = current.Digits
+			}
+		}
+		if !strings.Contains(numbering.Template, "{seq}") {
+			return fmt.Errorf("template must include {seq}")
+		}
+		if numbering.Yearly && !strings.Contains(numbering.Template, "{year}") {
+			return fmt.Errorf("template must include {year} when numbering restarts each year")
+		}
+		if numbering.Digits < 0 || numbering.Digits > 9 {
+			return fmt.Errorf("--digits must be between 0 and 9")
+		}
+		if cmd.Flags().Changed("next") && next < 1 {
+			return fmt.Errorf("--next must be 1 or more")
+		}
+
+		var settings *model.Settings
+		err := store.UpdateSettings(func(s *model.Settings) {
+			s.Numbering = &numbering
+			if cmd.Flags().Changed("next") {
+				if s.InvoiceSeq == nil {
+					s.InvoiceSeq = make(map[string]int)
+				}
+				s.InvoiceSeq[numbering.CounterKey(now)] = next - 1
+			}
+			settings = s
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Invoice numbering updated:")
+		printNumbering(settings, now)
+		return nil
+	},
+}
+
+var configPDFCmd = &cobra.Command{
+	Use:   "pdf",
+	Short: "Set the look of PDF invoices",
+	Long: `Set the font, logo, letterhead colour and page size used for PDF invoices.
+With no flags, shows the current settings.
+
+PDFs are set in the bundled DejaVu Sans unless you give a TrueType font,
+for example to cover scripts DejaVu lacks.
+
+Examples:
+  watchmen config pdf --logo ~/logo.png --color "#1f4e79"
+  watchmen config pdf --page-size Letter
+  watchmen config pdf --font NotoSansJP-Regular.ttf --bold-font NotoSansJP-Bold.ttf
+  watchmen config pdf --clear                  # Back to the defaults`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clear, _ := cmd.Flags().GetBool("clear")
+		changed := false
+		for _, name := range []string{"font", "bold-font", "logo", "color", "page-size"} {
+			changed = changed || cmd.Flags().Changed(name)
+		}
+
+		if clear {
+			if changed {
+				return fmt.Errorf("cannot use --clear with other flags")
+			}
+			if err := store.UpdateSettings(func(s *model.Settings) { s.PDF = nil }); err != nil {
+				return err
+			}
+			fmt.Println("PDF settings cleared")
+			return nil
+		}
+
+		pdf := model.PDFSettings{}
+		if current := store.GetSettings().PDF; current != nil {
+			pdf = *current
+		}
+		if !changed {
+			printPDFSettings(&pdf)
+			return nil
+		}
+
+		for _, f := range []struct {
+			flag string
+			exts []string
+			dst  *string
+		}{
+			{"font", []string{".ttf"}, &pdf.Font},
+			{"bold-font", []string{".ttf"}, &pdf.BoldFont},
+			{"logo", []string{".png", ".jpg", ".jpeg", ".gif"}, &pdf.Logo},
+		} {
+			if !cmd.Flags().Changed(f.flag) {
+				continue
+			}
+			path, _ := cmd.Flags().GetString(f.flag)
+			if path == "" {
+				*f.dst = ""
+				continue
+			}
+			path, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(f.exts, strings.ToLower(filepath.Ext(path))) {
+				return fmt.Errorf("--%s must be a %s file", f.flag, strings.Join(f.exts, ", "))
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("--%s: %w", f.flag, err)
+			}
+			*f.dst = path
+		}
+		if cmd.Flags().Changed("color") {
+			pdf.Color, _ = cmd.Flags().GetString("color")
+			if pdf.Color != "" {
+				if _, _, _, err := invoice.ParseColor(pdf.Color); err != nil {
+					return err
+				}
+			}
+		}
+		if cmd.Flags().Changed("page-size") {
+			size, _ := cmd.Flags().GetString("page-size")
+			pageSize, err := parsePageSize(size)
+			if err != nil {
+				return err
+			}
+			pdf.PageSize = pageSize
+		}
+		if pdf.BoldFont != "" && pdf.Font == "" {
+			return fmt.Errorf("--bold-font requires --font")
+		}
+
+		if err := store.UpdateSettings(func(s *model.Settings) { s.PDF = &pdf }); err != nil {
+			return err
+		}
+		fmt.Println("PDF settings updated:")
+		printPDFSettings(&pdf)
+		return nil
+	},
+}
+
+// parsePageSize checks a page size given on the command line
+func parsePageSize(s string) (string, error) {
+	for _, size := range []string{model.PageA4, model.PageLetter} {
+		if strings.EqualFold(s, size) {
+			return size, nil
+		}
+	}
+	return "", fmt.Errorf("invalid page size %q, use A4 or Letter", s)
+}
+
+func printPDFSettings(pdf *model.PDFSettings) {
+	font := "DejaVu Sans (bundled)"
+	if pdf.Font != "" {
+		font = pdf.Font
+	}
+	fmt.Printf("  Font:      %s\n", font)
+	if pdf.BoldFont != "" {
+		fmt.Printf("  Bold font: %s\n", pdf.BoldFont)
+	}
+	if pdf.Logo != "" {
+		fmt.Printf("  Logo:      %s\n", pdf.Logo)
+	}
+	if pdf.Color != "" {
+		fmt.Printf("  Colour:    %s\n", pdf.Color)
+	}
+	size := pdf.PageSize
+	if size == "" {
+		size = model.PageA4
+	}
+	fmt.Printf("  Page size: %s\n", size)
+}
+
+var configPaymentCmd = &cobra.Command{
+	Use:   "payment",
+	Short: "Set the payment instructions shown on invoices",
+	Long: `Set how clients should pay you, such as bank details or a payment page,
+shown at the foot of every invoice. With no flags, shows the current
+instructions.
+
+Examples:
+  watchmen config payment --instructions "Bank: Example Bank
+IBAN: GB00 EXMP 0000 0000 0000 00"
+  watchmen config payment --link https://pay.example.com/me
+  watchmen config payment --clear`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		instructions, _ := cmd.Flags().GetString("instructions")
+		link, _ := cmd.Flags().GetString("link")
+		clear, _ := cmd.Flags().GetBool("clear")
+		changed := cmd.Flags().Changed("instructions") || cmd.Flags().Changed("link")
+
+		if clear {
+			if changed {
+				return fmt.Errorf("cannot use --clear with other flags")
+			}
+			if err := store.UpdateSettings(func(s *model.Settings) { s.Payment = nil }); err != nil {
+				return err
+			}
+			fmt.Println("Payment instructions cleared")
+			return nil
+		}
+
+		payment := model.PaymentInfo{}
+		if current := store.GetSettings().Payment; current != nil {
+			payment = *current
+		}
+		if !changed {
+			if payment == (model.PaymentInfo{}) {
+				fmt.Println("No payment instructions configured. Use 'watchmen config payment --instructions' to add them.")
+				return nil
+			}
+			printPaymentInfo(&payment)
+			return nil
+		}
+
+		if cmd.Flags().Changed("instructions") {
+			payment.Instructions = strings.TrimSpace(instructions)
+		}
+		if cmd.Flags().Changed("link") {
+			payment.Link = strings.TrimSpace(link)
+		}
+		err := store.UpdateSettings(func(s *model.Settings) {
+			s.Payment = &payment
+			if payment == (model.PaymentInfo{}) {
+				s.Payment = nil
+			}
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Payment instructions updated:")
+		printPaymentInfo(&payment)
+		return nil
+	},
+}
+
+var configTimerCmd = &cobra.Command{
+	Use:   "timer",
+	Short: "Set how timers are warned about and paused",
+	Long: `Set when watchmen warns about a timer left running. 'status' and 'start'
+warn once the running segment is older than --warn-after hours (default 8,
+or a negative value to turn the warning off) or began on an earlier day.
+With --max-segment-hours set, 'stop' asks for confirmation before saving a
+longer segment. With --pause-per-project, each project may have a paused
+entry, to resume by name, while another is running. With no flags, shows
+the current settings.
+
+Examples:
+  watchmen config timer --warn-after 10
+  watchmen config timer --max-segment-hours 12
+  watchmen config timer --pause-per-project
+  watchmen config timer --clear`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		warnAfter, _ := cmd.Flags().GetFloat64("warn-after")
+		maxSegment, _ := cmd.Flags().GetFloat64("max-segment-hours")
+		clear, _ := cmd.Flags().GetBool("clear")
+		changed := cmd.Flags().Changed("warn-after") || cmd.Flags().Changed("max-segment-hours") ||
+			cmd.Flags().Changed("pause-per-project")
+
+		if clear {
+			if changed {
+				return fmt.Errorf("cannot use --clear with other flags")
+			}
+			if len(store.PausedEntries()) > 1 {
+				return fmt.Errorf("several entries are paused, resume and stop all but one first")
+			}
+			if err := store.UpdateSettings(func(s *model.Settings) { s.Timer = nil }); err != nil {
+				return err
+			}
+			fmt.Println("Timer settings reset to defaults")
+			return nil
+		}
+
+		timer := model.TimerSettings{}
+		if current := store.GetSettings().Timer; current != nil {
+			timer = *current
+		}
+		if !changed {
+			printTimerSettings(&timer)
+			return nil
+		}
+
+		if cmd.Flags().Changed("warn-after") {
+			timer.WarnAfterHours = warnAfter
+		}
+		if cmd.Flags().Changed("max-segment-hours") {
+			if maxSegment < 0 {
+				return fmt.Errorf("--max-segment-hours cannot be negative")
+			}
+			timer.MaxSegmentHours = maxSegment
+		}
+		if cmd.Flags().Changed("pause-per-project") {
+			timer.PausePerProject, _ = cmd.Flags().GetBool("pause-per-project")
+			if !timer.PausePerProject && len(store.PausedEntries()) > 1 {
+				return fmt.Errorf("several entries are paused, resume and stop all but one first")
+			}
+		}
+		err := store.UpdateSettings(func(s *model.Settings) {
+			s.Timer = &timer
+			if timer == (model.TimerSettings{}) {
+				s.Timer = nil
+			}
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Timer settings updated:")
+		printTimerSettings(&timer)
+		return nil
+	},
+}
+
+var configGoalsCmd = &cobra.Command{
+	Use:   "goals",
+	Short: "Set daily and weekly billable-hour goals",
+	Long: `Set targets for billable hours, which 'watchmen stats' measures
+utilisation against. The weekly goal is spread evenly over the days of a
+period; with only a daily goal, each weekday counts and weekends do not.
+With no flags, shows the current goals.
+
+Examples:
+  watchmen config goals --weekly 30
+  watchmen config goals --daily 6
+  watchmen config goals --clear`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		daily, _ := cmd.Flags().GetFloat64("daily")
+		weekly, _ := cmd.Flags().GetFloat64("weekly")
+		clear, _ := cmd.Flags().GetBool("clear")
+		changed := cmd.Flags().Changed("daily") || cmd.Flags().Changed("weekly")
+
+		if clear {
+			if changed {
+				return fmt.Errorf("cannot use --clear with other flags")
+			}
+			if err := store.UpdateSettings(func(s *model.Settings) { s.Goals = nil }); err != nil {
+				return err
+			}
+			fmt.Println("Goals removed")
+			return nil
+		}
+
+		goals := model.Goals{}
+		if current := store.GetSettings().Goals; current != nil {
+			goals = *current
+		}
+		if !changed {
+			if goals == (model.Goals{}) {
+				fmt.Println("No goals set")
+				return nil
+			}
+			printGoals(&goals)
+			return nil
+		}
+
+		if daily < 0 || weekly < 0 {
+			return fmt.Errorf("goals cannot be negative")
+		}
+		if cmd.Flags().Changed("daily") {
+			goals.DailyHours = daily
+		}
+		if cmd.Flags().Changed("weekly") {
+			goals.WeeklyHours = weekly
+		}
+		err := store.UpdateSettings(func(s *model.Settings) {
+			s.Goals = &goals
+			if goals == (model.Goals{}) {
+				s.Goals = nil
+			}
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Goals updated:")
+		printGoals(&goals)
+		return nil
+	},
+}
+
+var configTimezoneCmd = &cobra.Command{
+	Use:   "timezone [zone]",
+	Short: "Show or set your home timezone",
+	Long: `Show or set the timezone days are counted in: which day an entry was
+worked on, and where a day, week or month given to a command starts and
+ends. Without one, the system's timezone is used. A project can count its
+days in a timezone of its own, see 'watchmen project timezone'.
+
+Examples:
+  watchmen config timezone                    # Show the home timezone
+  watchmen config timezone America/New_York
+  watchmen config timezone --clear            # Back to the system's`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clearZone, _ := cmd.Flags().GetBool("clear")
+
+		if len(args) == 0 && !clearZone {
+			if zone := store.GetSettings().Timezone; zone != "" {
+				fmt.Printf("Home timezone: %s\n", zone)
+			} else {
+				fmt.Printf("Home timezone: %s (system)\n", time.Local)
+			}
+			return nil
+		}
+		if len(args) == 1 && clearZone {
+			return fmt.Errorf("cannot use --clear with a timezone")
+		}
+
+		zone := ""
+		if !clearZone {
+			loc, err := period.Location(args[0])
+			if err != nil {
+				return err
+			}
+			zone = loc.String()
+		}
+		if err := store.UpdateSettings(func(s *model.Settings) { s.Timezone = zone }); err != nil {
+			return err
+		}
+		if zone == "" {
+			fmt.Println("Home timezone removed, using the system's")
+		} else {
+			fmt.Printf("Home timezone set to %s\n", zone)
+		}
+		return nil
+	},
+}
+
+func printGoals(g *model.Goals) {
+	if g.DailyHours > 0 {
+		fmt.Printf("  Daily:  %gh billable\n", g.DailyHours)
+	}
+	if g.WeeklyHours > 0 {
+		fmt.Printf("  Weekly: %gh billable\n", g.WeeklyHours)
+	}
+}
+
+func printTimerSettings(t *model.TimerSettings) {
+	if d := t.WarnAfter(); d > 0 {
+		fmt.Printf("  Warn after:  %gh\n", d.Hours())
+	} else {
+		fmt.Printf("  Warn after:  off\n")
+	}
+	if d := t.MaxSegment(); d > 0 {
+		fmt.Printf("  Max segment: %gh\n", d.Hours())
+	} else {
+		fmt.Printf("  Max segment: no limit\n")
+	}
+	if t.PausePerProject {
+		fmt.Printf("  Paused:      one entry per project\n")
+	} else {
+		fmt.Printf("  Paused:      one entry at a time\n")
+	}
+}
+
+func printPaymentInfo(p *model.PaymentInfo) {
+	for _, line := range strings.Split(p.Instructions, "\n") {
+		if line != "" {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	if p.Link != "" {
+		fmt.Printf("  Link: %s\n", p.Link)
+	}
+}
+
+// printNumbering shows the numbering scheme and the number the next invoice
+// would be given
+func printNumbering(settings *model.Settings, now time.Time) {
+	n := settings.Numbering
+	counter := "global"
+	if n.Yearly {
+		counter = "restarts each year"
+	}
+	fmt.Printf("  Template: %s\n", n.Template)
+	fmt.Printf("  Counter:  %s\n", counter)
+	if n.Digits > 0 {
+		fmt.Printf("  Digits:   %d\n", n.Digits)
+	}
+	seq := settings.InvoiceSeq[n.CounterKey(now)] + 1
+	fmt.Printf("  Next:     %s\n", n.Format(seq, now, "{project}"))
+}
+
 func printContactInfo(c *model.ContactInfo) {
 	if c.Name != "" {
 		fmt.Printf("  Name:    %s\n", c.Name)
@@ -104,6 +648,9 @@ func printContactInfo(c *model.ContactInfo) {
 	if c.Email != "" {
 		fmt.Printf("  Email:   %s\n", c.Email)
 	}
+	if c.TaxID != "" {
+		fmt.Printf("  Tax ID:  %s\n", c.TaxID)
+	}
 }
 
 func init() {
@@ -113,7 +660,42 @@ func init() {
 	configSetCmd.Flags().String("address", "", "Your address")
 	configSetCmd.Flags().String("phone", "", "Your phone number")
 	configSetCmd.Flags().String("email", "", "Your email address")
+	configSetCmd.Flags().String("tax-id", "", "Your VAT/GST registration number, shown on invoices")
+
+	configNumberingCmd.Flags().String("template", "", "Number template using {year}, {seq} and {project}")
+	configNumberingCmd.Flags().Bool("yearly", false, "Restart the sequence each year")
+	configNumberingCmd.Flags().Int("digits", 0, "Zero-pad {seq} to this many digits")
+	configNumberingCmd.Flags().Int("next", 0, "Number the next invoice in the sequence")
+	configNumberingCmd.Flags().Bool("clear", false, "Remove the numbering scheme")
+
+	configPDFCmd.Flags().String("font", "", "TrueType font file for invoice text")
+	configPDFCmd.Flags().String("bold-font", "", "TrueType font file for bold text (default: --font)")
+	configPDFCmd.Flags().String("logo", "", "PNG, JPEG or GIF logo shown top right")
+	configPDFCmd.Flags().String("color", "", "Letterhead colour as #rrggbb")
+	configPDFCmd.Flags().String("page-size", "", "Page size: A4 or Letter")
+	configPDFCmd.Flags().Bool("clear", false, "Reset to the bundled font, no logo and A4")
+
+	configPaymentCmd.Flags().String("instructions", "", "Payment instructions, such as bank details (may span lines)")
+	configPaymentCmd.Flags().String("link", "", "URL of your online payment page")
+	configPaymentCmd.Flags().Bool("clear", false, "Remove the payment instructions")
+
+	configTimerCmd.Flags().Float64("warn-after", 0, "Warn when a segment has run this many hours (negative for never)")
+	configTimerCmd.Flags().Float64("max-segment-hours", 0, "Ask before stop saves a longer segment (0 for no limit)")
+	configTimerCmd.Flags().Bool("pause-per-project", false, "Allow a paused entry on each project (--pause-per-project=false to turn off)")
+	configTimerCmd.Flags().Bool("clear", false, "Reset to the defaults")
+
+	configGoalsCmd.Flags().Float64("daily", 0, "Billable hours to aim for each weekday (0 to remove)")
+	configGoalsCmd.Flags().Float64("weekly", 0, "Billable hours to aim for each week (0 to remove)")
+	configGoalsCmd.Flags().Bool("clear", false, "Remove both goals")
+
+	configTimezoneCmd.Flags().Bool("clear", false, "Use the system's timezone")
 
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configNumberingCmd)
+	configCmd.AddCommand(configPDFCmd)
+	configCmd.AddCommand(configPaymentCmd)
+	configCmd.AddCommand(configTimerCmd)
+	configCmd.AddCommand(configGoalsCmd)
+	configCmd.AddCommand(configTimezoneCmd)
 }
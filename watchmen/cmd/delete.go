@@ -16,9 +16,12 @@ var deleteCmd = &cobra.Command{
 Examples:
   watchmen delete 1        # Delete most recent entry (interactive confirmation)
   watchmen delete 1 --yes  # Skip confirmation
-  watchmen delete 2        # Delete second most recent entry`,
+  watchmen delete 2        # Delete second most recent entry
+
+Entries already on an invoice are only deleted with --force.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		yes, _ := cmd.Flags().GetBool("yes")
+		force, _ := cmd.Flags().GetBool("force")
 
 		if len(args) == 0 {
 			return fmt.Errorf("entry index required (e.g. watchmen delete 1)")
@@ -51,6 +54,9 @@ Examples:
 
 		entryIdx := completed[index-1]
 		entry := entries[entryIdx]
+		if entry.IsBilled() && !force {
+			return fmt.Errorf("entry #%d is billed on invoice %s, use --force to delete it anyway", index, entry.InvoiceID)
+		}
 
 		project, _ := store.GetProject(entry.ProjectID)
 		projectName := entry.ProjectID
@@ -66,6 +72,9 @@ Examples:
 		if entry.Note != "" {
 			fmt.Printf("  Note:     %q\n", entry.Note)
 		}
+		if entry.IsBilled() {
+			fmt.Printf("  Invoice:  %s\n", entry.InvoiceID)
+		}
 
 		if !yes {
 			fmt.Print("\nDelete this entry? [y/N] ")
@@ -88,4 +97,5 @@ Examples:
 
 func init() {
 	deleteCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompt")
+	deleteCmd.Flags().Bool("force", false, "Delete the entry even if it has been invoiced")
 }
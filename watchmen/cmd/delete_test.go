@@ -18,9 +18,9 @@ func TestDeleteEntryByIndex(t *testing.T) {
 	project, _ := s.AddProject("Test", 100, "")
 
 	// Create two completed entries
-	s.StartEntry(project.ID, "first entry")
+	s.StartEntry(project.ID, "first entry", nil)
 	s.StopEntry("")
-	s.StartEntry(project.ID, "second entry")
+	s.StartEntry(project.ID, "second entry", nil)
 	s.StopEntry("")
 
 	// Delete most recent (index 1 = "second entry")
@@ -82,7 +82,7 @@ func TestDeleteCommandNonInteractive(t *testing.T) {
 	}
 
 	project, _ := store.AddProject("Test", 100, "")
-	store.StartEntry(project.ID, "to delete")
+	store.StartEntry(project.ID, "to delete", nil)
 	store.StopEntry("")
 
 	// Execute delete command with --yes flag
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/doctor"
+	"watchmen/internal/model"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check your data for overlapping time and other problems",
+	Long: `Check the data file for problems that would otherwise end up in reports
+and invoices: entries sharing time, segments that end before they start or
+are out of order, open segments on stopped entries, and entries, invoices
+and expenses whose project or invoice no longer exists.
+
+With --fix, the safe cases are repaired: segments are put in order, stray
+open segments are dropped when the entry has time of its own to keep, and
+entries and expenses billed on an invoice that no longer exists are
+released. Everything else is reported for you to fix with 'watchmen amend' or 'watchmen delete'.
+
+Examples:
+  watchmen doctor
+  watchmen doctor --fix
+  watchmen doctor --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputJSON, _ := cmd.Flags().GetBool("json")
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		data, err := store.Snapshot()
+		if err != nil {
+			return err
+		}
+		problems := doctor.Check(data, time.Now())
+
+		fixedEntries, fixedExpenses := 0, 0
+		if fix {
+			if fixedEntries, fixedExpenses, err = fixProblems(data, problems); err != nil {
+				return err
+			}
+			if fixedEntries+fixedExpenses > 0 {
+				if data, err = store.Snapshot(); err != nil {
+					return err
+				}
+				problems = doctor.Check(data, time.Now())
+			}
+		}
+
+		if outputJSON {
+			if problems == nil {
+				problems = []doctor.Problem{}
+			}
+			output := map[string]interface{}{
+				"problems": problems,
+				"fixed":    fixedEntries + fixedExpenses,
+			}
+			jsonData, err := json.MarshalIndent(output, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(jsonData))
+			return nil
+		}
+
+		if fixedEntries > 0 {
+			fmt.Printf("Fixed %d entries\n", fixedEntries)
+		}
+		if fixedExpenses > 0 {
+			fmt.Printf("Released %d expenses from missing invoices\n", fixedExpenses)
+		}
+		if len(problems) == 0 {
+			fmt.Println("No problems found")
+			return nil
+		}
+		fixable := 0
+		fmt.Printf("Found %d problems:\n", len(problems))
+		for _, p := range problems {
+			marker := ""
+			if p.Fixable {
+				marker = " (fixable)"
+				fixable++
+			}
+			fmt.Printf("  %-18s %s%s\n", p.Kind, p.Message, marker)
+		}
+		if fixable > 0 {
+			fmt.Printf("\nRun 'watchmen doctor --fix' to repair the %d fixable problems.\n", fixable)
+		}
+		return nil
+	},
+}
+
+// fixProblems repairs the entries and expenses with fixable problems,
+// returning how many of each were changed. Each is repaired as stored when
+// it is updated, so changes made since the check are kept.
+func fixProblems(data *model.Data, problems []doctor.Problem) (int, int, error) {
+	invoices := make(map[string]bool)
+	for _, inv := range data.Invoices {
+		invoices[inv.ID] = true
+	}
+	invoiceExists := func(id string) bool { return invoices[id] }
+
+	var entryIDs, expenseIDs []string
+	for _, p := range problems {
+		switch {
+		case !p.Fixable:
+		case p.EntryID != "" && !slices.Contains(entryIDs, p.EntryID):
+			entryIDs = append(entryIDs, p.EntryID)
+		case p.ExpenseID != "" && !slices.Contains(expenseIDs, p.ExpenseID):
+			expenseIDs = append(expenseIDs, p.ExpenseID)
+		}
+	}
+
+	fixedEntries := 0
+	for _, id := range entryIDs {
+		changed := false
+		_, err := store.UpdateEntry(id, func(e *model.Entry) {
+			*e, changed = doctor.Fix(*e, invoiceExists)
+		})
+		if err != nil {
+			return fixedEntries, 0, err
+		}
+		if changed {
+			fixedEntries++
+		}
+	}
+	fixedExpenses := 0
+	for _, id := range expenseIDs {
+		changed := false
+		_, err := store.UpdateExpense(id, func(x *model.Expense) {
+			*x, changed = doctor.FixExpense(*x, invoiceExists)
+		})
+		if err != nil {
+			return fixedEntries, fixedExpenses, err
+		}
+		if changed {
+			fixedExpenses++
+		}
+	}
+	return fixedEntries, fixedExpenses, nil
+}
+
+func init() {
+	doctorCmd.Flags().Bool("json", false, "Output the problems as JSON")
+	doctorCmd.Flags().Bool("fix", false, "Repair the problems that are safe to fix")
+}
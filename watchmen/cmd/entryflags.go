@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+)
+
+// addEntryFlags adds the flags that set an entry's rate, category and
+// billable status
+func addEntryFlags(c *cobra.Command) {
+	c.Flags().Float64("rate", 0, "Hourly rate for this entry, overriding the project rate")
+	c.Flags().String("category", "", "Category or task for this entry (e.g. meetings, development)")
+	c.Flags().Bool("non-billable", false, "Mark the entry as non-billable, leaving it off invoices")
+}
+
+// entryUpdates reads the flags added by addEntryFlags, plus --billable,
+// --clear-rate and --clear-category where a command defines them. It
+// returns nil when none were set.
+func entryUpdates(cmd *cobra.Command, project *model.Project) (func(*model.Entry), error) {
+	flags := cmd.Flags()
+	if flags.Changed("billable") && flags.Changed("non-billable") {
+		return nil, fmt.Errorf("cannot use both --billable and --non-billable")
+	}
+	if flags.Changed("rate") && flags.Changed("clear-rate") {
+		return nil, fmt.Errorf("cannot use both --rate and --clear-rate")
+	}
+	if flags.Changed("category") && flags.Changed("clear-category") {
+		return nil, fmt.Errorf("cannot use both --category and --clear-category")
+	}
+
+	var updates []func(*model.Entry)
+
+	if flags.Changed("rate") {
+		major, _ := flags.GetFloat64("rate")
+		if major < 0 {
+			return nil, fmt.Errorf("--rate cannot be negative")
+		}
+		rate := money.FromMajor(major, project.CurrencyCode())
+		updates = append(updates, func(e *model.Entry) { e.Rate = &rate })
+	}
+	if clearRate, _ := flags.GetBool("clear-rate"); clearRate {
+		updates = append(updates, func(e *model.Entry) { e.Rate = nil })
+	}
+
+	if flags.Changed("category") {
+		category, _ := flags.GetString("category")
+		category = strings.TrimSpace(category)
+		updates = append(updates, func(e *model.Entry) { e.Category = category })
+	}
+	if clearCategory, _ := flags.GetBool("clear-category"); clearCategory {
+		updates = append(updates, func(e *model.Entry) { e.Category = "" })
+	}
+
+	if nonBillable, _ := flags.GetBool("non-billable"); nonBillable {
+		updates = append(updates, func(e *model.Entry) { e.NonBillable = true })
+	}
+	if billable, _ := flags.GetBool("billable"); billable {
+		updates = append(updates, func(e *model.Entry) { e.NonBillable = false })
+	}
+
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	return func(e *model.Entry) {
+		for _, update := range updates {
+			update(e)
+		}
+	}, nil
+}
+
+// printEntryDetails prints the rate, category and billable status of an
+// entry where they differ from the defaults
+func printEntryDetails(e *model.Entry, project *model.Project) {
+	if e.Rate != nil {
+		fmt.Printf("  Rate: %s/hr\n", money.Format(*e.Rate, project.CurrencyCode()))
+	}
+	if e.Category != "" {
+		fmt.Printf("  Category: %s\n", e.Category)
+	}
+	if !e.IsBillable() {
+		fmt.Println("  Non-billable")
+	}
+}
+
+// describeEntryDetails summarises an entry's rate, category and billable
+// status on one line
+func describeEntryDetails(e *model.Entry, project *model.Project) string {
+	rate := "project rate"
+	if e.Rate != nil {
+		rate = "rate " + money.Format(*e.Rate, project.CurrencyCode()) + "/hr"
+	}
+	category := "no category"
+	if e.Category != "" {
+		category = "category " + e.Category
+	}
+	billable := "billable"
+	if !e.IsBillable() {
+		billable = "non-billable"
+	}
+	return strings.Join([]string{rate, category, billable}, ", ")
+}
+
+// addEntryFilterFlags adds the flags that filter entries by category and
+// billable status
+func addEntryFilterFlags(c *cobra.Command) {
+	c.Flags().String("category", "", "Only entries in this category")
+	c.Flags().Bool("billable", false, "Only billable entries")
+	c.Flags().Bool("non-billable", false, "Only non-billable entries")
+}
+
+// filterEntries keeps the entries matching the flags added by
+// addEntryFilterFlags. Categories match case-insensitively.
+func filterEntries(cmd *cobra.Command, entries []model.Entry) ([]model.Entry, error) {
+	category, _ := cmd.Flags().GetString("category")
+	billable, _ := cmd.Flags().GetBool("billable")
+	nonBillable, _ := cmd.Flags().GetBool("non-billable")
+	if billable && nonBillable {
+		return nil, fmt.Errorf("cannot use both --billable and --non-billable")
+	}
+
+	var result []model.Entry
+	for _, e := range entries {
+		if category != "" && !strings.EqualFold(e.Category, category) {
+			continue
+		}
+		if billable && !e.IsBillable() {
+			continue
+		}
+		if nonBillable && e.IsBillable() {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// periodFlags reads --since and --until, and --week and --month where the
+// command has them
+func periodFlags(cmd *cobra.Command) period.Flags {
+	var f period.Flags
+	f.Since, _ = cmd.Flags().GetString("since")
+	f.Until, _ = cmd.Flags().GetString("until")
+	f.Week, _ = cmd.Flags().GetBool("week")
+	f.Month, _ = cmd.Flags().GetBool("month")
+	return f
+}
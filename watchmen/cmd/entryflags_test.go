@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/model"
+)
+
+func TestFilterEntries(t *testing.T) {
+	entries := []model.Entry{
+		{ID: "a", Category: "Meetings", NonBillable: true},
+		{ID: "b", Category: "design"},
+		{ID: "c"},
+		{ID: "d", Category: "meetings"},
+	}
+
+	tests := []struct {
+		name  string
+		flags []string
+		want  []string
+	}{
+		{"no filter", nil, []string{"a", "b", "c", "d"}},
+		{"category ignores case", []string{"--category", "MEETINGS"}, []string{"a", "d"}},
+		{"billable", []string{"--billable"}, []string{"b", "c", "d"}},
+		{"non-billable", []string{"--non-billable"}, []string{"a"}},
+		{"combined", []string{"--category", "meetings", "--billable"}, []string{"d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addEntryFilterFlags(cmd)
+			if err := cmd.ParseFlags(tt.flags); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			got, err := filterEntries(cmd, entries)
+			if err != nil {
+				t.Fatalf("filterEntries() error = %v", err)
+			}
+			var ids []string
+			for _, e := range got {
+				ids = append(ids, e.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("filterEntries() = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("filterEntries() = %v, want %v", ids, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	cmd := &cobra.Command{}
+	addEntryFilterFlags(cmd)
+	cmd.ParseFlags([]string{"--billable", "--non-billable"})
+	if _, err := filterEntries(cmd, entries); err == nil {
+		t.Error("filterEntries() should reject --billable with --non-billable")
+	}
+}
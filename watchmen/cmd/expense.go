@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+	"watchmen/internal/storage"
+)
+
+var expenseCmd = &cobra.Command{
+	Use:   "expense",
+	Short: "Manage billable expenses",
+	Long:  `Record billable expenses such as mileage or software licences. Expenses dated within an invoice's period are added to it as line items.`,
+}
+
+var expenseAddCmd = &cobra.Command{
+	Use:   "add <project> <amount> <description>",
+	Short: "Record a billable expense",
+	Long: `Record a billable expense against a project, in the project's currency.
+
+With --qty the amount is a unit price, e.g. a mileage rate.
+
+Examples:
+  watchmen expense add myproject 49.99 "IDE licence"
+  watchmen expense add myproject 0.67 "Mileage to client site" --qty 120
+  watchmen expense add myproject 230 "Train tickets" --date 2024-01-15`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dateStr, _ := cmd.Flags().GetString("date")
+		qty, _ := cmd.Flags().GetFloat64("qty")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		amount, err := money.Parse(args[1], project.CurrencyCode())
+		if err != nil {
+			return err
+		}
+		if amount <= 0 {
+			return fmt.Errorf("amount must be positive")
+		}
+		if qty < 0 {
+			return fmt.Errorf("--qty must be positive")
+		}
+
+		loc := projectLocation(project)
+		date := period.StartOfDay(time.Now(), loc)
+		if dateStr != "" {
+			date, err = period.ParseDay(dateStr, loc)
+			if err != nil {
+				return fmt.Errorf("invalid date format for --date, use YYYY-MM-DD")
+			}
+		}
+
+		expense, err := store.AddExpense(model.Expense{
+			ProjectID:   project.ID,
+			Date:        date,
+			Description: args[2],
+			Quantity:    qty,
+			UnitAmount:  amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Recorded expense on %s (ID: %s)\n", project.Name, expense.ID)
+		fmt.Printf("  Date:   %s\n", expense.Date.Format("Jan 2, 2006"))
+		fmt.Printf("  Item:   %s\n", expense.Description)
+		if expense.Quantity != 0 {
+			fmt.Printf("  Qty:    %g × %s\n", expense.Quantity, money.Format(expense.UnitAmount, project.CurrencyCode()))
+		}
+		fmt.Printf("  Amount: %s\n", money.Format(expense.Total(), project.CurrencyCode()))
+		return nil
+	},
+}
+
+var expenseListCmd = &cobra.Command{
+	Use:     "list [project]",
+	Short:   "List expenses",
+	Aliases: []string{"ls"},
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectFilter := ""
+		if len(args) == 1 {
+			project, err := store.GetProject(args[0])
+			if err != nil {
+				return fmt.Errorf("project %q not found", args[0])
+			}
+			projectFilter = project.ID
+		}
+
+		expenses := store.ListExpenses(projectFilter, nil, nil)
+		if len(expenses) == 0 {
+			fmt.Println("No expenses found")
+			return nil
+		}
+
+		fmt.Printf("%-16s %-12s %-12s %14s  %s\n", "ID", "DATE", "PROJECT", "AMOUNT", "DESCRIPTION")
+		fmt.Println("--------------------------------------------------------------------------------")
+		for _, e := range expenses {
+			projectName := e.ProjectID
+			currency := money.DefaultCurrency
+			if p, err := store.GetProject(e.ProjectID); err == nil {
+				projectName = p.Name
+				currency = p.CurrencyCode()
+			}
+			if len(projectName) > 12 {
+				projectName = projectName[:9] + "..."
+			}
+			fmt.Printf("%-16s %-12s %-12s %14s  %s\n",
+				e.ID,
+				e.Date.Format("Jan 2, 2006"),
+				projectName,
+				money.Format(e.Total(), currency),
+				e.Description)
+		}
+		return nil
+	},
+}
+
+var expenseDeleteCmd = &cobra.Command{
+	Use:   "delete <expense-id>",
+	Short: "Delete an expense",
+	Long: `Delete an expense.
+
+Expenses already on an invoice are only deleted with --force.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		for _, e := range store.ListExpenses("", nil, nil) {
+			if e.ID == args[0] && e.InvoiceID != "" && !force {
+				return fmt.Errorf("expense %s is billed on invoice %s, use --force to delete it anyway", e.ID, e.InvoiceID)
+			}
+		}
+
+		err := store.DeleteExpense(args[0])
+		if errors.Is(err, storage.ErrExpenseNotFound) {
+			return fmt.Errorf("expense %q not found", args[0])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted expense %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	expenseAddCmd.Flags().String("date", "", "Date of the expense (YYYY-MM-DD, default today)")
+	expenseAddCmd.Flags().Float64("qty", 0, "Quantity, making the amount a unit price (e.g. miles)")
+	expenseDeleteCmd.Flags().Bool("force", false, "Delete the expense even if it has been invoiced")
+
+	expenseCmd.AddCommand(expenseAddCmd)
+	expenseCmd.AddCommand(expenseListCmd)
+	expenseCmd.AddCommand(expenseDeleteCmd)
+}
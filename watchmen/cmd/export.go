@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/model"
+	"watchmen/internal/period"
+	"watchmen/internal/transfer"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [entries|projects|invoices]",
+	Short: "Export entries, projects or invoices as CSV or NDJSON",
+	Long: `Export data for spreadsheets or other tools, as CSV (the default) or
+newline-delimited JSON. Entries are exported unless projects or invoices
+are asked for. Exported entries can be read back with 'watchmen import
+watchmen'.
+
+Running entries are left out. Dates filter entries by start time and
+invoices by creation date.
+
+Examples:
+  watchmen export > entries.csv
+  watchmen export entries --project acme --since 2026-01-01 -o acme.csv
+  watchmen export invoices --format ndjson
+  watchmen export projects`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"entries", "projects", "invoices"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		outputFile, _ := cmd.Flags().GetString("output")
+		projectName, _ := cmd.Flags().GetString("project")
+		sinceStr, _ := cmd.Flags().GetString("since")
+		untilStr, _ := cmd.Flags().GetString("until")
+
+		kind := "entries"
+		if len(args) > 0 {
+			kind = args[0]
+		}
+		if format != "csv" && format != "ndjson" {
+			return fmt.Errorf("invalid format %q, use csv or ndjson", format)
+		}
+
+		var from, to *time.Time
+		if sinceStr != "" {
+			t, err := period.ParseDay(sinceStr, home)
+			if err != nil {
+				return fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
+			}
+			from = &t
+		}
+		if untilStr != "" {
+			t, err := period.ParseDay(untilStr, home)
+			if err != nil {
+				return fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
+			}
+			t = t.AddDate(0, 0, 1)
+			to = &t
+		}
+
+		var project *model.Project
+		if projectName != "" {
+			var err error
+			if project, err = store.GetProject(projectName); err != nil {
+				return fmt.Errorf("project %q not found", projectName)
+			}
+		}
+		projectID := ""
+		if project != nil {
+			projectID = project.ID
+		}
+
+		var table *transfer.Table
+		switch kind {
+		case "entries":
+			projects := make(map[string]*model.Project)
+			var entries []transfer.Entry
+			for _, e := range store.ListEntries(projectID, from, to) {
+				if !e.Completed {
+					continue
+				}
+				p, ok := projects[e.ProjectID]
+				if !ok {
+					var err error
+					if p, err = store.GetProject(e.ProjectID); err != nil {
+						p = &model.Project{ID: e.ProjectID, Name: e.ProjectID}
+					}
+					projects[e.ProjectID] = p
+				}
+				entries = append(entries, transfer.NewEntry(&e, p))
+			}
+			table = transfer.EntriesTable(entries)
+		case "projects":
+			var projects []model.Project
+			for _, p := range store.ListProjects() {
+				if project == nil || p.ID == project.ID {
+					projects = append(projects, p)
+				}
+			}
+			table = transfer.ProjectsTable(projects)
+		case "invoices":
+			var invoices []model.Invoice
+			for _, inv := range store.ListInvoices(projectID, "") {
+				if (from != nil && inv.CreatedAt.Before(*from)) || (to != nil && !inv.CreatedAt.Before(*to)) {
+					continue
+				}
+				invoices = append(invoices, inv)
+			}
+			table = transfer.InvoicesTable(invoices, time.Now())
+		default:
+			return fmt.Errorf("unknown export %q, use entries, projects or invoices", kind)
+		}
+
+		var out io.Writer = os.Stdout
+		if outputFile != "" {
+			f, err := os.Create(outputFile)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %v", err)
+			}
+			defer f.Close()
+			out = f
+		}
+		write := table.WriteCSV
+		if format == "ndjson" {
+			write = table.WriteNDJSON
+		}
+		if err := write(out); err != nil {
+			return err
+		}
+		if outputFile != "" {
+			fmt.Printf("Exported %d %s to %s\n", len(table.Rows), kind, outputFile)
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().String("format", "csv", "Output format: csv or ndjson")
+	exportCmd.Flags().StringP("output", "o", "", "Output file (default: stdout)")
+	exportCmd.Flags().StringP("project", "p", "", "Only export this project")
+	exportCmd.Flags().String("since", "", "Start date (YYYY-MM-DD)")
+	exportCmd.Flags().String("until", "", "End date, inclusive (YYYY-MM-DD)")
+}
@@ -0,0 +1,536 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/ics"
+	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+	"watchmen/internal/storage"
+	"watchmen/internal/transfer"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import time entries from other sources",
+}
+
+var importICSCmd = &cobra.Command{
+	Use:   "ics <file>",
+	Short: "Import calendar events as time entries",
+	Long: `Import events from an iCalendar (.ics) file as completed time entries.
+
+Events are assigned to projects by the rules set with 'watchmen import rule
+add', tried in order. Events no rule matches go to --project if given, and
+are skipped otherwise, and listed with project "-" by --dry-run. Each event
+is imported once: running the import again skips events already imported,
+by their UID.
+
+All-day, cancelled and upcoming events are skipped, as are recurring events,
+whose occurrences are not expanded. A changed occurrence of a recurring
+event is exported as an event of its own and is imported. Events overlapping
+time already tracked are skipped when importing.
+
+Examples:
+  watchmen import ics calendar.ics --dry-run       # Preview
+  watchmen import ics calendar.ics --since 2026-10-01
+  watchmen import ics work.ics --project acme      # Unmatched events to acme`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		projectName, _ := cmd.Flags().GetString("project")
+		calendarName, _ := cmd.Flags().GetString("calendar")
+		sinceStr, _ := cmd.Flags().GetString("since")
+		untilStr, _ := cmd.Flags().GetString("until")
+
+		var since, until time.Time
+		var err error
+		if sinceStr != "" {
+			if since, err = period.ParseDay(sinceStr, home); err != nil {
+				return fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
+			}
+		}
+		if untilStr != "" {
+			if until, err = period.ParseDay(untilStr, home); err != nil {
+				return fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
+			}
+			until = until.AddDate(0, 0, 1)
+		}
+
+		var fallback *model.Project
+		if projectName != "" {
+			if fallback, err = store.GetProject(projectName); err != nil {
+				return fmt.Errorf("project %q not found", projectName)
+			}
+		}
+
+		matcher, err := ics.NewMatcher(store.GetSettings().ImportRules)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		cal, err := ics.Parse(f, home)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", args[0], err)
+		}
+
+		imported := make(map[string]bool)
+		for _, e := range store.ListEntries("", nil, nil) {
+			if e.ExternalID != "" {
+				imported[e.ExternalID] = true
+			}
+		}
+
+		projects := make(map[string]*model.Project)
+		now := time.Now()
+		var count, seen, unmatched, skipped, overlapping int
+		var hours float64
+		for _, event := range cal.Events {
+			if calendarName != "" {
+				event.Calendar = calendarName
+			}
+			if (!since.IsZero() && event.Start.Before(since)) || (!until.IsZero() && !event.Start.Before(until)) {
+				continue
+			}
+			if event.AllDay || event.Cancelled() || event.Recurring || event.End.After(now) || event.Duration() <= 0 {
+				skipped++
+				continue
+			}
+			externalID := "ics:" + event.Key()
+			if imported[externalID] {
+				seen++
+				continue
+			}
+
+			rule := matcher.Match(&event)
+			if rule == nil && fallback != nil {
+				rule = &model.ImportRule{ProjectID: fallback.ID}
+			}
+			if rule == nil {
+				unmatched++
+				if dryRun {
+					printImportedEvent(&event, "-")
+				}
+				continue
+			}
+			project, ok := projects[rule.ProjectID]
+			if !ok {
+				if project, err = store.GetProject(rule.ProjectID); err != nil {
+					return fmt.Errorf("import rule for missing project %s", rule.ProjectID)
+				}
+				projects[rule.ProjectID] = project
+			}
+
+			imported[externalID] = true
+			count++
+			hours += event.Duration().Hours()
+			printImportedEvent(&event, project.Name)
+			if dryRun {
+				continue
+			}
+
+			_, err := store.LogEntry(project.ID, event.Summary, event.Start, event.End, func(e *model.Entry) {
+				e.ExternalID = externalID
+				e.Category = rule.Category
+				e.NonBillable = rule.NonBillable
+			})
+			if errors.Is(err, storage.ErrOverlap) {
+				fmt.Printf("    skipped, %v\n", err)
+				count--
+				hours -= event.Duration().Hours()
+				overlapping++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		verb := "Imported"
+		if dryRun {
+			verb = "Would import"
+		}
+		fmt.Printf("\n%s %d events (%.2f hours)\n", verb, count, hours)
+		if seen > 0 {
+			fmt.Printf("  %d already imported\n", seen)
+		}
+		if unmatched > 0 {
+			fmt.Printf("  %d matched no rule\n", unmatched)
+		}
+		if skipped > 0 {
+			fmt.Printf("  %d all-day, cancelled, recurring or upcoming skipped\n", skipped)
+		}
+		if overlapping > 0 {
+			fmt.Printf("  %d overlapping time already tracked skipped\n", overlapping)
+		}
+		return nil
+	},
+}
+
+func printImportedEvent(e *ics.Event, project string) {
+	printImportLine(e.Start, e.End, project, e.Summary)
+}
+
+// printImportLine shows one imported, or importable, entry
+func printImportLine(start, end time.Time, project, note string) {
+	if len(note) > 40 {
+		note = note[:37] + "..."
+	}
+	fmt.Printf("  %-12s %8s - %-8s %6.2fh  %-15s %s\n",
+		start.Format("Mon Jan 2"),
+		start.Format("3:04 PM"),
+		end.Format("3:04 PM"),
+		end.Sub(start).Hours(),
+		project,
+		note)
+}
+
+var importTogglCmd = &cobra.Command{
+	Use:   "toggl <file>",
+	Short: "Import entries from a Toggl Track CSV export",
+	Long: `Import time entries from a Toggl Track detailed report exported as CSV.
+The first tag of each entry becomes its category.
+` + recordImportHelp + `
+Examples:
+  watchmen import toggl Toggl_time_entries.csv --dry-run
+  watchmen import toggl export.csv --map "Acme Corp=acme" --map "Internal=admin"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRecordImport(func(r io.Reader) ([]transfer.Record, error) { return transfer.ReadToggl(r, home) }),
+}
+
+var importClockifyCmd = &cobra.Command{
+	Use:   "clockify <file>",
+	Short: "Import entries from a Clockify CSV export",
+	Long: `Import time entries from a Clockify detailed report exported as CSV. Dates
+are read month first, as Clockify writes them by default. The first tag of
+each entry becomes its category.
+` + recordImportHelp + `
+Examples:
+  watchmen import clockify Clockify_Time_Report.csv --dry-run
+  watchmen import clockify report.csv --project acme`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRecordImport(func(r io.Reader) ([]transfer.Record, error) { return transfer.ReadClockify(r, home) }),
+}
+
+var importWatchmenCmd = &cobra.Command{
+	Use:   "watchmen <file>",
+	Short: "Import entries exported by 'watchmen export'",
+	Long: `Import time entries written by 'watchmen export entries', as CSV or NDJSON,
+for example to merge data from another machine. A paused entry comes back
+as one stretch of the hours worked.
+` + recordImportHelp + `
+Examples:
+  watchmen import watchmen laptop.csv --dry-run
+  watchmen import watchmen entries.ndjson`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRecordImport(transfer.ReadWatchmen),
+}
+
+const recordImportHelp = `
+Entries go to the watchmen project with the same name, or the one given by
+--map. Entries for other projects go to --project if given, and are skipped
+otherwise. An entry is a duplicate, and skipped, if its project already has
+an entry starting in the same minute. Entries overlapping time already
+tracked are skipped when importing.
+`
+
+// runRecordImport returns the RunE of a command importing the entries read
+// from a file by read
+func runRecordImport(read func(io.Reader) ([]transfer.Record, error)) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		projectName, _ := cmd.Flags().GetString("project")
+		mappings, _ := cmd.Flags().GetStringArray("map")
+
+		names := make(map[string]string)
+		for _, m := range mappings {
+			from, to, ok := strings.Cut(m, "=")
+			if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+				return fmt.Errorf("invalid --map %q, use \"their project=watchmen project\"", m)
+			}
+			names[strings.ToLower(strings.TrimSpace(from))] = strings.TrimSpace(to)
+		}
+		var fallback *model.Project
+		if projectName != "" {
+			var err error
+			if fallback, err = store.GetProject(projectName); err != nil {
+				return fmt.Errorf("project %q not found", projectName)
+			}
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		records, err := read(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", args[0], err)
+		}
+
+		// Entries are duplicates if they start in the same minute on the
+		// same project
+		startKey := func(projectID string, start time.Time) string {
+			return projectID + "|" + start.Truncate(time.Minute).UTC().Format(time.RFC3339)
+		}
+		existing := make(map[string]bool)
+		for _, e := range store.ListEntries("", nil, nil) {
+			existing[startKey(e.ProjectID, e.StartTime())] = true
+		}
+
+		projects := make(map[string]*model.Project)
+		unknown := make(map[string]int)
+		var count, duplicates, invalid, overlapping int
+		var hours float64
+		for _, rec := range records {
+			name := rec.Project
+			if mapped, ok := names[strings.ToLower(name)]; ok {
+				name = mapped
+			}
+			project, ok := projects[name]
+			if !ok {
+				project, _ = store.GetProject(name)
+				if project == nil && name == rec.Project {
+					project = fallback
+				}
+				if project == nil && name != rec.Project {
+					return fmt.Errorf("project %q not found, mapped from %q", name, rec.Project)
+				}
+				projects[name] = project
+			}
+			if project == nil {
+				unknown[rec.Project]++
+				continue
+			}
+			if rec.Duration() <= 0 {
+				invalid++
+				continue
+			}
+			key := startKey(project.ID, rec.Start)
+			if existing[key] {
+				duplicates++
+				continue
+			}
+
+			var rate *int64
+			if rec.Rate != "" {
+				r, err := money.Parse(rec.Rate, project.CurrencyCode())
+				if err != nil {
+					return fmt.Errorf("line %d: invalid rate %q", rec.Line, rec.Rate)
+				}
+				rate = &r
+			}
+
+			existing[key] = true
+			count++
+			hours += rec.Duration().Hours()
+			printImportLine(rec.Start, rec.End, project.Name, rec.Note)
+			if dryRun {
+				continue
+			}
+
+			_, err := store.LogEntry(project.ID, rec.Note, rec.Start, rec.End, func(e *model.Entry) {
+				e.Category = rec.Category
+				e.NonBillable = !rec.Billable
+				e.Rate = rate
+			})
+			if errors.Is(err, storage.ErrOverlap) {
+				fmt.Printf("    skipped, %v\n", err)
+				count--
+				hours -= rec.Duration().Hours()
+				overlapping++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		verb := "Imported"
+		if dryRun {
+			verb = "Would import"
+		}
+		fmt.Printf("\n%s %d entries (%.2f hours)\n", verb, count, hours)
+		if duplicates > 0 {
+			fmt.Printf("  %d duplicates skipped\n", duplicates)
+		}
+		if invalid > 0 {
+			fmt.Printf("  %d without a positive duration skipped\n", invalid)
+		}
+		if overlapping > 0 {
+			fmt.Printf("  %d overlapping time already tracked skipped\n", overlapping)
+		}
+		if len(unknown) > 0 {
+			fmt.Println("  Skipped entries for projects not in watchmen (use --map or --project):")
+			for _, name := range sortedKeys(unknown) {
+				label := name
+				if label == "" {
+					label = "(no project)"
+				}
+				fmt.Printf("    %-24s %d\n", label, unknown[name])
+			}
+		}
+		return nil
+	}
+}
+
+var importRuleCmd = &cobra.Command{
+	Use:   "rule",
+	Short: "Manage the rules that map calendar events to projects",
+}
+
+var importRuleAddCmd = &cobra.Command{
+	Use:   "add <project>",
+	Short: "Add a rule mapping calendar events to a project",
+	Long: `Add a rule assigning imported calendar events to a project. Every condition
+given must match. Rules are tried in the order they were added.
+
+Examples:
+  watchmen import rule add acme --summary "acme|weekly sync"
+  watchmen import rule add acme --domain acme.com --category meetings
+  watchmen import rule add internal --calendar "Team" --non-billable`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		summary, _ := cmd.Flags().GetString("summary")
+		domain, _ := cmd.Flags().GetString("domain")
+		calendar, _ := cmd.Flags().GetString("calendar")
+		category, _ := cmd.Flags().GetString("category")
+		nonBillable, _ := cmd.Flags().GetBool("non-billable")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+		if summary == "" && domain == "" && calendar == "" {
+			return fmt.Errorf("provide at least one of --summary, --domain or --calendar")
+		}
+
+		rule := model.ImportRule{
+			ProjectID:   project.ID,
+			Summary:     summary,
+			Domain:      strings.ToLower(strings.TrimPrefix(domain, "@")),
+			Calendar:    calendar,
+			Category:    strings.TrimSpace(category),
+			NonBillable: nonBillable,
+		}
+		if _, err := regexp.Compile(summary); err != nil {
+			return fmt.Errorf("invalid --summary pattern: %v", err)
+		}
+
+		if err := store.UpdateSettings(func(s *model.Settings) {
+			s.ImportRules = append(s.ImportRules, rule)
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Added rule #%d: %s\n", len(store.GetSettings().ImportRules), describeImportRule(&rule))
+		return nil
+	},
+}
+
+var importRuleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List import rules in the order they are tried",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules := store.GetSettings().ImportRules
+		if len(rules) == 0 {
+			fmt.Println("No import rules. Use 'watchmen import rule add' to create one.")
+			return nil
+		}
+		for i, rule := range rules {
+			fmt.Printf("  %2d. %s\n", i+1, describeImportRule(&rule))
+		}
+		return nil
+	},
+}
+
+var importRuleRemoveCmd = &cobra.Command{
+	Use:   "remove <number>",
+	Short: "Remove an import rule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := strconv.Atoi(args[0])
+		rules := store.GetSettings().ImportRules
+		if err != nil || n < 1 || n > len(rules) {
+			return fmt.Errorf("no import rule #%s, see 'watchmen import rule list'", args[0])
+		}
+		removed := rules[n-1]
+
+		if err := store.UpdateSettings(func(s *model.Settings) {
+			s.ImportRules = append(s.ImportRules[:n-1:n-1], s.ImportRules[n:]...)
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Removed rule #%d: %s\n", n, describeImportRule(&removed))
+		return nil
+	},
+}
+
+// describeImportRule summarises a rule as its conditions and the project
+// they lead to
+func describeImportRule(r *model.ImportRule) string {
+	var conds []string
+	if r.Summary != "" {
+		conds = append(conds, fmt.Sprintf("summary ~ %q", r.Summary))
+	}
+	if r.Domain != "" {
+		conds = append(conds, "attendee @"+r.Domain)
+	}
+	if r.Calendar != "" {
+		conds = append(conds, fmt.Sprintf("calendar %q", r.Calendar))
+	}
+	target := r.ProjectID
+	if project, err := store.GetProject(r.ProjectID); err == nil {
+		target = project.Name
+	}
+	if r.Category != "" {
+		target += " [" + r.Category + "]"
+	}
+	if r.NonBillable {
+		target += " (non-billable)"
+	}
+	return strings.Join(conds, ", ") + " -> " + target
+}
+
+func init() {
+	importICSCmd.Flags().Bool("dry-run", false, "Show what would be imported without saving anything")
+	importICSCmd.Flags().StringP("project", "p", "", "Project for events no rule matches")
+	importICSCmd.Flags().String("calendar", "", "Calendar name for rules, overriding the file's own")
+	importICSCmd.Flags().String("since", "", "Only import events from this date (YYYY-MM-DD)")
+	importICSCmd.Flags().String("until", "", "Only import events up to and including this date (YYYY-MM-DD)")
+
+	importRuleAddCmd.Flags().String("summary", "", "Regular expression matched against the event title, ignoring case")
+	importRuleAddCmd.Flags().String("domain", "", "Email domain of any attendee or the organizer")
+	importRuleAddCmd.Flags().String("calendar", "", "Calendar name")
+	importRuleAddCmd.Flags().String("category", "", "Category for imported entries")
+	importRuleAddCmd.Flags().Bool("non-billable", false, "Mark imported entries as non-billable")
+
+	importRuleCmd.AddCommand(importRuleAddCmd)
+	importRuleCmd.AddCommand(importRuleListCmd)
+	importRuleCmd.AddCommand(importRuleRemoveCmd)
+
+	for _, c := range []*cobra.Command{importTogglCmd, importClockifyCmd, importWatchmenCmd} {
+		c.Flags().Bool("dry-run", false, "Show what would be imported without saving anything")
+		c.Flags().StringP("project", "p", "", "Project for entries whose project is not in watchmen")
+		c.Flags().StringArray("map", nil, "Import one project into another, as \"their name=watchmen project\" (repeatable)")
+	}
+
+	importCmd.AddCommand(importICSCmd)
+	importCmd.AddCommand(importTogglCmd)
+	importCmd.AddCommand(importClockifyCmd)
+	importCmd.AddCommand(importWatchmenCmd)
+	importCmd.AddCommand(importRuleCmd)
+}
@@ -1,20 +1,29 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
-	"sort"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/gitlog"
 	"watchmen/internal/invoice"
 	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+	"watchmen/internal/storage"
 )
 
 var invoiceCmd = &cobra.Command{
-	Use:   "invoice <project>",
-	Short: "Generate an invoice for a project",
-	Long: `Generate an invoice for time entries on a project.
+	Use:   "invoice [project]",
+	Short: "Generate an invoice for a project or client",
+	Long: `Generate an invoice for time entries on a project, or with --client on
+every project of a client.
 
 By default, generates a condensed invoice with a single line item (requires --desc).
 
@@ -24,13 +33,53 @@ Examples:
   watchmen invoice myproject --week -d "Weekly dev"     # This week's entries
   watchmen invoice myproject --since 2024-01-01 --until 2024-01-31 -d "Jan work"
   watchmen invoice myproject --pdf invoice.pdf -d "Dev" # Generate PDF
-  watchmen invoice myproject --one-shot -d "Dev"        # Auto-generate invoice + report`,
-	Args: cobra.ExactArgs(1),
+  watchmen invoice myproject --one-shot -d "Dev"        # Auto-generate invoice + report
+  watchmen invoice myproject -d "Dev" --discount 10%    # Take 10% off the subtotal
+  watchmen invoice myproject -d "Dev" --discount 250    # Take a flat 250.00 off
+  watchmen invoice myproject --detailed --by-category   # Add hours per category
+  watchmen invoice myproject -d "Dev" --template html -o invoice.html
+  watchmen invoice myproject -d "Design" --milestone Design  # Bill a fixed-price milestone
+  watchmen invoice --client acme -d "October"           # One invoice for all of acme's projects
+  watchmen invoice myproject --detailed --commits       # List the commits made during each entry
+
+Entries marked --non-billable are left off unless --include-non-billable
+is given. Saving the invoice marks its entries and expenses as billed, and
+later invoices skip them, so overlapping periods never bill time twice.
+Deleting the invoice with 'watchmen invoices delete' releases them again.
+
+Projects billed on a retainer or at a fixed price, see 'watchmen project
+model', charge the monthly fee with any overage, or the milestones given
+with --milestone, in place of hours × rate. Their time is still listed.
+The fee is charged once a month: a second invoice for a month already
+billed charges its time as overage.
+
+A client invoice, see 'watchmen client', lists each project's time under
+its name at the project's own rate and rounding. Its projects must all
+bill by the hour in the same currency and with the same tax.
+
+With --commits, a detailed invoice lists under each entry the commits made
+during it in the project's git repositories, set with 'watchmen project
+repos'. A one-shot report lists them under each day.
+
+Expenses recorded with 'watchmen expense add' that fall within the period
+are added as line items. Tax set with 'watchmen project tax' is charged on
+the subtotal after any discount. The due date comes from the project's
+payment terms, set with 'watchmen project terms', falling back to the
+client's, or --terms.
+
+PDFs use the font, logo, colour and page size set with 'watchmen config pdf',
+and every format ends with the payment instructions from
+'watchmen config payment'.
+
+Invoices can be rendered from your own templates, see 'watchmen template'.
+A project's default template, set with 'watchmen project template', is used
+unless --pdf or --markdown is given.
+
+Without --number, invoices are numbered from the scheme set with
+'watchmen config numbering', or INV-<project>-<date> if there is none.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		sinceStr, _ := cmd.Flags().GetString("since")
-		untilStr, _ := cmd.Flags().GetString("until")
-		thisWeek, _ := cmd.Flags().GetBool("week")
-		thisMonth, _ := cmd.Flags().GetBool("month")
+		clientName, _ := cmd.Flags().GetString("client")
 		pdfFile, _ := cmd.Flags().GetString("pdf")
 		invoiceNum, _ := cmd.Flags().GetString("number")
 		poNumber, _ := cmd.Flags().GetString("po")
@@ -41,6 +90,16 @@ Examples:
 		condensedDesc, _ := cmd.Flags().GetString("desc")
 		noSave, _ := cmd.Flags().GetBool("no-save")
 		oneShot, _ := cmd.Flags().GetBool("one-shot")
+		discountStr, _ := cmd.Flags().GetString("discount")
+		noTax, _ := cmd.Flags().GetBool("no-tax")
+		includeNonBillable, _ := cmd.Flags().GetBool("include-non-billable")
+		byCategory, _ := cmd.Flags().GetBool("by-category")
+		includeBilled, _ := cmd.Flags().GetBool("include-billed")
+		terms, _ := cmd.Flags().GetInt("terms")
+		templateName, _ := cmd.Flags().GetString("template")
+		pageSize, _ := cmd.Flags().GetString("page-size")
+		milestoneNames, _ := cmd.Flags().GetStringArray("milestone")
+		withCommits, _ := cmd.Flags().GetBool("commits")
 
 		// --detailed overrides --condensed
 		if detailed {
@@ -50,81 +109,210 @@ Examples:
 		if condensed && condensedDesc == "" {
 			return fmt.Errorf("--desc is required for condensed invoices")
 		}
+		if byCategory && condensed {
+			return fmt.Errorf("--by-category requires --detailed")
+		}
+		if withCommits && condensed && !oneShot {
+			return fmt.Errorf("--commits requires --detailed or --one-shot")
+		}
+		if templateName != "" && (markdown || pdfFile != "") {
+			return fmt.Errorf("--template cannot be used with --pdf or --markdown")
+		}
+		if includeBilled && !noSave {
+			return fmt.Errorf("--include-billed requires --no-save, as an entry can only be on one invoice")
+		}
 
-		project, err := store.GetProject(args[0])
-		if err != nil {
-			return fmt.Errorf("project %q not found", args[0])
+		var project *model.Project
+		var client *model.Client
+		var projects []model.Project
+		var err error
+		if clientName != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("give a project or --client, not both")
+			}
+			if oneShot {
+				return fmt.Errorf("--one-shot cannot be used with --client")
+			}
+			if client, err = store.GetClient(clientName); err != nil {
+				return fmt.Errorf("client %q not found", clientName)
+			}
+			if projects, err = clientInvoiceProjects(client); err != nil {
+				return err
+			}
+			// The client's projects are billed as one, under its name
+			project = &model.Project{
+				Name:         client.Name,
+				Currency:     projects[0].Currency,
+				Tax:          projects[0].Tax,
+				PaymentTerms: client.PaymentTerms,
+			}
+		} else {
+			if len(args) == 0 {
+				return fmt.Errorf("give a project to invoice, or --client")
+			}
+			if project, err = store.GetProject(args[0]); err != nil {
+				return fmt.Errorf("project %q not found", args[0])
+			}
+			projects = []model.Project{*project}
+			client = projectClient(project)
 		}
 
-		var from, to time.Time
-		now := time.Now()
+		// The project's template applies unless another format is asked for
+		if templateName == "" && !markdown && pdfFile == "" {
+			templateName = project.Template
+		}
+		var tmpl *invoice.Template
+		if templateName != "" {
+			tmpl, err = invoice.LoadTemplate(templateDir(), templateName)
+			if err != nil {
+				return err
+			}
+		}
 
+		// Days are counted in the project's timezone
+		loc := home
+		if clientName == "" {
+			loc = projectLocation(project)
+		}
+		now := time.Now()
+		var from, to time.Time
 		if oneShot {
+			if !periodFlags(cmd).IsZero() {
+				return fmt.Errorf("--one-shot works out its own period, so cannot be used with --since, --until, --week or --month")
+			}
 			// One-shot mode: auto-calculate date range
-			from, err = getOneShotStartDate(project.ID)
+			from, err = getOneShotStartDate(project.ID, loc)
 			if err != nil {
 				return err
 			}
 
 			// End date is 2 weeks from start, or today if sooner
-			twoWeeksOut := from.AddDate(0, 0, 14)
-			today := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, time.Local)
-			if today.Before(twoWeeksOut) {
+			to = from.AddDate(0, 0, 14)
+			if today := period.EndOfDay(now, loc); today.Before(to) {
 				to = today
-			} else {
-				to = twoWeeksOut
-			}
-		} else if thisWeek {
-			weekday := int(now.Weekday())
-			if weekday == 0 {
-				weekday = 7
-			}
-			from = now.AddDate(0, 0, -weekday+1)
-			from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
-			to = now
-		} else if thisMonth {
-			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
-			to = now
-		} else if sinceStr != "" {
-			from, err = time.Parse("2006-01-02", sinceStr)
+			}
+		} else {
+			r, err := periodFlags(cmd).Range(loc, now)
 			if err != nil {
-				return fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
+				return err
 			}
-			if untilStr != "" {
-				to, err = time.Parse("2006-01-02", untilStr)
-				if err != nil {
-					return fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
-				}
-			} else {
+			from, to = r.From, r.To
+			// Default to this month, or the month of --until
+			if to.IsZero() {
 				to = now
 			}
-		} else {
-			// Default to this month
-			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
-			to = now
+			if from.IsZero() {
+				from = period.StartOfMonth(to, loc)
+			}
 		}
 
 		fromPtr := &from
 		toPtr := &to
-		entries := store.ListEntries(project.ID, fromPtr, toPtr)
+		var entries []model.Entry
+		var expenses []model.Expense
+		for _, p := range projects {
+			entries = append(entries, store.ListEntries(p.ID, fromPtr, toPtr)...)
+			expenses = append(expenses, store.ListExpenses(p.ID, fromPtr, toPtr)...)
+		}
+		if !includeNonBillable {
+			entries = slices.DeleteFunc(entries, func(e model.Entry) bool { return !e.IsBillable() })
+		}
+		if !includeBilled {
+			entries = slices.DeleteFunc(entries, func(e model.Entry) bool { return e.IsBilled() })
+			expenses = slices.DeleteFunc(expenses, func(e model.Expense) bool { return e.InvoiceID != "" })
+		}
+
+		// A retainer's fee and a fixed price's milestones are due with or
+		// without time to bill
+		var milestones []model.Milestone
+		var carried float64
+		var billedMonths map[string]bool
+		switch project.BillingModel() {
+		case model.BillingFixed:
+			if milestones, err = invoiceMilestones(project, milestoneNames, noSave); err != nil {
+				return err
+			}
+		case model.BillingRetainer:
+			invoices := store.ListInvoices(project.ID, "")
+			carried = model.RolloverHours(invoices, project.ID)
+			if !project.Billing.Rollover {
+				carried = 0
+			}
+			billedMonths = model.BilledRetainerMonths(invoices, project.ID)
+		}
+		if len(milestoneNames) > 0 && project.BillingModel() != model.BillingFixed {
+			return fmt.Errorf("--milestone only applies to fixed-price projects, see 'watchmen project model'")
+		}
+
+		if len(entries) == 0 && len(expenses) == 0 && project.BillingModel() == model.BillingHourly {
+			if includeBilled {
+				return fmt.Errorf("no entries found for %s in the specified period", project.Name)
+			}
+			return fmt.Errorf("no unbilled entries found for %s in the specified period", project.Name)
+		}
 
-		if len(entries) == 0 {
-			return fmt.Errorf("no entries found for %s in the specified period", project.Name)
+		var discount *model.Discount
+		if discountStr != "" {
+			discount, err = parseDiscount(discountStr, project.CurrencyCode())
+			if err != nil {
+				return err
+			}
 		}
 
-		if invoiceNum == "" {
-			invoiceNum = fmt.Sprintf("INV-%s-%s", project.Name[:min(3, len(project.Name))], now.Format("20060102"))
+		tax := project.Tax
+		if noTax {
+			tax = nil
 		}
 
-		// Get contact info
+		if !cmd.Flags().Changed("terms") {
+			terms = project.PaymentTerms
+			if terms == 0 && client != nil {
+				terms = client.PaymentTerms
+			}
+		}
+		if terms < 0 {
+			return fmt.Errorf("--terms must be zero or more days")
+		}
+		var dueDate time.Time
+		if terms > 0 {
+			today := now.In(loc)
+			dueDate = time.Date(today.Year(), today.Month(), today.Day()+terms, 23, 59, 59, 0, loc)
+		}
+
+		// Get contact info and numbering
 		settings := store.GetSettings()
 
+		if invoiceNum != "" {
+			if !noSave && invoiceExists(invoiceNum) {
+				return fmt.Errorf("invoice %s already exists", invoiceNum)
+			}
+		} else if settings.Numbering != nil {
+			// Saving allocates the next number; a preview shows it without
+			// using it up
+			if noSave {
+				preview := *settings
+				preview.InvoiceSeq = maps.Clone(settings.InvoiceSeq)
+				invoiceNum = preview.NextInvoiceNumber(project.Name, now, invoiceExists)
+			}
+		} else {
+			prefix := []rune(project.Name)
+			invoiceNum = fmt.Sprintf("INV-%s-%s", string(prefix[:min(3, len(prefix))]), now.Format("20060102"))
+			for n, base := 2, invoiceNum; invoiceExists(invoiceNum); n++ {
+				invoiceNum = fmt.Sprintf("%s-%d", base, n)
+			}
+		}
+
 		// Use project PO as default, override with --po flag
 		po := project.PurchaseOrder
 		if poNumber != "" {
 			po = poNumber
 		}
 
+		var billTo *model.ContactInfo
+		if client != nil {
+			billTo = client.Contact
+		}
+
 		data := &invoice.InvoiceData{
 			InvoiceNumber:        invoiceNum,
 			PurchaseOrder:        po,
@@ -134,9 +322,121 @@ Examples:
 			From:                 from,
 			To:                   to,
 			FromContact:          settings.UserContact,
-			BillToContact:        project.BillingContact,
+			BillToContact:        billTo,
 			Condensed:            condensed,
 			CondensedDescription: condensedDesc,
+			Expenses:             expenses,
+			Discount:             discount,
+			Tax:                  tax,
+			ShowCategories:       byCategory,
+			Terms:                terms,
+			DueDate:              dueDate,
+			Payment:              settings.Payment,
+			PDF:                  settings.PDF,
+			Milestones:           milestones,
+			Carried:              carried,
+			BilledMonths:         billedMonths,
+			Location:             loc,
+		}
+		if project.BillingModel() == model.BillingRetainer && data.Months() == 0 && len(entries) == 0 && len(expenses) == 0 {
+			return fmt.Errorf("the retainer fee for %s is already invoiced for this period and there is nothing else to bill", project.Name)
+		}
+		if pageSize != "" {
+			pdfSettings := model.PDFSettings{}
+			if settings.PDF != nil {
+				pdfSettings = *settings.PDF
+			}
+			if pdfSettings.PageSize, err = parsePageSize(pageSize); err != nil {
+				return err
+			}
+			data.PDF = &pdfSettings
+		}
+		if clientName != "" {
+			data.Client = client
+			data.Projects = projects
+		}
+		var commits map[string][]gitlog.Commit
+		if withCommits {
+			if commits, err = entryCommits(projects, entries, now); err != nil {
+				return err
+			}
+			if !condensed {
+				data.Commits = commits
+			}
+		}
+
+		// Save the invoice record unless --no-save is set, before writing
+		// any output so documents carry its allocated number
+		if !noSave {
+			invRecord := &model.Invoice{
+				ID:          invoiceNum,
+				ProjectID:   project.ID,
+				ProjectName: project.Name,
+				PeriodStart: from,
+				PeriodEnd:   to,
+				Hours:       data.TotalHours(),
+				Rate:        data.Rate(),
+				Amount:      data.TotalAmount(),
+				Currency:    project.CurrencyCode(),
+				Description: condensedDesc,
+				Condensed:   condensed,
+				Labor:       data.LaborAmount(),
+				Expenses:    data.ExpensesAmount(),
+				Discount:    data.DiscountAmount(),
+				Tax:         data.TaxAmount(),
+			}
+			if data.Client != nil {
+				invRecord.ClientID = client.ID
+			}
+			if terms > 0 {
+				invRecord.Terms = terms
+				invRecord.DueDate = &dueDate
+			}
+			if raw := data.RawHours(); raw != invRecord.Hours {
+				invRecord.RawHours = raw
+			}
+			if tax != nil {
+				invRecord.TaxName = tax.Name
+				invRecord.TaxRate = tax.Rate
+			}
+			if groups := data.ProjectGroups(); data.MultipleRates() || len(groups) > 1 {
+				invRecord.Rate = 0
+				for _, pg := range groups {
+					for _, g := range pg.Rates {
+						invRecord.RateLines = append(invRecord.RateLines, model.RateLine{
+							Rate:   g.Rate,
+							Hours:  g.Hours(),
+							Amount: g.Amount(),
+						})
+					}
+				}
+			}
+			if !data.Hourly() {
+				invRecord.Rate = 0
+				invRecord.BillingModel = project.BillingModel()
+				invRecord.Charges = data.Charges()
+			}
+			if invRecord.BillingModel == model.BillingRetainer {
+				invRecord.IncludedHours, _, invRecord.RolloverHours = data.Retainer()
+				invRecord.RolloverIn = carried
+				invRecord.FeeMonths = data.FeeMonths()
+			}
+			entryIDs := make([]string, len(entries))
+			for i, e := range entries {
+				entryIDs[i] = e.ID
+			}
+			expenseIDs := make([]string, len(expenses))
+			for i, e := range expenses {
+				expenseIDs[i] = e.ID
+			}
+			if err := store.SaveInvoice(invRecord, entryIDs, expenseIDs); err != nil {
+				if errors.Is(err, storage.ErrDuplicateID) {
+					return fmt.Errorf("invoice %s already exists", invRecord.ID)
+				}
+				return fmt.Errorf("failed to save invoice record: %v", err)
+			}
+			invoiceNum = invRecord.ID
+			data.InvoiceNumber = invoiceNum
 		}
 
 		if oneShot {
@@ -160,6 +460,14 @@ Examples:
 			mdFile.Close()
 			fmt.Printf("Invoice MD:  %s\n", mdFileName)
 
+			if tmpl != nil {
+				docFileName := invoiceNum + tmpl.Ext()
+				if err := writeTemplate(docFileName, tmpl, data); err != nil {
+					return err
+				}
+				fmt.Printf("Invoice doc: %s\n", docFileName)
+			}
+
 			// Generate stakeholder report
 			var totalHours float64
 			for _, e := range entries {
@@ -173,6 +481,8 @@ Examples:
 				TotalHours:  totalHours,
 				InvoiceRef:  invoiceNum,
 				Entries:     entries,
+				Commits:     commits,
+				Location:    loc,
 			}
 
 			reportFile, err := os.Create(reportFileName)
@@ -184,14 +494,22 @@ Examples:
 			fmt.Printf("Report:      %s\n", reportFileName)
 
 			fmt.Printf("\nTotal hours: %.2f\n", data.TotalHours())
-			fmt.Printf("Total due:   $%.2f\n", data.TotalAmount())
+			fmt.Printf("Total due:   %s\n", data.FormatMoney(data.TotalAmount()))
 		} else if pdfFile != "" {
 			if err := invoice.GeneratePDF(pdfFile, data); err != nil {
 				return fmt.Errorf("failed to generate PDF: %v", err)
 			}
 			fmt.Printf("Invoice generated: %s\n", pdfFile)
 			fmt.Printf("  Total hours: %.2f\n", data.TotalHours())
-			fmt.Printf("  Total due:   $%.2f\n", data.TotalAmount())
+			fmt.Printf("  Total due:   %s\n", data.FormatMoney(data.TotalAmount()))
+		} else if tmpl != nil {
+			if outputFile == "" {
+				return tmpl.Execute(os.Stdout, data)
+			}
+			if err := writeTemplate(outputFile, tmpl, data); err != nil {
+				return err
+			}
+			fmt.Printf("Invoice generated: %s\n", outputFile)
 		} else if markdown {
 			var out *os.File
 			if outputFile != "" {
@@ -211,78 +529,151 @@ Examples:
 			invoice.GenerateText(os.Stdout, data)
 		}
 
-		// Save invoice record unless --no-save is set
-		if !noSave {
-			invRecord := &model.Invoice{
-				ID:          invoiceNum,
-				ProjectID:   project.ID,
-				ProjectName: project.Name,
-				PeriodStart: from,
-				PeriodEnd:   to,
-				Hours:       data.TotalHours(),
-				Rate:        project.HourlyRate,
-				Amount:      data.TotalAmount(),
-				Description: condensedDesc,
-				Condensed:   condensed,
-			}
-			if err := store.SaveInvoice(invRecord); err != nil {
-				return fmt.Errorf("failed to save invoice record: %v", err)
-			}
-		}
-
 		return nil
 	},
 }
 
-// getOneShotStartDate returns the start date for one-shot mode.
-// If there's a previous invoice, returns the day after its end date.
-// Otherwise, returns the date of the first entry for the project.
-func getOneShotStartDate(projectID string) (time.Time, error) {
-	// Get all invoices for this project
-	invoices := store.ListInvoices(projectID, "")
-
-	if len(invoices) > 0 {
-		// Sort by PeriodEnd descending to find the most recent
-		sort.Slice(invoices, func(i, j int) bool {
-			return invoices[i].PeriodEnd.After(invoices[j].PeriodEnd)
-		})
-
-		lastInvoice := invoices[0]
-		// Start from the day after the last invoice's end date
-		nextDay := lastInvoice.PeriodEnd.AddDate(0, 0, 1)
-		return time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 0, 0, 0, 0, time.Local), nil
+// writeTemplate renders the invoice with tmpl into a new file at path
+func writeTemplate(path string, tmpl *invoice.Template, data *invoice.InvoiceData) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
 	}
-
-	// No previous invoice - find first entry date
-	entries := store.ListEntries(projectID, nil, nil)
-	if len(entries) == 0 {
-		return time.Time{}, fmt.Errorf("no entries found for project")
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
+}
+
+// invoiceExists reports whether an invoice with the given ID is saved
+func invoiceExists(id string) bool {
+	_, err := store.GetInvoice(id)
+	return err == nil
+}
 
-	// Find the earliest entry
-	earliest := entries[0].StartTime()
-	for _, e := range entries[1:] {
-		if e.StartTime().Before(earliest) {
+// getOneShotStartDate returns the start date for one-shot mode: the day
+// of the project's earliest unbilled entry
+func getOneShotStartDate(projectID string, loc *time.Location) (time.Time, error) {
+	var earliest time.Time
+	for _, e := range store.ListEntries(projectID, nil, nil) {
+		if e.IsBilled() || !e.IsBillable() {
+			continue
+		}
+		if earliest.IsZero() || e.StartTime().Before(earliest) {
 			earliest = e.StartTime()
 		}
 	}
+	if earliest.IsZero() {
+		return time.Time{}, fmt.Errorf("no unbilled entries found for project")
+	}
 
-	return time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, time.Local), nil
+	return period.StartOfDay(earliest, loc), nil
+}
+
+// clientInvoiceProjects returns the projects billed on a client invoice,
+// which must all bill by the hour in one currency with the same tax
+func clientInvoiceProjects(client *model.Client) ([]model.Project, error) {
+	projects := clientProjects(client.ID)
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("client %s has no projects, add them with 'watchmen client set %s --add-project <project>'", client.Name, client.Name)
+	}
+	first := projects[0]
+	for _, p := range projects {
+		if p.BillingModel() != model.BillingHourly {
+			return nil, fmt.Errorf("%s is not billed by the hour, invoice it on its own", p.Name)
+		}
+		if p.CurrencyCode() != first.CurrencyCode() {
+			return nil, fmt.Errorf("%s is billed in %s and %s in %s, a client invoice needs one currency", first.Name, first.CurrencyCode(), p.Name, p.CurrencyCode())
+		}
+		if !sameTax(p.Tax, first.Tax) {
+			return nil, fmt.Errorf("%s and %s charge different tax, a client invoice needs the same tax", first.Name, p.Name)
+		}
+	}
+	return projects, nil
+}
+
+func sameTax(a, b *model.Tax) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// invoiceMilestones looks up the milestones to bill on a fixed-price
+// invoice. Each can be billed once, though a preview may show one again.
+func invoiceMilestones(project *model.Project, names []string, preview bool) ([]model.Milestone, error) {
+	billed := model.BilledMilestones(store.ListInvoices(project.ID, ""), project.ID)
+	if len(names) == 0 {
+		var open []string
+		for _, m := range project.Billing.Milestones {
+			if !billed[strings.ToLower(m.Name)] {
+				open = append(open, m.Name)
+			}
+		}
+		if len(open) == 0 {
+			return nil, fmt.Errorf("every milestone of %s is already invoiced", project.Name)
+		}
+		return nil, fmt.Errorf("%s is billed at a fixed price, choose milestones with --milestone (not invoiced: %s)", project.Name, strings.Join(open, ", "))
+	}
+	var milestones []model.Milestone
+	for _, name := range names {
+		m := project.Billing.Milestone(name)
+		if m == nil {
+			return nil, fmt.Errorf("milestone %q not found on %s", name, project.Name)
+		}
+		if billed[strings.ToLower(m.Name)] && !preview {
+			return nil, fmt.Errorf("milestone %q is already invoiced", m.Name)
+		}
+		if slices.ContainsFunc(milestones, func(x model.Milestone) bool { return x.Name == m.Name }) {
+			continue
+		}
+		milestones = append(milestones, *m)
+	}
+	return milestones, nil
+}
+
+// parseDiscount reads a discount given as a percentage ("10%") or a flat
+// amount in major units ("250")
+func parseDiscount(s, currency string) (*model.Discount, error) {
+	if pct, ok := strings.CutSuffix(strings.TrimSpace(s), "%"); ok {
+		percent, err := strconv.ParseFloat(pct, 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid discount %q, use a percentage such as 10%% or an amount", s)
+		}
+		return &model.Discount{Percent: percent}, nil
+	}
+	amount, err := money.Parse(s, currency)
+	if err != nil || amount <= 0 {
+		return nil, fmt.Errorf("invalid discount %q, use a percentage such as 10%% or an amount", s)
+	}
+	return &model.Discount{Amount: amount}, nil
 }
 
 func init() {
+	invoiceCmd.Flags().String("client", "", "Invoice every project of this client")
 	invoiceCmd.Flags().String("since", "", "Start date (YYYY-MM-DD)")
-	invoiceCmd.Flags().String("until", "", "End date (YYYY-MM-DD)")
+	invoiceCmd.Flags().String("until", "", "End date, inclusive (YYYY-MM-DD)")
 	invoiceCmd.Flags().BoolP("week", "w", false, "This week")
 	invoiceCmd.Flags().BoolP("month", "m", false, "This month")
 	invoiceCmd.Flags().String("pdf", "", "Output PDF file")
-	invoiceCmd.Flags().StringP("number", "n", "", "Invoice number")
+	invoiceCmd.Flags().StringP("number", "n", "", "Invoice number (default: the next number from 'config numbering')")
 	invoiceCmd.Flags().String("po", "", "Purchase order number")
 	invoiceCmd.Flags().Bool("markdown", false, "Output as markdown")
-	invoiceCmd.Flags().StringP("output", "o", "", "Output file (for markdown)")
+	invoiceCmd.Flags().StringP("output", "o", "", "Output file (for markdown or a template)")
 	invoiceCmd.Flags().BoolP("condensed", "c", true, "Generate condensed invoice with single line item (default)")
 	invoiceCmd.Flags().Bool("detailed", false, "Generate detailed invoice with all time entries")
 	invoiceCmd.Flags().StringP("desc", "d", "", "Description for condensed invoice line item (required for condensed)")
 	invoiceCmd.Flags().Bool("no-save", false, "Don't save invoice record (preview only)")
-	invoiceCmd.Flags().Bool("one-shot", false, "Generate invoice + report, auto-calculating dates from last invoice")
+	invoiceCmd.Flags().Bool("commits", false, "List the commits made during each entry (detailed invoices and one-shot reports)")
+	invoiceCmd.Flags().Bool("one-shot", false, "Generate invoice + report, starting from the earliest unbilled entry")
+	invoiceCmd.Flags().String("discount", "", "Discount off the subtotal, as a percentage (10%) or an amount (250)")
+	invoiceCmd.Flags().Bool("no-tax", false, "Don't charge the project's tax on this invoice")
+	invoiceCmd.Flags().Bool("include-non-billable", false, "Bill entries marked non-billable too")
+	invoiceCmd.Flags().Bool("by-category", false, "Summarise hours per category (detailed invoices only)")
+	invoiceCmd.Flags().Bool("include-billed", false, "Include entries already on an invoice (requires --no-save)")
+	invoiceCmd.Flags().String("page-size", "", "PDF page size: A4 or Letter (default: from 'config pdf')")
+	invoiceCmd.Flags().String("template", "", "Render with a template from 'watchmen template list' (default: the project's template)")
+	invoiceCmd.Flags().StringArray("milestone", nil, "Milestone to bill on a fixed-price project (repeatable)")
+	invoiceCmd.Flags().Int("terms", 0, "Days until payment is due (default: the project's or client's payment terms)")
 }
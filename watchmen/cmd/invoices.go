@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"math"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+	"watchmen/internal/storage"
 )
 
 var invoicesCmd = &cobra.Command{
@@ -15,54 +21,104 @@ var invoicesCmd = &cobra.Command{
 	Aliases: []string{"inv"},
 	Long: `List all invoices with optional filters.
 
+Unpaid invoices show as partial once a payment is recorded, and as overdue
+after their due date.
+
 Examples:
   watchmen invoices                    # List all invoices
   watchmen invoices --outstanding      # List unpaid invoices
+  watchmen invoices --overdue          # List invoices past their due date
   watchmen invoices --project iowa     # List invoices for a project
-  watchmen invoices --paid             # List paid invoices`,
+  watchmen invoices --client acme      # List a client's invoices, for one project or all
+  watchmen invoices --paid             # List paid invoices
+  watchmen invoices --aging            # Outstanding balances by age`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		projectFilter, _ := cmd.Flags().GetString("project")
+		clientFilter, _ := cmd.Flags().GetString("client")
 		outstanding, _ := cmd.Flags().GetBool("outstanding")
+		overdue, _ := cmd.Flags().GetBool("overdue")
 		paid, _ := cmd.Flags().GetBool("paid")
+		aging, _ := cmd.Flags().GetBool("aging")
 
 		var statusFilter model.InvoiceStatus
-		if outstanding {
+		if outstanding || overdue {
 			statusFilter = model.InvoiceStatusPending
 		} else if paid {
 			statusFilter = model.InvoiceStatusPaid
 		}
 
+		if projectFilter != "" && clientFilter != "" {
+			return fmt.Errorf("--project and --client cannot be used together")
+		}
+
+		now := time.Now()
 		invoices := store.ListInvoices(projectFilter, statusFilter)
+		if clientFilter != "" {
+			client, err := store.GetClient(clientFilter)
+			if err != nil {
+				return fmt.Errorf("client %q not found", clientFilter)
+			}
+			// A client invoice has no project of its own
+			projects := make(map[string]bool)
+			for _, p := range clientProjects(client.ID) {
+				projects[p.ID] = true
+			}
+			invoices = slices.DeleteFunc(invoices, func(inv model.Invoice) bool {
+				return inv.ClientID != client.ID && !projects[inv.ProjectID]
+			})
+		}
+		if overdue {
+			invoices = slices.DeleteFunc(invoices, func(inv model.Invoice) bool {
+				return inv.State(now) != model.InvoiceStatusOverdue
+			})
+		}
 		if len(invoices) == 0 {
 			fmt.Println("No invoices found")
 			return nil
 		}
+		if aging {
+			printAgingReport(invoices, now)
+			return nil
+		}
 
-		fmt.Printf("%-20s %-12s %-17s %10s %10s  %s\n", "INVOICE", "PROJECT", "PERIOD", "AMOUNT", "STATUS", "AGE/PAID")
-		fmt.Println("--------------------------------------------------------------------------------------------")
+		fmt.Printf("%-20s %-12s %-17s %14s %14s %8s  %s\n", "INVOICE", "PROJECT", "PERIOD", "AMOUNT", "BALANCE", "STATUS", "AGE/PAID")
+		fmt.Println("---------------------------------------------------------------------------------------------------------")
 
-		var totalOutstanding float64
+		// Totals are kept per currency; amounts in different currencies
+		// are never added together
+		totals := make(map[string]*currencyTotals)
 		for _, inv := range invoices {
 			projectName := inv.ProjectName
 			if len(projectName) > 12 {
 				projectName = projectName[:9] + "..."
 			}
 
-			status := string(inv.Status)
-			ageOrPaid := ""
+			currency := inv.CurrencyCode()
+			t := totals[currency]
+			if t == nil {
+				t = &currencyTotals{}
+				totals[currency] = t
+			}
+			if inv.Status != model.InvoiceStatusVoid {
+				t.invoiced += inv.Amount
+				t.outstanding += inv.Balance()
+				t.paid += inv.Amount - inv.Balance()
+			}
 
-			if inv.Status == model.InvoiceStatusPending {
-				age := time.Since(inv.CreatedAt)
-				days := int(math.Round(age.Hours() / 24))
-				if days == 0 {
-					ageOrPaid = "today"
-				} else if days == 1 {
-					ageOrPaid = "1 day"
-				} else {
-					ageOrPaid = fmt.Sprintf("%d days", days)
+			state := inv.State(now)
+			ageOrPaid := ""
+			switch {
+			case inv.IsCreditNote():
+				state = "credit"
+				ageOrPaid = "for " + inv.CreditFor
+			case inv.Status == model.InvoiceStatusPending:
+				ageOrPaid = formatDays(inv.DaysOutstanding(now))
+				if inv.DueDate != nil && state == model.InvoiceStatusOverdue {
+					ageOrPaid += fmt.Sprintf(", due %s", inv.DueDate.Format("Jan 2"))
 				}
-				totalOutstanding += inv.Amount
-			} else if inv.PaidAt != nil {
+			case inv.Status == model.InvoiceStatusVoid && inv.VoidedAt != nil:
+				ageOrPaid = inv.VoidedAt.Format("Jan 2, 2006")
+			case inv.PaidAt != nil:
 				ageOrPaid = inv.PaidAt.Format("Jan 2, 2006")
 			}
 
@@ -70,34 +126,292 @@ Examples:
 				inv.PeriodStart.Format("Jan 2"),
 				inv.PeriodEnd.Format("Jan 2"))
 
-			fmt.Printf("%-20s %-12s %-17s %10.2f %10s  %s\n",
+			fmt.Printf("%-20s %-12s %-17s %14s %14s %8s  %s\n",
 				inv.ID,
 				projectName,
 				period,
-				inv.Amount,
-				status,
+				money.Format(inv.Amount, currency),
+				money.Format(inv.Balance(), currency),
+				state,
 				ageOrPaid)
 		}
 
-		fmt.Println("--------------------------------------------------------------------------------------------")
-		if outstanding || statusFilter == "" {
-			fmt.Printf("Outstanding: $%.2f\n", totalOutstanding)
+		fmt.Println("---------------------------------------------------------------------------------------------------------")
+
+		fmt.Printf("%-8s %16s %16s %16s\n", "CURRENCY", "INVOICED", "PAID", "OUTSTANDING")
+		for _, code := range sortedKeys(totals) {
+			t := totals[code]
+			fmt.Printf("%-8s %16s %16s %16s\n", code,
+				money.Format(t.invoiced, code),
+				money.Format(t.paid, code),
+				money.Format(t.outstanding, code))
 		}
 
 		return nil
 	},
 }
 
+// currencyTotals accumulates invoice amounts in one currency
+type currencyTotals struct {
+	invoiced    int64
+	paid        int64
+	outstanding int64
+}
+
+// formatDays describes a number of days, e.g. "today" or "3 days"
+func formatDays(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// agingBuckets holds outstanding balances by days since issue: 0-30,
+// 31-60 and over 60
+type agingBuckets [3]int64
+
+func (b *agingBuckets) add(days int, amount int64) {
+	switch {
+	case days <= 30:
+		b[0] += amount
+	case days <= 60:
+		b[1] += amount
+	default:
+		b[2] += amount
+	}
+}
+
+func (b *agingBuckets) total() int64 {
+	return b[0] + b[1] + b[2]
+}
+
+// printAgingReport shows each project's outstanding balance by how long
+// it has been owed, with totals per currency
+func printAgingReport(invoices []model.Invoice, now time.Time) {
+	type projectKey struct{ name, currency string }
+	byProject := make(map[projectKey]*agingBuckets)
+	byCurrency := make(map[string]*agingBuckets)
+	for _, inv := range invoices {
+		balance := inv.Balance()
+		if balance <= 0 {
+			continue
+		}
+		key := projectKey{inv.ProjectName, inv.CurrencyCode()}
+		if byProject[key] == nil {
+			byProject[key] = &agingBuckets{}
+		}
+		if byCurrency[key.currency] == nil {
+			byCurrency[key.currency] = &agingBuckets{}
+		}
+		days := inv.DaysOutstanding(now)
+		byProject[key].add(days, balance)
+		byCurrency[key.currency].add(days, balance)
+	}
+	if len(byProject) == 0 {
+		fmt.Println("Nothing outstanding")
+		return
+	}
+
+	keys := make([]projectKey, 0, len(byProject))
+	for k := range byProject {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].currency < keys[j].currency
+	})
+
+	row := func(label, currency string, b *agingBuckets) {
+		fmt.Printf("%-16s %-8s %14s %14s %14s %14s\n", label, currency,
+			money.Format(b[0], currency),
+			money.Format(b[1], currency),
+			money.Format(b[2], currency),
+			money.Format(b.total(), currency))
+	}
+
+	fmt.Printf("%-16s %-8s %14s %14s %14s %14s\n", "PROJECT", "CURRENCY", "0-30 DAYS", "31-60 DAYS", "60+ DAYS", "TOTAL")
+	fmt.Println("-----------------------------------------------------------------------------------------")
+	for _, k := range keys {
+		name := k.name
+		if len(name) > 16 {
+			name = name[:13] + "..."
+		}
+		row(name, k.currency, byProject[k])
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------")
+	for _, code := range sortedKeys(byCurrency) {
+		row("TOTAL", code, byCurrency[code])
+	}
+}
+
 var invoicesPaidCmd = &cobra.Command{
 	Use:   "paid <invoice-id>",
-	Short: "Mark an invoice as paid",
+	Short: "Mark an invoice as paid in full",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inv, err := store.MarkInvoicePaid(args[0])
+		if errors.Is(err, storage.ErrInvoiceNotFound) {
+			return fmt.Errorf("invoice %q not found", args[0])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Marked %s as paid (%s)\n", inv.ID, money.Format(inv.Amount, inv.CurrencyCode()))
+		return nil
+	},
+}
+
+var invoicesPayCmd = &cobra.Command{
+	Use:   "pay <invoice-id> [amount]",
+	Short: "Record a payment against an invoice",
+	Long: `Record a full or partial payment against an invoice. Without an amount
+the whole balance is paid. The invoice is marked paid once nothing is owed.
+
+Examples:
+  watchmen invoices pay INV-acm-20260131 500 --method "bank transfer"
+  watchmen invoices pay INV-acm-20260131 --date 2026-02-14`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dateStr, _ := cmd.Flags().GetString("date")
+		method, _ := cmd.Flags().GetString("method")
+
+		inv, err := store.GetInvoice(args[0])
 		if err != nil {
 			return fmt.Errorf("invoice %q not found", args[0])
 		}
-		fmt.Printf("Marked %s as paid ($%.2f)\n", inv.ID, inv.Amount)
+
+		payment := model.Payment{Date: time.Now(), Amount: inv.Balance(), Method: method}
+		if len(args) == 2 {
+			payment.Amount, err = money.Parse(args[1], inv.CurrencyCode())
+			if err != nil {
+				return err
+			}
+		}
+		if dateStr != "" {
+			payment.Date, err = period.ParseDay(dateStr, home)
+			if err != nil {
+				return fmt.Errorf("invalid date format for --date, use YYYY-MM-DD")
+			}
+		}
+
+		inv, err = store.RecordPayment(inv.ID, payment)
+		if err != nil {
+			return err
+		}
+		currency := inv.CurrencyCode()
+		fmt.Printf("Recorded %s against %s\n", money.Format(payment.Amount, currency), inv.ID)
+		if inv.Status == model.InvoiceStatusPaid {
+			fmt.Println("  Paid in full")
+		} else {
+			fmt.Printf("  Balance: %s\n", money.Format(inv.Balance(), currency))
+		}
+		return nil
+	},
+}
+
+var invoicesVoidCmd = &cobra.Command{
+	Use:   "void <invoice-id>",
+	Short: "Void an unpaid invoice and release its entries",
+	Long: `Void an invoice that was issued in error. The record is kept, marked
+void, and its entries and expenses can be billed again. An invoice with
+payments against it needs a credit note instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		released := len(billedEntries(args[0]))
+		inv, err := store.VoidInvoice(args[0])
+		if errors.Is(err, storage.ErrInvoiceNotFound) {
+			return fmt.Errorf("invoice %q not found", args[0])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Voided invoice %s (%s)\n", inv.ID, money.Format(inv.Amount, inv.CurrencyCode()))
+		if released > 0 {
+			fmt.Printf("  Released %d entries for billing\n", released)
+		}
+		return nil
+	},
+}
+
+var invoicesCreditCmd = &cobra.Command{
+	Use:   "credit <invoice-id> [amount]",
+	Short: "Issue a credit note against an invoice",
+	Long: `Issue a credit note that reduces what is owed on an invoice. Without an
+amount the whole balance is credited. The credit note is saved as its own
+record, with a negative amount, referencing the original invoice.
+
+Examples:
+  watchmen invoices credit INV-acm-20260131 150 --reason "Agreed discount"
+  watchmen invoices credit INV-acm-20260131 --number CN-0001`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		number, _ := cmd.Flags().GetString("number")
+		reason, _ := cmd.Flags().GetString("reason")
+
+		original, err := store.GetInvoice(args[0])
+		if err != nil {
+			return fmt.Errorf("invoice %q not found", args[0])
+		}
+		if original.IsCreditNote() {
+			return fmt.Errorf("%s is itself a credit note", original.ID)
+		}
+
+		amount := original.Balance()
+		if len(args) == 2 {
+			amount, err = money.Parse(args[1], original.CurrencyCode())
+			if err != nil {
+				return err
+			}
+		}
+		if amount <= 0 {
+			return fmt.Errorf("nothing to credit on %s", original.ID)
+		}
+
+		if number == "" {
+			number = "CN-" + original.ID
+		}
+		if _, err := store.GetInvoice(number); err == nil {
+			return fmt.Errorf("invoice %q already exists, choose another with --number", number)
+		}
+
+		note := &model.Invoice{
+			ID:          number,
+			ProjectID:   original.ProjectID,
+			ClientID:    original.ClientID,
+			ProjectName: original.ProjectName,
+			PeriodStart: original.PeriodStart,
+			PeriodEnd:   original.PeriodEnd,
+			Amount:      -amount,
+			Currency:    original.Currency,
+			Description: reason,
+			CreditFor:   original.ID,
+		}
+		if err := store.SaveInvoice(note, nil, nil); err != nil {
+			return err
+		}
+
+		updated, err := store.GetInvoice(original.ID)
+		if err != nil {
+			return err
+		}
+		currency := original.CurrencyCode()
+		fmt.Printf("Issued credit note %s for %s against %s\n", note.ID, money.Format(amount, currency), original.ID)
+		fmt.Printf("  Balance: %s\n", money.Format(updated.Balance(), currency))
 		return nil
 	},
 }
@@ -113,18 +427,86 @@ var invoicesShowCmd = &cobra.Command{
 		}
 
 		fmt.Printf("Invoice:     %s\n", inv.ID)
-		fmt.Printf("Project:     %s\n", inv.ProjectName)
+		if inv.ClientID != "" {
+			fmt.Printf("Client:      %s\n", inv.ProjectName)
+		} else {
+			fmt.Printf("Project:     %s\n", inv.ProjectName)
+		}
 		fmt.Printf("Period:      %s - %s\n",
 			inv.PeriodStart.Format("Jan 2, 2006"),
 			inv.PeriodEnd.Format("Jan 2, 2006"))
 		fmt.Printf("Hours:       %.2f\n", inv.Hours)
-		fmt.Printf("Rate:        $%.2f/hour\n", inv.Rate)
-		fmt.Printf("Amount:      $%.2f\n", inv.Amount)
-		fmt.Printf("Status:      %s\n", inv.Status)
+		if n := len(billedEntries(inv.ID)); n > 0 {
+			fmt.Printf("Entries:     %d\n", n)
+		}
+		if inv.RawHours != 0 {
+			fmt.Printf("Worked:      %.2f (before rounding)\n", inv.RawHours)
+		}
+		if len(inv.Charges) > 0 {
+			for _, c := range inv.Charges {
+				fmt.Printf("Charge:      %s  %s\n", c.Description, money.Format(c.Amount, inv.CurrencyCode()))
+			}
+			if inv.BillingModel == model.BillingRetainer {
+				fmt.Printf("Included:    %.2f hours", inv.IncludedHours)
+				if inv.RolloverIn > 0 {
+					fmt.Printf(" (%.2f rolled over)", inv.RolloverIn)
+				}
+				fmt.Println()
+				if inv.RolloverHours > 0 {
+					fmt.Printf("Rolls over:  %.2f hours\n", inv.RolloverHours)
+				}
+			}
+		} else if len(inv.RateLines) > 0 {
+			for _, line := range inv.RateLines {
+				fmt.Printf("Rate:        %s/hour × %.2f = %s\n",
+					money.Format(line.Rate, inv.CurrencyCode()), line.Hours,
+					money.Format(line.Amount, inv.CurrencyCode()))
+			}
+		} else {
+			fmt.Printf("Rate:        %s/hour\n", money.Format(inv.Rate, inv.CurrencyCode()))
+		}
+		if inv.HasBreakdown() {
+			fmt.Printf("Labor:       %s\n", money.Format(inv.Labor, inv.CurrencyCode()))
+			if inv.Expenses != 0 {
+				fmt.Printf("Expenses:    %s\n", money.Format(inv.Expenses, inv.CurrencyCode()))
+			}
+			fmt.Printf("Subtotal:    %s\n", money.Format(inv.Subtotal(), inv.CurrencyCode()))
+			if inv.Discount != 0 {
+				fmt.Printf("Discount:    %s\n", money.Format(-inv.Discount, inv.CurrencyCode()))
+			}
+			if inv.TaxName != "" || inv.Tax != 0 {
+				fmt.Printf("Tax:         %s (%s %g%%)\n", money.Format(inv.Tax, inv.CurrencyCode()), inv.TaxName, inv.TaxRate)
+			}
+		}
+		currency := inv.CurrencyCode()
+		fmt.Printf("Amount:      %s\n", money.Format(inv.Amount, currency))
+		if inv.IsCreditNote() {
+			fmt.Printf("Credits:     %s\n", inv.CreditFor)
+		}
+		for _, p := range inv.Payments {
+			desc := p.Method
+			if p.Reference != "" {
+				desc = strings.TrimSpace(desc + " " + p.Reference)
+			}
+			if desc != "" {
+				desc = " (" + desc + ")"
+			}
+			fmt.Printf("Payment:     %s on %s%s\n", money.Format(p.Amount, currency), p.Date.Format("Jan 2, 2006"), desc)
+		}
+		if inv.Status == model.InvoiceStatusPending {
+			fmt.Printf("Balance:     %s\n", money.Format(inv.Balance(), currency))
+		}
+		fmt.Printf("Status:      %s\n", inv.State(time.Now()))
 		fmt.Printf("Created:     %s\n", inv.CreatedAt.Format("Jan 2, 2006"))
+		if inv.DueDate != nil {
+			fmt.Printf("Due:         %s (Net %d)\n", inv.DueDate.Format("Jan 2, 2006"), inv.Terms)
+		}
 		if inv.PaidAt != nil {
 			fmt.Printf("Paid:        %s\n", inv.PaidAt.Format("Jan 2, 2006"))
 		}
+		if inv.VoidedAt != nil {
+			fmt.Printf("Voided:      %s\n", inv.VoidedAt.Format("Jan 2, 2006"))
+		}
 		if inv.Description != "" {
 			fmt.Printf("Description: %s\n", inv.Description)
 		}
@@ -135,27 +517,58 @@ var invoicesShowCmd = &cobra.Command{
 
 var invoicesDeleteCmd = &cobra.Command{
 	Use:   "delete <invoice-id>",
-	Short: "Delete an invoice record",
-	Args:  cobra.ExactArgs(1),
+	Short: "Delete an invoice record and release its entries",
+	Long: `Delete an invoice record and release its entries and expenses for billing.
+
+Deleting a credit note takes its credit back off the invoice it credited.
+An invoice with a credit note can only be deleted once the note is.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inv, err := store.GetInvoice(args[0])
 		if err != nil {
 			return fmt.Errorf("invoice %q not found", args[0])
 		}
+		released := len(billedEntries(inv.ID))
 		if err := store.DeleteInvoice(args[0]); err != nil {
 			return err
 		}
-		fmt.Printf("Deleted invoice %s ($%.2f)\n", inv.ID, inv.Amount)
+		fmt.Printf("Deleted invoice %s (%s)\n", inv.ID, money.Format(inv.Amount, inv.CurrencyCode()))
+		if released > 0 {
+			fmt.Printf("  Released %d entries for billing\n", released)
+		}
 		return nil
 	},
 }
 
+// billedEntries returns the entries billed on an invoice
+func billedEntries(invoiceID string) []model.Entry {
+	var result []model.Entry
+	for _, e := range store.ListEntries("", nil, nil) {
+		if e.InvoiceID == invoiceID {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
 func init() {
 	invoicesCmd.Flags().StringP("project", "p", "", "Filter by project name or ID")
+	invoicesCmd.Flags().String("client", "", "Filter by client, including invoices for its projects")
 	invoicesCmd.Flags().BoolP("outstanding", "o", false, "Show only unpaid invoices")
 	invoicesCmd.Flags().Bool("paid", false, "Show only paid invoices")
+	invoicesCmd.Flags().Bool("overdue", false, "Show only invoices past their due date")
+	invoicesCmd.Flags().Bool("aging", false, "Show outstanding balances by days since issue (0-30, 31-60, 60+)")
+
+	invoicesPayCmd.Flags().String("date", "", "Date the payment was received (YYYY-MM-DD, default today)")
+	invoicesPayCmd.Flags().String("method", "", "How the payment was made (e.g. bank transfer, card)")
+
+	invoicesCreditCmd.Flags().StringP("number", "n", "", "Credit note number (default: CN-<invoice>)")
+	invoicesCreditCmd.Flags().String("reason", "", "Reason for the credit, shown on the record")
 
 	invoicesCmd.AddCommand(invoicesPaidCmd)
+	invoicesCmd.AddCommand(invoicesPayCmd)
+	invoicesCmd.AddCommand(invoicesVoidCmd)
+	invoicesCmd.AddCommand(invoicesCreditCmd)
 	invoicesCmd.AddCommand(invoicesShowCmd)
 	invoicesCmd.AddCommand(invoicesDeleteCmd)
 }
@@ -13,52 +13,30 @@ var listCmd = &cobra.Command{
 	Aliases: []string{"ls"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		projectFilter, _ := cmd.Flags().GetString("project")
-		sinceStr, _ := cmd.Flags().GetString("since")
-		untilStr, _ := cmd.Flags().GetString("until")
-		thisWeek, _ := cmd.Flags().GetBool("week")
-		thisMonth, _ := cmd.Flags().GetBool("month")
 		showSegments, _ := cmd.Flags().GetBool("segments")
 
+		r, err := periodFlags(cmd).Range(home, time.Now())
+		if err != nil {
+			return err
+		}
 		var from, to *time.Time
-
-		if thisWeek {
-			now := time.Now()
-			weekday := int(now.Weekday())
-			if weekday == 0 {
-				weekday = 7
-			}
-			start := now.AddDate(0, 0, -weekday+1)
-			start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
-			from = &start
-		} else if thisMonth {
-			now := time.Now()
-			start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
-			from = &start
-		} else {
-			if sinceStr != "" {
-				t, err := time.Parse("2006-01-02", sinceStr)
-				if err != nil {
-					return fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
-				}
-				from = &t
-			}
-			if untilStr != "" {
-				t, err := time.Parse("2006-01-02", untilStr)
-				if err != nil {
-					return fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
-				}
-				t = t.Add(24*time.Hour - time.Second)
-				to = &t
-			}
+		if !r.From.IsZero() {
+			from = &r.From
+		}
+		if !r.To.IsZero() {
+			to = &r.To
 		}
 
-		entries := store.ListEntries(projectFilter, from, to)
+		entries, err := filterEntries(cmd, store.ListEntries(projectFilter, from, to))
+		if err != nil {
+			return err
+		}
 		if len(entries) == 0 {
 			fmt.Println("No entries found")
 			return nil
 		}
 
-		var totalDuration time.Duration
+		var totalDuration, billableDuration time.Duration
 		fmt.Printf("%-12s %-20s %8s  %s\n", "DATE", "PROJECT", "HOURS", "NOTE")
 		fmt.Println("-------------------------------------------------------------------------------")
 
@@ -77,6 +55,9 @@ var listCmd = &cobra.Command{
 
 			duration := e.Duration()
 			totalDuration += duration
+			if e.IsBillable() {
+				billableDuration += duration
+			}
 
 			status := ""
 			if e.IsRunning() {
@@ -84,8 +65,14 @@ var listCmd = &cobra.Command{
 			} else if e.IsPaused() {
 				status = " (paused)"
 			}
+			if !e.IsBillable() {
+				status += " (non-billable)"
+			}
 
 			note := e.Note
+			if e.Category != "" {
+				note = "[" + e.Category + "] " + note
+			}
 			if len(note) > 40 {
 				note = note[:37] + "..."
 			}
@@ -114,6 +101,9 @@ var listCmd = &cobra.Command{
 
 		fmt.Println("-------------------------------------------------------------------------------")
 		fmt.Printf("%-12s %-20s %8.2f\n", "TOTAL", "", totalDuration.Hours())
+		if billableDuration != totalDuration {
+			fmt.Printf("%-12s %-20s %8.2f\n", "BILLABLE", "", billableDuration.Hours())
+		}
 
 		return nil
 	},
@@ -122,8 +112,9 @@ var listCmd = &cobra.Command{
 func init() {
 	listCmd.Flags().StringP("project", "p", "", "Filter by project name or ID")
 	listCmd.Flags().String("since", "", "Show entries since date (YYYY-MM-DD)")
-	listCmd.Flags().String("until", "", "Show entries until date (YYYY-MM-DD)")
+	listCmd.Flags().String("until", "", "Show entries until date, inclusive (YYYY-MM-DD)")
 	listCmd.Flags().BoolP("week", "w", false, "Show this week's entries")
 	listCmd.Flags().BoolP("month", "m", false, "Show this month's entries")
 	listCmd.Flags().BoolP("segments", "s", false, "Show individual time segments")
+	addEntryFilterFlags(listCmd)
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/period"
 )
 
 var logCmd = &cobra.Command{
@@ -23,8 +24,14 @@ Flag-based format:
   watchmen log myproject --duration 2h --note "Fixed bugs"
   watchmen log myproject --duration 1h30m --date 2024-01-15
   watchmen log myproject --start "9:00AM" --end "11:30AM" --note "Meeting"
+  watchmen log myproject "9am | 11am | weekend support" --rate 200
+  watchmen log myproject "2pm | 3pm | team sync" --category meetings --non-billable
 
-Time formats supported: 9am, 9AM, 9:30pm, 14:30, 1400, 2330`,
+Time formats supported: 9am, 9AM, 9:30pm, 14:30, 1400, 2330
+
+An end time before the start is on the next day, so "10pm | 1am" logs
+three hours across midnight. Times and --date are in the project's
+timezone, see 'watchmen project timezone'.`,
 	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		note, _ := cmd.Flags().GetString("note")
@@ -37,6 +44,10 @@ Time formats supported: 9am, 9AM, 9:30pm, 14:30, 1400, 2330`,
 		if err != nil {
 			return fmt.Errorf("project %q not found", args[0])
 		}
+		updates, err := entryUpdates(cmd, project)
+		if err != nil {
+			return err
+		}
 
 		// Check for condensed format as second argument
 		if len(args) == 2 {
@@ -51,12 +62,14 @@ Time formats supported: 9am, 9AM, 9:30pm, 14:30, 1400, 2330`,
 			}
 		}
 
+		// Times are read in the timezone the project's days are counted in
+		loc := projectLocation(project)
 		var startTime, endTime time.Time
-		baseDate := time.Now()
+		baseDate := time.Now().In(loc)
 
 		// Parse date if provided
 		if dateStr != "" {
-			parsed, err := time.Parse("2006-01-02", dateStr)
+			parsed, err := period.ParseDay(dateStr, loc)
 			if err != nil {
 				return fmt.Errorf("invalid date format, use YYYY-MM-DD")
 			}
@@ -73,7 +86,7 @@ Time formats supported: 9am, 9AM, 9:30pm, 14:30, 1400, 2330`,
 			if dateStr == "" {
 				endTime = time.Now()
 			} else {
-				endTime = time.Date(baseDate.Year(), baseDate.Month(), baseDate.Day(), 17, 0, 0, 0, time.Local)
+				endTime = time.Date(baseDate.Year(), baseDate.Month(), baseDate.Day(), 17, 0, 0, 0, loc)
 			}
 			startTime = endTime.Add(-duration)
 		} else if startStr != "" && endStr != "" {
@@ -86,11 +99,18 @@ Time formats supported: 9am, 9AM, 9:30pm, 14:30, 1400, 2330`,
 			if err != nil {
 				return fmt.Errorf("invalid end time: %v", err)
 			}
+			// An end before the start is on the next day
+			if endTime.Equal(startTime) {
+				return fmt.Errorf("end time must be after start time")
+			}
+			if endTime.Before(startTime) {
+				endTime = endTime.AddDate(0, 0, 1)
+			}
 		} else {
 			return fmt.Errorf("provide either --duration or both --start and --end, or use condensed format")
 		}
 
-		entry, err := store.LogEntry(project.ID, note, startTime, endTime)
+		entry, err := store.LogEntry(project.ID, note, startTime, endTime, updates)
 		if err != nil {
 			return err
 		}
@@ -98,10 +118,15 @@ Time formats supported: 9am, 9AM, 9:30pm, 14:30, 1400, 2330`,
 		duration := entry.Duration()
 		fmt.Printf("Logged %.2f hours on %s\n", duration.Hours(), project.Name)
 		fmt.Printf("  Date: %s\n", startTime.Format("Jan 2, 2006"))
-		fmt.Printf("  Time: %s - %s\n", startTime.Format("3:04 PM"), endTime.Format("3:04 PM"))
+		if endTime.Day() != startTime.Day() {
+			fmt.Printf("  Time: %s - %s (next day)\n", startTime.Format("3:04 PM"), endTime.Format("3:04 PM"))
+		} else {
+			fmt.Printf("  Time: %s - %s\n", startTime.Format("3:04 PM"), endTime.Format("3:04 PM"))
+		}
 		if note != "" {
 			fmt.Printf("  Note: %s\n", note)
 		}
+		printEntryDetails(entry, project)
 		return nil
 	},
 }
@@ -112,7 +137,7 @@ func parseTime(s string, baseDate time.Time) (time.Time, error) {
 	// Try 24-hour format without colon (1700, 2330, 900)
 	if hour, min, ok := parseMilitaryTime(s); ok {
 		return time.Date(baseDate.Year(), baseDate.Month(), baseDate.Day(),
-			hour, min, 0, 0, time.Local), nil
+			hour, min, 0, 0, baseDate.Location()), nil
 	}
 
 	// Normalize: uppercase AM/PM for consistent parsing
@@ -135,12 +160,12 @@ func parseTime(s string, baseDate time.Time) (time.Time, error) {
 		// Try with original string
 		if t, err := time.Parse(f, s); err == nil {
 			return time.Date(baseDate.Year(), baseDate.Month(), baseDate.Day(),
-				t.Hour(), t.Minute(), 0, 0, time.Local), nil
+				t.Hour(), t.Minute(), 0, 0, baseDate.Location()), nil
 		}
 		// Try with uppercased string (handles am/pm -> AM/PM)
 		if t, err := time.Parse(strings.ToUpper(f), upper); err == nil {
 			return time.Date(baseDate.Year(), baseDate.Month(), baseDate.Day(),
-				t.Hour(), t.Minute(), 0, 0, time.Local), nil
+				t.Hour(), t.Minute(), 0, 0, baseDate.Location()), nil
 		}
 	}
 	return time.Time{}, fmt.Errorf("cannot parse time %q", s)
@@ -178,4 +203,5 @@ func init() {
 	logCmd.Flags().String("date", "", "Date for the entry (YYYY-MM-DD, default: today)")
 	logCmd.Flags().String("start", "", "Start time (e.g., 9:00AM, 14:30)")
 	logCmd.Flags().String("end", "", "End time (e.g., 5:00PM, 17:00)")
+	addEntryFlags(logCmd)
 }
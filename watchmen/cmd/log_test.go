@@ -111,3 +111,17 @@ func TestParseTime(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTimeInLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	result, err := parseTime("9am", time.Date(2024, 6, 15, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatalf("parseTime() error = %v", err)
+	}
+	if !result.Equal(time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseTime() = %v, want 9am in Tokyo", result)
+	}
+}
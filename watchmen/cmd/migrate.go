@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/storage"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move your data to another storage backend",
+	Long: `Copy all projects, entries, invoices and settings into a new storage backend.
+
+The SQLite database is written next to the current data file as data.db.
+Once ~/.watchmen/data.db exists watchmen uses it by default; the old JSON
+file is left untouched and can be removed after checking the result.
+
+Examples:
+  watchmen migrate --to sqlite
+  watchmen migrate --to sqlite --output ~/backup/watchmen.db`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetString("to")
+		output, _ := cmd.Flags().GetString("output")
+
+		if to != "sqlite" {
+			return fmt.Errorf("unsupported backend %q (supported: sqlite)", to)
+		}
+		if storage.IsSQLitePath(storePath) {
+			return fmt.Errorf("data is already stored in SQLite (%s)", storePath)
+		}
+
+		if output == "" {
+			output = filepath.Join(filepath.Dir(storePath), "data.db")
+		}
+		if _, err := os.Stat(output); err == nil {
+			return fmt.Errorf("%s already exists", output)
+		}
+
+		// Build the database under a temporary name so a failed migration
+		// never leaves a half-filled data.db that would be picked up by default
+		tmpPath := output + ".migrating"
+		os.Remove(tmpPath)
+		dst, err := storage.NewSQLite(tmpPath)
+		if err != nil {
+			return err
+		}
+		if err := storage.Migrate(store, dst); err != nil {
+			dst.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("migration failed: %v", err)
+		}
+		if err := dst.Close(); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		if err := os.Rename(tmpPath, output); err != nil {
+			return err
+		}
+
+		data, _ := store.Snapshot()
+		fmt.Printf("Migrated to %s\n", output)
+		fmt.Printf("  Projects: %d\n", len(data.Projects))
+		fmt.Printf("  Entries:  %d\n", len(data.Entries))
+		fmt.Printf("  Invoices: %d\n", len(data.Invoices))
+		fmt.Printf("\n%s was left in place and can be removed once you have checked the result.\n", storePath)
+		return nil
+	},
+}
+
+func init() {
+	migrateCmd.Flags().String("to", "", "Target backend (sqlite)")
+	migrateCmd.Flags().StringP("output", "o", "", "Path for the new data file (default: data.db next to the current file)")
+	migrateCmd.MarkFlagRequired("to")
+}
@@ -1,10 +1,21 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/gitlog"
+	"watchmen/internal/invoice"
 	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+	"watchmen/internal/storage"
 )
 
 var projectCmd = &cobra.Command{
@@ -15,18 +26,61 @@ var projectCmd = &cobra.Command{
 var projectAddCmd = &cobra.Command{
 	Use:   "add <name>",
 	Short: "Add a new project",
-	Args:  cobra.ExactArgs(1),
+	Long: `Add a new project. A project added to a client takes the client's
+currency unless --currency is given.
+
+Examples:
+  watchmen project add website --rate 150
+  watchmen project add app --client acme --rate 120`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		rate, _ := cmd.Flags().GetFloat64("rate")
 		desc, _ := cmd.Flags().GetString("description")
+		currencyCode, _ := cmd.Flags().GetString("currency")
+		clientName, _ := cmd.Flags().GetString("client")
+
+		var client *model.Client
+		if clientName != "" {
+			c, err := store.GetClient(clientName)
+			if err != nil {
+				return fmt.Errorf("client %q not found", clientName)
+			}
+			client = c
+			if !cmd.Flags().Changed("currency") {
+				currencyCode = client.CurrencyCode()
+			}
+		}
+		currency, err := money.Lookup(currencyCode)
+		if err != nil {
+			return err
+		}
 
-		p, err := store.AddProject(args[0], rate, desc)
+		p, err := store.AddProject(args[0], money.FromMajor(rate, currency.Code), desc)
 		if err != nil {
 			return err
 		}
+		if currency.Code != money.DefaultCurrency || client != nil {
+			err = store.UpdateProject(p.ID, func(p *model.Project) {
+				if currency.Code != money.DefaultCurrency {
+					p.Currency = currency.Code
+				}
+				if client != nil {
+					p.ClientID = client.ID
+				}
+			})
+			if err != nil {
+				return err
+			}
+			if currency.Code != money.DefaultCurrency {
+				p.Currency = currency.Code
+			}
+		}
 		fmt.Printf("Created project: %s (ID: %s)\n", p.Name, p.ID)
-		if rate > 0 {
-			fmt.Printf("  Hourly rate: $%.2f\n", rate)
+		if client != nil {
+			fmt.Printf("  Client: %s\n", client.Name)
+		}
+		if p.HourlyRate > 0 {
+			fmt.Printf("  Hourly rate: %s\n", money.Format(p.HourlyRate, p.CurrencyCode()))
 		}
 		return nil
 	},
@@ -42,14 +96,14 @@ var projectListCmd = &cobra.Command{
 			fmt.Println("No projects yet. Create one with: watchmen project add <name>")
 			return nil
 		}
-		fmt.Printf("%-16s %-20s %10s  %s\n", "ID", "NAME", "RATE", "DESCRIPTION")
+		fmt.Printf("%-16s %-20s %14s  %s\n", "ID", "NAME", "RATE", "DESCRIPTION")
 		fmt.Println("-------------------------------------------------------------------------------")
 		for _, p := range projects {
 			rate := "-"
-			if p.HourlyRate > 0 {
-				rate = fmt.Sprintf("$%.2f/hr", p.HourlyRate)
+			if current := p.CurrentRate(); current > 0 {
+				rate = money.Format(current, p.CurrencyCode()) + "/hr"
 			}
-			fmt.Printf("%-16s %-20s %10s  %s\n", p.ID, p.Name, rate, p.Description)
+			fmt.Printf("%-16s %-20s %14s  %s\n", p.ID, p.Name, rate, p.Description)
 		}
 		return nil
 	},
@@ -58,29 +112,35 @@ var projectListCmd = &cobra.Command{
 var projectBillingCmd = &cobra.Command{
 	Use:   "billing <project>",
 	Short: "Set billing contact and PO for a project",
-	Long: `Set the billing contact information and purchase order for a project.
+	Long: `Set the billing contact and purchase order for a project.
+
+The contact belongs to the project's client and is shared by all of the
+client's projects. A project without a client gets one named after it when
+a contact is first set. The PO stays with the project.
 
 Examples:
   watchmen project billing myproject --name "John Doe" --company "Acme Inc"
   watchmen project billing myproject --name "Jane" --email "jane@acme.com" --address "123 Main St"
-  watchmen project billing myproject --po "PO-2026-001"`,
+  watchmen project billing myproject --po "PO-2026-001"
+  watchmen project billing myproject --tax-id "GB123456789"`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		name, _ := cmd.Flags().GetString("name")
-		company, _ := cmd.Flags().GetString("company")
-		address, _ := cmd.Flags().GetString("address")
-		phone, _ := cmd.Flags().GetString("phone")
-		email, _ := cmd.Flags().GetString("email")
 		po, _ := cmd.Flags().GetString("po")
 
 		project, err := store.GetProject(args[0])
 		if err != nil {
 			return fmt.Errorf("project %q not found", args[0])
 		}
+		client := projectClient(project)
 
-		if name == "" && company == "" && address == "" && phone == "" && email == "" && po == "" {
+		contact := &model.ContactInfo{}
+		if client != nil && client.Contact != nil {
+			*contact = *client.Contact
+		}
+		contactSet := applyContactFlags(cmd, contact)
+		if !contactSet && po == "" {
 			// Show current billing info
-			hasBilling := project.BillingContact != nil
+			hasBilling := client != nil && client.Contact != nil
 			hasPO := project.PurchaseOrder != ""
 			if !hasBilling && !hasPO {
 				fmt.Printf("No billing info set for %s\n", project.Name)
@@ -91,41 +151,25 @@ Examples:
 				fmt.Printf("  PO #: %s\n", project.PurchaseOrder)
 			}
 			if hasBilling {
-				printContactInfo(project.BillingContact)
+				fmt.Printf("  Client: %s\n", client.Name)
+				printContactInfo(client.Contact)
 			}
 			return nil
 		}
 
-		// Get existing contact or create new
-		contact := project.BillingContact
-		if contact == nil {
-			contact = &model.ContactInfo{}
-		}
-
-		if name != "" {
-			contact.Name = name
-		}
-		if company != "" {
-			contact.Company = company
-		}
-		if address != "" {
-			contact.Address = address
-		}
-		if phone != "" {
-			contact.Phone = phone
-		}
-		if email != "" {
-			contact.Email = email
-		}
-
-		err = store.UpdateProject(args[0], func(p *model.Project) {
-			if name != "" || company != "" || address != "" || phone != "" || email != "" {
-				p.BillingContact = contact
-			}
+		setPO := func(p *model.Project) {
 			if po != "" {
 				p.PurchaseOrder = po
 			}
-		})
+		}
+		if contactSet {
+			client, err = store.SetBillingContact(project.ID, contact, setPO)
+			if errors.Is(err, storage.ErrClientExists) {
+				return fmt.Errorf("client %q already exists, add the project to it with 'watchmen client set %s --add-project %s'", project.Name, project.Name, project.Name)
+			}
+		} else {
+			err = store.UpdateProject(project.ID, setPO)
+		}
 		if err != nil {
 			return err
 		}
@@ -134,7 +178,8 @@ Examples:
 		if po != "" {
 			fmt.Printf("  PO #: %s\n", po)
 		}
-		if name != "" || company != "" || address != "" || phone != "" || email != "" {
+		if contactSet {
+			fmt.Printf("  Client: %s\n", client.Name)
 			printContactInfo(contact)
 		}
 		return nil
@@ -153,50 +198,987 @@ var projectShowCmd = &cobra.Command{
 
 		fmt.Printf("Project: %s\n", project.Name)
 		fmt.Printf("  ID:   %s\n", project.ID)
-		if project.HourlyRate > 0 {
-			fmt.Printf("  Rate: $%.2f/hr\n", project.HourlyRate)
+		if current := project.CurrentRate(); current > 0 {
+			fmt.Printf("  Rate: %s/hr\n", money.Format(current, project.CurrencyCode()))
+		}
+		if len(project.Rates) > 0 {
+			fmt.Println("  Rate history:")
+			printRateHistory(project, "    ")
 		}
+		fmt.Printf("  Currency: %s\n", project.CurrencyCode())
 		if project.Description != "" {
 			fmt.Printf("  Desc: %s\n", project.Description)
 		}
 		if project.PurchaseOrder != "" {
 			fmt.Printf("  PO #: %s\n", project.PurchaseOrder)
 		}
-		if project.BillingContact != nil {
-			fmt.Println("  Billing Contact:")
-			if project.BillingContact.Name != "" {
-				fmt.Printf("    Name:    %s\n", project.BillingContact.Name)
+		if project.Tax != nil {
+			fmt.Printf("  Tax:  %s\n", project.Tax.Label())
+		}
+		if project.Rounding != nil {
+			fmt.Printf("  Rounding: %s\n", project.Rounding)
+		}
+		if project.PaymentTerms > 0 {
+			fmt.Printf("  Terms: Net %d\n", project.PaymentTerms)
+		}
+		if project.Template != "" {
+			fmt.Printf("  Template: %s\n", project.Template)
+		}
+		if len(project.Repos) > 0 {
+			fmt.Printf("  Repos: %s\n", strings.Join(project.Repos, ", "))
+		}
+		if project.Timezone != "" {
+			fmt.Printf("  Timezone: %s\n", project.Timezone)
+		}
+		if project.Budget != nil {
+			fmt.Printf("  Budget: %s\n", project.Budget.String(project.CurrencyCode()))
+			printBudgetUse(project, "    ")
+		}
+		if project.Billing != nil {
+			fmt.Printf("  Billing: %s\n", project.Billing.String(project.CurrencyCode()))
+			printBilling(project, "    ")
+		}
+		if client := projectClient(project); client != nil {
+			fmt.Printf("  Client: %s\n", client.Name)
+			if contact := client.Contact; contact != nil {
+				fmt.Println("  Billing Contact:")
+				if contact.Name != "" {
+					fmt.Printf("    Name:    %s\n", contact.Name)
+				}
+				if contact.Title != "" {
+					fmt.Printf("    Title:   %s\n", contact.Title)
+				}
+				if contact.Company != "" {
+					fmt.Printf("    Company: %s\n", contact.Company)
+				}
+				if contact.Address != "" {
+					fmt.Printf("    Address: %s\n", contact.Address)
+				}
+				if contact.Phone != "" {
+					fmt.Printf("    Phone:   %s\n", contact.Phone)
+				}
+				if contact.Email != "" {
+					fmt.Printf("    Email:   %s\n", contact.Email)
+				}
+				if contact.TaxID != "" {
+					fmt.Printf("    Tax ID:  %s\n", contact.TaxID)
+				}
 			}
-			if project.BillingContact.Company != "" {
-				fmt.Printf("    Company: %s\n", project.BillingContact.Company)
+		}
+		return nil
+	},
+}
+
+var projectCurrencyCmd = &cobra.Command{
+	Use:   "currency <project> [code]",
+	Short: "Show or set a project's currency",
+	Long: `Show or set the currency a project is billed in.
+
+The hourly rate and its history, the budget and any retainer or milestone
+amounts keep their value in major units, so a rate of 150.00 USD becomes
+150.00 EUR.
+
+Supported currencies: ` + strings.Join(money.Codes(), ", ") + `
+
+Examples:
+  watchmen project currency myproject       # Show the current currency
+  watchmen project currency myproject EUR   # Bill myproject in euros`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 {
+			fmt.Printf("%s is billed in %s\n", project.Name, project.CurrencyCode())
+			return nil
+		}
+
+		currency, err := money.Lookup(args[1])
+		if err != nil {
+			return err
+		}
+
+		var updated model.Project
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			convert := func(amount int64) int64 {
+				return money.FromMajor(money.ToMajor(amount, p.CurrencyCode()), currency.Code)
 			}
-			if project.BillingContact.Address != "" {
-				fmt.Printf("    Address: %s\n", project.BillingContact.Address)
+			p.HourlyRate = convert(p.HourlyRate)
+			for i := range p.Rates {
+				p.Rates[i].Rate = convert(p.Rates[i].Rate)
 			}
-			if project.BillingContact.Phone != "" {
-				fmt.Printf("    Phone:   %s\n", project.BillingContact.Phone)
+			if p.Budget != nil {
+				p.Budget.Amount = convert(p.Budget.Amount)
 			}
-			if project.BillingContact.Email != "" {
-				fmt.Printf("    Email:   %s\n", project.BillingContact.Email)
+			if p.Billing != nil {
+				p.Billing.Fee = convert(p.Billing.Fee)
+				p.Billing.OverageRate = convert(p.Billing.OverageRate)
+				for i := range p.Billing.Milestones {
+					p.Billing.Milestones[i].Amount = convert(p.Billing.Milestones[i].Amount)
+				}
 			}
+			p.Currency = currency.Code
+			updated = *p
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s is now billed in %s\n", updated.Name, updated.CurrencyCode())
+		if current := updated.CurrentRate(); current > 0 {
+			fmt.Printf("  Hourly rate: %s\n", money.Format(current, updated.CurrencyCode()))
 		}
 		return nil
 	},
 }
 
+var projectRateCmd = &cobra.Command{
+	Use:   "rate <project> [rate]",
+	Short: "Show or change a project's hourly rate",
+	Long: `Show a project's rate history, or set a new hourly rate.
+
+A new rate takes effect from the start of --from (default today). Time
+tracked before that date is still billed at the earlier rate, so changing a
+rate never rewrites past invoices. Setting a rate again for the same date
+replaces it.
+
+Examples:
+  watchmen project rate myproject                         # Show rate history
+  watchmen project rate myproject 150                     # 150/hour from today
+  watchmen project rate myproject 150 --from 2026-01-01   # 150/hour from Jan 1`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromStr, _ := cmd.Flags().GetString("from")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 {
+			fmt.Printf("Rates for %s:\n", project.Name)
+			printRateHistory(project, "  ")
+			return nil
+		}
+
+		rate, err := money.Parse(args[1], project.CurrencyCode())
+		if err != nil {
+			return err
+		}
+		if rate < 0 {
+			return fmt.Errorf("rate cannot be negative")
+		}
+
+		loc := projectLocation(project)
+		from := period.StartOfDay(time.Now(), loc)
+		if fromStr != "" {
+			from, err = period.ParseDay(fromStr, loc)
+			if err != nil {
+				return fmt.Errorf("invalid date format for --from, use YYYY-MM-DD")
+			}
+		}
+
+		var updated model.Project
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.SetRate(rate, from)
+			updated = *p
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s is billed at %s/hr from %s\n", updated.Name,
+			money.Format(rate, updated.CurrencyCode()), from.Format("Jan 2, 2006"))
+		return nil
+	},
+}
+
+// printRateHistory lists the initial rate and each dated change after it,
+// marking the one in effect now
+func printRateHistory(p *model.Project, indent string) {
+	now := time.Now()
+	marker := func(active bool) string {
+		if active {
+			return "  (current)"
+		}
+		return ""
+	}
+
+	active := len(p.Rates) == 0 || p.Rates[0].From.After(now)
+	fmt.Printf("%s%-15s %s/hr%s\n", indent, "initial", money.Format(p.HourlyRate, p.CurrencyCode()), marker(active))
+	for i, rc := range p.Rates {
+		active := !rc.From.After(now) && (i == len(p.Rates)-1 || p.Rates[i+1].From.After(now))
+		fmt.Printf("%sfrom %-10s %s/hr%s\n", indent, rc.From.Format("2006-01-02"), money.Format(rc.Rate, p.CurrencyCode()), marker(active))
+	}
+}
+
+var projectTaxCmd = &cobra.Command{
+	Use:   "tax <project> [rate]",
+	Short: "Show or set the tax charged on a project's invoices",
+	Long: `Show or set a percentage-based tax, such as VAT or GST, added to every
+invoice for the project. The rate is a percentage of the subtotal after any
+discount.
+
+Your own tax ID is set with 'watchmen config set --tax-id', and the client's
+with 'watchmen project billing --tax-id'.
+
+Examples:
+  watchmen project tax myproject                # Show the current tax
+  watchmen project tax myproject 20 --name VAT  # Charge 20% VAT
+  watchmen project tax myproject 10 --name GST  # Charge 10% GST
+  watchmen project tax myproject --clear        # Stop charging tax`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		clearTax, _ := cmd.Flags().GetBool("clear")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 && !clearTax {
+			if project.Tax == nil {
+				fmt.Printf("No tax set for %s\n", project.Name)
+				return nil
+			}
+			fmt.Printf("%s charges %s\n", project.Name, project.Tax.Label())
+			return nil
+		}
+
+		var tax *model.Tax
+		if !clearTax {
+			rate, err := strconv.ParseFloat(args[1], 64)
+			if err != nil || rate < 0 || rate > 100 {
+				return fmt.Errorf("invalid tax rate %q, use a percentage such as 20", args[1])
+			}
+			tax = &model.Tax{Name: name, Rate: rate}
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.Tax = tax
+		})
+		if err != nil {
+			return err
+		}
+
+		if tax == nil {
+			fmt.Printf("%s no longer charges tax\n", project.Name)
+		} else {
+			fmt.Printf("%s now charges %s\n", project.Name, tax.Label())
+		}
+		return nil
+	},
+}
+
+var projectRoundingCmd = &cobra.Command{
+	Use:   "rounding <project> [increment]",
+	Short: "Show or set how a project's billed time is rounded",
+	Long: `Show or set the rounding applied to a project's time on invoices.
+
+Each entry is rounded to a multiple of the increment: to the nearest
+multiple by default, or always up or down with --mode. With --daily-min,
+every day with time logged is billed at least that much. Invoices show the
+hours worked next to the hours billed.
+
+Examples:
+  watchmen project rounding myproject                       # Show the policy
+  watchmen project rounding myproject 6m                    # Nearest 6 minutes
+  watchmen project rounding myproject 15m --mode up         # Round each entry up
+  watchmen project rounding myproject 15m --daily-min 2h    # Bill at least 2h a day
+  watchmen project rounding myproject --clear               # Bill raw time`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, _ := cmd.Flags().GetString("mode")
+		dailyMinStr, _ := cmd.Flags().GetString("daily-min")
+		clearRounding, _ := cmd.Flags().GetBool("clear")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 && !clearRounding && dailyMinStr == "" {
+			if project.Rounding == nil {
+				fmt.Printf("%s bills raw time\n", project.Name)
+				return nil
+			}
+			fmt.Printf("Rounding for %s: %s\n", project.Name, project.Rounding)
+			return nil
+		}
+
+		var rounding *model.Rounding
+		if !clearRounding {
+			rounding = &model.Rounding{}
+			if len(args) == 2 {
+				if rounding.Increment, err = parseMinutes(args[1]); err != nil || rounding.Increment <= 0 {
+					return fmt.Errorf("invalid increment %q, use whole minutes such as 6m or 15m", args[1])
+				}
+				switch mode {
+				case model.RoundNearest, model.RoundUp, model.RoundDown:
+					rounding.Mode = mode
+				default:
+					return fmt.Errorf("invalid --mode %q, use nearest, up or down", mode)
+				}
+			}
+			if dailyMinStr != "" {
+				if rounding.DailyMinimum, err = parseMinutes(dailyMinStr); err != nil || rounding.DailyMinimum <= 0 {
+					return fmt.Errorf("invalid --daily-min %q, use a duration such as 2h or 90m", dailyMinStr)
+				}
+			}
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.Rounding = rounding
+		})
+		if err != nil {
+			return err
+		}
+
+		if rounding == nil {
+			fmt.Printf("%s now bills raw time\n", project.Name)
+		} else {
+			fmt.Printf("Rounding for %s set to %s\n", project.Name, rounding)
+		}
+		return nil
+	},
+}
+
+var projectTermsCmd = &cobra.Command{
+	Use:   "terms <project> [days]",
+	Short: "Show or set a project's payment terms",
+	Long: `Show or set the payment terms for new invoices on a project, as the
+number of days the client has to pay (net days). Invoices are overdue once
+their due date has passed.
+
+Examples:
+  watchmen project terms myproject            # Show the current terms
+  watchmen project terms myproject 30         # Net 30
+  watchmen project terms myproject --clear    # No due date`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clearTerms, _ := cmd.Flags().GetBool("clear")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 && !clearTerms {
+			if project.PaymentTerms == 0 {
+				fmt.Printf("No payment terms set for %s\n", project.Name)
+				return nil
+			}
+			fmt.Printf("%s invoices are due net %d\n", project.Name, project.PaymentTerms)
+			return nil
+		}
+
+		days := 0
+		if !clearTerms {
+			days, err = strconv.Atoi(args[1])
+			if err != nil || days <= 0 {
+				return fmt.Errorf("invalid payment terms %q, use a number of days such as 30", args[1])
+			}
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.PaymentTerms = days
+		})
+		if err != nil {
+			return err
+		}
+
+		if days == 0 {
+			fmt.Printf("%s invoices no longer have a due date\n", project.Name)
+		} else {
+			fmt.Printf("%s invoices are now due net %d\n", project.Name, days)
+		}
+		return nil
+	},
+}
+
+// parseMinutes reads a duration such as "15m" or "2h" as whole minutes
+func parseMinutes(s string) (int, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d%time.Minute != 0 {
+		return 0, fmt.Errorf("%s is not a whole number of minutes", s)
+	}
+	return int(d / time.Minute), nil
+}
+
+var projectTemplateCmd = &cobra.Command{
+	Use:   "template <project> [name]",
+	Short: "Show or set a project's default invoice template",
+	Long: `Show or set the template used for a project's invoices when neither --pdf,
+--markdown nor --template is given. See 'watchmen template' for how
+templates work.
+
+Examples:
+  watchmen project template myproject          # Show the current template
+  watchmen project template myproject html     # Use the bundled HTML template
+  watchmen project template myproject acme     # Use ~/.watchmen/templates/acme.*
+  watchmen project template myproject --clear  # Back to plain text`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clearTemplate, _ := cmd.Flags().GetBool("clear")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 && !clearTemplate {
+			if project.Template == "" {
+				fmt.Printf("No invoice template set for %s\n", project.Name)
+				return nil
+			}
+			fmt.Printf("%s invoices use the %s template\n", project.Name, project.Template)
+			return nil
+		}
+
+		name := ""
+		if !clearTemplate {
+			name = args[1]
+			if _, err := invoice.LoadTemplate(templateDir(), name); err != nil {
+				return err
+			}
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.Template = name
+		})
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			fmt.Printf("%s invoices no longer use a template\n", project.Name)
+		} else {
+			fmt.Printf("%s invoices now use the %s template\n", project.Name, name)
+		}
+		return nil
+	},
+}
+
+var projectTimezoneCmd = &cobra.Command{
+	Use:   "timezone <project> [zone]",
+	Short: "Show or set the timezone a project's days are counted in",
+	Long: `Show or set the timezone a project's time is counted in, for when its work
+happens somewhere other than home. Logged times, and the days of its
+timesheet rows, reports and invoices, are in this timezone. Without one the
+home timezone is used, see 'watchmen config timezone'.
+
+Examples:
+  watchmen project timezone myproject                  # Show the timezone
+  watchmen project timezone myproject Asia/Tokyo
+  watchmen project timezone myproject --clear          # Back to the home timezone`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clearZone, _ := cmd.Flags().GetBool("clear")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 && !clearZone {
+			if project.Timezone == "" {
+				fmt.Printf("%s uses the home timezone (%s)\n", project.Name, home)
+				return nil
+			}
+			fmt.Printf("%s days are counted in %s\n", project.Name, project.Timezone)
+			return nil
+		}
+		if len(args) == 2 && clearZone {
+			return fmt.Errorf("cannot use --clear with a timezone")
+		}
+
+		zone := ""
+		if !clearZone {
+			loc, err := period.Location(args[1])
+			if err != nil {
+				return err
+			}
+			zone = loc.String()
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.Timezone = zone
+		})
+		if err != nil {
+			return err
+		}
+
+		if zone == "" {
+			fmt.Printf("%s now uses the home timezone (%s)\n", project.Name, home)
+		} else {
+			fmt.Printf("%s days are now counted in %s\n", project.Name, zone)
+		}
+		return nil
+	},
+}
+
+var projectReposCmd = &cobra.Command{
+	Use:   "repos <project> [path...]",
+	Short: "Show or set a project's git repositories",
+	Long: `Show the local git repositories a project's work is committed to, or add
+them. 'watchmen report --commits' and 'watchmen invoice --detailed
+--commits' list the commits made in them during each entry. When a
+repository sets user.email, only that author's commits are listed.
+
+Examples:
+  watchmen project repos myproject                     # Show the repositories
+  watchmen project repos myproject ~/src/api ~/src/web
+  watchmen project repos myproject --remove ~/src/web
+  watchmen project repos myproject --clear`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remove, _ := cmd.Flags().GetStringArray("remove")
+		clearRepos, _ := cmd.Flags().GetBool("clear")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if len(args) == 1 && len(remove) == 0 && !clearRepos {
+			if len(project.Repos) == 0 {
+				fmt.Printf("No repositories set for %s\n", project.Name)
+				return nil
+			}
+			fmt.Printf("Repositories for %s:\n", project.Name)
+			for _, repo := range project.Repos {
+				fmt.Printf("  %s\n", repo)
+			}
+			return nil
+		}
+
+		repos := slices.Clone(project.Repos)
+		if clearRepos {
+			repos = nil
+		}
+		for _, path := range remove {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			i := slices.Index(repos, abs)
+			if i < 0 {
+				return fmt.Errorf("%s is not a repository of %s", abs, project.Name)
+			}
+			repos = slices.Delete(repos, i, i+1)
+		}
+		for _, path := range args[1:] {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			if !gitlog.IsRepo(abs) {
+				return fmt.Errorf("%s is not a git repository", abs)
+			}
+			if !slices.Contains(repos, abs) {
+				repos = append(repos, abs)
+			}
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.Repos = repos
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(repos) == 0 {
+			fmt.Printf("No repositories set for %s\n", project.Name)
+			return nil
+		}
+		fmt.Printf("Repositories for %s:\n", project.Name)
+		for _, repo := range repos {
+			fmt.Printf("  %s\n", repo)
+		}
+		return nil
+	},
+}
+
+var projectBudgetCmd = &cobra.Command{
+	Use:   "budget <project>",
+	Short: "Show or set a project's hour or money budget",
+	Long: `Show or set a budget of hours, money or both for a project, over its whole
+life or, with --monthly, each calendar month. Only billable time counts,
+rounded and priced as it would be on an invoice.
+
+Status and timesheet show how much is left, and warn once 80% of the
+budget is spent and again when it runs out.
+
+Examples:
+  watchmen project budget myproject                        # Show the budget and what is spent
+  watchmen project budget myproject --hours 40 --monthly   # 40 hours a month
+  watchmen project budget myproject --amount 12000         # 12,000 over the project
+  watchmen project budget myproject --clear                # Remove the budget`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hours, _ := cmd.Flags().GetFloat64("hours")
+		amountStr, _ := cmd.Flags().GetString("amount")
+		monthly, _ := cmd.Flags().GetBool("monthly")
+		clearBudget, _ := cmd.Flags().GetBool("clear")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+
+		if hours == 0 && amountStr == "" && !clearBudget {
+			if project.Budget == nil {
+				fmt.Printf("No budget set for %s\n", project.Name)
+				return nil
+			}
+			fmt.Printf("Budget for %s: %s\n", project.Name, project.Budget.String(project.CurrencyCode()))
+			printBudgetUse(project, "  ")
+			return nil
+		}
+
+		var budget *model.Budget
+		if !clearBudget {
+			if hours < 0 {
+				return fmt.Errorf("--hours cannot be negative")
+			}
+			budget = &model.Budget{Hours: hours, Monthly: monthly}
+			if amountStr != "" {
+				if budget.Amount, err = money.Parse(amountStr, project.CurrencyCode()); err != nil {
+					return err
+				}
+				if budget.Amount <= 0 {
+					return fmt.Errorf("--amount must be more than zero")
+				}
+			}
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.Budget = budget
+		})
+		if err != nil {
+			return err
+		}
+
+		if budget == nil {
+			fmt.Printf("%s no longer has a budget\n", project.Name)
+			return nil
+		}
+		project.Budget = budget
+		fmt.Printf("Budget for %s set to %s\n", project.Name, budget.String(project.CurrencyCode()))
+		printBudgetUse(project, "  ")
+		return nil
+	},
+}
+
+// budgetUse returns what has been spent of a project's budget in the
+// current period, or nil if it has none
+func budgetUse(p *model.Project) *model.BudgetUse {
+	if p.Budget == nil {
+		return nil
+	}
+	var from *time.Time
+	loc := projectLocation(p)
+	if since := p.Budget.Since(time.Now(), loc); !since.IsZero() {
+		from = &since
+	}
+	use := p.Budget.Use(p, store.ListEntries(p.ID, from, nil), loc)
+	return &use
+}
+
+// printBudgetUse prints what has been spent of a project's budget and what
+// is left, with a warning once most of it is gone
+func printBudgetUse(p *model.Project, indent string) {
+	use := budgetUse(p)
+	if use == nil {
+		return
+	}
+	period := ""
+	if p.Budget.Monthly {
+		period = " this month"
+	}
+	if p.Budget.Hours > 0 {
+		fmt.Printf("%sHours: %.2f of %s spent%s, %.2f left\n", indent, use.Hours,
+			strconv.FormatFloat(p.Budget.Hours, 'f', -1, 64), period, max(p.Budget.Hours-use.Hours, 0))
+	}
+	if p.Budget.Amount > 0 {
+		currency := p.CurrencyCode()
+		fmt.Printf("%sAmount: %s of %s spent%s, %s left\n", indent, money.Format(use.Amount, currency),
+			money.Format(p.Budget.Amount, currency), period, money.Format(max(p.Budget.Amount-use.Amount, 0), currency))
+	}
+	if warning := use.Warning(); warning != "" {
+		fmt.Printf("%sWarning: %s\n", indent, warning)
+	}
+}
+
+// budgetJSON describes a project's budget and what has been spent of it
+// for JSON output, or returns nil if it has none
+func budgetJSON(p *model.Project) map[string]interface{} {
+	use := budgetUse(p)
+	if use == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"hours":        p.Budget.Hours,
+		"amount":       money.ToMajor(p.Budget.Amount, p.CurrencyCode()),
+		"monthly":      p.Budget.Monthly,
+		"hours_spent":  use.Hours,
+		"amount_spent": money.ToMajor(use.Amount, p.CurrencyCode()),
+		"fraction":     use.Fraction,
+		"warning":      use.Warning(),
+	}
+}
+
+var projectModelCmd = &cobra.Command{
+	Use:   "model <project> [hourly|retainer|fixed]",
+	Short: "Show or set how a project is billed",
+	Long: `Show or set a project's billing model, which decides the charges on its
+invoices.
+
+  hourly    hours × rate, the default
+  retainer  a monthly fee covering --hours each month, with time beyond
+            them billed at --overage-rate (the project rate if not set).
+            With --rollover, unused hours carry over to the next invoice.
+  fixed     a price per milestone, billed once each with
+            'watchmen invoice --milestone'
+
+Time is still tracked, and listed on invoices, under every model. Giving
+retainer or fixed again changes only the flags given, and --milestone adds
+a milestone or changes its amount.
+
+Examples:
+  watchmen project model myproject                                     # Show the billing model
+  watchmen project model myproject retainer --fee 5000 --hours 20 --overage-rate 175 --rollover
+  watchmen project model myproject fixed --milestone "Design=3000" --milestone "Build=9000"
+  watchmen project model myproject fixed --remove-milestone Design
+  watchmen project model myproject hourly                              # Back to hourly billing`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		feeStr, _ := cmd.Flags().GetString("fee")
+		hours, _ := cmd.Flags().GetFloat64("hours")
+		overageStr, _ := cmd.Flags().GetString("overage-rate")
+		rollover, _ := cmd.Flags().GetBool("rollover")
+		milestones, _ := cmd.Flags().GetStringArray("milestone")
+		remove, _ := cmd.Flags().GetStringArray("remove-milestone")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+		currency := project.CurrencyCode()
+
+		if len(args) == 1 {
+			if project.Billing == nil {
+				fmt.Printf("%s is billed hourly\n", project.Name)
+				return nil
+			}
+			fmt.Printf("Billing for %s: %s\n", project.Name, project.Billing.String(currency))
+			printBilling(project, "  ")
+			return nil
+		}
+
+		retainerFlags := cmd.Flags().Changed("fee") || cmd.Flags().Changed("hours") ||
+			cmd.Flags().Changed("overage-rate") || cmd.Flags().Changed("rollover")
+		fixedFlags := len(milestones) > 0 || len(remove) > 0
+
+		var billing *model.Billing
+		switch args[1] {
+		case model.BillingHourly:
+			if retainerFlags || fixedFlags {
+				return fmt.Errorf("hourly billing takes no flags")
+			}
+		case model.BillingRetainer:
+			if fixedFlags {
+				return fmt.Errorf("--milestone and --remove-milestone only apply to fixed billing")
+			}
+			billing = &model.Billing{Model: model.BillingRetainer}
+			if project.BillingModel() == model.BillingRetainer {
+				*billing = *project.Billing
+			}
+			if feeStr != "" {
+				if billing.Fee, err = money.Parse(feeStr, currency); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("hours") {
+				billing.Hours = hours
+			}
+			if overageStr != "" {
+				if billing.OverageRate, err = money.Parse(overageStr, currency); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("rollover") {
+				billing.Rollover = rollover
+			}
+			if billing.Fee <= 0 {
+				return fmt.Errorf("a retainer needs a --fee of more than zero")
+			}
+			if billing.Hours <= 0 {
+				return fmt.Errorf("a retainer needs --hours of more than zero")
+			}
+			if billing.OverageRate < 0 {
+				return fmt.Errorf("--overage-rate cannot be negative")
+			}
+		case model.BillingFixed:
+			if retainerFlags {
+				return fmt.Errorf("--fee, --hours, --overage-rate and --rollover only apply to a retainer")
+			}
+			billing = &model.Billing{Model: model.BillingFixed}
+			if project.BillingModel() == model.BillingFixed {
+				billing.Milestones = slices.Clone(project.Billing.Milestones)
+			}
+			billed := model.BilledMilestones(store.ListInvoices(project.ID, ""), project.ID)
+			for _, name := range remove {
+				m := billing.Milestone(name)
+				if m == nil {
+					return fmt.Errorf("milestone %q not found", name)
+				}
+				if billed[strings.ToLower(m.Name)] {
+					return fmt.Errorf("milestone %q is already invoiced", m.Name)
+				}
+				billing.Milestones = slices.DeleteFunc(billing.Milestones, func(x model.Milestone) bool { return x.Name == m.Name })
+			}
+			for _, s := range milestones {
+				m, err := parseMilestone(s, currency)
+				if err != nil {
+					return err
+				}
+				if existing := billing.Milestone(m.Name); existing != nil {
+					if billed[strings.ToLower(existing.Name)] {
+						return fmt.Errorf("milestone %q is already invoiced", existing.Name)
+					}
+					existing.Amount = m.Amount
+					continue
+				}
+				billing.Milestones = append(billing.Milestones, m)
+			}
+			if len(billing.Milestones) == 0 {
+				return fmt.Errorf("fixed billing needs at least one --milestone")
+			}
+		default:
+			return fmt.Errorf("unknown billing model %q, use hourly, retainer or fixed", args[1])
+		}
+
+		err = store.UpdateProject(project.ID, func(p *model.Project) {
+			p.Billing = billing
+		})
+		if err != nil {
+			return err
+		}
+
+		if billing == nil {
+			fmt.Printf("%s is now billed hourly\n", project.Name)
+			return nil
+		}
+		project.Billing = billing
+		fmt.Printf("Billing for %s set to %s\n", project.Name, billing.String(currency))
+		printBilling(project, "  ")
+		return nil
+	},
+}
+
+// parseMilestone reads a milestone given as name=amount, with the amount
+// in major units
+func parseMilestone(s, currency string) (model.Milestone, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return model.Milestone{}, fmt.Errorf("invalid milestone %q, use name=amount", s)
+	}
+	name := strings.TrimSpace(s[:i])
+	amount, err := money.Parse(strings.TrimSpace(s[i+1:]), currency)
+	if err != nil {
+		return model.Milestone{}, err
+	}
+	if name == "" || amount <= 0 {
+		return model.Milestone{}, fmt.Errorf("invalid milestone %q, use name=amount", s)
+	}
+	return model.Milestone{Name: name, Amount: amount}, nil
+}
+
+// printBilling prints the detail behind a project's billing model: the
+// hours rolled over on a retainer, or each milestone and whether it has
+// been invoiced
+func printBilling(p *model.Project, indent string) {
+	invoices := store.ListInvoices(p.ID, "")
+	switch p.BillingModel() {
+	case model.BillingRetainer:
+		if p.Billing.Rollover {
+			fmt.Printf("%sRolled over: %.2f hours\n", indent, model.RolloverHours(invoices, p.ID))
+		}
+	case model.BillingFixed:
+		billed := model.BilledMilestones(invoices, p.ID)
+		for _, m := range p.Billing.Milestones {
+			state := "not invoiced"
+			if billed[strings.ToLower(m.Name)] {
+				state = "invoiced"
+			}
+			fmt.Printf("%s%-24s %12s  %s\n", indent, m.Name, money.Format(m.Amount, p.CurrencyCode()), state)
+		}
+	}
+}
+
+// projectLocation returns the timezone a project's days are counted in
+func projectLocation(p *model.Project) *time.Location {
+	return period.LocationOr(p.Timezone, home)
+}
+
 func init() {
 	projectAddCmd.Flags().Float64P("rate", "r", 0, "Hourly rate for the project")
 	projectAddCmd.Flags().StringP("description", "d", "", "Project description")
+	projectAddCmd.Flags().String("currency", money.DefaultCurrency, "Currency code for the rate and invoices (e.g. USD, EUR, GBP)")
+	projectAddCmd.Flags().String("client", "", "Client the project belongs to")
 
-	projectBillingCmd.Flags().String("name", "", "Contact name")
-	projectBillingCmd.Flags().String("company", "", "Company name")
-	projectBillingCmd.Flags().String("address", "", "Address")
-	projectBillingCmd.Flags().String("phone", "", "Phone number")
-	projectBillingCmd.Flags().String("email", "", "Email address")
+	addContactFlags(projectBillingCmd)
 	projectBillingCmd.Flags().String("po", "", "Purchase order number")
 
+	projectRateCmd.Flags().String("from", "", "Date the rate takes effect (YYYY-MM-DD, default today)")
+
+	projectTaxCmd.Flags().String("name", "Tax", "Name of the tax shown on invoices (e.g. VAT, GST)")
+	projectTaxCmd.Flags().Bool("clear", false, "Remove the tax from the project")
+
+	projectRoundingCmd.Flags().String("mode", model.RoundNearest, "Round each entry to the nearest increment, or always up or down")
+	projectRoundingCmd.Flags().String("daily-min", "", "Minimum billed per day with time logged (e.g. 2h)")
+	projectRoundingCmd.Flags().Bool("clear", false, "Bill raw time with no rounding")
+
+	projectTermsCmd.Flags().Bool("clear", false, "Remove the payment terms from the project")
+
+	projectTemplateCmd.Flags().Bool("clear", false, "Remove the default template from the project")
+
+	projectTimezoneCmd.Flags().Bool("clear", false, "Count the project's days in the home timezone")
+
+	projectReposCmd.Flags().StringArray("remove", nil, "Repository to stop reading commits from (repeatable)")
+	projectReposCmd.Flags().Bool("clear", false, "Remove every repository from the project")
+
+	projectBudgetCmd.Flags().Float64("hours", 0, "Hours budgeted")
+	projectBudgetCmd.Flags().String("amount", "", "Money budgeted, in the project currency")
+	projectBudgetCmd.Flags().Bool("monthly", false, "Budget each calendar month rather than the whole project")
+	projectBudgetCmd.Flags().Bool("clear", false, "Remove the budget from the project")
+
+	projectModelCmd.Flags().String("fee", "", "Retainer fee each month, in the project currency")
+	projectModelCmd.Flags().Float64("hours", 0, "Hours the retainer covers each month")
+	projectModelCmd.Flags().String("overage-rate", "", "Hourly rate beyond the retainer's hours (default: the project rate)")
+	projectModelCmd.Flags().Bool("rollover", false, "Carry unused retainer hours over to the next invoice")
+	projectModelCmd.Flags().StringArray("milestone", nil, "Fixed-price milestone as name=amount (repeatable)")
+	projectModelCmd.Flags().StringArray("remove-milestone", nil, "Remove a milestone not yet invoiced (repeatable)")
+
 	projectCmd.AddCommand(projectAddCmd)
 	projectCmd.AddCommand(projectListCmd)
 	projectCmd.AddCommand(projectBillingCmd)
 	projectCmd.AddCommand(projectShowCmd)
+	projectCmd.AddCommand(projectCurrencyCmd)
+	projectCmd.AddCommand(projectRateCmd)
+	projectCmd.AddCommand(projectTaxCmd)
+	projectCmd.AddCommand(projectRoundingCmd)
+	projectCmd.AddCommand(projectTermsCmd)
+	projectCmd.AddCommand(projectTemplateCmd)
+	projectCmd.AddCommand(projectReposCmd)
+	projectCmd.AddCommand(projectTimezoneCmd)
+	projectCmd.AddCommand(projectBudgetCmd)
+	projectCmd.AddCommand(projectModelCmd)
 }
@@ -3,10 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/gitlog"
 	"watchmen/internal/invoice"
+	"watchmen/internal/model"
+	"watchmen/internal/period"
 )
 
 var reportCmd = &cobra.Command{
@@ -18,57 +23,47 @@ Examples:
   watchmen report myproject --week              # This week's work
   watchmen report myproject --month             # This month's work
   watchmen report myproject --since 2026-01-01  # Since a specific date
-  watchmen report myproject --invoice INV-foo-123 -o report.md`,
+  watchmen report myproject --invoice INV-foo-123 -o report.md
+  watchmen report myproject --month --billable  # Only billable work
+  watchmen report myproject --category design   # Only one category
+  watchmen report myproject --week --commits    # With the commits made each day
+
+With --commits, the commits made during each entry in the project's git
+repositories, set with 'watchmen project repos', are listed under the day
+they were worked.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		sinceStr, _ := cmd.Flags().GetString("since")
-		untilStr, _ := cmd.Flags().GetString("until")
-		thisWeek, _ := cmd.Flags().GetBool("week")
-		thisMonth, _ := cmd.Flags().GetBool("month")
 		invoiceRef, _ := cmd.Flags().GetString("invoice")
 		outputFile, _ := cmd.Flags().GetString("output")
+		withCommits, _ := cmd.Flags().GetBool("commits")
 
 		project, err := store.GetProject(args[0])
 		if err != nil {
 			return fmt.Errorf("project %q not found", args[0])
 		}
 
-		var from, to time.Time
+		// Days are counted in the project's timezone
+		loc := projectLocation(project)
 		now := time.Now()
-
-		if thisWeek {
-			weekday := int(now.Weekday())
-			if weekday == 0 {
-				weekday = 7
-			}
-			from = now.AddDate(0, 0, -weekday+1)
-			from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
-			to = now
-		} else if thisMonth {
-			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
-			to = now
-		} else if sinceStr != "" {
-			from, err = time.Parse("2006-01-02", sinceStr)
-			if err != nil {
-				return fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
-			}
-			if untilStr != "" {
-				to, err = time.Parse("2006-01-02", untilStr)
-				if err != nil {
-					return fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
-				}
-			} else {
-				to = now
-			}
-		} else {
-			// Default to this month
-			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		r, err := periodFlags(cmd).Range(loc, now)
+		if err != nil {
+			return err
+		}
+		from, to := r.From, r.To
+		// Default to this month, or the month of --until
+		if to.IsZero() {
 			to = now
 		}
+		if from.IsZero() {
+			from = period.StartOfMonth(to, loc)
+		}
 
 		fromPtr := &from
 		toPtr := &to
-		entries := store.ListEntries(project.ID, fromPtr, toPtr)
+		entries, err := filterEntries(cmd, store.ListEntries(project.ID, fromPtr, toPtr))
+		if err != nil {
+			return err
+		}
 
 		if len(entries) == 0 {
 			return fmt.Errorf("no entries found for %s in the specified period", project.Name)
@@ -87,6 +82,12 @@ Examples:
 			TotalHours:  totalHours,
 			InvoiceRef:  invoiceRef,
 			Entries:     entries,
+			Location:    loc,
+		}
+		if withCommits {
+			if data.Commits, err = entryCommits([]model.Project{*project}, entries, now); err != nil {
+				return err
+			}
 		}
 
 		var out *os.File
@@ -112,11 +113,45 @@ Examples:
 	},
 }
 
+// entryCommits reads the commits made during each entry in its project's
+// repositories, keyed by entry ID and sorted by time. A repository that
+// cannot be read is skipped with a warning, keeping the others' commits.
+func entryCommits(projects []model.Project, entries []model.Entry, now time.Time) (map[string][]gitlog.Commit, error) {
+	commits := make(map[string][]gitlog.Commit)
+	var names []string
+	for _, p := range projects {
+		if len(p.Repos) == 0 {
+			names = append(names, p.Name)
+			continue
+		}
+		own := slices.DeleteFunc(slices.Clone(entries), func(e model.Entry) bool { return e.ProjectID != p.ID })
+		for _, repo := range p.Repos {
+			matched, err := gitlog.ForEntries([]string{repo}, own, now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v, skipping its commits\n", err)
+				continue
+			}
+			for id, c := range matched {
+				commits[id] = append(commits[id], c...)
+			}
+		}
+	}
+	if len(names) == len(projects) {
+		return nil, fmt.Errorf("no repositories set for %s, add them with 'watchmen project repos'", strings.Join(names, ", "))
+	}
+	for _, c := range commits {
+		slices.SortStableFunc(c, func(a, b gitlog.Commit) int { return a.Time.Compare(b.Time) })
+	}
+	return commits, nil
+}
+
 func init() {
 	reportCmd.Flags().String("since", "", "Start date (YYYY-MM-DD)")
-	reportCmd.Flags().String("until", "", "End date (YYYY-MM-DD)")
+	reportCmd.Flags().String("until", "", "End date, inclusive (YYYY-MM-DD)")
 	reportCmd.Flags().BoolP("week", "w", false, "This week")
 	reportCmd.Flags().BoolP("month", "m", false, "This month")
 	reportCmd.Flags().StringP("invoice", "i", "", "Invoice number to reference in header")
 	reportCmd.Flags().StringP("output", "o", "", "Output file (default: stdout)")
+	reportCmd.Flags().Bool("commits", false, "List the commits made during each day's entries")
+	addEntryFilterFlags(reportCmd)
 }
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func TestEntryCommitsSkipsUnreadableRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "web")
+	os.MkdirAll(repo, 0755)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "me@example.com"},
+		{"config", "user.name", "Me"},
+		{"commit", "-q", "--allow-empty", "-m", "Fix login"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_DATE=2026-10-05T09:15:00Z", "GIT_COMMITTER_DATE=2026-10-05T09:15:00Z",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	start := time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	entries := []model.Entry{{ID: "e1", ProjectID: "p1", Segments: []model.TimeSegment{{Start: start, End: &end}}}}
+	projects := []model.Project{{ID: "p1", Name: "web", Repos: []string{filepath.Join(t.TempDir(), "gone"), repo}}}
+
+	// The missing repository is warned about and the other still read
+	commits, err := entryCommits(projects, entries, end)
+	if err != nil {
+		t.Fatalf("entryCommits() error = %v", err)
+	}
+	if c := commits["e1"]; len(c) != 1 || c[0].Subject != "Fix login" {
+		t.Errorf("commits = %+v, want Fix login", c)
+	}
+}
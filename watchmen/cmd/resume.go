@@ -1,29 +1,48 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/storage"
 )
 
 var resumeCmd = &cobra.Command{
-	Use:   "resume",
+	Use:   "resume [project]",
 	Short: "Resume a paused time entry",
+	Long: `Resume a paused time entry. With a paused entry allowed on each project
+(see 'watchmen config timer --pause-per-project'), name the project to
+resume when several are paused.
+
+Examples:
+  watchmen resume
+  watchmen resume acme`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		entry, err := store.ResumeEntry()
+		projectID := ""
+		if len(args) > 0 {
+			project, err := store.GetProject(args[0])
+			if err != nil {
+				return fmt.Errorf("project %q not found", args[0])
+			}
+			projectID = project.ID
+		}
+		entry, err := store.ResumeEntry(projectID)
+		if errors.Is(err, storage.ErrSeveralPaused) {
+			return fmt.Errorf("several entries are paused, name the project to resume: %s", pausedProjectNames())
+		}
+		if errors.Is(err, storage.ErrNoPausedEntry) && len(args) > 0 {
+			return fmt.Errorf("%s has no paused entry", args[0])
+		}
 		if err != nil {
 			return err
 		}
 
-		project, _ := store.GetProject(entry.ProjectID)
-		projectName := entry.ProjectID
-		if project != nil {
-			projectName = project.Name
-		}
-
 		duration := entry.Duration()
-		fmt.Printf("Resumed tracking on %s\n", projectName)
+		fmt.Printf("Resumed tracking on %s\n", projectName(entry.ProjectID))
 		fmt.Printf("  Resumed at: %s\n", time.Now().Format("3:04 PM"))
 		fmt.Printf("  Previous time: %s (%.2f hours)\n", formatDuration(duration), duration.Hours())
 		fmt.Printf("  Segments: %d\n", len(entry.Segments))
@@ -34,6 +53,15 @@ var resumeCmd = &cobra.Command{
 	},
 }
 
+// pausedProjectNames lists the projects with a paused entry
+func pausedProjectNames() string {
+	var names []string
+	for _, e := range store.PausedEntries() {
+		names = append(names, projectName(e.ProjectID))
+	}
+	return strings.Join(names, ", ")
+}
+
 func init() {
 	rootCmd.AddCommand(resumeCmd)
 }
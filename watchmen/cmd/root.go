@@ -3,30 +3,55 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/period"
 	"watchmen/internal/storage"
 )
 
-var store *storage.Store
+var store storage.Store
 var dataPath string
 
+// storePath is the resolved path of the open data file
+var storePath string
+
+// home is the home timezone from the settings, which days are counted in
+// unless a project has its own
+var home = time.Local
+
 var rootCmd = &cobra.Command{
 	Use:   "watchmen",
 	Short: "Track work hours and generate invoices",
 	Long:  `A CLI tool to track time spent on projects with notes, and generate invoices.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		path := dataPath
-		if path == "" {
+		storePath = dataPath
+		if storePath == "" {
 			var err error
-			path, err = storage.DefaultPath()
+			storePath, err = storage.DefaultPath()
 			if err != nil {
 				return err
 			}
 		}
 		var err error
-		store, err = storage.New(path)
-		return err
+		store, err = storage.Open(storePath)
+		if err != nil {
+			return err
+		}
+		// A bad saved timezone must not lock out 'config timezone', which
+		// is how it gets fixed
+		zone := store.GetSettings().Timezone
+		if home, err = period.Location(zone); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: home timezone %q is unknown, using the system's; set it with 'watchmen config timezone'\n", zone)
+			home = time.Local
+		}
+		return nil
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if store == nil {
+			return nil
+		}
+		return store.Close()
 	},
 }
 
@@ -38,14 +63,19 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&dataPath, "data", "", "Path to data file (default: ~/.watchmen/data.json)")
+	rootCmd.PersistentFlags().StringVar(&dataPath, "data", "", "Path to data file, .json or .db (default: ~/.watchmen/data.db if present, else data.json)")
 
+	rootCmd.AddCommand(clientCmd)
 	rootCmd.AddCommand(projectCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(switchCmd)
+	rootCmd.AddCommand(trimCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(logCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(timesheetCmd)
+	rootCmd.AddCommand(statsCmd)
 	rootCmd.AddCommand(amendCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(invoiceCmd)
@@ -54,4 +84,11 @@ func init() {
 	rootCmd.AddCommand(pauseCmd)
 	rootCmd.AddCommand(resumeCmd)
 	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(expenseCmd)
+	rootCmd.AddCommand(templateCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(serveCmd)
 }
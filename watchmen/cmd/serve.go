@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local HTTP/JSON API for status bars and editor plugins",
+	Long: `Serve the timer and your data over HTTP on localhost, so integrations
+need not run 'watchmen status --json' and read the whole data file each
+time. Requests are handled one at a time, so clients never race on the
+data file, and changes made with the CLI meanwhile are picked up.
+
+Endpoints (POST bodies are JSON, e.g. {"project": "acme", "note": "..."}):
+  GET  /status                           Timer status, as 'status --json'
+  POST /start     {project, note}        Start tracking on a project
+  POST /stop      {note, force}          Stop the timer; force stops a segment
+                                         longer than max_segment_hours
+  POST /pause                            Pause the timer
+  POST /resume    {project}              Resume a paused entry
+  GET  /entries?project=&since=&until=   List entries (dates YYYY-MM-DD)
+  GET  /projects                         List projects
+  GET  /events                           Server-Sent Events: a "status" event
+                                         on connecting, on every change and
+                                         each --interval while running
+
+Only loopback addresses are allowed, and requests must be addressed to
+localhost. Errors are returned as {"error": "..."}.
+
+Examples:
+  watchmen serve
+  watchmen serve --addr 127.0.0.1:9000 --interval 5s
+  curl -s localhost:7717/status
+  curl -s -X POST -H 'Content-Type: application/json' -d '{"project":"acme"}' localhost:7717/start
+  curl -N localhost:7717/events`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		interval, _ := cmd.Flags().GetDuration("interval")
+
+		if err := server.CheckAddr(addr); err != nil {
+			return err
+		}
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be more than zero")
+		}
+
+		srv := server.New(storePath, store, interval)
+		// The server may reopen the store, so close whichever it ends with
+		defer func() { store = srv.Store() }()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		httpServer := &http.Server{
+			Handler:           srv.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+			// Cancelled on shutdown, which ends open event streams
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		}
+		go srv.Run(ctx.Done())
+
+		errc := make(chan error, 1)
+		go func() { errc <- httpServer.Serve(listener) }()
+		fmt.Fprintf(os.Stderr, "Serving on http://%s (Ctrl-C to stop)\n", listener.Addr())
+
+		select {
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "127.0.0.1:7717", "Address to listen on, which must be a loopback address")
+	serveCmd.Flags().Duration("interval", 10*time.Second, "How often to send the status to /events clients while the timer runs")
+}
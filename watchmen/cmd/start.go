@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/storage"
 )
 
 var startCmd = &cobra.Command{
@@ -18,8 +21,18 @@ var startCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("project %q not found", args[0])
 		}
+		updates, err := entryUpdates(cmd, project)
+		if err != nil {
+			return err
+		}
 
-		entry, err := store.StartEntry(project.ID, note)
+		if active := store.ActiveEntry(); active != nil {
+			printTimerWarnings(os.Stderr, timerWarnings(active))
+		}
+		entry, err := store.StartEntry(project.ID, note, updates)
+		if errors.Is(err, storage.ErrActiveEntry) {
+			return fmt.Errorf("%w, use 'watchmen switch %s' to stop it and start this one", err, args[0])
+		}
 		if err != nil {
 			return err
 		}
@@ -29,10 +42,12 @@ var startCmd = &cobra.Command{
 		if note != "" {
 			fmt.Printf("  Note: %s\n", note)
 		}
+		printEntryDetails(entry, project)
 		return nil
 	},
 }
 
 func init() {
 	startCmd.Flags().StringP("note", "n", "", "Note for this time entry")
+	addEntryFlags(startCmd)
 }
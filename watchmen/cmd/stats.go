@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+	"watchmen/internal/stats"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show utilisation, working patterns and effective hourly rate",
+	Long: `Show how the time in a period was spent: hours worked and billable,
+utilisation against your goals, average day length, longest run of days
+worked, hours by weekday and the effective hourly rate. The period is this
+month up to today unless given.
+
+Utilisation is billable hours over the hours your goals call for in the
+period (see 'watchmen config goals'), or over all hours worked when no
+goals are set. The effective rate is what invoices charged for the
+period's time, spread over the hours behind it and any non-billable
+hours, so unpaid work brings it down. Time not invoiced yet is left out.
+
+Examples:
+  watchmen stats                                  # This month so far
+  watchmen stats --week
+  watchmen stats --since 2026-01-01 --until 2026-06-30
+  watchmen stats -p acme --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectFilter, _ := cmd.Flags().GetString("project")
+		sinceStr, _ := cmd.Flags().GetString("since")
+		untilStr, _ := cmd.Flags().GetString("until")
+		thisWeek, _ := cmd.Flags().GetBool("week")
+		outputJSON, _ := cmd.Flags().GetBool("json")
+
+		now := time.Now()
+		from := period.StartOfMonth(now, home)
+		until := period.StartOfDay(now, home)
+		if thisWeek {
+			if sinceStr != "" || untilStr != "" {
+				return fmt.Errorf("cannot use --week with --since or --until")
+			}
+			from = period.StartOfWeek(now, home)
+		}
+		var err error
+		if sinceStr != "" {
+			if from, err = period.ParseDay(sinceStr, home); err != nil {
+				return fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
+			}
+		}
+		if untilStr != "" {
+			if until, err = period.ParseDay(untilStr, home); err != nil {
+				return fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
+			}
+		}
+		if until.Before(from) {
+			return fmt.Errorf("--until is before --since")
+		}
+		days := period.Days(from, until) + 1
+
+		projects := store.ListProjects()
+		if projectFilter != "" {
+			project, err := store.GetProject(projectFilter)
+			if err != nil {
+				return fmt.Errorf("project %q not found", projectFilter)
+			}
+			projectFilter = project.ID
+		}
+		// Invoices share their labour among all the entries they billed,
+		// so entries outside the period are needed too
+		entries, err := filterEntries(cmd, store.ListEntries(projectFilter, nil, nil))
+		if err != nil {
+			return err
+		}
+		invoices := store.ListInvoices(projectFilter, "")
+
+		st := stats.Compute(entries, projects, invoices, store.GetSettings().Goals, from, days, now)
+		if outputJSON {
+			return printStatsJSON(st)
+		}
+
+		fmt.Printf("Stats for %s - %s\n", st.From.Format("Jan 2, 2006"), st.To.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+		fmt.Printf("  Hours worked:   %.2f\n", st.Hours)
+		fmt.Printf("  Billable hours: %.2f\n", st.BillableHours)
+		if st.GoalHours > 0 {
+			fmt.Printf("  Utilisation:    %.0f%% of %.2f goal hours\n", st.Utilisation*100, st.GoalHours)
+		} else {
+			fmt.Printf("  Utilisation:    %.0f%% of hours worked (no goals set)\n", st.Utilisation*100)
+		}
+		if st.DailyGoal > 0 {
+			fmt.Printf("  Daily goal met: %d of %d days worked (%gh)\n", st.DaysMetGoal, st.DaysWorked, st.DailyGoal)
+		}
+		fmt.Printf("  Days worked:    %d of %d\n", st.DaysWorked, st.Days)
+		if st.DaysWorked > 0 {
+			fmt.Printf("  Average day:    %s\n", formatHours(st.AverageDay))
+			last := st.StreakFrom.AddDate(0, 0, st.LongestStreak-1)
+			fmt.Printf("  Longest streak: %d days (%s - %s)\n", st.LongestStreak, st.StreakFrom.Format("Jan 2"), last.Format("Jan 2"))
+		}
+
+		fmt.Println("\nBy weekday:")
+		for _, wd := range st.Weekdays {
+			fmt.Printf("  %-10s %8.2f\n", wd.Day, wd.Hours)
+		}
+
+		fmt.Println("\nEffective rate:")
+		if len(st.Rates) == 0 {
+			fmt.Println("  No invoiced time in this period")
+		}
+		for _, r := range st.Rates {
+			fmt.Printf("  %s/hr  (%s over %.2f hours)\n", money.Format(r.Rate, r.Currency), money.Format(r.Invoiced, r.Currency), r.Hours)
+		}
+		return nil
+	},
+}
+
+func printStatsJSON(st *stats.Stats) error {
+	weekdays := make([]map[string]interface{}, 0, len(st.Weekdays))
+	for _, wd := range st.Weekdays {
+		weekdays = append(weekdays, map[string]interface{}{"day": wd.Day, "hours": wd.Hours})
+	}
+	rates := make([]map[string]interface{}, 0, len(st.Rates))
+	for _, r := range st.Rates {
+		rates = append(rates, map[string]interface{}{
+			"currency": r.Currency,
+			"invoiced": money.ToMajor(r.Invoiced, r.Currency),
+			"hours":    r.Hours,
+			"rate":     money.ToMajor(r.Rate, r.Currency),
+		})
+	}
+	output := map[string]interface{}{
+		"from":              st.From.Format("2006-01-02"),
+		"until":             st.To.AddDate(0, 0, -1).Format("2006-01-02"),
+		"days":              st.Days,
+		"hours":             st.Hours,
+		"billable_hours":    st.BillableHours,
+		"goal_hours":        st.GoalHours,
+		"utilisation":       st.Utilisation,
+		"daily_goal":        st.DailyGoal,
+		"days_met_goal":     st.DaysMetGoal,
+		"days_worked":       st.DaysWorked,
+		"average_day_hours": st.AverageDay,
+		"longest_streak":    st.LongestStreak,
+		"weekdays":          weekdays,
+		"effective_rates":   rates,
+	}
+	if st.LongestStreak > 0 {
+		output["streak_from"] = st.StreakFrom.Format("2006-01-02")
+	}
+	jsonData, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+// formatHours formats a number of hours as e.g. "7h 30m"
+func formatHours(h float64) string {
+	d := time.Duration(h * float64(time.Hour)).Round(time.Minute)
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func init() {
+	statsCmd.Flags().StringP("project", "p", "", "Only this project")
+	statsCmd.Flags().String("since", "", "First day (YYYY-MM-DD, default the 1st of this month)")
+	statsCmd.Flags().String("until", "", "Last day (YYYY-MM-DD, default today)")
+	statsCmd.Flags().BoolP("week", "w", false, "This week so far")
+	statsCmd.Flags().Bool("json", false, "Output the stats as JSON")
+	addEntryFilterFlags(statsCmd)
+}
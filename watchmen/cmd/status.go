@@ -3,9 +3,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/model"
 )
 
 var statusCmd = &cobra.Command{
@@ -24,13 +27,11 @@ var statusCmd = &cobra.Command{
 			return nil
 		}
 
+		name := projectName(entry.ProjectID)
 		project, _ := store.GetProject(entry.ProjectID)
-		projectName := entry.ProjectID
-		if project != nil {
-			projectName = project.Name
-		}
 
 		duration := entry.Duration()
+		warnings := timerWarnings(entry)
 		status := "running"
 		if entry.IsPaused() {
 			status = "paused"
@@ -42,12 +43,17 @@ var statusCmd = &cobra.Command{
 				"paused":          entry.IsPaused(),
 				"status":          status,
 				"project_id":      entry.ProjectID,
-				"project_name":    projectName,
+				"project_name":    name,
 				"started_at":      entry.StartTime(),
 				"elapsed_seconds": int(duration.Seconds()),
 				"hours":           duration.Hours(),
 				"segments":        len(entry.Segments),
 				"note":            entry.Note,
+				"warnings":        warnings,
+				"also_paused":     pausedJSON(otherPaused(entry)),
+			}
+			if project != nil && project.Budget != nil {
+				output["budget"] = budgetJSON(project)
 			}
 			jsonData, err := json.Marshal(output)
 			if err != nil {
@@ -57,13 +63,24 @@ var statusCmd = &cobra.Command{
 			return nil
 		}
 
-		fmt.Printf("Currently tracking: %s (%s)\n", projectName, status)
+		fmt.Printf("Currently tracking: %s (%s)\n", name, status)
 		fmt.Printf("  Started: %s\n", entry.StartTime().Format("3:04 PM"))
 		fmt.Printf("  Accumulated: %s (%.2f hours)\n", formatDuration(duration), duration.Hours())
 		fmt.Printf("  Segments: %d\n", len(entry.Segments))
 		if entry.Note != "" {
 			fmt.Printf("  Note: %s\n", entry.Note)
 		}
+		printTimerWarnings(os.Stdout, warnings)
+		if project != nil && project.Budget != nil {
+			fmt.Printf("  Budget: %s\n", project.Budget.String(project.CurrencyCode()))
+			printBudgetUse(project, "    ")
+		}
+		if others := otherPaused(entry); len(others) > 0 {
+			fmt.Println("Also paused:")
+			for _, e := range others {
+				fmt.Printf("  %-20s %s (%.2f hours)\n", projectName(e.ProjectID), formatDuration(e.Duration()), e.Duration().Hours())
+			}
+		}
 		return nil
 	},
 }
@@ -72,6 +89,50 @@ func init() {
 	statusCmd.Flags().Bool("json", false, "Output status as JSON")
 }
 
+// otherPaused returns the paused entries other than entry, when each
+// project may have one
+func otherPaused(entry *model.Entry) []model.Entry {
+	var others []model.Entry
+	for _, e := range store.PausedEntries() {
+		if e.ID != entry.ID {
+			others = append(others, e)
+		}
+	}
+	return others
+}
+
+func pausedJSON(entries []model.Entry) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, e := range entries {
+		result = append(result, map[string]interface{}{
+			"project_id":   e.ProjectID,
+			"project_name": projectName(e.ProjectID),
+			"hours":        e.Duration().Hours(),
+			"note":         e.Note,
+		})
+	}
+	return result
+}
+
+// timerWarnings returns why entry looks like a timer left running by mistake
+func timerWarnings(entry *model.Entry) []string {
+	warnings := entry.TimerWarnings(time.Now(), store.GetSettings().Timer.WarnAfter(), home)
+	if warnings == nil {
+		return []string{}
+	}
+	return warnings
+}
+
+func printTimerWarnings(w io.Writer, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  Warning: %s\n", warning)
+	}
+	fmt.Fprintln(w, "  If you forgot to stop it, use 'watchmen trim --at <time>'")
+}
+
 func formatDuration(d time.Duration) string {
 	h := int(d.Hours())
 	m := int(d.Minutes()) % 60
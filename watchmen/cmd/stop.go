@@ -1,32 +1,68 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
+	"watchmen/internal/storage"
 )
 
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop the current time entry",
+	Long: `Stop the current time entry.
+
+If max_segment_hours is set (see 'watchmen config timer') and the running
+segment is longer, stop asks before saving it, as the timer was probably
+left running. With --json it does not ask, and instead reports a warning
+without stopping. Use --yes to stop anyway, or 'watchmen trim' to stop at
+the time you actually finished.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		note, _ := cmd.Flags().GetString("note")
+		yes, _ := cmd.Flags().GetBool("yes")
+		outputJSON, _ := cmd.Flags().GetBool("json")
+
+		active := store.ActiveEntry()
+		if active == nil {
+			return storage.ErrNoActiveEntry
+		}
+		var warnings []string
+		if seg := active.OpenSegment(); seg != nil {
+			limit := store.GetSettings().Timer.MaxSegment()
+			if elapsed := time.Since(seg.Start); limit > 0 && elapsed > limit {
+				warnings = append(warnings, fmt.Sprintf("segment has been running for %.1f hours, longer than max_segment_hours (%g)",
+					elapsed.Hours(), limit.Hours()))
+			}
+		}
+
+		if len(warnings) > 0 && !yes {
+			if outputJSON {
+				return printStopJSON(false, active.ProjectID, 0, active.Note, warnings)
+			}
+			fmt.Printf("Warning: %s\n", warnings[0])
+			fmt.Print("Stop and save it anyway? [y/N] ")
+			var confirm string
+			fmt.Scanln(&confirm)
+			if confirm != "y" && confirm != "Y" {
+				fmt.Println("Not stopped. Use 'watchmen trim --at <time>' to stop at the time you finished.")
+				return nil
+			}
+		}
 
 		entry, err := store.StopEntry(note)
 		if err != nil {
 			return err
 		}
 
-		project, _ := store.GetProject(entry.ProjectID)
-		projectName := entry.ProjectID
-		if project != nil {
-			projectName = project.Name
-		}
-
 		duration := entry.Duration()
 		hours := duration.Hours()
+		if outputJSON {
+			return printStopJSON(true, entry.ProjectID, hours, entry.Note, warnings)
+		}
 
-		fmt.Printf("Stopped tracking time on %s\n", projectName)
+		fmt.Printf("Stopped tracking time on %s\n", projectName(entry.ProjectID))
 		fmt.Printf("  Duration: %s (%.2f hours)\n", formatDuration(duration), hours)
 		if entry.Note != "" {
 			fmt.Printf("  Note: %s\n", entry.Note)
@@ -35,6 +71,36 @@ var stopCmd = &cobra.Command{
 	},
 }
 
+func printStopJSON(stopped bool, projectID string, hours float64, note string, warnings []string) error {
+	if warnings == nil {
+		warnings = []string{}
+	}
+	output := map[string]interface{}{
+		"stopped":      stopped,
+		"project_id":   projectID,
+		"project_name": projectName(projectID),
+		"hours":        hours,
+		"note":         note,
+		"warnings":     warnings,
+	}
+	jsonData, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+// projectName returns the name of a project, or its ID if it has gone
+func projectName(id string) string {
+	if project, _ := store.GetProject(id); project != nil {
+		return project.Name
+	}
+	return id
+}
+
 func init() {
 	stopCmd.Flags().StringP("note", "n", "", "Add note when stopping")
+	stopCmd.Flags().BoolP("yes", "y", false, "Stop without asking, however long the segment")
+	stopCmd.Flags().Bool("json", false, "Output the result as JSON")
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var switchCmd = &cobra.Command{
+	Use:   "switch <project>",
+	Short: "Stop the current time entry and start another",
+	Long: `Stop the running time entry and start one on another project at the same
+instant, leaving no gap between them. If nothing is running, just starts.
+
+With a paused entry allowed on each project (see 'watchmen config timer
+--pause-per-project'), --pause pauses the running entry instead, to resume
+later with 'watchmen resume <project>'.
+
+Examples:
+  watchmen switch beta
+  watchmen switch beta -n "Release prep"
+  watchmen switch beta --pause`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		note, _ := cmd.Flags().GetString("note")
+		pause, _ := cmd.Flags().GetBool("pause")
+
+		project, err := store.GetProject(args[0])
+		if err != nil {
+			return fmt.Errorf("project %q not found", args[0])
+		}
+		updates, err := entryUpdates(cmd, project)
+		if err != nil {
+			return err
+		}
+		if pause && !store.GetSettings().PausePerProject() {
+			return fmt.Errorf("--pause needs a paused entry per project, enable it with 'watchmen config timer --pause-per-project'")
+		}
+
+		previous, entry, err := store.SwitchEntry(project.ID, note, pause, updates)
+		if err != nil {
+			return err
+		}
+
+		if previous != nil {
+			verb := "Stopped"
+			if pause {
+				verb = "Paused"
+			}
+			duration := previous.Duration()
+			fmt.Printf("%s %s after %s (%.2f hours)\n", verb, projectName(previous.ProjectID), formatDuration(duration), duration.Hours())
+		}
+		fmt.Printf("Started tracking time on %s\n", project.Name)
+		fmt.Printf("  Started: %s\n", entry.StartTime().Format("3:04 PM"))
+		if note != "" {
+			fmt.Printf("  Note: %s\n", note)
+		}
+		printEntryDetails(entry, project)
+		return nil
+	},
+}
+
+func init() {
+	switchCmd.Flags().StringP("note", "n", "", "Note for the new time entry")
+	switchCmd.Flags().Bool("pause", false, "Pause the current entry instead of stopping it")
+	addEntryFlags(switchCmd)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/invoice"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage invoice templates",
+	Long: `Manage the invoice templates kept in the templates directory next to your
+data file, ~/.watchmen/templates by default.
+
+Templates are Go templates (https://pkg.go.dev/text/template). Files ending
+in .html or .htm are HTML templates, with their output escaped; anything else
+is plain text. Templates are executed with the invoice view model:
+
+  .Number .PurchaseOrder .Date .DueDate .Terms
+  .Project .Description .PeriodStart .PeriodEnd .Rate .Included .Rounding
+  .Currency
+  .From .BillTo           contact info (.Name .Title .Company .Address
+                          .Phone .Email .TaxID), nil if not set
+  .Lines                  time rows (.Date .Worked .Hours .Rate .Description
+                          .Commits), with commits only given --commits,
+                          and on a client invoice .Project, with .NewProject
+                          set on each project's first row
+  .ShowWorked .ShowRates  whether rows carry hours worked and their own rate
+  .Categories             hours by category (.Name .Hours), with --by-category
+  .Expenses               expenses (.Date .Quantity .Description .Amount)
+  .Summary                rows before the total (.Label .Amount)
+  .WorkedHours .TotalHours .Total
+  .Payment .PaymentLink   payment instruction lines, and the payment URL
+  .Data                   the full invoice data, for anything else
+
+The bundled HTML template is called "html". Use 'watchmen template init' to
+copy it as a starting point for your own.`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List invoice templates",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := templateDir()
+		names, err := invoice.ListTemplates(dir)
+		if err != nil {
+			return err
+		}
+		builtin := true
+		for _, name := range names {
+			if strings.TrimSuffix(name, filepath.Ext(name)) == invoice.BuiltinHTML {
+				builtin = false
+			}
+		}
+		if builtin {
+			fmt.Printf("  %-20s (built-in)\n", invoice.BuiltinHTML)
+		}
+		for _, name := range names {
+			fmt.Printf("  %s\n", name)
+		}
+		fmt.Printf("\nTemplates directory: %s\n", dir)
+		return nil
+	},
+}
+
+var templateInitCmd = &cobra.Command{
+	Use:   "init <name>",
+	Short: "Create a template from the bundled HTML template",
+	Long: `Create <name>.html in the templates directory as a copy of the bundled HTML
+template, ready to edit.
+
+Examples:
+  watchmen template init acme                # Then edit acme.html
+  watchmen project template myproject acme   # Use it for a project`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if strings.ContainsRune(name, filepath.Separator) {
+			return fmt.Errorf("template name %q cannot contain %c", name, filepath.Separator)
+		}
+		if filepath.Ext(name) == "" {
+			name += ".html"
+		}
+
+		dir := templateDir()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := f.WriteString(invoice.BuiltinHTMLSource()); err != nil {
+			return err
+		}
+
+		fmt.Printf("Created %s\n", path)
+		return nil
+	},
+}
+
+// templateDir returns the directory holding invoice templates, next to the
+// data file
+func templateDir() string {
+	return filepath.Join(filepath.Dir(storePath), "templates")
+}
+
+func init() {
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateInitCmd)
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+	"watchmen/internal/timesheet"
+)
+
+var timesheetCmd = &cobra.Command{
+	Use:   "timesheet",
+	Short: "Show hours per project for each day of a week",
+	Long: `Show a grid of the hours put into each project on each day, for this week
+by default. Time is counted on the day it was worked, so a segment running
+past midnight is split between the two days. Projects with a budget show
+how much of it is spent, with a warning at 80% and 100%.
+
+Examples:
+  watchmen timesheet                          # This week, Monday to Sunday
+  watchmen timesheet --last-week
+  watchmen timesheet --since 2026-10-01 --until 2026-10-14
+  watchmen timesheet --format markdown > week.md
+  watchmen timesheet -p acme --format json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectFilter, _ := cmd.Flags().GetString("project")
+		sinceStr, _ := cmd.Flags().GetString("since")
+		untilStr, _ := cmd.Flags().GetString("until")
+		lastWeek, _ := cmd.Flags().GetBool("last-week")
+		format, _ := cmd.Flags().GetString("format")
+
+		switch format {
+		case "text", "markdown", "json":
+		default:
+			return fmt.Errorf("unknown format %q, use text, markdown or json", format)
+		}
+
+		now := time.Now()
+		from := period.StartOfWeek(now, home)
+		days := 7
+		if lastWeek {
+			from = from.AddDate(0, 0, -7)
+		}
+		if sinceStr != "" || untilStr != "" {
+			if lastWeek {
+				return fmt.Errorf("cannot use --last-week with --since or --until")
+			}
+			var err error
+			if sinceStr != "" {
+				if from, err = period.ParseDay(sinceStr, home); err != nil {
+					return fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
+				}
+			}
+			until := from.AddDate(0, 0, 6)
+			if untilStr != "" {
+				if until, err = period.ParseDay(untilStr, home); err != nil {
+					return fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
+				}
+			}
+			if until.Before(from) {
+				return fmt.Errorf("--until is before --since")
+			}
+			days = period.Days(from, until) + 1
+			if days > 31 {
+				return fmt.Errorf("a timesheet covers at most 31 days")
+			}
+		}
+
+		projects := store.ListProjects()
+		if projectFilter != "" {
+			project, err := store.GetProject(projectFilter)
+			if err != nil {
+				return fmt.Errorf("project %q not found", projectFilter)
+			}
+			projects = []model.Project{*project}
+			projectFilter = project.ID
+		}
+
+		// Entries started earlier may have segments in the period, which
+		// the sheet clips to its days
+		to := from.AddDate(0, 0, days)
+		entries, err := filterEntries(cmd, store.ListEntries(projectFilter, nil, &to))
+		if err != nil {
+			return err
+		}
+		sheet := timesheet.New(entries, projects, from, days, now)
+		for i, row := range sheet.Rows {
+			if project, err := store.GetProject(row.ProjectID); err == nil && project.Budget != nil {
+				sheet.Rows[i].Budget = timesheetBudget(project)
+			}
+		}
+
+		switch format {
+		case "markdown":
+			sheet.WriteMarkdown(os.Stdout)
+		case "json":
+			jsonData, err := json.MarshalIndent(sheet, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(jsonData))
+		default:
+			sheet.WriteText(os.Stdout)
+		}
+		return nil
+	},
+}
+
+// timesheetBudget describes how a project stands against its budget
+func timesheetBudget(p *model.Project) *timesheet.Budget {
+	use := budgetUse(p)
+	currency := p.CurrencyCode()
+	var left []string
+	if p.Budget.Hours > 0 {
+		left = append(left, fmt.Sprintf("%.2fh", max(p.Budget.Hours-use.Hours, 0)))
+	}
+	if p.Budget.Amount > 0 {
+		left = append(left, money.Format(max(p.Budget.Amount-use.Amount, 0), currency))
+	}
+	return &timesheet.Budget{
+		Description: p.Budget.String(currency),
+		Remaining:   strings.Join(left, " and ") + " left",
+		HoursSpent:  use.Hours,
+		AmountSpent: money.ToMajor(use.Amount, currency),
+		Fraction:    use.Fraction,
+		Warning:     use.Warning(),
+	}
+}
+
+func init() {
+	timesheetCmd.Flags().StringP("project", "p", "", "Only this project")
+	timesheetCmd.Flags().String("since", "", "First day (YYYY-MM-DD, default Monday this week)")
+	timesheetCmd.Flags().String("until", "", "Last day (YYYY-MM-DD, default six days after the first)")
+	timesheetCmd.Flags().Bool("last-week", false, "Show last week")
+	timesheetCmd.Flags().String("format", "text", "Output format: text, markdown or json")
+	addEntryFilterFlags(timesheetCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"watchmen/internal/period"
+)
+
+var trimCmd = &cobra.Command{
+	Use:   "trim",
+	Short: "Stop a forgotten timer at the time you finished",
+	Long: `Close the running segment at an earlier time, for a timer left running by
+mistake. The entry is stopped, or paused with --pause so it can be resumed.
+
+--at is taken on the day the segment started, or the day after if that is
+earlier than the start, so a timer started at 10pm can be trimmed to 1am.
+Use --date to give another day.
+
+Examples:
+  watchmen trim --at 18:30
+  watchmen trim --at 5:45pm --pause
+  watchmen trim --at 17:00 --date 2026-10-14`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atStr, _ := cmd.Flags().GetString("at")
+		dateStr, _ := cmd.Flags().GetString("date")
+		pause, _ := cmd.Flags().GetBool("pause")
+
+		active := store.ActiveEntry()
+		if active == nil {
+			return fmt.Errorf("no active time entry")
+		}
+		seg := active.OpenSegment()
+		if seg == nil {
+			return fmt.Errorf("the timer is paused, there is nothing to trim")
+		}
+
+		// Times are read in the timezone the project's days are counted in
+		loc := home
+		if project, err := store.GetProject(active.ProjectID); err == nil {
+			loc = projectLocation(project)
+		}
+		baseDate := seg.Start.In(loc)
+		if dateStr != "" {
+			var err error
+			baseDate, err = period.ParseDay(dateStr, loc)
+			if err != nil {
+				return fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
+			}
+		}
+		at, err := parseTime(atStr, baseDate)
+		if err != nil {
+			return err
+		}
+		if dateStr == "" && !at.After(seg.Start) {
+			at = at.AddDate(0, 0, 1)
+		}
+
+		before := active.Duration()
+		entry, err := store.TrimEntry(at, pause)
+		if err != nil {
+			return err
+		}
+
+		verb := "Stopped"
+		if pause {
+			verb = "Paused"
+		}
+		fmt.Printf("%s %s at %s\n", verb, projectName(entry.ProjectID), at.Format("Mon Jan 2 3:04 PM"))
+		fmt.Printf("  Duration: %s (%.2f hours)\n", formatDuration(entry.Duration()), entry.Duration().Hours())
+		fmt.Printf("  Trimmed:  %s\n", formatDuration(before-entry.Duration()))
+		return nil
+	},
+}
+
+func init() {
+	trimCmd.Flags().String("at", "", "Time you stopped working (e.g., 18:30, 6:30PM)")
+	trimCmd.Flags().String("date", "", "Date of --at (YYYY-MM-DD, default: the day the segment started)")
+	trimCmd.Flags().Bool("pause", false, "Pause the entry instead of stopping it")
+	trimCmd.MarkFlagRequired("at")
+}
@@ -0,0 +1,309 @@
+// Package doctor checks watchmen data for problems that would otherwise
+// flow into reports and invoices, such as overlapping time or entries
+// whose project has gone.
+package doctor
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+// Kinds of problem
+const (
+	KindOverlap          = "overlap"            // two entries share time
+	KindNegativeSegment  = "negative_segment"   // a segment ends before it starts
+	KindSegmentOrder     = "segment_order"      // an entry's segments are out of order
+	KindOpenSegment      = "open_segment"       // a completed entry has a segment without an end
+	KindMissingProject   = "missing_project"    // an entry's project does not exist
+	KindMissingInvoice   = "missing_invoice"    // an entry is billed on an invoice that does not exist
+	KindInvoiceNoProject = "invoice_no_project" // an invoice's project does not exist
+	KindInvoiceNoClient  = "invoice_no_client"  // a client invoice's client does not exist
+	KindExpenseNoProject = "expense_no_project" // an expense's project does not exist
+	KindExpenseNoInvoice = "expense_no_invoice" // an expense is billed on an invoice that does not exist
+	KindMultipleActive   = "multiple_active"    // more entries are running or paused than the settings allow
+	KindEmptyEntry       = "empty_entry"        // an entry has no segments
+	KindDuplicateInvoice = "duplicate_invoice"  // two invoices share a number
+)
+
+// Problem is one thing wrong with the data
+type Problem struct {
+	Kind      string `json:"kind"`
+	Message   string `json:"message"`
+	EntryID   string `json:"entry_id,omitempty"`
+	OtherID   string `json:"other_id,omitempty"` // the second entry of an overlap
+	ProjectID string `json:"project_id,omitempty"`
+	InvoiceID string `json:"invoice_id,omitempty"`
+	ExpenseID string `json:"expense_id,omitempty"`
+	Fixable   bool   `json:"fixable"` // Fix can repair it without losing anything
+}
+
+// Check scans data for problems, treating open segments as running until
+// now
+func Check(data *model.Data, now time.Time) []Problem {
+	projects := make(map[string]bool)
+	for _, p := range data.Projects {
+		projects[p.ID] = true
+	}
+	clients := make(map[string]bool)
+	for _, c := range data.Clients {
+		clients[c.ID] = true
+	}
+	invoices := make(map[string]bool)
+	var problems []Problem
+	for _, inv := range data.Invoices {
+		if invoices[inv.ID] {
+			problems = append(problems, Problem{
+				Kind:      KindDuplicateInvoice,
+				Message:   fmt.Sprintf("invoice number %s is used more than once", inv.ID),
+				InvoiceID: inv.ID,
+			})
+		}
+		invoices[inv.ID] = true
+	}
+
+	for _, e := range data.Entries {
+		problems = append(problems, checkEntry(&e, projects, invoices)...)
+	}
+	problems = append(problems, checkActive(data)...)
+	problems = append(problems, overlaps(data.Entries, now)...)
+
+	for _, inv := range data.Invoices {
+		// A client invoice bills several projects and has none of its own
+		if inv.ClientID != "" {
+			if !clients[inv.ClientID] {
+				problems = append(problems, Problem{
+					Kind:      KindInvoiceNoClient,
+					Message:   fmt.Sprintf("invoice %s is for client %q, which does not exist", inv.ID, inv.ProjectName),
+					InvoiceID: inv.ID,
+				})
+			}
+			continue
+		}
+		if !projects[inv.ProjectID] {
+			problems = append(problems, Problem{
+				Kind:      KindInvoiceNoProject,
+				Message:   fmt.Sprintf("invoice %s is for project %q, which does not exist", inv.ID, inv.ProjectName),
+				InvoiceID: inv.ID,
+				ProjectID: inv.ProjectID,
+			})
+		}
+	}
+	for _, x := range data.Expenses {
+		if !projects[x.ProjectID] {
+			problems = append(problems, Problem{
+				Kind:      KindExpenseNoProject,
+				Message:   fmt.Sprintf("expense %q on %s is for a project that does not exist", x.Description, x.Date.Format("2006-01-02")),
+				ExpenseID: x.ID,
+				ProjectID: x.ProjectID,
+			})
+		}
+		if x.InvoiceID != "" && !invoices[x.InvoiceID] {
+			problems = append(problems, Problem{
+				Kind:      KindExpenseNoInvoice,
+				Message:   fmt.Sprintf("expense %q is billed on invoice %s, which does not exist", x.Description, x.InvoiceID),
+				ExpenseID: x.ID,
+				InvoiceID: x.InvoiceID,
+				Fixable:   true,
+			})
+		}
+	}
+	return problems
+}
+
+// checkActive reports more than one running entry, and more than one
+// paused entry unless each project may have one
+func checkActive(data *model.Data) []Problem {
+	var running, active []string
+	perProject := make(map[string][]string)
+	for _, e := range data.Entries {
+		if e.Completed || len(e.Segments) == 0 {
+			continue
+		}
+		if e.IsRunning() {
+			running = append(running, e.ID)
+		}
+		active = append(active, e.ID)
+		perProject[e.ProjectID] = append(perProject[e.ProjectID], e.ID)
+	}
+	multiple := func(ids []string, format string, args ...any) Problem {
+		return Problem{
+			Kind:    KindMultipleActive,
+			Message: fmt.Sprintf(format, args...),
+			EntryID: ids[0],
+			OtherID: ids[1],
+		}
+	}
+
+	var problems []Problem
+	if len(running) > 1 {
+		problems = append(problems, multiple(running, "%d entries are running, only one should be", len(running)))
+	}
+	if !data.Settings.PausePerProject() {
+		if len(active) > 1 {
+			problems = append(problems, multiple(active, "%d entries are running or paused, only one should be", len(active)))
+		}
+		return problems
+	}
+	for _, projectID := range slices.Sorted(maps.Keys(perProject)) {
+		if ids := perProject[projectID]; len(ids) > 1 {
+			p := multiple(ids, "%d entries are running or paused on project %s, only one should be", len(ids), projectID)
+			p.ProjectID = projectID
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+func checkEntry(e *model.Entry, projects, invoices map[string]bool) []Problem {
+	var problems []Problem
+	add := func(kind string, fixable bool, format string, args ...any) {
+		problems = append(problems, Problem{
+			Kind:      kind,
+			Message:   describe(e) + ": " + fmt.Sprintf(format, args...),
+			EntryID:   e.ID,
+			ProjectID: e.ProjectID,
+			Fixable:   fixable,
+		})
+	}
+
+	if len(e.Segments) == 0 {
+		add(KindEmptyEntry, false, "has no time segments")
+	}
+	if !projects[e.ProjectID] {
+		add(KindMissingProject, false, "project %s does not exist", e.ProjectID)
+	}
+	if e.InvoiceID != "" && !invoices[e.InvoiceID] {
+		problems = append(problems, Problem{
+			Kind:      KindMissingInvoice,
+			Message:   describe(e) + ": billed on invoice " + e.InvoiceID + ", which does not exist",
+			EntryID:   e.ID,
+			InvoiceID: e.InvoiceID,
+			Fixable:   true,
+		})
+	}
+	for i, seg := range e.Segments {
+		if seg.End == nil {
+			if e.Completed || i < len(e.Segments)-1 {
+				add(KindOpenSegment, hasClosedSegment(e), "segment %d has no end and counts time until now", i+1)
+			}
+			continue
+		}
+		if seg.End.Before(seg.Start) {
+			add(KindNegativeSegment, false, "segment %d ends before it starts", i+1)
+		}
+	}
+	if !sort.SliceIsSorted(e.Segments, func(i, j int) bool { return e.Segments[i].Start.Before(e.Segments[j].Start) }) {
+		add(KindSegmentOrder, true, "segments are out of order")
+	}
+	return problems
+}
+
+// overlaps finds entries sharing time, sweeping all segments in order of
+// start and comparing each with every earlier one still open
+func overlaps(entries []model.Entry, now time.Time) []Problem {
+	type span struct {
+		entry      *model.Entry
+		start, end time.Time
+	}
+	var spans []span
+	for i := range entries {
+		for _, seg := range entries[i].Segments {
+			end := now
+			if seg.End != nil {
+				end = *seg.End
+			}
+			if end.After(seg.Start) {
+				spans = append(spans, span{&entries[i], seg.Start, end})
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	var problems []Problem
+	seen := make(map[[2]string]bool)
+	var open []*span
+	for i := range spans {
+		s := &spans[i]
+		open = slices.DeleteFunc(open, func(o *span) bool { return !o.end.After(s.start) })
+		for _, o := range open {
+			if o.entry.ID == s.entry.ID {
+				continue
+			}
+			pair := [2]string{o.entry.ID, s.entry.ID}
+			if pair[1] < pair[0] {
+				pair[0], pair[1] = pair[1], pair[0]
+			}
+			if seen[pair] {
+				continue
+			}
+			seen[pair] = true
+			end := s.end
+			if o.end.Before(end) {
+				end = o.end
+			}
+			problems = append(problems, Problem{
+				Kind:    KindOverlap,
+				Message: fmt.Sprintf("%s overlaps %s by %s", describe(o.entry), describe(s.entry), strings.TrimSuffix(end.Sub(s.start).Round(time.Minute).String(), "0s")),
+				EntryID: o.entry.ID,
+				OtherID: s.entry.ID,
+			})
+		}
+		open = append(open, s)
+	}
+	return problems
+}
+
+// Fix repairs the fixable problems of an entry, returning the entry
+// repaired and whether anything changed. Segments are put in order, stray
+// open segments are dropped if the entry has a closed one to keep, and a
+// missing invoice is cleared, releasing the entry to be billed again.
+func Fix(e model.Entry, invoiceExists func(string) bool) (model.Entry, bool) {
+	changed := false
+	var segments []model.TimeSegment
+	for i, seg := range e.Segments {
+		stray := seg.End == nil && (e.Completed || i < len(e.Segments)-1)
+		if stray && hasClosedSegment(&e) {
+			changed = true
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	sorted := slices.IsSortedFunc(segments, func(a, b model.TimeSegment) int { return a.Start.Compare(b.Start) })
+	if !sorted {
+		slices.SortStableFunc(segments, func(a, b model.TimeSegment) int { return a.Start.Compare(b.Start) })
+		changed = true
+	}
+	e.Segments = segments
+	if e.InvoiceID != "" && !invoiceExists(e.InvoiceID) {
+		e.InvoiceID = ""
+		changed = true
+	}
+	return e, changed
+}
+
+// FixExpense clears an expense's missing invoice, releasing it to be billed
+// again, returning the expense and whether anything changed
+func FixExpense(x model.Expense, invoiceExists func(string) bool) (model.Expense, bool) {
+	if x.InvoiceID == "" || invoiceExists(x.InvoiceID) {
+		return x, false
+	}
+	x.InvoiceID = ""
+	return x, true
+}
+
+func hasClosedSegment(e *model.Entry) bool {
+	return slices.ContainsFunc(e.Segments, func(seg model.TimeSegment) bool { return seg.End != nil })
+}
+
+func describe(e *model.Entry) string {
+	if len(e.Segments) == 0 {
+		return "entry " + e.ID
+	}
+	return "entry of " + e.StartTime().Local().Format("Jan 2 3:04 PM")
+}
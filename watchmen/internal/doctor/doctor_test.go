@@ -0,0 +1,176 @@
+package doctor
+
+import (
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func TestCheck(t *testing.T) {
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local)
+	at := func(h int) *time.Time { t := day.Add(time.Duration(h) * time.Hour); return &t }
+	seg := func(start, end int) model.TimeSegment { return model.TimeSegment{Start: *at(start), End: at(end)} }
+
+	data := &model.Data{
+		Projects: []model.Project{{ID: "p1", Name: "acme"}},
+		Entries: []model.Entry{
+			{ID: "a", ProjectID: "p1", Completed: true, Segments: []model.TimeSegment{seg(9, 11)}},
+			{ID: "b", ProjectID: "p1", Completed: true, Segments: []model.TimeSegment{seg(10, 12)}},
+			{ID: "c", ProjectID: "p1", Completed: true, Segments: []model.TimeSegment{seg(15, 16), seg(13, 14)}},
+			{ID: "d", ProjectID: "p1", Completed: true, Segments: []model.TimeSegment{seg(17, 18), {Start: *at(19)}}},
+			{ID: "e", ProjectID: "gone", Completed: true, Segments: []model.TimeSegment{seg(21, 20)}, InvoiceID: "INV-9"},
+		},
+		Clients: []model.Client{{ID: "c1", Name: "bigco"}},
+		Invoices: []model.Invoice{
+			{ID: "INV-1", ProjectID: "gone", ProjectName: "old"},
+			{ID: "INV-2", ClientID: "c1", ProjectName: "bigco"},
+			{ID: "INV-3", ClientID: "lost", ProjectName: "lostco"},
+		},
+		Expenses: []model.Expense{
+			{ID: "x1", ProjectID: "p1", Date: day, Description: "Train", InvoiceID: "INV-7"},
+		},
+	}
+
+	problems := Check(data, day.Add(23*time.Hour))
+	kinds := make(map[string]Problem)
+	for _, p := range problems {
+		kinds[p.Kind+":"+p.EntryID] = p
+	}
+	for _, want := range []struct {
+		key     string
+		fixable bool
+	}{
+		{KindOverlap + ":a", false},
+		{KindSegmentOrder + ":c", true},
+		{KindOpenSegment + ":d", true},
+		{KindMissingProject + ":e", false},
+		{KindNegativeSegment + ":e", false},
+		{KindMissingInvoice + ":e", true},
+		{KindInvoiceNoProject + ":", false},
+		{KindInvoiceNoClient + ":", false},
+		{KindExpenseNoInvoice + ":", true},
+	} {
+		p, ok := kinds[want.key]
+		if !ok {
+			t.Errorf("missing problem %s", want.key)
+			continue
+		}
+		if p.Fixable != want.fixable {
+			t.Errorf("%s fixable = %v, want %v", want.key, p.Fixable, want.fixable)
+		}
+	}
+	if p := kinds[KindOverlap+":a"]; p.OtherID != "b" {
+		t.Errorf("overlap other = %q, want b", p.OtherID)
+	}
+	// The invoice for an existing client is fine without a project
+	if len(problems) != 9 {
+		for _, p := range problems {
+			t.Log(p.Kind, p.Message)
+		}
+		t.Errorf("got %d problems, want 9", len(problems))
+	}
+
+	// The open segment of d runs until now, over the start of nothing else
+	if got := Check(&model.Data{Projects: data.Projects, Entries: data.Entries[:1]}, day); len(got) != 0 {
+		t.Errorf("expected no problems for one entry, got %v", got)
+	}
+}
+
+func TestOverlapsNested(t *testing.T) {
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local)
+	at := func(h, m int) *time.Time {
+		t := day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+		return &t
+	}
+	entry := func(id string, h1, m1, h2, m2 int) model.Entry {
+		return model.Entry{ID: id, Completed: true, Segments: []model.TimeSegment{{Start: *at(h1, m1), End: at(h2, m2)}}}
+	}
+	// B and C both sit inside A, and overlap each other as well
+	entries := []model.Entry{entry("a", 9, 0, 12, 0), entry("b", 10, 0, 11, 0), entry("c", 10, 30, 11, 30)}
+
+	pairs := make(map[string]bool)
+	for _, p := range overlaps(entries, day.Add(23*time.Hour)) {
+		pairs[p.EntryID+"-"+p.OtherID] = true
+	}
+	if len(pairs) != 3 || !pairs["a-b"] || !pairs["a-c"] || !pairs["b-c"] {
+		t.Errorf("overlaps = %v, want a-b, a-c and b-c", pairs)
+	}
+}
+
+func TestCheckActive(t *testing.T) {
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local)
+	at := func(h int) *time.Time { t := day.Add(time.Duration(h) * time.Hour); return &t }
+	paused := func(id, projectID string, h int) model.Entry {
+		return model.Entry{ID: id, ProjectID: projectID, Segments: []model.TimeSegment{{Start: *at(h), End: at(h + 1)}}}
+	}
+	data := &model.Data{
+		Projects: []model.Project{{ID: "p1"}, {ID: "p2"}},
+		Entries:  []model.Entry{paused("a", "p1", 9), paused("b", "p2", 11)},
+	}
+
+	count := func() int {
+		n := 0
+		for _, p := range Check(data, day.Add(23*time.Hour)) {
+			if p.Kind == KindMultipleActive {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(); n != 1 {
+		t.Errorf("two paused entries: got %d multiple_active problems, want 1", n)
+	}
+
+	data.Settings = &model.Settings{Timer: &model.TimerSettings{PausePerProject: true}}
+	if n := count(); n != 0 {
+		t.Errorf("paused entries on different projects: got %d multiple_active problems, want 0", n)
+	}
+	data.Entries = append(data.Entries, paused("c", "p1", 13))
+	if n := count(); n != 1 {
+		t.Errorf("two paused entries on p1: got %d multiple_active problems, want 1", n)
+	}
+}
+
+func TestFix(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.Local)
+	end := start.Add(time.Hour)
+	later, laterEnd := start.Add(2*time.Hour), start.Add(3*time.Hour)
+	noInvoices := func(string) bool { return false }
+
+	e := model.Entry{
+		Completed: true,
+		InvoiceID: "INV-9",
+		Segments: []model.TimeSegment{
+			{Start: later, End: &laterEnd},
+			{Start: start, End: &end},
+			{Start: laterEnd},
+		},
+	}
+	fixed, changed := Fix(e, noInvoices)
+	if !changed {
+		t.Fatal("expected changes")
+	}
+	if len(fixed.Segments) != 2 || !fixed.Segments[0].Start.Equal(start) || fixed.InvoiceID != "" {
+		t.Errorf("unexpected fix: %+v", fixed)
+	}
+	if len(e.Segments) != 3 {
+		t.Error("Fix changed the original entry")
+	}
+	if _, changed := Fix(fixed, noInvoices); changed {
+		t.Error("expected a fixed entry to need no changes")
+	}
+
+	running := model.Entry{Segments: []model.TimeSegment{{Start: start}}}
+	if _, changed := Fix(running, noInvoices); changed {
+		t.Error("expected a running entry to be left alone")
+	}
+
+	x, changed := FixExpense(model.Expense{InvoiceID: "INV-9"}, noInvoices)
+	if !changed || x.InvoiceID != "" {
+		t.Errorf("FixExpense() = %+v, %v, want the invoice cleared", x, changed)
+	}
+	if _, changed := FixExpense(model.Expense{InvoiceID: "INV-1"}, func(string) bool { return true }); changed {
+		t.Error("expected an expense on an existing invoice to be left alone")
+	}
+}
@@ -0,0 +1,175 @@
+// Package gitlog reads commits from local git repositories and matches
+// them to the time entries they were made during
+package gitlog
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+// Commit is one commit read from a repository
+type Commit struct {
+	Repo    string // base name of the repository directory
+	Hash    string
+	Time    time.Time // authored
+	Subject string
+}
+
+// Short returns the abbreviated hash
+func (c Commit) Short() string {
+	return c.Hash[:min(7, len(c.Hash))]
+}
+
+// String describes the commit as "<short hash> <subject> (<repo>)"
+func (c Commit) String() string {
+	return fmt.Sprintf("%s %s (%s)", c.Short(), c.Subject, c.Repo)
+}
+
+// fieldSep separates the fields of each line git prints
+const fieldSep = "\x1f"
+
+// IsRepo reports whether path is inside a git working tree
+func IsRepo(path string) bool {
+	out, err := git(path, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// Log reads the commits in repo authored between since and until. When
+// the repository has a user.email configured, only that author's commits
+// are read.
+//
+// git filters --since and --until on the committer date, which a rebase or
+// cherry-pick moves later than the author date. Only --since is given to
+// git, as a commit is never committed before it is authored, and the rest
+// are dropped here by author date.
+func Log(repo string, since, until time.Time) ([]Commit, error) {
+	args := []string{
+		"log", "--all", "--no-merges",
+		"--since=" + since.Format(time.RFC3339),
+		"--format=%H" + fieldSep + "%aI" + fieldSep + "%s",
+	}
+	if email, err := git(repo, "config", "user.email"); err == nil && strings.TrimSpace(email) != "" {
+		args = append(args, "--author="+strings.TrimSpace(email))
+	}
+	out, err := git(repo, args...)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := parseLog(filepath.Base(repo), out)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(commits, func(c Commit) bool {
+		return c.Time.Before(since) || c.Time.After(until)
+	}), nil
+}
+
+// parseLog reads the lines printed by Log's git log format
+func parseLog(repo, out string) ([]Commit, error) {
+	var commits []Commit
+	for line := range strings.Lines(out) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, fieldSep, 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git log output: %q", line)
+		}
+		t, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected commit date %q: %v", fields[1], err)
+		}
+		commits = append(commits, Commit{Repo: repo, Hash: fields[0], Time: t, Subject: fields[2]})
+	}
+	return commits, nil
+}
+
+// ForEntries reads the commits in repos authored during the entries'
+// segments, keyed by entry ID and sorted by time. Open segments run until
+// now.
+func ForEntries(repos []string, entries []model.Entry, now time.Time) (map[string][]Commit, error) {
+	var since, until time.Time
+	for _, e := range entries {
+		for _, seg := range e.Segments {
+			end := now
+			if seg.End != nil {
+				end = *seg.End
+			}
+			if since.IsZero() || seg.Start.Before(since) {
+				since = seg.Start
+			}
+			if end.After(until) {
+				until = end
+			}
+		}
+	}
+	if since.IsZero() || len(repos) == 0 {
+		return nil, nil
+	}
+
+	var commits []Commit
+	for _, repo := range repos {
+		c, err := Log(repo, since, until)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c...)
+	}
+	return Match(commits, entries, now), nil
+}
+
+// Match assigns each commit to the entry with a segment it was authored
+// in, keyed by entry ID and sorted by time. Commits outside every segment
+// are dropped.
+func Match(commits []Commit, entries []model.Entry, now time.Time) map[string][]Commit {
+	matched := make(map[string][]Commit)
+	for _, c := range commits {
+		for _, e := range entries {
+			if during(c.Time, e, now) {
+				matched[e.ID] = append(matched[e.ID], c)
+				break
+			}
+		}
+	}
+	for _, c := range matched {
+		slices.SortStableFunc(c, func(a, b Commit) int { return a.Time.Compare(b.Time) })
+	}
+	return matched
+}
+
+// during reports whether t falls within one of the entry's segments
+func during(t time.Time, e model.Entry, now time.Time) bool {
+	for _, seg := range e.Segments {
+		end := now
+		if seg.End != nil {
+			end = *seg.End
+		}
+		if !t.Before(seg.Start) && t.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// git runs git in dir and returns what it printed
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s in %s: %s", args[0], dir, msg)
+		}
+		return "", fmt.Errorf("git %s in %s: %v", args[0], dir, err)
+	}
+	return string(out), nil
+}
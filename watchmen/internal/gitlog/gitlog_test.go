@@ -0,0 +1,120 @@
+package gitlog
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func TestParseLog(t *testing.T) {
+	out := "abc123def456" + fieldSep + "2026-10-05T09:30:00+02:00" + fieldSep + "Add login" + fieldSep + "page\n" +
+		"0123456789ab" + fieldSep + "2026-10-05T10:00:00Z" + fieldSep + "Fix typo\n"
+	commits, err := parseLog("api", out)
+	if err != nil {
+		t.Fatalf("parseLog() error = %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+	c := commits[0]
+	if c.Repo != "api" || c.Short() != "abc123d" || c.Subject != "Add login"+fieldSep+"page" {
+		t.Errorf("Commit = %+v", c)
+	}
+	if !c.Time.Equal(time.Date(2026, 10, 5, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("Time = %v", c.Time)
+	}
+	if got := commits[1].String(); got != "0123456 Fix typo (api)" {
+		t.Errorf("String() = %q", got)
+	}
+
+	if _, err := parseLog("api", "not a commit\n"); err == nil {
+		t.Error("Expected an error for unexpected output")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 5, h, m, 0, 0, time.UTC) }
+	end1, end2 := at(10, 0), at(12, 0)
+	entries := []model.Entry{
+		{ID: "e1", Segments: []model.TimeSegment{{Start: at(9, 0), End: &end1}}},
+		{ID: "e2", Segments: []model.TimeSegment{{Start: at(11, 0), End: &end2}, {Start: at(14, 0)}}},
+	}
+	commits := []Commit{
+		{Hash: "late", Time: at(15, 0)},   // during e2's open segment
+		{Hash: "first", Time: at(9, 0)},   // on e1's start
+		{Hash: "gap", Time: at(10, 30)},   // between entries
+		{Hash: "edge", Time: at(10, 0)},   // on e1's end, so outside it
+		{Hash: "second", Time: at(11, 5)}, // during e2
+	}
+
+	matched := Match(commits, entries, at(16, 0))
+	hashes := func(id string) []string {
+		var h []string
+		for _, c := range matched[id] {
+			h = append(h, c.Hash)
+		}
+		return h
+	}
+	if got := hashes("e1"); len(got) != 1 || got[0] != "first" {
+		t.Errorf("e1 commits = %v, want [first]", got)
+	}
+	if got := hashes("e2"); len(got) != 2 || got[0] != "second" || got[1] != "late" {
+		t.Errorf("e2 commits = %v, want [second late]", got)
+	}
+}
+
+func TestForEntries(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "web")
+	run := func(date string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	os.MkdirAll(repo, 0755)
+	run("", "init", "-q")
+	run("", "config", "user.email", "me@example.com")
+	run("", "config", "user.name", "Me")
+	run("2026-10-05T09:15:00Z", "commit", "-q", "--allow-empty", "-m", "Mine")
+	run("2026-10-05T09:30:00Z", "commit", "-q", "--allow-empty", "-m", "Theirs", "--author", "Other <other@example.com>")
+	run("2026-10-05T11:00:00Z", "commit", "-q", "--allow-empty", "-m", "After")
+	// Rebased a day later, so only its author date is within the entry
+	rebased := exec.Command("git", "-C", repo, "commit", "-q", "--allow-empty", "-m", "Rebased")
+	rebased.Env = append(os.Environ(),
+		"GIT_AUTHOR_DATE=2026-10-05T09:45:00Z", "GIT_COMMITTER_DATE=2026-10-06T08:00:00Z",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	if out, err := rebased.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, out)
+	}
+
+	if !IsRepo(repo) || IsRepo(t.TempDir()) {
+		t.Fatal("IsRepo() wrong")
+	}
+
+	start := time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	entries := []model.Entry{{ID: "e1", Segments: []model.TimeSegment{{Start: start, End: &end}}}}
+	matched, err := ForEntries([]string{repo}, entries, end)
+	if err != nil {
+		t.Fatalf("ForEntries() error = %v", err)
+	}
+	// Another author's commit and one after the entry are left out
+	if c := matched["e1"]; len(c) != 2 || c[0].Subject != "Mine" || c[1].Subject != "Rebased" || c[0].Repo != "web" {
+		t.Errorf("Commits = %+v, want Mine and Rebased", c)
+	}
+
+	if _, err := ForEntries([]string{t.TempDir()}, entries, end); err == nil {
+		t.Error("Expected an error for a directory that is not a repository")
+	}
+}
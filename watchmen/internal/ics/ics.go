@@ -0,0 +1,350 @@
+// Package ics reads calendar events from iCalendar (RFC 5545) files.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+// Calendar is a parsed iCalendar file
+type Calendar struct {
+	Name   string // X-WR-CALNAME, if set
+	Events []Event
+}
+
+// Event is one VEVENT
+type Event struct {
+	UID          string
+	RecurrenceID string // set on a changed instance of a recurring event
+	Summary      string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Recurring    bool   // has an RRULE; occurrences are not expanded
+	Status       string // e.g. CONFIRMED, CANCELLED
+	Organizer    string // email address
+	Attendees    []string
+	Calendar     string
+}
+
+// Key identifies the event across imports: its UID, qualified by the
+// recurrence ID for a changed instance of a recurring event
+func (e *Event) Key() string {
+	if e.RecurrenceID != "" {
+		return e.UID + "/" + e.RecurrenceID
+	}
+	return e.UID
+}
+
+// Duration returns the length of the event
+func (e *Event) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// Cancelled reports whether the event was cancelled
+func (e *Event) Cancelled() bool {
+	return strings.EqualFold(e.Status, "CANCELLED")
+}
+
+// Domains returns the email domains of the organizer and attendees
+func (e *Event) Domains() []string {
+	var domains []string
+	for _, addr := range append([]string{e.Organizer}, e.Attendees...) {
+		if _, domain, ok := strings.Cut(addr, "@"); ok {
+			domains = append(domains, strings.ToLower(domain))
+		}
+	}
+	return domains
+}
+
+// property is one content line: NAME;PARAM=value:VALUE
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads an iCalendar stream. Times with no timezone of their own are
+// read in loc.
+func Parse(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &Calendar{}
+	var event *Event
+	var duration time.Duration // from DURATION, when there is no DTEND
+	var stack []string
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch p.name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(p.value))
+			if len(stack) == 2 && stack[1] == "VEVENT" {
+				event, duration = &Event{}, 0
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, p.value)
+			}
+			if len(stack) == 2 && event != nil {
+				if err := finishEvent(event, duration); err != nil {
+					return nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				cal.Events = append(cal.Events, *event)
+				event = nil
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		switch {
+		case len(stack) == 1 && p.name == "X-WR-CALNAME":
+			cal.Name = unescape(p.value)
+		case len(stack) == 2 && event != nil:
+			if err := setEventProperty(event, &duration, p, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+		}
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1])
+	}
+
+	for i := range cal.Events {
+		cal.Events[i].Calendar = cal.Name
+	}
+	return cal, nil
+}
+
+// unfold joins continuation lines, which start with a space or tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty splits a content line into its name, parameters and value.
+// Parameter values may be quoted and contain ':' or ';'.
+func parseProperty(line string) (property, error) {
+	p := property{params: make(map[string]string)}
+	inQuotes := false
+	start := 0
+	var parts []string
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ';' && !inQuotes:
+			parts = append(parts, line[start:i])
+			start = i + 1
+		case r == ':' && !inQuotes:
+			parts = append(parts, line[start:i])
+			p.name = strings.ToUpper(parts[0])
+			for _, param := range parts[1:] {
+				key, value, _ := strings.Cut(param, "=")
+				p.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+			p.value = line[i+1:]
+			return p, nil
+		}
+	}
+	return p, fmt.Errorf("malformed line %q", line)
+}
+
+func setEventProperty(e *Event, duration *time.Duration, p property, loc *time.Location) error {
+	var err error
+	switch p.name {
+	case "UID":
+		e.UID = p.value
+	case "RECURRENCE-ID":
+		e.RecurrenceID = p.value
+	case "SUMMARY":
+		e.Summary = unescape(p.value)
+	case "DESCRIPTION":
+		e.Description = unescape(p.value)
+	case "STATUS":
+		e.Status = p.value
+	case "RRULE":
+		e.Recurring = true
+	case "ORGANIZER":
+		e.Organizer = mailto(p.value)
+	case "ATTENDEE":
+		if addr := mailto(p.value); addr != "" {
+			e.Attendees = append(e.Attendees, addr)
+		}
+	case "DTSTART":
+		e.Start, e.AllDay, err = parseDateTime(p, loc)
+	case "DTEND":
+		e.End, _, err = parseDateTime(p, loc)
+	case "DURATION":
+		*duration, err = parseDuration(p.value)
+	}
+	return err
+}
+
+// finishEvent checks an event is complete and fills in a missing end from
+// its duration. An all-day event with neither lasts a day.
+func finishEvent(e *Event, duration time.Duration) error {
+	if e.UID == "" {
+		return fmt.Errorf("event %q has no UID", e.Summary)
+	}
+	if e.Start.IsZero() {
+		return fmt.Errorf("event %s has no start", e.UID)
+	}
+	if e.End.IsZero() {
+		if duration == 0 && e.AllDay {
+			duration = 24 * time.Hour
+		}
+		e.End = e.Start.Add(duration)
+	}
+	return nil
+}
+
+// parseDateTime reads a DATE or DATE-TIME value. UTC times end in Z and are
+// given in loc; others are in the TZID zone, or loc if it is unknown or
+// absent.
+func parseDateTime(p property, loc *time.Location) (time.Time, bool, error) {
+	zone := loc
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			zone = l
+		}
+	}
+	value := p.value
+	if p.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, zone)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+		}
+		return t, true, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+		}
+		return t.In(loc), false, nil
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, zone)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+	}
+	return t, false, nil
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration reads an RFC 5545 duration such as PT1H30M or P1D
+func parseDuration(s string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// mailto returns the address from a mailto: URI
+func mailto(value string) string {
+	if len(value) < 7 || !strings.EqualFold(value[:7], "mailto:") {
+		return ""
+	}
+	return strings.ToLower(value[7:])
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+// Matcher finds the import rule for an event
+type Matcher struct {
+	rules    []model.ImportRule
+	patterns []*regexp.Regexp
+}
+
+// NewMatcher compiles the rules' summary patterns
+func NewMatcher(rules []model.ImportRule) (*Matcher, error) {
+	m := &Matcher{rules: rules, patterns: make([]*regexp.Regexp, len(rules))}
+	for i, r := range rules {
+		if r.Summary == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + r.Summary)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: invalid summary pattern: %w", i+1, err)
+		}
+		m.patterns[i] = re
+	}
+	return m, nil
+}
+
+// Match returns the first rule matching the event, or nil
+func (m *Matcher) Match(e *Event) *model.ImportRule {
+	for i, r := range m.rules {
+		if r.Summary == "" && r.Domain == "" && r.Calendar == "" {
+			continue
+		}
+		if m.patterns[i] != nil && !m.patterns[i].MatchString(e.Summary) {
+			continue
+		}
+		if r.Calendar != "" && !strings.EqualFold(r.Calendar, e.Calendar) {
+			continue
+		}
+		if r.Domain != "" && !hasDomain(e.Domains(), r.Domain) {
+			continue
+		}
+		return &m.rules[i]
+	}
+	return nil
+}
+
+// hasDomain reports whether any of domains is domain or one of its
+// subdomains
+func hasDomain(domains []string, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "@"))
+	for _, d := range domains {
+		if d == domain || strings.HasSuffix(d, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+const sample = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"X-WR-CALNAME:Work\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:sync-1@example.com\r\n" +
+	"SUMMARY:Weekly sync\\, Acme\r\n" +
+	"DTSTART;TZID=America/New_York:20261015T090000\r\n" +
+	"DTEND;TZID=America/New_York:20261015T100000\r\n" +
+	"ORGANIZER;CN=\"Smith; Jane\":mailto:jane@eng.acme.com\r\n" +
+	"ATTENDEE;CN=Me:MAILTO:me@example.com\r\n" +
+	"DESCRIPTION:Agenda:\\n- status\\n- plans that go on for a very long\r\n" +
+	"  time\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review-2\r\n" +
+	"DTSTART:20261016T140000Z\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"SUMMARY:Design review\r\n" +
+	"RRULE:FREQ=WEEKLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday\r\n" +
+	"DTSTART;VALUE=DATE:20261012\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	cal, err := Parse(strings.NewReader(sample), time.Local)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if cal.Name != "Work" || len(cal.Events) != 3 {
+		t.Fatalf("Parse() = %q with %d events, want Work with 3", cal.Name, len(cal.Events))
+	}
+
+	sync := cal.Events[0]
+	ny, _ := time.LoadLocation("America/New_York")
+	if !sync.Start.Equal(time.Date(2026, 10, 15, 9, 0, 0, 0, ny)) || sync.Duration() != time.Hour {
+		t.Errorf("Sync runs %v for %v", sync.Start, sync.Duration())
+	}
+	if sync.Summary != "Weekly sync, Acme" || sync.Calendar != "Work" {
+		t.Errorf("Sync summary %q, calendar %q", sync.Summary, sync.Calendar)
+	}
+	if want := "Agenda:\n- status\n- plans that go on for a very long time"; sync.Description != want {
+		t.Errorf("Description = %q, want %q (alarm text must not leak in)", sync.Description, want)
+	}
+	if got := sync.Domains(); len(got) != 2 || got[0] != "eng.acme.com" || got[1] != "example.com" {
+		t.Errorf("Domains() = %v", got)
+	}
+
+	review := cal.Events[1]
+	if !review.Start.Equal(time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)) || review.Duration() != 90*time.Minute {
+		t.Errorf("Review runs %v for %v", review.Start, review.Duration())
+	}
+	if !review.Recurring {
+		t.Error("Review should be recurring")
+	}
+
+	holiday := cal.Events[2]
+	if !holiday.AllDay || holiday.Duration() != 24*time.Hour {
+		t.Errorf("Holiday all-day %v for %v", holiday.AllDay, holiday.Duration())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unterminated", "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:x\nDTSTART:20261015T090000Z\n"},
+		{"no uid", "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20261015T090000Z\nEND:VEVENT\nEND:VCALENDAR\n"},
+		{"bad time", "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:x\nDTSTART:2026-10-15\nEND:VEVENT\nEND:VCALENDAR\n"},
+		{"no colon", "BEGIN:VCALENDAR\nnonsense\nEND:VCALENDAR\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.input), time.Local); err == nil {
+				t.Error("Parse() should fail")
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"PT1H30M", 90 * time.Minute},
+		{"P1D", 24 * time.Hour},
+		{"P1W", 7 * 24 * time.Hour},
+		{"PT45S", 45 * time.Second},
+		{"-PT15M", -15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got, err := parseDuration(tt.input); err != nil || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "P", "PT", "1H", "PT1X"} {
+		if _, err := parseDuration(bad); err == nil {
+			t.Errorf("parseDuration(%q) should fail", bad)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	rules := []model.ImportRule{
+		{ProjectID: "empty"},
+		{ProjectID: "acme", Summary: "acme|design", Calendar: "work"},
+		{ProjectID: "acme-domain", Domain: "acme.com"},
+		{ProjectID: "personal", Calendar: "Home"},
+	}
+	m, err := NewMatcher(rules)
+	if err != nil {
+		t.Fatalf("NewMatcher() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{"summary and calendar", Event{Summary: "ACME kickoff", Calendar: "Work"}, "acme"},
+		{"summary alone is not enough", Event{Summary: "Design review", Calendar: "Other"}, ""},
+		{"subdomain", Event{Summary: "Call", Attendees: []string{"bob@eng.acme.com"}}, "acme-domain"},
+		{"lookalike domain", Event{Summary: "Call", Attendees: []string{"bob@notacme.com"}}, ""},
+		{"calendar", Event{Summary: "Dentist", Calendar: "home"}, "personal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if rule := m.Match(&tt.event); rule != nil {
+				got = rule.ProjectID
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewMatcher([]model.ImportRule{{ProjectID: "x", Summary: "("}}); err == nil {
+		t.Error("NewMatcher() should reject an invalid pattern")
+	}
+}
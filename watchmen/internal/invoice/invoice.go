@@ -3,29 +3,58 @@ package invoice
 import (
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"watchmen/internal/gitlog"
 	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
 )
 
 // InvoiceData holds data needed to generate an invoice
 type InvoiceData struct {
 	InvoiceNumber        string
-	PurchaseOrder        string             // Client's PO number
+	PurchaseOrder        string // Client's PO number
 	Date                 time.Time
 	Project              model.Project
 	Entries              []model.Entry
 	From                 time.Time
 	To                   time.Time
-	FromContact          *model.ContactInfo // User's contact info
-	BillToContact        *model.ContactInfo // Client's billing contact
-	Condensed            bool               // If true, show single line item
-	CondensedDescription string             // Description for condensed invoice
+	FromContact          *model.ContactInfo         // User's contact info
+	BillToContact        *model.ContactInfo         // Client's billing contact
+	Condensed            bool                       // If true, show single line item
+	CondensedDescription string                     // Description for condensed invoice
+	Expenses             []model.Expense            // Billable expenses for the period
+	Discount             *model.Discount            // Applied to the subtotal before tax
+	Tax                  *model.Tax                 // Applied after the discount
+	ShowCategories       bool                       // If true, summarise hours per category (detailed only)
+	Terms                int                        // Net days to pay, zero if none
+	DueDate              time.Time                  // Zero if there are no terms
+	Payment              *model.PaymentInfo         // How to pay, shown at the foot
+	PDF                  *model.PDFSettings         // Font, logo, colour and page size for PDFs
+	Milestones           []model.Milestone          // Billed on a fixed-price invoice
+	Carried              float64                    // Unused retainer hours rolled over from the last invoice
+	BilledMonths         map[string]bool            // Months, as YYYY-MM, an earlier invoice charged the retainer fee for
+	Client               *model.Client              // Set on an invoice for every project of a client
+	Projects             []model.Project            // The client's projects, whose rates and rounding bill their entries
+	Commits              map[string][]gitlog.Commit // By entry ID, listed under a detailed invoice's entries
+	Location             *time.Location             // Entries' days are counted in, nil for each entry's own
 }
 
-// TotalHours calculates total hours worked
+// TotalHours calculates total hours billed, after the project's rounding
 func (d *InvoiceData) TotalHours() float64 {
+	var total time.Duration
+	for _, b := range d.billed() {
+		total += b
+	}
+	return total.Hours()
+}
+
+// RawHours calculates total hours worked, before rounding
+func (d *InvoiceData) RawHours() float64 {
 	var total time.Duration
 	for _, e := range d.Entries {
 		total += e.Duration()
@@ -33,9 +62,521 @@ func (d *InvoiceData) TotalHours() float64 {
 	return total.Hours()
 }
 
-// TotalAmount calculates total billable amount
-func (d *InvoiceData) TotalAmount() float64 {
-	return d.TotalHours() * d.Project.HourlyRate
+// Rounded reports whether the project rounds billed time, in which case
+// hours worked are shown alongside hours billed. On a client invoice any
+// project rounding counts.
+func (d *InvoiceData) Rounded() bool {
+	if d.Client != nil {
+		return slices.ContainsFunc(d.Projects, func(p model.Project) bool { return p.Rounding != nil })
+	}
+	return d.Project.Rounding != nil
+}
+
+// RoundingText describes how billed time is rounded, naming each project
+// on a client invoice
+func (d *InvoiceData) RoundingText() string {
+	if d.Client == nil {
+		return d.Project.Rounding.String()
+	}
+	var parts []string
+	for _, g := range d.ProjectGroups() {
+		if g.Project.Rounding != nil {
+			parts = append(parts, g.Project.Name+": "+g.Project.Rounding.String())
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// day returns t in the timezone the invoice counts days in
+func (d *InvoiceData) day(t time.Time) time.Time {
+	if d.Location == nil {
+		return t
+	}
+	return t.In(d.Location)
+}
+
+// ProjectLabel is the heading for the project's name: "Client" on an
+// invoice for a whole client, otherwise "Project"
+func (d *InvoiceData) ProjectLabel() string {
+	if d.Client != nil {
+		return "Client"
+	}
+	return "Project"
+}
+
+// projectFor returns the project an entry is billed under
+func (d *InvoiceData) projectFor(e model.Entry) *model.Project {
+	for i := range d.Projects {
+		if d.Projects[i].ID == e.ProjectID {
+			return &d.Projects[i]
+		}
+	}
+	return &d.Project
+}
+
+// billed returns the billed time for each entry, rounded as its project
+// rounds time. Daily minimums count days in each project's timezone.
+func (d *InvoiceData) billed() []time.Duration {
+	if d.Client == nil {
+		return d.Project.Rounding.Billed(d.Entries, d.Location)
+	}
+	billed := make([]time.Duration, len(d.Entries))
+	for _, p := range d.Projects {
+		var index []int
+		var entries []model.Entry
+		for i, e := range d.Entries {
+			if e.ProjectID == p.ID {
+				index = append(index, i)
+				entries = append(entries, e)
+			}
+		}
+		for j, b := range p.Rounding.Billed(entries, period.LocationOr(p.Timezone, d.Location)) {
+			billed[index[j]] = b
+		}
+	}
+	return billed
+}
+
+// RateFor returns the hourly rate an entry is billed at: its own override,
+// otherwise the project rate in effect when it started
+func (d *InvoiceData) RateFor(e model.Entry) int64 {
+	if e.Rate != nil {
+		return *e.Rate
+	}
+	return d.projectFor(e).RateAt(e.StartTime())
+}
+
+// RateGroup is the time on an invoice billed at one hourly rate
+type RateGroup struct {
+	Rate    int64
+	Entries []model.Entry
+	Billed  []time.Duration // billed time for each entry, after rounding
+}
+
+// Hours returns the total hours billed in the group
+func (g RateGroup) Hours() float64 {
+	var total time.Duration
+	for _, b := range g.Billed {
+		total += b
+	}
+	return total.Hours()
+}
+
+// RawHours returns the total hours worked in the group, before rounding
+func (g RateGroup) RawHours() float64 {
+	var total time.Duration
+	for _, e := range g.Entries {
+		total += e.Duration()
+	}
+	return total.Hours()
+}
+
+// Amount returns the group's hours times its rate in minor units
+func (g RateGroup) Amount() int64 {
+	return money.Multiply(g.Rate, g.Hours())
+}
+
+// RateGroups splits the entries by the rate they are billed at, in order
+// of each rate's first entry
+func (d *InvoiceData) RateGroups() []RateGroup {
+	return d.groupByRate(d.Entries, d.billed())
+}
+
+func (d *InvoiceData) groupByRate(entries []model.Entry, billed []time.Duration) []RateGroup {
+	var groups []RateGroup
+	index := make(map[int64]int)
+	for i, e := range entries {
+		rate := d.RateFor(e)
+		g, ok := index[rate]
+		if !ok {
+			g = len(groups)
+			index[rate] = g
+			groups = append(groups, RateGroup{Rate: rate})
+		}
+		groups[g].Entries = append(groups[g].Entries, e)
+		groups[g].Billed = append(groups[g].Billed, billed[i])
+	}
+	return groups
+}
+
+// ProjectGroup is the time on an invoice for one project, split by rate
+type ProjectGroup struct {
+	Project model.Project
+	Rates   []RateGroup
+}
+
+// ProjectGroups splits the entries by project, in the order of Projects
+// and leaving out projects with no time, then by rate. An invoice for a
+// single project has one group.
+func (d *InvoiceData) ProjectGroups() []ProjectGroup {
+	if d.Client == nil {
+		return []ProjectGroup{{Project: d.Project, Rates: d.RateGroups()}}
+	}
+	billed := d.billed()
+	var groups []ProjectGroup
+	for _, p := range d.Projects {
+		var entries []model.Entry
+		var times []time.Duration
+		for i, e := range d.Entries {
+			if e.ProjectID == p.ID {
+				entries = append(entries, e)
+				times = append(times, billed[i])
+			}
+		}
+		if len(entries) > 0 {
+			groups = append(groups, ProjectGroup{Project: p, Rates: d.groupByRate(entries, times)})
+		}
+	}
+	return groups
+}
+
+// MultipleRates reports whether the entries are billed at more than one
+// rate. Time on a retainer or fixed-price invoice has no rate of its own.
+func (d *InvoiceData) MultipleRates() bool {
+	return d.Hourly() && len(d.RateGroups()) > 1
+}
+
+// Hourly reports whether the project bills by the hour
+func (d *InvoiceData) Hourly() bool {
+	return d.Project.BillingModel() == model.BillingHourly
+}
+
+// FeeMonths returns the calendar months, as YYYY-MM, the period touches
+// that no earlier invoice charged the retainer fee for
+func (d *InvoiceData) FeeMonths() []string {
+	from, to := d.day(d.From), d.day(d.To)
+	last := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var months []string
+	for m := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(last); m = m.AddDate(0, 1, 0) {
+		if key := m.Format("2006-01"); !d.BilledMonths[key] {
+			months = append(months, key)
+		}
+	}
+	return months
+}
+
+// Months returns the number of FeeMonths, each of which bills a retainer
+// fee
+func (d *InvoiceData) Months() int {
+	return len(d.FeeMonths())
+}
+
+// OverageRate returns the hourly rate for time beyond a retainer's hours
+func (d *InvoiceData) OverageRate() int64 {
+	if d.Project.Billing != nil && d.Project.Billing.OverageRate > 0 {
+		return d.Project.Billing.OverageRate
+	}
+	return d.Project.RateAt(d.To)
+}
+
+// Retainer returns the hours a retainer invoice covers, with any carried
+// in, the hours billed beyond them and the unused hours to carry forward
+func (d *InvoiceData) Retainer() (allowance, overage, unused float64) {
+	return d.Project.Billing.Retainer(d.Months(), d.Carried, d.TotalHours())
+}
+
+// Charges returns what a retainer or fixed-price invoice bills in place of
+// hours × rate: the fee and any overage, or each milestone. It is nil for
+// an hourly project.
+func (d *InvoiceData) Charges() []model.Charge {
+	switch d.Project.BillingModel() {
+	case model.BillingRetainer:
+		b := d.Project.Billing
+		charges := []model.Charge{}
+		if months := d.FeeMonths(); len(months) > 0 {
+			first, _ := time.Parse("2006-01", months[0])
+			last, _ := time.Parse("2006-01", months[len(months)-1])
+			desc := "Retainer, " + first.Format("January 2006")
+			if len(months) > 1 {
+				desc = fmt.Sprintf("Retainer, %s - %s (%d months)", first.Format("Jan 2006"), last.Format("Jan 2006"), len(months))
+			}
+			charges = append(charges, model.Charge{Description: desc, Amount: b.Fee * int64(len(months))})
+		}
+		if _, overage, _ := d.Retainer(); overage > 0 {
+			rate := d.OverageRate()
+			charges = append(charges, model.Charge{
+				Description: fmt.Sprintf("Overage, %.2f hours @ %s/hr", overage, d.FormatMoney(rate)),
+				Hours:       overage,
+				Rate:        rate,
+				Amount:      money.Multiply(rate, overage),
+			})
+		}
+		return charges
+	case model.BillingFixed:
+		charges := []model.Charge{}
+		for _, m := range d.Milestones {
+			charges = append(charges, model.Charge{Description: "Milestone: " + m.Name, Amount: m.Amount, Milestone: m.Name})
+		}
+		return charges
+	}
+	return nil
+}
+
+// Included describes the hours a retainer invoice covers, e.g. "24.00
+// hours (4.00 rolled over), 2.00 unused carried forward", or returns ""
+// for any other invoice
+func (d *InvoiceData) Included() string {
+	if d.Project.BillingModel() != model.BillingRetainer {
+		return ""
+	}
+	allowance, _, unused := d.Retainer()
+	s := fmt.Sprintf("%.2f hours", allowance)
+	if d.Carried > 0 {
+		s += fmt.Sprintf(" (%.2f rolled over)", d.Carried)
+	}
+	if d.Project.Billing.Rollover {
+		s += fmt.Sprintf(", %.2f unused carried forward", unused)
+	}
+	return s
+}
+
+// Rate returns the single hourly rate the invoice bills at. With no
+// entries it is the project rate at the end of the period; with several
+// rates it is the rate of the first group.
+func (d *InvoiceData) Rate() int64 {
+	if groups := d.RateGroups(); len(groups) > 0 {
+		return groups[0].Rate
+	}
+	return d.Project.RateAt(d.To)
+}
+
+// RateText describes the hourly rate, or every rate when there are
+// several. A retainer or fixed-price invoice describes its billing instead.
+func (d *InvoiceData) RateText() string {
+	if !d.Hourly() {
+		b := *d.Project.Billing
+		if b.Model == model.BillingRetainer {
+			b.OverageRate = d.OverageRate()
+		}
+		return b.String(d.Currency())
+	}
+	groups := d.RateGroups()
+	if len(groups) <= 1 {
+		return d.FormatMoney(d.Rate()) + "/hour"
+	}
+	rates := make([]string, len(groups))
+	for i, g := range groups {
+		rates[i] = d.FormatMoney(g.Rate)
+	}
+	return strings.Join(rates, ", ") + "/hour"
+}
+
+// LaborAmount calculates the amount for hours worked in minor units, or
+// the sum of the charges on a retainer or fixed-price invoice
+func (d *InvoiceData) LaborAmount() int64 {
+	var total int64
+	if !d.Hourly() {
+		for _, c := range d.Charges() {
+			total += c.Amount
+		}
+		return total
+	}
+	for _, pg := range d.ProjectGroups() {
+		for _, g := range pg.Rates {
+			total += g.Amount()
+		}
+	}
+	return total
+}
+
+// ExpensesAmount sums the billable expenses in minor units
+func (d *InvoiceData) ExpensesAmount() int64 {
+	var total int64
+	for _, e := range d.Expenses {
+		total += e.Total()
+	}
+	return total
+}
+
+// Subtotal is labor plus expenses, before discount and tax
+func (d *InvoiceData) Subtotal() int64 {
+	return d.LaborAmount() + d.ExpensesAmount()
+}
+
+// DiscountAmount is the discount taken off the subtotal
+func (d *InvoiceData) DiscountAmount() int64 {
+	if d.Discount == nil {
+		return 0
+	}
+	return d.Discount.Apply(d.Subtotal())
+}
+
+// TaxAmount is the tax charged on the discounted subtotal
+func (d *InvoiceData) TaxAmount() int64 {
+	if d.Tax == nil {
+		return 0
+	}
+	return d.Tax.Amount(d.Subtotal() - d.DiscountAmount())
+}
+
+// TotalAmount calculates the total due in minor units
+func (d *InvoiceData) TotalAmount() int64 {
+	return d.Subtotal() - d.DiscountAmount() + d.TaxAmount()
+}
+
+// SummaryLine is one row of the totals shown above the total due
+type SummaryLine struct {
+	Label  string
+	Amount int64
+}
+
+// Summary returns the rows leading up to the total due: labor (one row per
+// rate, per project and rate on a client invoice, or per charge on a
+// retainer or fixed-price invoice), expenses, subtotal, discount and tax.
+// It is empty for a single project's invoice that only bills time at one
+// hourly rate, which shows the total due alone.
+func (d *InvoiceData) Summary() []SummaryLine {
+	groups := d.RateGroups()
+	discount := d.DiscountAmount()
+	if d.Client == nil && d.Hourly() && len(groups) <= 1 && len(d.Expenses) == 0 && discount == 0 && d.Tax == nil {
+		return nil
+	}
+
+	var lines []SummaryLine
+	if d.Client != nil {
+		for _, pg := range d.ProjectGroups() {
+			for _, g := range pg.Rates {
+				lines = append(lines, SummaryLine{
+					Label:  fmt.Sprintf("%s, %.2f hours @ %s/hr", pg.Project.Name, g.Hours(), d.FormatMoney(g.Rate)),
+					Amount: g.Amount(),
+				})
+			}
+		}
+	} else if !d.Hourly() {
+		for _, c := range d.Charges() {
+			lines = append(lines, SummaryLine{Label: c.Description, Amount: c.Amount})
+		}
+	} else if len(groups) > 1 {
+		for _, g := range groups {
+			lines = append(lines, SummaryLine{
+				Label:  fmt.Sprintf("Labor, %.2f hours @ %s/hr", g.Hours(), d.FormatMoney(g.Rate)),
+				Amount: g.Amount(),
+			})
+		}
+	} else {
+		lines = append(lines, SummaryLine{Label: "Labor", Amount: d.LaborAmount()})
+	}
+	if len(d.Expenses) > 0 {
+		lines = append(lines, SummaryLine{Label: "Expenses", Amount: d.ExpensesAmount()})
+	}
+	if discount != 0 || d.Tax != nil {
+		lines = append(lines, SummaryLine{Label: "Subtotal", Amount: d.Subtotal()})
+	}
+	if discount != 0 {
+		label := "Discount"
+		if d.Discount.Percent != 0 {
+			label = fmt.Sprintf("Discount (%s%%)", strconv.FormatFloat(d.Discount.Percent, 'f', -1, 64))
+		}
+		lines = append(lines, SummaryLine{Label: label, Amount: -discount})
+	}
+	if d.Tax != nil {
+		lines = append(lines, SummaryLine{Label: d.Tax.Label(), Amount: d.TaxAmount()})
+	}
+	return lines
+}
+
+// Currency returns the invoice currency code
+func (d *InvoiceData) Currency() string {
+	return d.Project.CurrencyCode()
+}
+
+// FormatMoney formats an amount in minor units in the invoice currency
+func (d *InvoiceData) FormatMoney(amount int64) string {
+	return money.Format(amount, d.Currency())
+}
+
+// CategoryHours is the time spent on one category over the invoice period
+type CategoryHours struct {
+	Category string
+	Hours    float64
+}
+
+// CategoryHours totals the billed hours per category, in order of first
+// appearance, with uncategorised time last
+func (d *InvoiceData) CategoryHours() []CategoryHours {
+	var cats []CategoryHours
+	index := make(map[string]int)
+	var uncategorised time.Duration
+	billed := d.billed()
+	for j, e := range d.Entries {
+		if e.Category == "" {
+			uncategorised += billed[j]
+			continue
+		}
+		i, ok := index[e.Category]
+		if !ok {
+			i = len(cats)
+			index[e.Category] = i
+			cats = append(cats, CategoryHours{Category: e.Category})
+		}
+		cats[i].Hours += billed[j].Hours()
+	}
+	if uncategorised > 0 {
+		cats = append(cats, CategoryHours{Category: "Uncategorised", Hours: uncategorised.Hours()})
+	}
+	return cats
+}
+
+// showCategories reports whether the category summary belongs on the invoice
+func (d *InvoiceData) showCategories() bool {
+	return d.ShowCategories && !d.Condensed && len(d.Entries) > 0
+}
+
+// LineItem is one row of the time table on an invoice
+type LineItem struct {
+	Project     string  // on a client invoice, the project the time was for
+	Date        string  // day, or the period for a condensed invoice
+	Hours       float64 // billed
+	RawHours    float64 // worked, before rounding
+	Rate        int64
+	Description string
+	Commits     []gitlog.Commit // made during the entry, on a detailed invoice
+}
+
+// LineItems returns the rows of the time table. A condensed invoice has one
+// row per rate; a detailed one has a row per entry, grouped by rate. On a
+// client invoice the rows are grouped by project first.
+func (d *InvoiceData) LineItems() []LineItem {
+	var items []LineItem
+	for _, pg := range d.ProjectGroups() {
+		items = append(items, d.lineItems(pg)...)
+	}
+	return items
+}
+
+func (d *InvoiceData) lineItems(pg ProjectGroup) []LineItem {
+	var items []LineItem
+	project := ""
+	if d.Client != nil {
+		project = pg.Project.Name
+	}
+	for _, g := range pg.Rates {
+		if d.Condensed {
+			period := fmt.Sprintf("%s - %s", d.From.Format("Jan 2"), d.To.Format("Jan 2"))
+			desc := d.CondensedDescription
+			if desc == "" {
+				desc = "Consulting services"
+			}
+			items = append(items, LineItem{Project: project, Date: period, Hours: g.Hours(), RawHours: g.RawHours(), Rate: g.Rate, Description: desc})
+			continue
+		}
+		for i, e := range g.Entries {
+			note := e.Note
+			if note == "" {
+				note = "-"
+			}
+			items = append(items, LineItem{
+				Project:     project,
+				Date:        d.day(e.StartTime()).Format("Jan 2"),
+				Hours:       g.Billed[i].Hours(),
+				RawHours:    e.Duration().Hours(),
+				Rate:        g.Rate,
+				Description: note,
+				Commits:     d.Commits[e.ID],
+			})
+		}
+	}
+	return items
 }
 
 // GenerateText generates a plain text invoice
@@ -62,48 +603,98 @@ func GenerateText(w io.Writer, data *InvoiceData) error {
 		fmt.Fprintf(w, "PO #:       %s\n", data.PurchaseOrder)
 	}
 	fmt.Fprintf(w, "Date:       %s\n", data.Date.Format("January 2, 2006"))
+	if !data.DueDate.IsZero() {
+		fmt.Fprintf(w, "Due:        %s (Net %d)\n", data.DueDate.Format("January 2, 2006"), data.Terms)
+	}
 	fmt.Fprintf(w, "Period:     %s - %s\n\n",
 		data.From.Format("Jan 2, 2006"),
 		data.To.Format("Jan 2, 2006"))
 
-	fmt.Fprintf(w, "Project:    %s\n", data.Project.Name)
+	fmt.Fprintf(w, "%-11s %s\n", data.ProjectLabel()+":", data.Project.Name)
 	if data.Project.Description != "" {
 		fmt.Fprintf(w, "            %s\n", data.Project.Description)
 	}
-	fmt.Fprintf(w, "Rate:       $%.2f/hour\n\n", data.Project.HourlyRate)
+	fmt.Fprintf(w, "Rate:       %s\n", data.RateText())
+	if included := data.Included(); included != "" {
+		fmt.Fprintf(w, "Included:   %s\n", included)
+	}
+	if data.Rounded() {
+		fmt.Fprintf(w, "Rounding:   %s\n", data.RoundingText())
+	}
+	fmt.Fprintln(w)
 
 	fmt.Fprintf(w, "%s\n", strings.Repeat("-", 60))
-	fmt.Fprintf(w, "%-12s %8s  %s\n", "DATE", "HOURS", "DESCRIPTION")
+	// With rounding, hours worked sit beside hours billed; with more than
+	// one rate, line items are grouped by rate with the rate on each line
+	row := func(date, worked, hours, rate, desc string) {
+		fmt.Fprintf(w, "%-12s", date)
+		if data.Rounded() {
+			fmt.Fprintf(w, " %8s", worked)
+		}
+		fmt.Fprintf(w, " %8s", hours)
+		if data.MultipleRates() {
+			fmt.Fprintf(w, " %12s", rate)
+		}
+		fmt.Fprintf(w, "  %s\n", desc)
+	}
+	row("DATE", "WORKED", "HOURS", "RATE", "DESCRIPTION")
 	fmt.Fprintf(w, "%s\n", strings.Repeat("-", 60))
-
-	if data.Condensed {
-		// Single line item with total hours and custom description
-		period := fmt.Sprintf("%s - %s", data.From.Format("Jan 2"), data.To.Format("Jan 2"))
-		desc := data.CondensedDescription
-		if desc == "" {
-			desc = "Consulting services"
+	project := ""
+	for _, line := range data.LineItems() {
+		if line.Project != project {
+			project = line.Project
+			fmt.Fprintf(w, "%s\n", strings.ToUpper(project))
 		}
-		fmt.Fprintf(w, "%-12s %8.2f  %s\n", period, data.TotalHours(), desc)
-	} else {
-		for _, e := range data.Entries {
-			note := e.Note
-			if note == "" {
-				note = "-"
-			}
-			fmt.Fprintf(w, "%-12s %8.2f  %s\n",
-				e.StartTime().Format("Jan 2"),
-				e.Duration().Hours(),
-				note)
+		row(line.Date, fmt.Sprintf("%.2f", line.RawHours), fmt.Sprintf("%.2f", line.Hours), data.FormatMoney(line.Rate), line.Description)
+		for _, c := range line.Commits {
+			row("", "", "", "", "  "+c.String())
 		}
 	}
 
 	fmt.Fprintf(w, "%s\n", strings.Repeat("-", 60))
-	fmt.Fprintf(w, "%-12s %8.2f\n\n", "TOTAL HOURS", data.TotalHours())
+	if data.Rounded() {
+		fmt.Fprintf(w, "%-12s %8.2f %8.2f\n\n", "TOTAL HOURS", data.RawHours(), data.TotalHours())
+	} else {
+		fmt.Fprintf(w, "%-12s %8.2f\n\n", "TOTAL HOURS", data.TotalHours())
+	}
+
+	if data.showCategories() {
+		fmt.Fprintf(w, "HOURS BY CATEGORY\n")
+		for _, c := range data.CategoryHours() {
+			fmt.Fprintf(w, "  %-24s %8.2f\n", c.Category, c.Hours)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(data.Expenses) > 0 {
+		fmt.Fprintf(w, "%s\n", strings.Repeat("-", 60))
+		fmt.Fprintf(w, "%-12s %8s  %-24s %12s\n", "DATE", "QTY", "EXPENSE", "AMOUNT")
+		fmt.Fprintf(w, "%s\n", strings.Repeat("-", 60))
+		for _, e := range data.Expenses {
+			fmt.Fprintf(w, "%-12s %8s  %-24s %12s\n",
+				e.Date.Format("Jan 2"),
+				formatQuantity(e.Quantity),
+				e.Description,
+				data.FormatMoney(e.Total()))
+		}
+		fmt.Fprintf(w, "%s\n\n", strings.Repeat("-", 60))
+	}
+
+	for _, line := range data.Summary() {
+		fmt.Fprintf(w, "%-48s %10s\n", line.Label+":", data.FormatMoney(line.Amount))
+	}
 
 	fmt.Fprintf(w, "%s\n", strings.Repeat("=", 60))
-	fmt.Fprintf(w, "%-48s %10s\n", "TOTAL DUE:", fmt.Sprintf("$%.2f", data.TotalAmount()))
+	fmt.Fprintf(w, "%-48s %10s\n", "TOTAL DUE:", data.FormatMoney(data.TotalAmount()))
 	fmt.Fprintf(w, "%s\n", strings.Repeat("=", 60))
 
+	if data.hasPayment() {
+		fmt.Fprintf(w, "\nPAYMENT:\n")
+		for _, line := range data.paymentLines() {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
 	return nil
 }
 
@@ -114,6 +705,9 @@ func GenerateMarkdown(w io.Writer, data *InvoiceData) error {
 		fmt.Fprintf(w, "**PO #:** %s\n\n", data.PurchaseOrder)
 	}
 	fmt.Fprintf(w, "**Date:** %s\n\n", data.Date.Format("January 2, 2006"))
+	if !data.DueDate.IsZero() {
+		fmt.Fprintf(w, "**Due:** %s (Net %d)\n\n", data.DueDate.Format("January 2, 2006"), data.Terms)
+	}
 
 	// From section (user's info)
 	if data.FromContact != nil && hasContactInfo(data.FromContact) {
@@ -130,52 +724,134 @@ func GenerateMarkdown(w io.Writer, data *InvoiceData) error {
 	}
 
 	fmt.Fprintf(w, "## Details\n\n")
-	fmt.Fprintf(w, "- **Project:** %s\n", data.Project.Name)
+	fmt.Fprintf(w, "- **%s:** %s\n", data.ProjectLabel(), data.Project.Name)
 	if data.Project.Description != "" {
 		fmt.Fprintf(w, "- **Description:** %s\n", data.Project.Description)
 	}
 	fmt.Fprintf(w, "- **Period:** %s - %s\n",
 		data.From.Format("Jan 2, 2006"),
 		data.To.Format("Jan 2, 2006"))
-	fmt.Fprintf(w, "- **Rate:** $%.2f/hour\n\n", data.Project.HourlyRate)
+	fmt.Fprintf(w, "- **Rate:** %s\n", data.RateText())
+	if included := data.Included(); included != "" {
+		fmt.Fprintf(w, "- **Included:** %s\n", included)
+	}
+	if data.Rounded() {
+		fmt.Fprintf(w, "- **Rounding:** %s\n", data.RoundingText())
+	}
+	fmt.Fprintln(w)
 
 	fmt.Fprintf(w, "## Time Entries\n\n")
-	fmt.Fprintf(w, "| Date | Hours | Description |\n")
-	fmt.Fprintf(w, "|------|------:|-------------|\n")
+	header, align := "| Date |", "|------|"
+	if data.Rounded() {
+		header, align = header+" Worked |", align+"-------:|"
+	}
+	header, align = header+" Hours |", align+"------:|"
+	if data.MultipleRates() {
+		// Line items are grouped by rate, with the rate on each line
+		header, align = header+" Rate |", align+"-----:|"
+	}
+	fmt.Fprintf(w, "%s Description |\n", header)
+	fmt.Fprintf(w, "%s-------------|\n", align)
+	// On a client invoice each project's rows follow a row naming it
+	project := ""
+	for _, line := range data.LineItems() {
+		if line.Project != project {
+			project = line.Project
+			fmt.Fprintf(w, "| **%s** |%s\n", project, strings.Repeat(" |", strings.Count(header, "|")-1))
+		}
+		fmt.Fprintf(w, "| %s |", line.Date)
+		if data.Rounded() {
+			fmt.Fprintf(w, " %.2f |", line.RawHours)
+		}
+		fmt.Fprintf(w, " %.2f |", line.Hours)
+		if data.MultipleRates() {
+			fmt.Fprintf(w, " %s |", data.FormatMoney(line.Rate))
+		}
+		fmt.Fprintf(w, " %s |\n", line.Description)
+		for _, c := range line.Commits {
+			fmt.Fprintf(w, "|%s `%s` %s (%s) |\n", strings.Repeat(" |", strings.Count(header, "|")-1), c.Short(), c.Subject, c.Repo)
+		}
+	}
 
-	if data.Condensed {
-		// Single line item with total hours and custom description
-		period := fmt.Sprintf("%s - %s", data.From.Format("Jan 2"), data.To.Format("Jan 2"))
-		desc := data.CondensedDescription
-		if desc == "" {
-			desc = "Consulting services"
+	if data.showCategories() {
+		fmt.Fprintf(w, "\n## Hours by Category\n\n")
+		fmt.Fprintf(w, "| Category | Hours |\n")
+		fmt.Fprintf(w, "|----------|------:|\n")
+		for _, c := range data.CategoryHours() {
+			fmt.Fprintf(w, "| %s | %.2f |\n", c.Category, c.Hours)
 		}
-		fmt.Fprintf(w, "| %s | %.2f | %s |\n", period, data.TotalHours(), desc)
-	} else {
-		for _, e := range data.Entries {
-			note := e.Note
-			if note == "" {
-				note = "-"
-			}
-			fmt.Fprintf(w, "| %s | %.2f | %s |\n",
-				e.StartTime().Format("Jan 2"),
-				e.Duration().Hours(),
-				note)
+	}
+
+	if len(data.Expenses) > 0 {
+		fmt.Fprintf(w, "\n## Expenses\n\n")
+		fmt.Fprintf(w, "| Date | Qty | Description | Amount |\n")
+		fmt.Fprintf(w, "|------|----:|-------------|-------:|\n")
+		for _, e := range data.Expenses {
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n",
+				e.Date.Format("Jan 2"),
+				formatQuantity(e.Quantity),
+				e.Description,
+				data.FormatMoney(e.Total()))
 		}
 	}
 
 	fmt.Fprintf(w, "\n## Summary\n\n")
 	fmt.Fprintf(w, "| | |\n")
 	fmt.Fprintf(w, "|---|---:|\n")
+	if data.Rounded() {
+		fmt.Fprintf(w, "| **Hours Worked** | %.2f |\n", data.RawHours())
+	}
 	fmt.Fprintf(w, "| **Total Hours** | %.2f |\n", data.TotalHours())
-	fmt.Fprintf(w, "| **Rate** | $%.2f/hr |\n", data.Project.HourlyRate)
-	fmt.Fprintf(w, "| **Total Due** | **$%.2f** |\n", data.TotalAmount())
+	if data.Hourly() && !data.MultipleRates() {
+		fmt.Fprintf(w, "| **Rate** | %s/hr |\n", data.FormatMoney(data.Rate()))
+	}
+	for _, line := range data.Summary() {
+		fmt.Fprintf(w, "| **%s** | %s |\n", line.Label, data.FormatMoney(line.Amount))
+	}
+	fmt.Fprintf(w, "| **Total Due** | **%s** |\n", data.FormatMoney(data.TotalAmount()))
+
+	if data.hasPayment() {
+		fmt.Fprintf(w, "\n## Payment\n\n")
+		for _, line := range data.paymentLines() {
+			fmt.Fprintf(w, "%s  \n", line)
+		}
+	}
 
 	return nil
 }
 
+// hasPayment reports whether there are payment instructions to show
+func (d *InvoiceData) hasPayment() bool {
+	return d.Payment != nil && (d.Payment.Instructions != "" || d.Payment.Link != "")
+}
+
+// paymentInstructions returns the payment instructions line by line
+func (d *InvoiceData) paymentInstructions() []string {
+	if d.Payment == nil || d.Payment.Instructions == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(d.Payment.Instructions), "\n")
+}
+
+// paymentLines returns the payment instructions followed by the payment link
+func (d *InvoiceData) paymentLines() []string {
+	lines := d.paymentInstructions()
+	if d.Payment.Link != "" {
+		lines = append(lines, "Pay online: "+d.Payment.Link)
+	}
+	return lines
+}
+
+// formatQuantity shows an expense quantity, leaving it blank for single items
+func formatQuantity(q float64) string {
+	if q == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
 func hasContactInfo(c *model.ContactInfo) bool {
-	return c.Name != "" || c.Title != "" || c.Company != "" || c.Address != "" || c.Phone != "" || c.Email != ""
+	return c.Name != "" || c.Title != "" || c.Company != "" || c.Address != "" || c.Phone != "" || c.Email != "" || c.TaxID != ""
 }
 
 func writeContactText(w io.Writer, c *model.ContactInfo, prefix string) {
@@ -197,6 +873,9 @@ func writeContactText(w io.Writer, c *model.ContactInfo, prefix string) {
 	if c.Email != "" {
 		fmt.Fprintf(w, "%s%s\n", prefix, c.Email)
 	}
+	if c.TaxID != "" {
+		fmt.Fprintf(w, "%sTax ID: %s\n", prefix, c.TaxID)
+	}
 }
 
 func writeContactMarkdown(w io.Writer, c *model.ContactInfo) {
@@ -218,4 +897,7 @@ func writeContactMarkdown(w io.Writer, c *model.ContactInfo) {
 	if c.Email != "" {
 		fmt.Fprintf(w, "%s\n", c.Email)
 	}
+	if c.TaxID != "" {
+		fmt.Fprintf(w, "Tax ID: %s\n", c.TaxID)
+	}
 }
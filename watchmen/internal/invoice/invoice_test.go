@@ -2,10 +2,14 @@ package invoice
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"watchmen/internal/gitlog"
 	"watchmen/internal/model"
 )
 
@@ -19,7 +23,7 @@ func TestGenerateMarkdown(t *testing.T) {
 		Project: model.Project{
 			ID:          "test-id",
 			Name:        "Test Project",
-			HourlyRate:  100.0,
+			HourlyRate:  10000,
 			Description: "Test description",
 		},
 		Entries: []model.Entry{
@@ -65,6 +69,350 @@ func TestGenerateMarkdown(t *testing.T) {
 	}
 }
 
+func TestGenerateMarkdownInProjectCurrency(t *testing.T) {
+	baseTime := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	endTime := time.Date(2024, 6, 15, 10, 30, 0, 0, time.Local)
+
+	data := &InvoiceData{
+		InvoiceNumber: "INV-002",
+		Date:          baseTime,
+		Project: model.Project{
+			ID:         "test-id",
+			Name:       "Test Project",
+			HourlyRate: 123450,
+			Currency:   "EUR",
+		},
+		Entries: []model.Entry{
+			{
+				ID:        "entry-1",
+				ProjectID: "test-id",
+				Note:      "Did some work",
+				Segments: []model.TimeSegment{
+					{Start: baseTime, End: &endTime},
+				},
+				Completed: true,
+			},
+		},
+		From: baseTime,
+		To:   endTime,
+	}
+
+	if got := data.TotalAmount(); got != 185175 {
+		t.Errorf("TotalAmount() = %d, want 185175", got)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateMarkdown(&buf, data); err != nil {
+		t.Fatalf("GenerateMarkdown() error = %v", err)
+	}
+
+	output := buf.String()
+	checks := []string{
+		"**Rate:** 1.234,50 €/hour",
+		"| **Total Due** | **1.851,75 €** |",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateMarkdown() output missing %q", check)
+		}
+	}
+	if strings.Contains(output, "$") {
+		t.Error("GenerateMarkdown() output should not contain a dollar sign")
+	}
+
+	tmpFile := t.TempDir() + "/test-invoice.pdf"
+	if err := GeneratePDF(tmpFile, data); err != nil {
+		t.Fatalf("GeneratePDF() error = %v", err)
+	}
+}
+
+func TestGenerateTextWithExpensesDiscountAndTax(t *testing.T) {
+	baseTime := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	endTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+
+	data := &InvoiceData{
+		InvoiceNumber: "INV-003",
+		Date:          baseTime,
+		Project: model.Project{
+			ID:         "test-id",
+			Name:       "Test Project",
+			HourlyRate: 10000,
+		},
+		Entries: []model.Entry{
+			{
+				ID:        "entry-1",
+				ProjectID: "test-id",
+				Note:      "Did some work",
+				Segments: []model.TimeSegment{
+					{Start: baseTime, End: &endTime},
+				},
+				Completed: true,
+			},
+		},
+		Expenses: []model.Expense{
+			{Date: baseTime, Description: "Mileage", Quantity: 120, UnitAmount: 50},
+			{Date: baseTime, Description: "IDE licence", UnitAmount: 4999},
+		},
+		From:        baseTime,
+		To:          endTime,
+		FromContact: &model.ContactInfo{Name: "Me", TaxID: "GB123456789"},
+		Discount:    &model.Discount{Percent: 10},
+		Tax:         &model.Tax{Name: "VAT", Rate: 20},
+	}
+
+	// 300.00 labor + 109.99 expenses, less 41.00 discount, plus 73.80 VAT
+	if got := data.TotalAmount(); got != 44279 {
+		t.Errorf("TotalAmount() = %d, want 44279", got)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateText(&buf, data); err != nil {
+		t.Fatalf("GenerateText() error = %v", err)
+	}
+	output := buf.String()
+
+	checks := []string{
+		"Tax ID: GB123456789",
+		"Mileage",
+		"$60.00",
+		"IDE licence",
+		"$49.99",
+		"Labor:",
+		"$300.00",
+		"Subtotal:",
+		"$409.99",
+		"Discount (10%):",
+		"-$41.00",
+		"VAT 20%:",
+		"$73.80",
+		"$442.79",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateText() output missing %q", check)
+		}
+	}
+
+	buf.Reset()
+	if err := GenerateMarkdown(&buf, data); err != nil {
+		t.Fatalf("GenerateMarkdown() error = %v", err)
+	}
+	output = buf.String()
+	checks = []string{
+		"## Expenses",
+		"| Jun 15 | 120 | Mileage | $60.00 |",
+		"| **Discount (10%)** | -$41.00 |",
+		"| **VAT 20%** | $73.80 |",
+		"| **Total Due** | **$442.79** |",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateMarkdown() output missing %q", check)
+		}
+	}
+
+	tmpFile := t.TempDir() + "/test-invoice.pdf"
+	if err := GeneratePDF(tmpFile, data); err != nil {
+		t.Fatalf("GeneratePDF() error = %v", err)
+	}
+}
+
+func TestRetainerInvoice(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+	end := start.Add(26 * time.Hour)
+	data := &InvoiceData{
+		InvoiceNumber: "INV-R",
+		Date:          end,
+		Project: model.Project{
+			Name:       "Retained",
+			HourlyRate: 10000,
+			Billing:    &model.Billing{Model: model.BillingRetainer, Fee: 200000, Hours: 20, OverageRate: 15000, Rollover: true},
+		},
+		Entries: []model.Entry{{
+			ID:        "e1",
+			Segments:  []model.TimeSegment{{Start: start, End: &end}},
+			Completed: true,
+		}},
+		From:    time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local),
+		To:      time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local),
+		Carried: 4,
+	}
+
+	// 26 hours against 20 plus 4 rolled over leaves 2 hours of overage
+	charges := data.Charges()
+	if len(charges) != 2 || charges[0].Amount != 200000 || charges[1].Hours != 2 || charges[1].Amount != 30000 {
+		t.Fatalf("Charges() = %+v, want the fee and 2h of overage at 150", charges)
+	}
+	if got := data.TotalAmount(); got != 230000 {
+		t.Errorf("TotalAmount() = %d, want 230000", got)
+	}
+	if data.MultipleRates() {
+		t.Error("MultipleRates() = true on a retainer invoice")
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateText(&buf, data); err != nil {
+		t.Fatalf("GenerateText() error = %v", err)
+	}
+	output := buf.String()
+	for _, check := range []string{
+		"Rate:       retainer of $2,000.00 a month for 20h, then $150.00/hour, unused hours roll over",
+		"Included:   24.00 hours (4.00 rolled over), 0.00 unused carried forward",
+		"Retainer, March 2026:",
+		"Overage, 2.00 hours @ $150.00/hr:",
+		"$2,300.00",
+	} {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateText() output missing %q", check)
+		}
+	}
+
+	// A quieter month carries its unused hours forward
+	data.Carried = 0
+	short := start.Add(12 * time.Hour)
+	data.Entries[0].Segments[0].End = &short
+	if _, overage, unused := data.Retainer(); overage != 0 || unused != 8 {
+		t.Errorf("Retainer() overage, unused = %v, %v, want 0, 8", overage, unused)
+	}
+	if got := data.TotalAmount(); got != 200000 {
+		t.Errorf("TotalAmount() without overage = %d, want 200000", got)
+	}
+
+	// A second invoice for a month already billed charges no fee again,
+	// and its time is all overage
+	data.BilledMonths = map[string]bool{"2026-03": true}
+	if charges := data.Charges(); len(charges) != 1 || charges[0].Hours != 12 {
+		t.Errorf("Charges() = %+v, want only 12h of overage", charges)
+	}
+	data.To = time.Date(2026, 4, 15, 0, 0, 0, 0, time.Local)
+	if months := data.FeeMonths(); len(months) != 1 || months[0] != "2026-04" {
+		t.Errorf("FeeMonths() = %v, want only April", months)
+	}
+	if charges := data.Charges(); charges[0].Description != "Retainer, April 2026" || charges[0].Amount != 200000 {
+		t.Errorf("Charges() = %+v, want April's fee", charges)
+	}
+}
+
+func TestFixedPriceInvoice(t *testing.T) {
+	data := &InvoiceData{
+		InvoiceNumber: "INV-F",
+		Date:          time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local),
+		Project: model.Project{
+			Name:       "Fixed",
+			HourlyRate: 10000,
+			Billing: &model.Billing{Model: model.BillingFixed, Milestones: []model.Milestone{
+				{Name: "Design", Amount: 300000}, {Name: "Build", Amount: 900000},
+			}},
+		},
+		Milestones: []model.Milestone{{Name: "Design", Amount: 300000}},
+		From:       time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local),
+		To:         time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local),
+		Tax:        &model.Tax{Name: "VAT", Rate: 20},
+	}
+	if got := data.TotalAmount(); got != 360000 {
+		t.Errorf("TotalAmount() = %d, want 360000", got)
+	}
+	if charges := data.Charges(); len(charges) != 1 || charges[0].Milestone != "Design" {
+		t.Errorf("Charges() = %+v, want the Design milestone", charges)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateMarkdown(&buf, data); err != nil {
+		t.Fatalf("GenerateMarkdown() error = %v", err)
+	}
+	output := buf.String()
+	for _, check := range []string{
+		"- **Rate:** fixed price, 2 milestones totalling $12,000.00",
+		"| **Milestone: Design** | $3,000.00 |",
+		"| **Total Due** | **$3,600.00** |",
+	} {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateMarkdown() output missing %q", check)
+		}
+	}
+	if strings.Contains(output, "/hr |") {
+		t.Error("GenerateMarkdown() shows an hourly rate on a fixed-price invoice")
+	}
+}
+
+func TestInvoiceGroupsByRate(t *testing.T) {
+	day := func(d, hours int) model.Entry {
+		start := time.Date(2026, 1, d, 9, 0, 0, 0, time.Local)
+		end := start.Add(time.Duration(hours) * time.Hour)
+		return model.Entry{
+			ID:        fmt.Sprintf("entry-%d", d),
+			Note:      fmt.Sprintf("Work on day %d", d),
+			Segments:  []model.TimeSegment{{Start: start, End: &end}},
+			Completed: true,
+		}
+	}
+
+	project := model.Project{Name: "Test Project", HourlyRate: 10000}
+	project.SetRate(15000, time.Date(2026, 1, 10, 0, 0, 0, 0, time.Local))
+
+	premium := int64(20000)
+	entries := []model.Entry{day(5, 2), day(12, 3), day(13, 1), day(6, 1)}
+	entries[2].Rate = &premium
+
+	data := &InvoiceData{
+		InvoiceNumber: "INV-004",
+		Date:          time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local),
+		Project:       project,
+		Entries:       entries,
+		From:          time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local),
+		To:            time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local),
+	}
+
+	groups := data.RateGroups()
+	if len(groups) != 3 {
+		t.Fatalf("RateGroups() returned %d groups, want 3", len(groups))
+	}
+	if groups[0].Rate != 10000 || groups[0].Hours() != 3 {
+		t.Errorf("first group = %d for %.2fh, want 10000 for 3h", groups[0].Rate, groups[0].Hours())
+	}
+
+	// 3h at 100 + 3h at 150 + 1h at 200
+	if got := data.TotalAmount(); got != 95000 {
+		t.Errorf("TotalAmount() = %d, want 95000", got)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateText(&buf, data); err != nil {
+		t.Fatalf("GenerateText() error = %v", err)
+	}
+	output := buf.String()
+	checks := []string{
+		"Rate:       $100.00, $150.00, $200.00/hour",
+		"RATE",
+		"Labor, 3.00 hours @ $150.00/hr:",
+		"$950.00",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateText() output missing %q", check)
+		}
+	}
+	// Entries at the same rate are listed together
+	if strings.Index(output, "Work on day 6") > strings.Index(output, "Work on day 12") {
+		t.Error("GenerateText() should group entries by rate")
+	}
+
+	data.Condensed = true
+	data.CondensedDescription = "Development"
+	buf.Reset()
+	if err := GenerateMarkdown(&buf, data); err != nil {
+		t.Fatalf("GenerateMarkdown() error = %v", err)
+	}
+	if got := strings.Count(buf.String(), "| Development |"); got != 3 {
+		t.Errorf("condensed markdown has %d line items, want one per rate (3)", got)
+	}
+
+	tmpFile := t.TempDir() + "/test-invoice.pdf"
+	if err := GeneratePDF(tmpFile, data); err != nil {
+		t.Fatalf("GeneratePDF() error = %v", err)
+	}
+}
+
 func TestGenerateMarkdownWithContacts(t *testing.T) {
 	baseTime := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
 	endTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
@@ -75,7 +423,7 @@ func TestGenerateMarkdownWithContacts(t *testing.T) {
 		Project: model.Project{
 			ID:         "test-id",
 			Name:       "Test Project",
-			HourlyRate: 100.0,
+			HourlyRate: 10000,
 		},
 		Entries: []model.Entry{
 			{
@@ -142,7 +490,7 @@ func TestGenerateTextWithContacts(t *testing.T) {
 		Project: model.Project{
 			ID:         "test-id",
 			Name:       "Test Project",
-			HourlyRate: 100.0,
+			HourlyRate: 10000,
 		},
 		Entries: []model.Entry{
 			{
@@ -204,7 +552,7 @@ func TestGeneratePDFWithNonASCIIDescription(t *testing.T) {
 		Project: model.Project{
 			ID:         "test-id",
 			Name:       "Test Project",
-			HourlyRate: 100.0,
+			HourlyRate: 10000,
 		},
 		Entries: []model.Entry{
 			{
@@ -230,34 +578,71 @@ func TestGeneratePDFWithNonASCIIDescription(t *testing.T) {
 	}
 }
 
-func TestSanitizePDFText(t *testing.T) {
-	tests := []struct {
-		name  string
-		input string
-		want  string
-	}{
-		{"ascii only", "Hello world", "Hello world"},
-		{"empty string", "", ""},
-		{"latin1 chars", "café résumé", "café résumé"},
-		{"right arrow", "iad→ewr", "iad->ewr"},
-		{"left arrow", "ewr←iad", "ewr<-iad"},
-		{"em dash", "foo—bar", "foo--bar"},
-		{"en dash", "foo–bar", "foo-bar"},
-		{"smart quotes", "\u201cquoted\u201d", "\"quoted\""},
-		{"smart single quotes", "\u2018quoted\u2019", "'quoted'"},
-		{"ellipsis", "wait\u2026", "wait..."},
-		{"bullet", "\u2022 item", "* item"},
-		{"unknown non-latin1", "test\u4e16\u754cend", "test??end"},
-		{"mixed", "Infrastructure migration (iad\u2192ewr)", "Infrastructure migration (iad->ewr)"},
+func TestGeneratePDFWithSettings(t *testing.T) {
+	baseTime := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	endTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	data := &InvoiceData{
+		InvoiceNumber: "INV-001",
+		Date:          baseTime,
+		Project:       model.Project{ID: "p", Name: "Проект", HourlyRate: 10000},
+		Entries: []model.Entry{{
+			ID:        "e1",
+			ProjectID: "p",
+			Note:      "Θεσσαλονίκη rollout — 東京",
+			Segments:  []model.TimeSegment{{Start: baseTime, End: &endTime}},
+			Completed: true,
+		}},
+		From:          baseTime,
+		To:            endTime,
+		BillToContact: &model.ContactInfo{Name: "Zoë Ångström", Address: "ул. Тверская 1, Москва"},
+		Payment:       &model.PaymentInfo{Instructions: "IBAN GB00 0000 0000\nRef INV-001", Link: "https://pay.example.com/inv-001"},
+		PDF:           &model.PDFSettings{PageSize: model.PageLetter, Color: "#1f4e79"},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := sanitizePDFText(tt.input)
-			if got != tt.want {
-				t.Errorf("sanitizePDFText(%q) = %q, want %q", tt.input, got, tt.want)
-			}
-		})
+	path := filepath.Join(t.TempDir(), "invoice.pdf")
+	if err := GeneratePDF(path, data); err != nil {
+		t.Fatalf("GeneratePDF() error = %v", err)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte("/MediaBox [0 0 612.00 792.00]")) {
+		t.Error("PDF is not US Letter")
+	}
+	if !bytes.Contains(out, []byte("/FontFile2")) || !bytes.Contains(out, []byte("/Identity-H")) {
+		t.Error("PDF does not embed a Unicode font")
+	}
+	if !bytes.Contains(out, []byte("https://pay.example.com/inv-001")) {
+		t.Error("PDF is missing the payment link")
+	}
+
+	data.PDF = &model.PDFSettings{Color: "blue"}
+	if err := GeneratePDF(path, data); err == nil {
+		t.Error("GeneratePDF() should reject an invalid colour")
+	}
+	data.PDF = &model.PDFSettings{Logo: filepath.Join(t.TempDir(), "missing.png")}
+	if err := GeneratePDF(path, data); err == nil {
+		t.Error("GeneratePDF() should fail on a missing logo")
+	}
+}
+
+func TestGenerateTextWithPayment(t *testing.T) {
+	baseTime := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	data := &InvoiceData{
+		InvoiceNumber: "INV-001",
+		Date:          baseTime,
+		Project:       model.Project{ID: "p", Name: "Test", HourlyRate: 10000},
+		From:          baseTime,
+		To:            baseTime,
+		Payment:       &model.PaymentInfo{Instructions: "Sort code 00-00-00\nAccount 12345678", Link: "https://pay.example.com"},
+	}
+
+	var buf bytes.Buffer
+	GenerateText(&buf, data)
+	want := "PAYMENT:\n  Sort code 00-00-00\n  Account 12345678\n  Pay online: https://pay.example.com\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("GenerateText() should end with payment instructions, got:\n%s", buf.String())
 	}
 }
 
@@ -286,3 +671,211 @@ func TestHasContactInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestInvoiceCategoryHours(t *testing.T) {
+	entry := func(d, hours int, category string) model.Entry {
+		start := time.Date(2026, 2, d, 9, 0, 0, 0, time.Local)
+		end := start.Add(time.Duration(hours) * time.Hour)
+		return model.Entry{
+			ID:        fmt.Sprintf("entry-%d", d),
+			Note:      fmt.Sprintf("Work on day %d", d),
+			Category:  category,
+			Segments:  []model.TimeSegment{{Start: start, End: &end}},
+			Completed: true,
+		}
+	}
+
+	data := &InvoiceData{
+		InvoiceNumber:  "INV-005",
+		Date:           time.Date(2026, 2, 28, 0, 0, 0, 0, time.Local),
+		Project:        model.Project{Name: "Test Project", HourlyRate: 10000},
+		Entries:        []model.Entry{entry(2, 2, "design"), entry(3, 1, ""), entry(4, 3, "development"), entry(5, 1, "design")},
+		From:           time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local),
+		To:             time.Date(2026, 2, 28, 0, 0, 0, 0, time.Local),
+		ShowCategories: true,
+	}
+
+	want := []CategoryHours{{"design", 3}, {"development", 3}, {"Uncategorised", 1}}
+	got := data.CategoryHours()
+	if len(got) != len(want) {
+		t.Fatalf("CategoryHours() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("CategoryHours()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateMarkdown(&buf, data); err != nil {
+		t.Fatalf("GenerateMarkdown() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "| design | 3.00 |") {
+		t.Error("GenerateMarkdown() output missing category summary")
+	}
+
+	// A condensed invoice has no room for the breakdown
+	data.Condensed = true
+	buf.Reset()
+	if err := GenerateText(&buf, data); err != nil {
+		t.Fatalf("GenerateText() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "HOURS BY CATEGORY") {
+		t.Error("GenerateText() should not summarise categories on a condensed invoice")
+	}
+}
+
+func TestInvoiceRounding(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	end := start.Add(82 * time.Minute)
+	data := &InvoiceData{
+		InvoiceNumber: "INV-006",
+		Date:          time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local),
+		Project: model.Project{
+			Name:       "Test Project",
+			HourlyRate: 10000,
+			Rounding:   &model.Rounding{Increment: 15, Mode: model.RoundUp},
+		},
+		Entries: []model.Entry{{
+			ID:        "entry-1",
+			Note:      "Fixes",
+			Segments:  []model.TimeSegment{{Start: start, End: &end}},
+			Completed: true,
+		}},
+		From: time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local),
+		To:   time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local),
+	}
+
+	if got := data.TotalHours(); got != 1.5 {
+		t.Errorf("TotalHours() = %v, want 1.5", got)
+	}
+	if got := data.TotalAmount(); got != 15000 {
+		t.Errorf("TotalAmount() = %d, want 15000", got)
+	}
+	items := data.LineItems()
+	if len(items) != 1 || items[0].Hours != 1.5 || fmt.Sprintf("%.2f", items[0].RawHours) != "1.37" {
+		t.Errorf("LineItems() = %+v, want 1.37h worked billed as 1.50h", items)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateMarkdown(&buf, data); err != nil {
+		t.Fatalf("GenerateMarkdown() error = %v", err)
+	}
+	output := buf.String()
+	checks := []string{
+		"- **Rounding:** up to 15 min",
+		"| Date | Worked | Hours | Description |",
+		"| Mar 2 | 1.37 | 1.50 | Fixes |",
+		"| **Hours Worked** | 1.37 |",
+		"| **Total Hours** | 1.50 |",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateMarkdown() output missing %q", check)
+		}
+	}
+}
+
+func TestClientInvoice(t *testing.T) {
+	entry := func(id, projectID string, day, minutes int) model.Entry {
+		start := time.Date(2026, 10, day, 9, 0, 0, 0, time.Local)
+		end := start.Add(time.Duration(minutes) * time.Minute)
+		return model.Entry{ID: id, ProjectID: projectID, Note: id, Segments: []model.TimeSegment{{Start: start, End: &end}}, Completed: true}
+	}
+	client := &model.Client{ID: "acme", Name: "Acme"}
+	data := &InvoiceData{
+		InvoiceNumber: "INV-007",
+		Date:          time.Date(2026, 10, 31, 0, 0, 0, 0, time.Local),
+		Project:       model.Project{Name: "Acme"},
+		Client:        client,
+		Projects: []model.Project{
+			{ID: "web", Name: "Website", HourlyRate: 10000},
+			{ID: "app", Name: "App", HourlyRate: 12000, Rounding: &model.Rounding{Increment: 30, Mode: model.RoundUp}},
+		},
+		Entries: []model.Entry{
+			entry("a1", "app", 1, 70),
+			entry("w1", "web", 2, 120),
+			entry("w2", "web", 3, 60),
+		},
+		From: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local),
+		To:   time.Date(2026, 10, 31, 0, 0, 0, 0, time.Local),
+	}
+
+	// The app's 70 minutes round up to 1.5 hours; the website isn't rounded
+	if got := data.TotalHours(); got != 4.5 {
+		t.Errorf("TotalHours() = %v, want 4.5", got)
+	}
+	if got := data.TotalAmount(); got != 48000 {
+		t.Errorf("TotalAmount() = %d, want 48000", got)
+	}
+
+	items := data.LineItems()
+	var got []string
+	for _, item := range items {
+		got = append(got, fmt.Sprintf("%s %s %.2f", item.Project, item.Description, item.Hours))
+	}
+	want := []string{"Website w1 2.00", "Website w2 1.00", "App a1 1.50"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("LineItems() = %v, want %v", got, want)
+	}
+
+	summary := data.Summary()
+	if len(summary) != 2 || summary[0].Label != "Website, 3.00 hours @ $100.00/hr" || summary[1].Label != "App, 1.50 hours @ $120.00/hr" {
+		t.Errorf("Summary() = %+v", summary)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateText(&buf, data); err != nil {
+		t.Fatalf("GenerateText() error = %v", err)
+	}
+	output := buf.String()
+	for _, check := range []string{"Client:     Acme\n", "WEBSITE\n", "APP\n", "Rounding:   App: up to 30 min"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("GenerateText() output missing %q", check)
+		}
+	}
+}
+
+func TestCommitsOnReportAndInvoice(t *testing.T) {
+	start := time.Date(2026, 10, 5, 9, 0, 0, 0, time.Local)
+	end := start.Add(2 * time.Hour)
+	entries := []model.Entry{{
+		ID:        "entry-1",
+		Note:      "Login page",
+		Segments:  []model.TimeSegment{{Start: start, End: &end}},
+		Completed: true,
+	}}
+	commits := map[string][]gitlog.Commit{
+		"entry-1": {{Repo: "web", Hash: "abc123def", Time: start.Add(time.Hour), Subject: "Add login form"}},
+	}
+
+	var buf bytes.Buffer
+	GenerateReport(&buf, &ReportData{ProjectName: "Test", From: start, To: end, TotalHours: 2, Entries: entries, Commits: commits})
+	want := "### October 5, 2026\n- Login page\n\n**Commits:**\n- `abc123d` Add login form (web)\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("GenerateReport() output missing commits:\n%s", buf.String())
+	}
+
+	data := &InvoiceData{
+		InvoiceNumber: "INV-008",
+		Date:          end,
+		Project:       model.Project{Name: "Test", HourlyRate: 10000},
+		Entries:       entries,
+		From:          start,
+		To:            end,
+		Commits:       commits,
+	}
+	buf.Reset()
+	if err := GenerateMarkdown(&buf, data); err != nil {
+		t.Fatalf("GenerateMarkdown() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "| Oct 5 | 2.00 | Login page |\n| | | `abc123d` Add login form (web) |\n") {
+		t.Errorf("GenerateMarkdown() output missing commits:\n%s", buf.String())
+	}
+
+	// A condensed invoice has no entry rows to list them under
+	data.Condensed = true
+	if items := data.LineItems(); len(items) != 1 || items[0].Commits != nil {
+		t.Errorf("LineItems() = %+v, want no commits", items)
+	}
+}
@@ -1,55 +1,127 @@
 package invoice
 
 import (
+	_ "embed"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/jung-kurt/gofpdf"
 	"watchmen/internal/model"
 )
 
-// sanitizePDFText replaces non-Latin1 characters (codepoint > 255) with ASCII
-// equivalents. gofpdf's built-in fonts use a 256-element width table and panic
-// on any rune above 255.
-func sanitizePDFText(s string) string {
-	var b strings.Builder
-	for _, r := range s {
-		if r <= 255 {
-			b.WriteRune(r)
-			continue
-		}
-		switch r {
-		case '\u2192', '\u2794': // → ➔
-			b.WriteString("->")
-		case '\u2190': // ←
-			b.WriteString("<-")
-		case '\u2014': // —
-			b.WriteString("--")
-		case '\u2013': // –
-			b.WriteString("-")
-		case '\u201c', '\u201d': // " "
-			b.WriteByte('"')
-		case '\u2018', '\u2019': // ' '
-			b.WriteByte('\'')
-		case '\u2026': // …
-			b.WriteString("...")
-		case '\u2022': // •
-			b.WriteByte('*')
-		default:
-			b.WriteByte('?')
+//go:embed fonts/DejaVuSansCondensed.ttf
+var fontRegular []byte
+
+//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+var fontBold []byte
+
+//go:embed fonts/DejaVuSansCondensed-Oblique.ttf
+var fontItalic []byte
+
+// pdfFont is the family name fonts are registered under
+const pdfFont = "body"
+
+// addFonts registers the Unicode fonts invoices are set in: the bundled
+// DejaVu Sans, or the TTF files in settings
+func addFonts(pdf *gofpdf.Fpdf, settings *model.PDFSettings) error {
+	if settings == nil || settings.Font == "" {
+		pdf.AddUTF8FontFromBytes(pdfFont, "", fontRegular)
+		pdf.AddUTF8FontFromBytes(pdfFont, "B", fontBold)
+		pdf.AddUTF8FontFromBytes(pdfFont, "I", fontItalic)
+		return pdf.Error()
+	}
+
+	regular, err := os.ReadFile(settings.Font)
+	if err != nil {
+		return fmt.Errorf("reading font: %w", err)
+	}
+	bold := regular
+	if settings.BoldFont != "" {
+		if bold, err = os.ReadFile(settings.BoldFont); err != nil {
+			return fmt.Errorf("reading bold font: %w", err)
 		}
 	}
-	return b.String()
+	pdf.AddUTF8FontFromBytes(pdfFont, "", regular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", bold)
+	pdf.AddUTF8FontFromBytes(pdfFont, "I", regular)
+	return pdf.Error()
+}
+
+// ParseColor reads a colour given as #rrggbb
+func ParseColor(s string) (r, g, b int, err error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid colour %q, use #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid colour %q, use #rrggbb", s)
+	}
+	return int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff), nil
 }
 
 // GeneratePDF generates a PDF invoice
 func GeneratePDF(filename string, data *InvoiceData) error {
-	pdf := gofpdf.New("P", "mm", "A4", "")
+	settings := data.PDF
+	if settings == nil {
+		settings = &model.PDFSettings{}
+	}
+	pageSize := settings.PageSize
+	if pageSize == "" {
+		pageSize = model.PageA4
+	}
+	pdf := gofpdf.New("P", "mm", pageSize, "")
+	if err := addFonts(pdf, settings); err != nil {
+		return err
+	}
+
+	// The letterhead colour marks the title and tints the table headers
+	accent, fill := [3]int{0, 0, 0}, [3]int{240, 240, 240}
+	if settings.Color != "" {
+		r, g, b, err := ParseColor(settings.Color)
+		if err != nil {
+			return err
+		}
+		accent = [3]int{r, g, b}
+		for i, c := range accent {
+			fill[i] = 255 - (255-c)*15/100
+		}
+	}
+
 	pdf.AddPage()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	marginLeft, marginTop, marginRight, marginBottom := pdf.GetMargins()
+
+	// Logo, top right
+	if settings.Logo != "" {
+		opts := gofpdf.ImageOptions{ReadDpi: true}
+		info := pdf.RegisterImageOptions(settings.Logo, opts)
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("reading logo: %w", err)
+		}
+		width, height := info.Width()*18/info.Height(), 18.0
+		if width > 60 {
+			width, height = 60, info.Height()*60/info.Width()
+		}
+		pdf.ImageOptions(settings.Logo, pageWidth-marginRight-width, marginTop, width, height, false, opts, 0, "")
+	}
 
 	// Title
-	pdf.SetFont("Arial", "B", 24)
+	pdf.SetTextColor(accent[0], accent[1], accent[2])
+	pdf.SetFont(pdfFont, "B", 24)
 	pdf.Cell(0, 15, "INVOICE")
+	pdf.SetTextColor(0, 0, 0)
+	if settings.Color != "" {
+		y := pdf.GetY() + 17
+		lineWidth := pdf.GetLineWidth()
+		pdf.SetDrawColor(accent[0], accent[1], accent[2])
+		pdf.SetLineWidth(0.8)
+		pdf.Line(marginLeft, y, pageWidth-marginRight, y)
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.SetLineWidth(lineWidth)
+	}
 	pdf.Ln(20)
 
 	// From and Bill To sections side by side
@@ -60,20 +132,20 @@ func GeneratePDF(filename string, data *InvoiceData) error {
 
 		// From section (left side)
 		if data.FromContact != nil && hasContactInfo(data.FromContact) {
-			pdf.SetFont("Arial", "B", 10)
+			pdf.SetFont(pdfFont, "B", 10)
 			pdf.Cell(90, 6, "FROM:")
 			pdf.Ln(6)
-			pdf.SetFont("Arial", "", 10)
+			pdf.SetFont(pdfFont, "", 10)
 			writeContactPDF(pdf, data.FromContact)
 		}
 
 		// Bill To section (right side)
 		if data.BillToContact != nil && hasContactInfo(data.BillToContact) {
 			pdf.SetXY(105, startY)
-			pdf.SetFont("Arial", "B", 10)
+			pdf.SetFont(pdfFont, "B", 10)
 			pdf.Cell(90, 6, "BILL TO:")
 			pdf.SetXY(105, startY+6)
-			pdf.SetFont("Arial", "", 10)
+			pdf.SetFont(pdfFont, "", 10)
 			writeContactPDFAt(pdf, data.BillToContact, 105)
 		}
 
@@ -81,14 +153,14 @@ func GeneratePDF(filename string, data *InvoiceData) error {
 	}
 
 	// Invoice details
-	pdf.SetFont("Arial", "", 11)
+	pdf.SetFont(pdfFont, "", 11)
 	pdf.Cell(30, 6, "Invoice #:")
-	pdf.Cell(0, 6, sanitizePDFText(data.InvoiceNumber))
+	pdf.Cell(0, 6, data.InvoiceNumber)
 	pdf.Ln(6)
 
 	if data.PurchaseOrder != "" {
 		pdf.Cell(30, 6, "PO #:")
-		pdf.Cell(0, 6, sanitizePDFText(data.PurchaseOrder))
+		pdf.Cell(0, 6, data.PurchaseOrder)
 		pdf.Ln(6)
 	}
 
@@ -96,55 +168,81 @@ func GeneratePDF(filename string, data *InvoiceData) error {
 	pdf.Cell(0, 6, data.Date.Format("January 2, 2006"))
 	pdf.Ln(6)
 
+	if !data.DueDate.IsZero() {
+		pdf.Cell(30, 6, "Due:")
+		pdf.Cell(0, 6, fmt.Sprintf("%s (Net %d)", data.DueDate.Format("January 2, 2006"), data.Terms))
+		pdf.Ln(6)
+	}
+
 	pdf.Cell(30, 6, "Period:")
 	pdf.Cell(0, 6, fmt.Sprintf("%s - %s", data.From.Format("Jan 2, 2006"), data.To.Format("Jan 2, 2006")))
 	pdf.Ln(12)
 
 	// Project details
-	pdf.SetFont("Arial", "B", 11)
-	pdf.Cell(30, 6, "Project:")
-	pdf.SetFont("Arial", "", 11)
-	pdf.Cell(0, 6, sanitizePDFText(data.Project.Name))
+	pdf.SetFont(pdfFont, "B", 11)
+	pdf.Cell(30, 6, data.ProjectLabel()+":")
+	pdf.SetFont(pdfFont, "", 11)
+	pdf.Cell(0, 6, data.Project.Name)
 	pdf.Ln(6)
 
 	if data.Project.Description != "" {
 		pdf.Cell(30, 6, "")
-		pdf.SetFont("Arial", "I", 10)
-		pdf.Cell(0, 6, sanitizePDFText(data.Project.Description))
-		pdf.SetFont("Arial", "", 11)
+		pdf.SetFont(pdfFont, "I", 10)
+		pdf.Cell(0, 6, data.Project.Description)
+		pdf.SetFont(pdfFont, "", 11)
 		pdf.Ln(6)
 	}
 
 	pdf.Cell(30, 6, "Rate:")
-	pdf.Cell(0, 6, fmt.Sprintf("$%.2f/hour", data.Project.HourlyRate))
-	pdf.Ln(15)
+	pdf.Cell(0, 6, data.RateText())
+	pdf.Ln(6)
+
+	if included := data.Included(); included != "" {
+		pdf.Cell(30, 6, "Included:")
+		pdf.Cell(0, 6, included)
+		pdf.Ln(6)
+	}
 
-	// Table header
-	pdf.SetFillColor(240, 240, 240)
-	pdf.SetFont("Arial", "B", 10)
-	pdf.CellFormat(30, 8, "DATE", "1", 0, "L", true, 0, "")
-	pdf.CellFormat(25, 8, "HOURS", "1", 0, "R", true, 0, "")
-	pdf.CellFormat(0, 8, "DESCRIPTION", "1", 1, "L", true, 0, "")
+	if data.Rounded() {
+		pdf.Cell(30, 6, "Rounding:")
+		pdf.Cell(0, 6, data.RoundingText())
+		pdf.Ln(6)
+	}
+	pdf.Ln(9)
+
+	// With rounding, hours worked sit before hours billed; with more than
+	// one rate, a rate column sits between hours and description
+	workedWidth := 0.0
+	if data.Rounded() {
+		workedWidth = 25
+	}
+	rateWidth := 0.0
+	if data.MultipleRates() {
+		rateWidth = 30
+	}
 
 	// Table rows
-	pdf.SetFont("Arial", "", 10)
-	pageWidth, pageHeight := pdf.GetPageSize()
-	marginLeft, _, marginRight, marginBottom := pdf.GetMargins()
-	descWidth := pageWidth - marginLeft - marginRight - 30 - 25 // remaining width for description
+	descWidth := pageWidth - marginLeft - marginRight - 30 - workedWidth - 25 - rateWidth // remaining width for description
 
 	// Helper function to draw table header
 	drawTableHeader := func() {
-		pdf.SetFillColor(240, 240, 240)
-		pdf.SetFont("Arial", "B", 10)
+		pdf.SetFillColor(fill[0], fill[1], fill[2])
+		pdf.SetFont(pdfFont, "B", 10)
 		pdf.CellFormat(30, 8, "DATE", "1", 0, "L", true, 0, "")
+		if workedWidth > 0 {
+			pdf.CellFormat(workedWidth, 8, "WORKED", "1", 0, "R", true, 0, "")
+		}
 		pdf.CellFormat(25, 8, "HOURS", "1", 0, "R", true, 0, "")
+		if rateWidth > 0 {
+			pdf.CellFormat(rateWidth, 8, "RATE", "1", 0, "R", true, 0, "")
+		}
 		pdf.CellFormat(0, 8, "DESCRIPTION", "1", 1, "L", true, 0, "")
-		pdf.SetFont("Arial", "", 10)
+		pdf.SetFont(pdfFont, "", 10)
 	}
+	drawTableHeader()
 
 	// Helper function to draw a single table row
-	drawRow := func(dateStr string, hours float64, note string) {
-		note = sanitizePDFText(note)
+	drawRow := func(dateStr string, worked, hours float64, rate int64, note string) {
 		// Calculate height needed for the note text
 		lines := pdf.SplitText(note, descWidth)
 		lineHeight := 5.0
@@ -166,11 +264,18 @@ func GeneratePDF(filename string, data *InvoiceData) error {
 		// Draw date cell
 		pdf.CellFormat(30, cellHeight, dateStr, "1", 0, "L", false, 0, "")
 
-		// Draw hours cell
+		// Draw hours cells
+		if workedWidth > 0 {
+			pdf.CellFormat(workedWidth, cellHeight, fmt.Sprintf("%.2f", worked), "1", 0, "R", false, 0, "")
+		}
 		pdf.CellFormat(25, cellHeight, fmt.Sprintf("%.2f", hours), "1", 0, "R", false, 0, "")
 
+		if rateWidth > 0 {
+			pdf.CellFormat(rateWidth, cellHeight, data.FormatMoney(rate), "1", 0, "R", false, 0, "")
+		}
+
 		// Draw description cell with MultiCell for wrapping
-		descX := x + 30 + 25
+		descX := x + 30 + workedWidth + 25 + rateWidth
 		pdf.SetXY(descX, y)
 		// Draw border manually since MultiCell doesn't handle it well in this context
 		pdf.Rect(descX, y, descWidth, cellHeight, "D")
@@ -181,63 +286,138 @@ func GeneratePDF(filename string, data *InvoiceData) error {
 		pdf.SetXY(x, y+cellHeight)
 	}
 
-	if data.Condensed {
-		// Single line item with total hours and custom description
-		period := fmt.Sprintf("%s - %s", data.From.Format("Jan 2"), data.To.Format("Jan 2"))
-		desc := data.CondensedDescription
-		if desc == "" {
-			desc = "Consulting services"
-		}
-		drawRow(period, data.TotalHours(), desc)
-	} else {
-		for _, e := range data.Entries {
-			note := e.Note
-			if note == "" {
-				note = "-"
+	// On a client invoice each project's rows follow a row naming it
+	project := ""
+	for _, line := range data.LineItems() {
+		if line.Project != project {
+			project = line.Project
+			if _, y := pdf.GetXY(); y+7 > pageHeight-marginBottom {
+				pdf.AddPage()
+				drawTableHeader()
 			}
-			drawRow(e.StartTime().Format("Jan 2"), e.Duration().Hours(), note)
+			pdf.SetFont(pdfFont, "B", 10)
+			pdf.CellFormat(0, 7, project, "1", 1, "L", false, 0, "")
+			pdf.SetFont(pdfFont, "", 10)
+		}
+		desc := line.Description
+		for _, c := range line.Commits {
+			desc += "\n" + c.String()
 		}
+		drawRow(line.Date, line.RawHours, line.Hours, line.Rate, desc)
 	}
 
 	// Total hours
-	pdf.SetFont("Arial", "B", 10)
+	pdf.SetFont(pdfFont, "B", 10)
 	pdf.CellFormat(30, 8, "TOTAL", "1", 0, "L", true, 0, "")
+	if workedWidth > 0 {
+		pdf.CellFormat(workedWidth, 8, fmt.Sprintf("%.2f", data.RawHours()), "1", 0, "R", true, 0, "")
+	}
 	pdf.CellFormat(25, 8, fmt.Sprintf("%.2f", data.TotalHours()), "1", 0, "R", true, 0, "")
 	pdf.CellFormat(0, 8, "", "1", 1, "L", true, 0, "")
 
+	// Hours by category
+	if data.showCategories() {
+		pdf.Ln(6)
+		pdf.SetFillColor(fill[0], fill[1], fill[2])
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(80, 8, "CATEGORY", "1", 0, "L", true, 0, "")
+		pdf.CellFormat(25, 8, "HOURS", "1", 1, "R", true, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		for _, c := range data.CategoryHours() {
+			pdf.CellFormat(80, 7, c.Category, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(25, 7, fmt.Sprintf("%.2f", c.Hours), "1", 1, "R", false, 0, "")
+		}
+	}
+
+	// Expenses
+	if len(data.Expenses) > 0 {
+		pdf.Ln(6)
+		amountWidth := 35.0
+		expenseDescWidth := pageWidth - marginLeft - marginRight - 30 - 25 - amountWidth
+
+		pdf.SetFillColor(fill[0], fill[1], fill[2])
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(30, 8, "DATE", "1", 0, "L", true, 0, "")
+		pdf.CellFormat(25, 8, "QTY", "1", 0, "R", true, 0, "")
+		pdf.CellFormat(expenseDescWidth, 8, "EXPENSE", "1", 0, "L", true, 0, "")
+		pdf.CellFormat(amountWidth, 8, "AMOUNT", "1", 1, "R", true, 0, "")
+
+		pdf.SetFont(pdfFont, "", 10)
+		for _, e := range data.Expenses {
+			pdf.CellFormat(30, 7, e.Date.Format("Jan 2"), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(25, 7, formatQuantity(e.Quantity), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(expenseDescWidth, 7, e.Description, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(amountWidth, 7, data.FormatMoney(e.Total()), "1", 1, "R", false, 0, "")
+		}
+	}
+
 	pdf.Ln(10)
 
+	// Subtotal, discount and tax
+	summary := data.Summary()
+	if len(summary) > 0 {
+		pdf.SetFont(pdfFont, "", 11)
+		for _, line := range summary {
+			pdf.Cell(140, 7, line.Label+":")
+			pdf.Cell(0, 7, data.FormatMoney(line.Amount))
+			pdf.Ln(7)
+		}
+		pdf.Ln(3)
+	}
+
 	// Total due
-	pdf.SetFont("Arial", "B", 14)
+	pdf.SetFont(pdfFont, "B", 14)
 	pdf.Cell(140, 10, "TOTAL DUE:")
-	pdf.Cell(0, 10, fmt.Sprintf("$%.2f", data.TotalAmount()))
+	pdf.Cell(0, 10, data.FormatMoney(data.TotalAmount()))
+
+	// Payment instructions
+	if data.hasPayment() {
+		pdf.Ln(16)
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.Cell(0, 6, "PAYMENT")
+		pdf.Ln(7)
+		pdf.SetFont(pdfFont, "", 10)
+		for _, line := range data.paymentInstructions() {
+			pdf.Cell(0, 5, line)
+			pdf.Ln(5)
+		}
+		if link := data.Payment.Link; link != "" {
+			pdf.SetTextColor(0, 0, 238)
+			pdf.CellFormat(0, 5, "Pay online: "+link, "", 1, "L", false, 0, link)
+			pdf.SetTextColor(0, 0, 0)
+		}
+	}
 
 	return pdf.OutputFileAndClose(filename)
 }
 
 func writeContactPDF(pdf *gofpdf.Fpdf, c *model.ContactInfo) {
 	if c.Name != "" {
-		pdf.Cell(90, 5, sanitizePDFText(c.Name))
+		pdf.Cell(90, 5, c.Name)
 		pdf.Ln(5)
 	}
 	if c.Title != "" {
-		pdf.Cell(90, 5, sanitizePDFText(c.Title))
+		pdf.Cell(90, 5, c.Title)
 		pdf.Ln(5)
 	}
 	if c.Company != "" {
-		pdf.Cell(90, 5, sanitizePDFText(c.Company))
+		pdf.Cell(90, 5, c.Company)
 		pdf.Ln(5)
 	}
 	if c.Address != "" {
-		pdf.Cell(90, 5, sanitizePDFText(c.Address))
+		pdf.Cell(90, 5, c.Address)
 		pdf.Ln(5)
 	}
 	if c.Phone != "" {
-		pdf.Cell(90, 5, sanitizePDFText(c.Phone))
+		pdf.Cell(90, 5, c.Phone)
 		pdf.Ln(5)
 	}
 	if c.Email != "" {
-		pdf.Cell(90, 5, sanitizePDFText(c.Email))
+		pdf.Cell(90, 5, c.Email)
+		pdf.Ln(5)
+	}
+	if c.TaxID != "" {
+		pdf.Cell(90, 5, "Tax ID: "+c.TaxID)
 		pdf.Ln(5)
 	}
 }
@@ -246,32 +426,37 @@ func writeContactPDFAt(pdf *gofpdf.Fpdf, c *model.ContactInfo, x float64) {
 	y := pdf.GetY()
 	if c.Name != "" {
 		pdf.SetXY(x, y)
-		pdf.Cell(90, 5, sanitizePDFText(c.Name))
+		pdf.Cell(90, 5, c.Name)
 		y += 5
 	}
 	if c.Title != "" {
 		pdf.SetXY(x, y)
-		pdf.Cell(90, 5, sanitizePDFText(c.Title))
+		pdf.Cell(90, 5, c.Title)
 		y += 5
 	}
 	if c.Company != "" {
 		pdf.SetXY(x, y)
-		pdf.Cell(90, 5, sanitizePDFText(c.Company))
+		pdf.Cell(90, 5, c.Company)
 		y += 5
 	}
 	if c.Address != "" {
 		pdf.SetXY(x, y)
-		pdf.Cell(90, 5, sanitizePDFText(c.Address))
+		pdf.Cell(90, 5, c.Address)
 		y += 5
 	}
 	if c.Phone != "" {
 		pdf.SetXY(x, y)
-		pdf.Cell(90, 5, sanitizePDFText(c.Phone))
+		pdf.Cell(90, 5, c.Phone)
 		y += 5
 	}
 	if c.Email != "" {
 		pdf.SetXY(x, y)
-		pdf.Cell(90, 5, sanitizePDFText(c.Email))
+		pdf.Cell(90, 5, c.Email)
+		y += 5
+	}
+	if c.TaxID != "" {
+		pdf.SetXY(x, y)
+		pdf.Cell(90, 5, "Tax ID: "+c.TaxID)
 		y += 5
 	}
 	pdf.SetY(y)
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"time"
 
+	"watchmen/internal/gitlog"
 	"watchmen/internal/model"
 )
 
@@ -17,6 +18,8 @@ type ReportData struct {
 	TotalHours  float64
 	InvoiceRef  string // Optional invoice reference
 	Entries     []model.Entry
+	Commits     map[string][]gitlog.Commit // by entry ID, when commits are asked for
+	Location    *time.Location             // days are counted in, nil for each entry's own
 }
 
 // GenerateReport generates a markdown stakeholder report
@@ -34,7 +37,11 @@ func GenerateReport(w io.Writer, data *ReportData) error {
 	// Group entries by date
 	entriesByDate := make(map[string][]model.Entry)
 	for _, e := range data.Entries {
-		dateKey := e.StartTime().Format("2006-01-02")
+		start := e.StartTime()
+		if data.Location != nil {
+			start = start.In(data.Location)
+		}
+		dateKey := start.Format("2006-01-02")
 		entriesByDate[dateKey] = append(entriesByDate[dateKey], e)
 	}
 
@@ -49,10 +56,18 @@ func GenerateReport(w io.Writer, data *ReportData) error {
 		entries := entriesByDate[dateKey]
 		date, _ := time.Parse("2006-01-02", dateKey)
 		fmt.Fprintf(w, "\n### %s\n", date.Format("January 2, 2006"))
+		var commits []gitlog.Commit
 		for _, e := range entries {
 			if e.Note != "" {
 				fmt.Fprintf(w, "- %s\n", e.Note)
 			}
+			commits = append(commits, data.Commits[e.ID]...)
+		}
+		if len(commits) > 0 {
+			fmt.Fprintf(w, "\n**Commits:**\n")
+			for _, c := range commits {
+				fmt.Fprintf(w, "- `%s` %s (%s)\n", c.Short(), c.Subject, c.Repo)
+			}
 		}
 	}
 
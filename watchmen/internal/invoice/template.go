@@ -0,0 +1,271 @@
+package invoice
+
+import (
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"watchmen/internal/model"
+)
+
+// BuiltinHTML names the bundled HTML template
+const BuiltinHTML = "html"
+
+//go:embed templates/invoice.html
+var builtinHTMLSource string
+
+// BuiltinHTMLSource returns the bundled HTML template, as a starting point
+// for a custom one
+func BuiltinHTMLSource() string {
+	return builtinHTMLSource
+}
+
+// View is the data an invoice template is executed with. Dates and amounts
+// are formatted ready for display; Data gives access to everything else.
+type View struct {
+	Number        string
+	PurchaseOrder string
+	Date          string // e.g. January 2, 2006
+	DueDate       string // empty if there are no terms
+	Terms         int    // net days, zero if none
+	Project       string
+	Description   string // project description
+	PeriodStart   string
+	PeriodEnd     string
+	From          *model.ContactInfo // nil if not configured
+	BillTo        *model.ContactInfo // nil if not configured
+	Currency      string             // ISO 4217 code
+	Rate          string             // e.g. $150.00/hour, listing every rate if several
+	Included      string             // retainer hours covered, empty on other invoices
+	Rounding      string             // empty if billed time is not rounded
+	Condensed     bool
+	ShowWorked    bool // lines carry hours worked as well as billed
+	ShowRates     bool // lines carry their own rate
+	Lines         []ViewLine
+	Categories    []ViewCategory // empty unless requested
+	Expenses      []ViewExpense
+	Summary       []ViewTotal // rows leading up to the total, may be empty
+	WorkedHours   string
+	TotalHours    string
+	Total         string
+	Payment       []string // payment instructions line by line
+	PaymentLink   string   // URL of the online payment page
+	Data          *InvoiceData
+}
+
+// ViewLine is one row of the time table
+type ViewLine struct {
+	Project     string // on a client invoice, the project the time was for
+	NewProject  bool   // the first row of its project on a client invoice
+	Date        string
+	Worked      string // hours before rounding
+	Hours       string // hours billed
+	Rate        string
+	Description string
+	Commits     []string // made during the entry, on a detailed invoice
+}
+
+// ViewCategory is the time billed to one category
+type ViewCategory struct {
+	Name  string
+	Hours string
+}
+
+// ViewExpense is one billable expense
+type ViewExpense struct {
+	Date        string
+	Quantity    string // empty for a single item
+	Description string
+	Amount      string
+}
+
+// ViewTotal is one labelled amount in the totals
+type ViewTotal struct {
+	Label  string
+	Amount string
+}
+
+// NewView builds the template view of an invoice
+func NewView(d *InvoiceData) *View {
+	v := &View{
+		Number:        d.InvoiceNumber,
+		PurchaseOrder: d.PurchaseOrder,
+		Date:          d.Date.Format("January 2, 2006"),
+		Terms:         d.Terms,
+		Project:       d.Project.Name,
+		Description:   d.Project.Description,
+		PeriodStart:   d.From.Format("Jan 2, 2006"),
+		PeriodEnd:     d.To.Format("Jan 2, 2006"),
+		Currency:      d.Currency(),
+		Rate:          d.RateText(),
+		Included:      d.Included(),
+		Condensed:     d.Condensed,
+		ShowWorked:    d.Rounded(),
+		ShowRates:     d.MultipleRates(),
+		WorkedHours:   fmt.Sprintf("%.2f", d.RawHours()),
+		TotalHours:    fmt.Sprintf("%.2f", d.TotalHours()),
+		Total:         d.FormatMoney(d.TotalAmount()),
+		Data:          d,
+	}
+	if !d.DueDate.IsZero() {
+		v.DueDate = d.DueDate.Format("January 2, 2006")
+	}
+	if d.FromContact != nil && hasContactInfo(d.FromContact) {
+		v.From = d.FromContact
+	}
+	if d.BillToContact != nil && hasContactInfo(d.BillToContact) {
+		v.BillTo = d.BillToContact
+	}
+	if d.hasPayment() {
+		v.Payment = d.paymentInstructions()
+		v.PaymentLink = d.Payment.Link
+	}
+	if d.Rounded() {
+		v.Rounding = d.RoundingText()
+	}
+	project := ""
+	for _, line := range d.LineItems() {
+		var commits []string
+		for _, c := range line.Commits {
+			commits = append(commits, c.String())
+		}
+		v.Lines = append(v.Lines, ViewLine{
+			Project:     line.Project,
+			NewProject:  line.Project != project,
+			Date:        line.Date,
+			Worked:      fmt.Sprintf("%.2f", line.RawHours),
+			Hours:       fmt.Sprintf("%.2f", line.Hours),
+			Rate:        d.FormatMoney(line.Rate),
+			Description: line.Description,
+			Commits:     commits,
+		})
+		project = line.Project
+	}
+	if d.showCategories() {
+		for _, c := range d.CategoryHours() {
+			v.Categories = append(v.Categories, ViewCategory{Name: c.Category, Hours: fmt.Sprintf("%.2f", c.Hours)})
+		}
+	}
+	for _, e := range d.Expenses {
+		v.Expenses = append(v.Expenses, ViewExpense{
+			Date:        e.Date.Format("Jan 2"),
+			Quantity:    formatQuantity(e.Quantity),
+			Description: e.Description,
+			Amount:      d.FormatMoney(e.Total()),
+		})
+	}
+	for _, line := range d.Summary() {
+		v.Summary = append(v.Summary, ViewTotal{Label: line.Label, Amount: d.FormatMoney(line.Amount)})
+	}
+	return v
+}
+
+// Template is a parsed invoice template. Templates ending in .html or .htm
+// are HTML, with their output escaped; any other file is plain text.
+type Template struct {
+	Name string
+	HTML bool
+	exec func(io.Writer, any) error
+}
+
+// Ext returns the file extension for the template's output
+func (t *Template) Ext() string {
+	if t.HTML {
+		return ".html"
+	}
+	if ext := filepath.Ext(t.Name); ext != "" {
+		return ext
+	}
+	return ".txt"
+}
+
+// Execute renders the invoice with the template
+func (t *Template) Execute(w io.Writer, data *InvoiceData) error {
+	if err := t.exec(w, NewView(data)); err != nil {
+		return fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	return nil
+}
+
+// LoadTemplate finds the named template in dir, by file name with or
+// without its extension, or by path. BuiltinHTML is the bundled template
+// unless dir has one of the same name.
+func LoadTemplate(dir, name string) (*Template, error) {
+	path, err := findTemplate(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return parseTemplate(BuiltinHTML+".html", builtinHTMLSource)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplate(filepath.Base(path), string(src))
+}
+
+// findTemplate returns the file for a template name, or "" for the
+// bundled template
+func findTemplate(dir, name string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) {
+		return name, nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+		return filepath.Join(dir, name), nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, name+".*"))
+	if len(matches) > 1 {
+		return "", fmt.Errorf("template %q is ambiguous, use the file name", name)
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if name == BuiltinHTML {
+		return "", nil
+	}
+	return "", fmt.Errorf("template %q not found in %s", name, dir)
+}
+
+func parseTemplate(name, src string) (*Template, error) {
+	t := &Template{Name: name}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".html", ".htm":
+		t.HTML = true
+		tmpl, err := htmltemplate.New(name).Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		t.exec = tmpl.Execute
+	default:
+		tmpl, err := texttemplate.New(name).Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		t.exec = tmpl.Execute
+	}
+	return t, nil
+}
+
+// ListTemplates returns the file names of the templates in dir
+func ListTemplates(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && !strings.HasPrefix(f.Name(), ".") {
+			names = append(names, f.Name())
+		}
+	}
+	return names, nil
+}
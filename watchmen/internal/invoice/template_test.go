@@ -0,0 +1,95 @@
+package invoice
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func templateTestData() *InvoiceData {
+	start := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	end := start.Add(3 * time.Hour)
+	return &InvoiceData{
+		InvoiceNumber: "INV-007",
+		Date:          start,
+		Project:       model.Project{ID: "p", Name: "Test Project", HourlyRate: 10000},
+		Entries: []model.Entry{{
+			ID:        "e1",
+			ProjectID: "p",
+			Note:      "Fixed <script> handling",
+			Segments:  []model.TimeSegment{{Start: start, End: &end}},
+			Completed: true,
+		}},
+		From:          start,
+		To:            end,
+		BillToContact: &model.ContactInfo{Name: "Jane & Co"},
+	}
+}
+
+func TestBuiltinHTMLTemplate(t *testing.T) {
+	tmpl, err := LoadTemplate(t.TempDir(), BuiltinHTML)
+	if err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+	if !tmpl.HTML || tmpl.Ext() != ".html" {
+		t.Errorf("Bundled template should be HTML, got %+v", tmpl)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateTestData()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	output := buf.String()
+	checks := []string{
+		"<title>Invoice INV-007</title>",
+		"Fixed &lt;script&gt; handling",
+		"Jane &amp; Co",
+		"<td class=\"num\">3.00</td>",
+		"$300.00",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("HTML output missing %q", check)
+		}
+	}
+	if strings.Contains(output, "<h2>From</h2>") {
+		t.Error("HTML output has a From section with no contact info")
+	}
+}
+
+func TestCustomTemplate(t *testing.T) {
+	dir := t.TempDir()
+	src := "{{.Number}} for {{.Project}}: {{range .Lines}}{{.Hours}}h {{.Description}}; {{end}}total {{.Total}}\n"
+	if err := os.WriteFile(filepath.Join(dir, "plain.txt"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := LoadTemplate(dir, "plain")
+	if err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+	if tmpl.HTML || tmpl.Ext() != ".txt" {
+		t.Errorf("Text template loaded as %+v", tmpl)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateTestData()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "INV-007 for Test Project: 3.00h Fixed <script> handling; total $300.00\n"
+	if buf.String() != want {
+		t.Errorf("Execute() = %q, want %q", buf.String(), want)
+	}
+
+	if _, err := LoadTemplate(dir, "missing"); err == nil {
+		t.Error("LoadTemplate() should fail for a missing template")
+	}
+	names, _ := ListTemplates(dir)
+	if len(names) != 1 || names[0] != "plain.txt" {
+		t.Errorf("ListTemplates() = %v", names)
+	}
+}
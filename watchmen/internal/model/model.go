@@ -1,6 +1,15 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"watchmen/internal/money"
+)
 
 // ContactInfo holds contact details for invoicing
 type ContactInfo struct {
@@ -10,17 +19,91 @@ type ContactInfo struct {
 	Address string `json:"address,omitempty"`
 	Phone   string `json:"phone,omitempty"`
 	Email   string `json:"email,omitempty"`
+	TaxID   string `json:"tax_id,omitempty"` // VAT/GST registration number
+}
+
+// Client is a customer owning one or more projects. Its contact details
+// head their invoices, and its terms and currency are the defaults for them.
+type Client struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Contact      *ContactInfo `json:"contact,omitempty"`
+	PaymentTerms int          `json:"payment_terms,omitempty"` // net days, unless the project sets its own
+	Currency     string       `json:"currency,omitempty"`      // ISO 4217 code for new projects, empty means USD
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// CurrencyCode returns the client's currency, defaulting to USD
+func (c *Client) CurrencyCode() string {
+	if c.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return c.Currency
 }
 
 // Project represents a client project
 type Project struct {
-	ID             string       `json:"id"`
-	Name           string       `json:"name"`
-	HourlyRate     float64      `json:"hourly_rate"`
-	Description    string       `json:"description,omitempty"`
-	BillingContact *ContactInfo `json:"billing_contact,omitempty"`
-	PurchaseOrder  string       `json:"purchase_order,omitempty"`
-	CreatedAt      time.Time    `json:"created_at"`
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	ClientID      string       `json:"client_id,omitempty"`
+	HourlyRate    int64        `json:"hourly_rate"`        // minor units of Currency, before any dated rate change
+	Rates         []RateChange `json:"rates,omitempty"`    // sorted by From
+	Currency      string       `json:"currency,omitempty"` // ISO 4217 code, empty means USD
+	Description   string       `json:"description,omitempty"`
+	PurchaseOrder string       `json:"purchase_order,omitempty"`
+	Tax           *Tax         `json:"tax,omitempty"`           // applied to new invoices
+	Rounding      *Rounding    `json:"rounding,omitempty"`      // applied to billed hours
+	PaymentTerms  int          `json:"payment_terms,omitempty"` // net days for new invoices, zero for the client's
+	Template      string       `json:"template,omitempty"`      // default invoice template name
+	Budget        *Budget      `json:"budget,omitempty"`
+	Billing       *Billing     `json:"billing,omitempty"`  // nil bills by the hour
+	Repos         []string     `json:"repos,omitempty"`    // local git repositories, for commits in reports
+	Timezone      string       `json:"timezone,omitempty"` // IANA name days are counted in, empty for the home timezone
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// CurrencyCode returns the project's currency, defaulting to USD
+func (p *Project) CurrencyCode() string {
+	if p.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return p.Currency
+}
+
+// RateChange sets a project's hourly rate from a point in time onward
+type RateChange struct {
+	From time.Time `json:"from"`
+	Rate int64     `json:"rate"` // minor units of the project currency
+}
+
+// RateAt returns the hourly rate in effect at t
+func (p *Project) RateAt(t time.Time) int64 {
+	rate := p.HourlyRate
+	for _, rc := range p.Rates {
+		if rc.From.After(t) {
+			break
+		}
+		rate = rc.Rate
+	}
+	return rate
+}
+
+// CurrentRate returns the hourly rate in effect now
+func (p *Project) CurrentRate() int64 {
+	return p.RateAt(time.Now())
+}
+
+// SetRate makes rate effective from the given time, replacing a change
+// already recorded for that exact time and keeping Rates sorted
+func (p *Project) SetRate(rate int64, from time.Time) {
+	i := sort.Search(len(p.Rates), func(i int) bool {
+		return !p.Rates[i].From.Before(from)
+	})
+	if i < len(p.Rates) && p.Rates[i].From.Equal(from) {
+		p.Rates[i].Rate = rate
+		return
+	}
+	p.Rates = slices.Insert(p.Rates, i, RateChange{From: from, Rate: rate})
 }
 
 // TimeSegment represents a continuous period of work
@@ -37,13 +120,38 @@ func (s *TimeSegment) Duration() time.Duration {
 	return s.End.Sub(s.Start)
 }
 
+// Between returns how much of the segment falls between from and to,
+// counting an open segment as running until now
+func (s *TimeSegment) Between(from, to, now time.Time) time.Duration {
+	end := now
+	if s.End != nil {
+		end = *s.End
+	}
+	start := s.Start
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
 // Entry represents a time entry with one or more segments
 type Entry struct {
-	ID        string        `json:"id"`
-	ProjectID string        `json:"project_id"`
-	Note      string        `json:"note,omitempty"`
-	Segments  []TimeSegment `json:"segments"`
-	Completed bool          `json:"completed,omitempty"`
+	ID          string        `json:"id"`
+	ProjectID   string        `json:"project_id"`
+	Note        string        `json:"note,omitempty"`
+	Segments    []TimeSegment `json:"segments"`
+	Completed   bool          `json:"completed,omitempty"`
+	Rate        *int64        `json:"rate,omitempty"` // overrides the project rate, minor units
+	Category    string        `json:"category,omitempty"`
+	NonBillable bool          `json:"non_billable,omitempty"` // internal or pro-bono time, left off invoices
+	InvoiceID   string        `json:"invoice_id,omitempty"`   // invoice that billed the entry
+	ExternalID  string        `json:"external_id,omitempty"`  // source of an imported entry, e.g. ics:<uid>
 }
 
 // Duration returns the total duration across all segments
@@ -71,6 +179,16 @@ func (e *Entry) IsPaused() bool {
 	return e.Segments[len(e.Segments)-1].End != nil
 }
 
+// IsBillable returns true if the entry's time should be invoiced
+func (e *Entry) IsBillable() bool {
+	return !e.NonBillable
+}
+
+// IsBilled returns true if the entry is on an invoice
+func (e *Entry) IsBilled() bool {
+	return e.InvoiceID != ""
+}
+
 // StartTime returns the start time of the first segment
 func (e *Entry) StartTime() time.Time {
 	if len(e.Segments) == 0 {
@@ -79,9 +197,507 @@ func (e *Entry) StartTime() time.Time {
 	return e.Segments[0].Start
 }
 
+// OpenSegment returns the segment a running entry is accruing time on, or
+// nil if it is not running
+func (e *Entry) OpenSegment() *TimeSegment {
+	if !e.IsRunning() {
+		return nil
+	}
+	return &e.Segments[len(e.Segments)-1]
+}
+
+// TimerWarnings returns why a running entry looks like a forgotten timer at
+// now: its open segment is older than warnAfter, or started on an earlier
+// day in loc. A warnAfter of zero only checks for midnight.
+func (e *Entry) TimerWarnings(now time.Time, warnAfter time.Duration, loc *time.Location) []string {
+	seg := e.OpenSegment()
+	if seg == nil {
+		return nil
+	}
+	var warnings []string
+	if elapsed := now.Sub(seg.Start); warnAfter > 0 && elapsed > warnAfter {
+		warnings = append(warnings, fmt.Sprintf("timer has been running for %.1f hours", elapsed.Hours()))
+	}
+	start := seg.Start.In(loc)
+	if sy, sm, sd := start.Date(); !now.Before(time.Date(sy, sm, sd+1, 0, 0, 0, 0, loc)) {
+		warnings = append(warnings, fmt.Sprintf("timer has been running since %s", start.Format("Mon Jan 2 3:04 PM")))
+	}
+	return warnings
+}
+
+// Tax is a percentage-based sales tax such as VAT or GST
+type Tax struct {
+	Name string  `json:"name"` // e.g. VAT, GST
+	Rate float64 `json:"rate"` // percent, e.g. 20 for 20%
+}
+
+// Label returns the tax name with its rate, e.g. "VAT 20%"
+func (t *Tax) Label() string {
+	name := t.Name
+	if name == "" {
+		name = "Tax"
+	}
+	return fmt.Sprintf("%s %s%%", name, strconv.FormatFloat(t.Rate, 'f', -1, 64))
+}
+
+// Amount returns the tax due on a taxable amount in minor units
+func (t *Tax) Amount(taxable int64) int64 {
+	return money.Multiply(taxable, t.Rate/100)
+}
+
+// Rounding modes for billed time
+const (
+	RoundNearest = "nearest"
+	RoundUp      = "up"
+	RoundDown    = "down"
+)
+
+// Rounding is how a project's time is rounded for billing. Each entry is
+// rounded to a multiple of Increment, then every day with any time on it is
+// billed at least DailyMinimum.
+type Rounding struct {
+	Increment    int    `json:"increment_minutes,omitempty"`
+	Mode         string `json:"mode,omitempty"` // nearest, up or down; empty means nearest
+	DailyMinimum int    `json:"daily_minimum_minutes,omitempty"`
+}
+
+// String describes the policy, e.g. "up to 15 min, 2h daily minimum"
+func (r *Rounding) String() string {
+	var parts []string
+	if r.Increment > 0 {
+		switch r.Mode {
+		case RoundUp, RoundDown:
+			parts = append(parts, fmt.Sprintf("%s to %d min", r.Mode, r.Increment))
+		default:
+			parts = append(parts, fmt.Sprintf("nearest %d min", r.Increment))
+		}
+	}
+	if r.DailyMinimum > 0 {
+		minimum := time.Duration(r.DailyMinimum) * time.Minute
+		parts = append(parts, strings.TrimSuffix(strings.TrimSuffix(minimum.String(), "0s"), "0m")+" daily minimum")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Round rounds a single entry's duration to the increment. A nil policy
+// leaves it unchanged.
+func (r *Rounding) Round(d time.Duration) time.Duration {
+	if r == nil || r.Increment <= 0 {
+		return d
+	}
+	inc := time.Duration(r.Increment) * time.Minute
+	switch r.Mode {
+	case RoundUp:
+		if rem := d % inc; rem != 0 {
+			return d - rem + inc
+		}
+		return d
+	case RoundDown:
+		return d - d%inc
+	default:
+		return d.Round(inc)
+	}
+}
+
+// Billed returns the time billed for each entry, in the same order. Each
+// entry is rounded, then any shortfall against the daily minimum is added
+// to the day's last entry. Days are counted in loc, or in each entry's own
+// timezone if nil.
+func (r *Rounding) Billed(entries []Entry, loc *time.Location) []time.Duration {
+	billed := make([]time.Duration, len(entries))
+	for i, e := range entries {
+		billed[i] = r.Round(e.Duration())
+	}
+	if r == nil || r.DailyMinimum <= 0 {
+		return billed
+	}
+
+	minimum := time.Duration(r.DailyMinimum) * time.Minute
+	totals := make(map[string]time.Duration)
+	last := make(map[string]int)
+	var days []string
+	for i, e := range entries {
+		start := e.StartTime()
+		if loc != nil {
+			start = start.In(loc)
+		}
+		day := start.Format("2006-01-02")
+		if _, ok := last[day]; !ok {
+			days = append(days, day)
+		}
+		if j, ok := last[day]; !ok || !e.StartTime().Before(entries[j].StartTime()) {
+			last[day] = i
+		}
+		totals[day] += billed[i]
+	}
+	for _, day := range days {
+		if total := totals[day]; total > 0 && total < minimum {
+			billed[last[day]] += minimum - total
+		}
+	}
+	return billed
+}
+
+// BudgetWarnAt is the share of a budget spent at which status and
+// timesheet start warning
+const BudgetWarnAt = 0.8
+
+// Budget caps the time or money put into a project, over its whole life
+// or each calendar month. Only billable time counts against it.
+type Budget struct {
+	Hours   float64 `json:"hours,omitempty"`
+	Amount  int64   `json:"amount,omitempty"`  // minor units of the project currency
+	Monthly bool    `json:"monthly,omitempty"` // starts afresh on the 1st of each month
+}
+
+// String describes the budget, e.g. "40h and $5,000.00 a month"
+func (b *Budget) String(currency string) string {
+	var parts []string
+	if b.Hours > 0 {
+		parts = append(parts, strconv.FormatFloat(b.Hours, 'f', -1, 64)+"h")
+	}
+	if b.Amount > 0 {
+		parts = append(parts, money.Format(b.Amount, currency))
+	}
+	s := strings.Join(parts, " and ")
+	if b.Monthly {
+		return s + " a month"
+	}
+	return s + " in total"
+}
+
+// Since returns when the budget period containing now began, in the
+// project's timezone loc, or the zero time for a total budget
+func (b *Budget) Since(now time.Time, loc *time.Location) time.Time {
+	if !b.Monthly {
+		return time.Time{}
+	}
+	now = now.In(loc)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+}
+
+// BudgetUse is how much of a project's budget has been spent
+type BudgetUse struct {
+	Hours    float64 `json:"hours"`
+	Amount   int64   `json:"amount"`   // minor units, at the rates the time is billed at
+	Fraction float64 `json:"fraction"` // of the hours or amount budget, whichever is further spent
+}
+
+// Use totals the billable entries against the budget. Entries are expected
+// to fall in the current period; time is rounded as on invoices, with days
+// counted in loc, and priced at each entry's rate.
+func (b *Budget) Use(p *Project, entries []Entry, loc *time.Location) BudgetUse {
+	var billable []Entry
+	for _, e := range entries {
+		if e.IsBillable() {
+			billable = append(billable, e)
+		}
+	}
+	var use BudgetUse
+	var total time.Duration
+	for i, d := range p.Rounding.Billed(billable, loc) {
+		total += d
+		rate := p.RateAt(billable[i].StartTime())
+		if billable[i].Rate != nil {
+			rate = *billable[i].Rate
+		}
+		use.Amount += money.Multiply(rate, d.Hours())
+	}
+	use.Hours = total.Hours()
+	if b.Hours > 0 {
+		use.Fraction = use.Hours / b.Hours
+	}
+	if b.Amount > 0 {
+		use.Fraction = max(use.Fraction, float64(use.Amount)/float64(b.Amount))
+	}
+	return use
+}
+
+// Warning returns a warning once BudgetWarnAt of the budget is spent, or ""
+func (u BudgetUse) Warning() string {
+	switch {
+	case u.Fraction >= 1:
+		return fmt.Sprintf("budget used up (%.0f%% spent)", u.Fraction*100)
+	case u.Fraction >= BudgetWarnAt:
+		return fmt.Sprintf("%.0f%% of budget spent", u.Fraction*100)
+	}
+	return ""
+}
+
+// Billing models. A project without a Billing is hourly.
+const (
+	BillingHourly   = "hourly"
+	BillingRetainer = "retainer"
+	BillingFixed    = "fixed"
+)
+
+// Billing charges a project's time as a monthly retainer or as fixed-price
+// milestones rather than by the hour
+type Billing struct {
+	Model string `json:"model"` // BillingRetainer or BillingFixed
+
+	// A retainer's Fee covers Hours each month. Time beyond them is
+	// billed at OverageRate, and with Rollover unused hours carry over to
+	// the next invoice.
+	Fee         int64   `json:"fee,omitempty"` // minor units of the project currency
+	Hours       float64 `json:"hours,omitempty"`
+	OverageRate int64   `json:"overage_rate,omitempty"` // zero means the project's hourly rate
+	Rollover    bool    `json:"rollover,omitempty"`
+
+	Milestones []Milestone `json:"milestones,omitempty"` // fixed price, in order
+}
+
+// Milestone is a fixed-price stage of a project, billed once
+type Milestone struct {
+	Name   string `json:"name"`
+	Amount int64  `json:"amount"` // minor units of the project currency
+}
+
+// BillingModel returns the project's billing model, BillingHourly if none
+// is set
+func (p *Project) BillingModel() string {
+	if p.Billing == nil {
+		return BillingHourly
+	}
+	return p.Billing.Model
+}
+
+// String describes the billing, e.g. "retainer of $5,000.00 a month for
+// 20h, then $150.00/hour" or "fixed price, 3 milestones totalling
+// $12,000.00"
+func (b *Billing) String(currency string) string {
+	if b.Model == BillingFixed {
+		var total int64
+		for _, m := range b.Milestones {
+			total += m.Amount
+		}
+		noun := "milestones"
+		if len(b.Milestones) == 1 {
+			noun = "milestone"
+		}
+		return fmt.Sprintf("fixed price, %d %s totalling %s", len(b.Milestones), noun, money.Format(total, currency))
+	}
+	s := fmt.Sprintf("retainer of %s a month for %sh", money.Format(b.Fee, currency), strconv.FormatFloat(b.Hours, 'f', -1, 64))
+	if b.OverageRate > 0 {
+		s += ", then " + money.Format(b.OverageRate, currency) + "/hour"
+	}
+	if b.Rollover {
+		s += ", unused hours roll over"
+	}
+	return s
+}
+
+// Milestone returns the milestone with the given name, ignoring case, or
+// nil
+func (b *Billing) Milestone(name string) *Milestone {
+	for i := range b.Milestones {
+		if strings.EqualFold(b.Milestones[i].Name, name) {
+			return &b.Milestones[i]
+		}
+	}
+	return nil
+}
+
+// Retainer splits hours billed over a number of retainer months. The
+// allowance is the hours the fees cover plus any carried in; time beyond
+// it is overage, and with Rollover what is left of it is unused and
+// carries forward.
+func (b *Billing) Retainer(months int, carried, billed float64) (allowance, overage, unused float64) {
+	allowance = b.Hours*float64(months) + carried
+	overage = max(billed-allowance, 0)
+	if b.Rollover {
+		unused = max(allowance-billed, 0)
+	}
+	return allowance, overage, unused
+}
+
+// Discount reduces an invoice subtotal by a percentage or a flat amount
+type Discount struct {
+	Percent float64 `json:"percent,omitempty"` // e.g. 10 for 10%
+	Amount  int64   `json:"amount,omitempty"`  // flat, in minor units
+}
+
+// Apply returns the discount on subtotal, never more than subtotal itself
+func (d *Discount) Apply(subtotal int64) int64 {
+	amount := d.Amount
+	if d.Percent != 0 {
+		amount = money.Multiply(subtotal, d.Percent/100)
+	}
+	return min(amount, subtotal)
+}
+
+// Expense is a billable cost such as mileage or a software licence,
+// charged to a project in the project's currency
+type Expense struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Quantity    float64   `json:"quantity,omitempty"`   // e.g. miles; zero means 1
+	UnitAmount  int64     `json:"unit_amount"`          // minor units
+	InvoiceID   string    `json:"invoice_id,omitempty"` // invoice that billed the expense
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Total returns the expense amount in minor units
+func (e *Expense) Total() int64 {
+	if e.Quantity == 0 {
+		return e.UnitAmount
+	}
+	return money.Multiply(e.UnitAmount, e.Quantity)
+}
+
 // Settings holds user configuration
 type Settings struct {
-	UserContact *ContactInfo `json:"user_contact,omitempty"`
+	UserContact *ContactInfo   `json:"user_contact,omitempty"`
+	Numbering   *Numbering     `json:"numbering,omitempty"`
+	InvoiceSeq  map[string]int `json:"invoice_seq,omitempty"` // last number allocated, by Numbering.CounterKey
+	PDF         *PDFSettings   `json:"pdf,omitempty"`
+	Payment     *PaymentInfo   `json:"payment,omitempty"` // shown at the foot of invoices
+	ImportRules []ImportRule   `json:"import_rules,omitempty"`
+	Timer       *TimerSettings `json:"timer,omitempty"`
+	Goals       *Goals         `json:"goals,omitempty"`
+	Timezone    string         `json:"timezone,omitempty"` // IANA name of the home timezone, empty for the system's
+}
+
+// Goals are targets for billable hours, used to work out utilisation
+type Goals struct {
+	DailyHours  float64 `json:"daily_hours,omitempty"`  // per weekday, Monday to Friday
+	WeeklyHours float64 `json:"weekly_hours,omitempty"` // takes precedence over DailyHours for utilisation
+}
+
+// Target returns the billable hours the goals call for from the midnight
+// starting from for days days: the weekly goal spread over each day, or
+// else the daily goal for each weekday. It is zero with no goals.
+func (g *Goals) Target(from time.Time, days int) float64 {
+	if g == nil {
+		return 0
+	}
+	if g.WeeklyHours > 0 {
+		return g.WeeklyHours * float64(days) / 7
+	}
+	target := 0.0
+	for d := range days {
+		if wd := from.AddDate(0, 0, d).Weekday(); wd != time.Saturday && wd != time.Sunday {
+			target += g.DailyHours
+		}
+	}
+	return target
+}
+
+// DefaultWarnAfterHours is how long a segment may run before status and
+// start warn about it, unless configured
+const DefaultWarnAfterHours = 8
+
+// TimerSettings guard against timers left running
+type TimerSettings struct {
+	WarnAfterHours  float64 `json:"warn_after_hours,omitempty"`  // zero means DefaultWarnAfterHours, negative turns warnings off
+	MaxSegmentHours float64 `json:"max_segment_hours,omitempty"` // stop asks before saving a longer segment, zero for no limit
+	PausePerProject bool    `json:"pause_per_project,omitempty"` // allow a paused entry on each project, not just one
+}
+
+// PausePerProject reports whether each project may have a paused entry of
+// its own, rather than only one entry being paused or running at a time
+func (s *Settings) PausePerProject() bool {
+	return s != nil && s.Timer != nil && s.Timer.PausePerProject
+}
+
+// WarnAfter returns how long a segment may run before warnings, or zero if
+// they are off
+func (t *TimerSettings) WarnAfter() time.Duration {
+	hours := float64(DefaultWarnAfterHours)
+	if t != nil && t.WarnAfterHours != 0 {
+		hours = t.WarnAfterHours
+	}
+	if hours < 0 {
+		return 0
+	}
+	return time.Duration(hours * float64(time.Hour))
+}
+
+// MaxSegment returns the longest segment stop saves without asking, or zero
+// for no limit
+func (t *TimerSettings) MaxSegment() time.Duration {
+	if t == nil || t.MaxSegmentHours <= 0 {
+		return 0
+	}
+	return time.Duration(t.MaxSegmentHours * float64(time.Hour))
+}
+
+// ImportRule maps imported calendar events to a project. Every condition
+// set must match; the first matching rule wins.
+type ImportRule struct {
+	ProjectID   string `json:"project_id"`
+	Summary     string `json:"summary,omitempty"`  // regular expression, case-insensitive
+	Domain      string `json:"domain,omitempty"`   // email domain of any attendee or the organizer
+	Calendar    string `json:"calendar,omitempty"` // calendar name, case-insensitive
+	Category    string `json:"category,omitempty"` // given to imported entries
+	NonBillable bool   `json:"non_billable,omitempty"`
+}
+
+// Page sizes for PDF invoices
+const (
+	PageA4     = "A4"
+	PageLetter = "Letter"
+)
+
+// PDFSettings controls the look of PDF invoices
+type PDFSettings struct {
+	Font     string `json:"font,omitempty"`      // TTF file, the bundled DejaVu Sans if empty
+	BoldFont string `json:"bold_font,omitempty"` // TTF file, Font if empty
+	Logo     string `json:"logo,omitempty"`      // PNG, JPEG or GIF file
+	Color    string `json:"color,omitempty"`     // letterhead colour as #rrggbb
+	PageSize string `json:"page_size,omitempty"` // PageA4 or PageLetter, A4 if empty
+}
+
+// PaymentInfo tells clients how to pay
+type PaymentInfo struct {
+	Instructions string `json:"instructions,omitempty"` // e.g. bank details, may span lines
+	Link         string `json:"link,omitempty"`         // URL of an online payment page
+}
+
+// Numbering is the scheme for invoice numbers. Template may use {year},
+// {seq} and {project}; {seq} counts up across all invoices, or restarts
+// each year when Yearly is set.
+type Numbering struct {
+	Template string `json:"template"` // e.g. INV-{year}-{seq}
+	Yearly   bool   `json:"yearly,omitempty"`
+	Digits   int    `json:"digits,omitempty"` // zero-pad {seq} to this width
+}
+
+// CounterKey names the sequence an invoice issued at t draws from
+func (n *Numbering) CounterKey(t time.Time) string {
+	if n.Yearly {
+		return strconv.Itoa(t.Year())
+	}
+	return "all"
+}
+
+// Format fills in the template for the given sequence number
+func (n *Numbering) Format(seq int, t time.Time, project string) string {
+	return strings.NewReplacer(
+		"{year}", strconv.Itoa(t.Year()),
+		"{seq}", fmt.Sprintf("%0*d", n.Digits, seq),
+		"{project}", strings.Join(strings.Fields(project), "-"),
+	).Replace(n.Template)
+}
+
+// NextInvoiceNumber allocates the next number in the sequence, skipping
+// any that exists reports as taken
+func (s *Settings) NextInvoiceNumber(project string, t time.Time, exists func(string) bool) string {
+	if s.InvoiceSeq == nil {
+		s.InvoiceSeq = make(map[string]int)
+	}
+	key := s.Numbering.CounterKey(t)
+	for {
+		s.InvoiceSeq[key]++
+		if id := s.Numbering.Format(s.InvoiceSeq[key], t, project); !exists(id) {
+			return id
+		}
+	}
 }
 
 // InvoiceStatus represents the payment status of an invoice
@@ -90,30 +706,240 @@ type InvoiceStatus string
 const (
 	InvoiceStatusPending InvoiceStatus = "pending"
 	InvoiceStatusPaid    InvoiceStatus = "paid"
+	InvoiceStatusVoid    InvoiceStatus = "void"
+
+	// Computed by Invoice.State, never stored
+	InvoiceStatusPartial InvoiceStatus = "partial"
+	InvoiceStatusOverdue InvoiceStatus = "overdue"
 )
 
 // Invoice represents a generated invoice record
 type Invoice struct {
 	ID          string        `json:"id"`
-	ProjectID   string        `json:"project_id"`
-	ProjectName string        `json:"project_name"`
+	ProjectID   string        `json:"project_id"`          // empty on an invoice for a whole client
+	ProjectName string        `json:"project_name"`        // or the client's name
+	ClientID    string        `json:"client_id,omitempty"` // set on an invoice for a whole client
 	CreatedAt   time.Time     `json:"created_at"`
 	PeriodStart time.Time     `json:"period_start"`
 	PeriodEnd   time.Time     `json:"period_end"`
-	Hours       float64       `json:"hours"`
-	Rate        float64       `json:"rate"`
-	Amount      float64       `json:"amount"`
+	Hours       float64       `json:"hours"`               // billed, after rounding
+	RawHours    float64       `json:"raw_hours,omitempty"` // worked, when rounding changed it
+	Rate        int64         `json:"rate"`                // minor units of Currency, zero if RateLines or Charges is set
+	Amount      int64         `json:"amount"`              // total due, minor units of Currency; negative on a credit note
+	Currency    string        `json:"currency,omitempty"`  // ISO 4217 code, empty means USD
 	Status      InvoiceStatus `json:"status"`
 	PaidAt      *time.Time    `json:"paid_at,omitempty"`
 	Description string        `json:"description,omitempty"`
 	Condensed   bool          `json:"condensed,omitempty"`
+
+	// Payment terms and what has been received against Amount
+	Terms     int        `json:"terms,omitempty"` // net days, zero if none were set
+	DueDate   *time.Time `json:"due_date,omitempty"`
+	Payments  []Payment  `json:"payments,omitempty"`
+	VoidedAt  *time.Time `json:"voided_at,omitempty"`
+	CreditFor string     `json:"credit_for,omitempty"` // on a credit note, the invoice it credits
+
+	// Breakdown of Amount; zero on invoices saved before it was recorded
+	RateLines []RateLine `json:"rate_lines,omitempty"` // set when hours were billed at more than one rate
+	Labor     int64      `json:"labor,omitempty"`      // sum of hours × rate
+	Expenses  int64      `json:"expenses,omitempty"`   // sum of billed expenses
+	Discount  int64      `json:"discount,omitempty"`
+	TaxName   string     `json:"tax_name,omitempty"`
+	TaxRate   float64    `json:"tax_rate,omitempty"` // percent
+	Tax       int64      `json:"tax,omitempty"`
+
+	// Set on retainer and fixed-price invoices, whose Labor is the sum of
+	// Charges rather than hours × rate
+	BillingModel  string   `json:"billing_model,omitempty"` // empty means hourly
+	Charges       []Charge `json:"charges,omitempty"`
+	IncludedHours float64  `json:"included_hours,omitempty"` // retainer hours covered, with any rolled over
+	RolloverIn    float64  `json:"rollover_in,omitempty"`    // unused hours carried in from the last invoice
+	RolloverHours float64  `json:"rollover_hours,omitempty"` // unused hours carried to the next invoice
+	FeeMonths     []string `json:"fee_months,omitempty"`     // months the retainer fee was charged for, as YYYY-MM
+}
+
+// Charge is one line of a retainer or fixed-price invoice: the retainer
+// fee, overage hours or a milestone
+type Charge struct {
+	Description string  `json:"description"`
+	Hours       float64 `json:"hours,omitempty"` // overage only
+	Rate        int64   `json:"rate,omitempty"`  // overage only
+	Amount      int64   `json:"amount"`
+	Milestone   string  `json:"milestone,omitempty"` // name of the milestone billed
+}
+
+// Payment is money received against an invoice, or a credit note applied
+// to it
+type Payment struct {
+	Date      time.Time `json:"date"`
+	Amount    int64     `json:"amount"`              // minor units of the invoice currency
+	Method    string    `json:"method,omitempty"`    // e.g. bank transfer, card
+	Reference string    `json:"reference,omitempty"` // e.g. the credit note number
+}
+
+// IsCreditNote reports whether the record credits another invoice
+func (i *Invoice) IsCreditNote() bool {
+	return i.CreditFor != ""
+}
+
+// AmountPaid returns the total received. Invoices marked paid before
+// payments were recorded count as paid in full.
+func (i *Invoice) AmountPaid() int64 {
+	if len(i.Payments) == 0 && i.Status == InvoiceStatusPaid {
+		return i.Amount
+	}
+	var total int64
+	for _, p := range i.Payments {
+		total += p.Amount
+	}
+	return total
+}
+
+// Balance returns the amount still owed, zero once paid or voided
+func (i *Invoice) Balance() int64 {
+	if i.Status == InvoiceStatusVoid {
+		return 0
+	}
+	return i.Amount - i.AmountPaid()
+}
+
+// State returns the invoice status at now, distinguishing partly paid and
+// overdue invoices from pending ones
+func (i *Invoice) State(now time.Time) InvoiceStatus {
+	if i.Status != InvoiceStatusPending {
+		return i.Status
+	}
+	if i.DueDate != nil && now.After(*i.DueDate) {
+		return InvoiceStatusOverdue
+	}
+	if i.AmountPaid() > 0 {
+		return InvoiceStatusPartial
+	}
+	return InvoiceStatusPending
+}
+
+// AddPayment records a payment of up to the balance, marking the invoice
+// paid once nothing is owed
+func (i *Invoice) AddPayment(p Payment) error {
+	if i.Status != InvoiceStatusPending {
+		return fmt.Errorf("invoice %s is %s", i.ID, i.Status)
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("payment must be positive")
+	}
+	if balance := i.Balance(); p.Amount > balance {
+		return fmt.Errorf("payment of %s is more than the %s owed",
+			money.Format(p.Amount, i.CurrencyCode()), money.Format(balance, i.CurrencyCode()))
+	}
+	i.Payments = append(i.Payments, p)
+	if i.Balance() == 0 {
+		i.Status = InvoiceStatusPaid
+		paidAt := p.Date
+		i.PaidAt = &paidAt
+	}
+	return nil
+}
+
+// DaysOutstanding returns the whole days since the invoice was issued
+func (i *Invoice) DaysOutstanding(now time.Time) int {
+	return int(now.Sub(i.CreatedAt).Hours() / 24)
+}
+
+// RateLine is the time on an invoice billed at one hourly rate
+type RateLine struct {
+	Rate   int64   `json:"rate"`
+	Hours  float64 `json:"hours"`
+	Amount int64   `json:"amount"`
+}
+
+// BilledMilestones returns the names of the project milestones already on
+// an invoice, ignoring voided invoices and credit notes
+func BilledMilestones(invoices []Invoice, projectID string) map[string]bool {
+	billed := make(map[string]bool)
+	for _, inv := range invoices {
+		if inv.ProjectID != projectID || inv.Status == InvoiceStatusVoid || inv.IsCreditNote() {
+			continue
+		}
+		for _, c := range inv.Charges {
+			if c.Milestone != "" {
+				billed[strings.ToLower(c.Milestone)] = true
+			}
+		}
+	}
+	return billed
+}
+
+// BilledRetainerMonths returns the months, as YYYY-MM, the project's
+// retainer fee has already been charged for, ignoring voided invoices and
+// credit notes. Retainer invoices saved before FeeMonths was recorded
+// charged for every month their period touches.
+func BilledRetainerMonths(invoices []Invoice, projectID string) map[string]bool {
+	billed := make(map[string]bool)
+	for _, inv := range invoices {
+		if inv.ProjectID != projectID || inv.BillingModel != BillingRetainer || inv.Status == InvoiceStatusVoid || inv.IsCreditNote() {
+			continue
+		}
+		if inv.FeeMonths == nil {
+			last := time.Date(inv.PeriodEnd.Year(), inv.PeriodEnd.Month(), 1, 0, 0, 0, 0, time.UTC)
+			for m := time.Date(inv.PeriodStart.Year(), inv.PeriodStart.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(last); m = m.AddDate(0, 1, 0) {
+				billed[m.Format("2006-01")] = true
+			}
+		}
+		for _, m := range inv.FeeMonths {
+			billed[m] = true
+		}
+	}
+	return billed
+}
+
+// RolloverHours returns the unused retainer hours carried forward by the
+// project's most recently issued retainer invoice, ignoring voided
+// invoices and credit notes. Each invoice's unused hours include those it
+// carried in, so the latest issued holds the carry whatever periods the
+// invoices cover.
+func RolloverHours(invoices []Invoice, projectID string) float64 {
+	var latest *Invoice
+	for i := range invoices {
+		inv := &invoices[i]
+		if inv.ProjectID != projectID || inv.BillingModel != BillingRetainer || inv.Status == InvoiceStatusVoid || inv.IsCreditNote() {
+			continue
+		}
+		if latest == nil || inv.CreatedAt.After(latest.CreatedAt) ||
+			inv.CreatedAt.Equal(latest.CreatedAt) && inv.PeriodEnd.After(latest.PeriodEnd) {
+			latest = inv
+		}
+	}
+	if latest == nil {
+		return 0
+	}
+	return latest.RolloverHours
+}
+
+// HasBreakdown reports whether the invoice recorded its line totals
+func (i *Invoice) HasBreakdown() bool {
+	return i.Labor != 0 || i.Expenses != 0 || i.Discount != 0 || i.Tax != 0
+}
+
+// Subtotal returns labor plus expenses, before discount and tax
+func (i *Invoice) Subtotal() int64 {
+	return i.Labor + i.Expenses
+}
+
+// CurrencyCode returns the invoice's currency, defaulting to USD
+func (i *Invoice) CurrencyCode() string {
+	if i.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return i.Currency
 }
 
 // Data is the root structure for JSON storage
 type Data struct {
 	Version  int       `json:"version"`
+	Clients  []Client  `json:"clients,omitempty"`
 	Projects []Project `json:"projects"`
 	Entries  []Entry   `json:"entries"`
 	Invoices []Invoice `json:"invoices,omitempty"`
+	Expenses []Expense `json:"expenses,omitempty"`
 	Settings *Settings `json:"settings,omitempty"`
 }
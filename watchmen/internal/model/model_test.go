@@ -267,3 +267,428 @@ func TestEntryStartTime(t *testing.T) {
 		})
 	}
 }
+
+func TestEntryTimerWarnings(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	ended := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		start     time.Time
+		end       *time.Time
+		warnAfter time.Duration
+		want      int
+	}{
+		{"short run", now.Add(-2 * time.Hour), nil, 8 * time.Hour, 0},
+		{"long run", now.Add(-9 * time.Hour), nil, 8 * time.Hour, 1},
+		{"since yesterday", now.Add(-10 * time.Hour), nil, 8 * time.Hour, 2},
+		{"across midnight only", now.Add(-10 * time.Hour), nil, 0, 1},
+		{"paused", now.Add(-20 * time.Hour), &ended, 8 * time.Hour, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{Segments: []TimeSegment{{Start: tt.start, End: tt.end}}}
+			if got := e.TimerWarnings(now, tt.warnAfter, time.Local); len(got) != tt.want {
+				t.Errorf("TimerWarnings() = %q, want %d warnings", got, tt.want)
+			}
+		})
+	}
+
+	// Midnight is passed in the timezone given, not the one now is in
+	ahead := time.FixedZone("UTC+14", 14*60*60)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) // 11pm in UTC+14
+	e := Entry{Segments: []TimeSegment{{Start: start}}}
+	if got := e.TimerWarnings(start.Add(2*time.Hour), 0, ahead); len(got) != 1 {
+		t.Errorf("TimerWarnings() in UTC+14 = %q, want the midnight warning", got)
+	}
+	if got := e.TimerWarnings(start.Add(2*time.Hour), 0, time.UTC); len(got) != 0 {
+		t.Errorf("TimerWarnings() in UTC = %q, want none", got)
+	}
+}
+
+func TestTimerSettings(t *testing.T) {
+	var unset *TimerSettings
+	if unset.WarnAfter() != DefaultWarnAfterHours*time.Hour || unset.MaxSegment() != 0 {
+		t.Errorf("unset: WarnAfter() = %v, MaxSegment() = %v", unset.WarnAfter(), unset.MaxSegment())
+	}
+	off := &TimerSettings{WarnAfterHours: -1, MaxSegmentHours: 12.5}
+	if off.WarnAfter() != 0 || off.MaxSegment() != 12*time.Hour+30*time.Minute {
+		t.Errorf("set: WarnAfter() = %v, MaxSegment() = %v", off.WarnAfter(), off.MaxSegment())
+	}
+}
+
+func TestTaxAmount(t *testing.T) {
+	tax := &Tax{Name: "VAT", Rate: 20}
+	if got := tax.Amount(36899); got != 7380 {
+		t.Errorf("Tax.Amount() = %d, want 7380", got)
+	}
+	if got := tax.Label(); got != "VAT 20%" {
+		t.Errorf("Tax.Label() = %q, want %q", got, "VAT 20%")
+	}
+	if got := (&Tax{Rate: 7.5}).Label(); got != "Tax 7.5%" {
+		t.Errorf("Tax.Label() = %q, want %q", got, "Tax 7.5%")
+	}
+}
+
+func TestDiscountApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount Discount
+		subtotal int64
+		want     int64
+	}{
+		{"percentage", Discount{Percent: 10}, 40999, 4100},
+		{"flat amount", Discount{Amount: 2500}, 40999, 2500},
+		{"capped at subtotal", Discount{Amount: 50000}, 40999, 40999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.discount.Apply(tt.subtotal); got != tt.want {
+				t.Errorf("Discount.Apply() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpenseTotal(t *testing.T) {
+	if got := (&Expense{UnitAmount: 4999}).Total(); got != 4999 {
+		t.Errorf("Expense.Total() = %d, want 4999", got)
+	}
+	if got := (&Expense{UnitAmount: 67, Quantity: 12.5}).Total(); got != 838 {
+		t.Errorf("Expense.Total() = %d, want 838", got)
+	}
+}
+
+func TestProjectRateAt(t *testing.T) {
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	mar := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+
+	p := &Project{HourlyRate: 10000}
+	// Changes may be recorded out of order
+	p.SetRate(20000, mar)
+	p.SetRate(15000, jan)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int64
+	}{
+		{"before any change", jan.Add(-time.Hour), 10000},
+		{"on the change date", jan, 15000},
+		{"between changes", jan.AddDate(0, 1, 0), 15000},
+		{"after the last change", mar.AddDate(1, 0, 0), 20000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.RateAt(tt.at); got != tt.want {
+				t.Errorf("RateAt() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Setting a rate for an existing date replaces it
+	p.SetRate(17500, jan)
+	if len(p.Rates) != 2 || p.RateAt(jan) != 17500 {
+		t.Errorf("SetRate() did not replace the existing change: %+v", p.Rates)
+	}
+}
+
+func TestRoundingRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding *Rounding
+		in       time.Duration
+		want     time.Duration
+	}{
+		{"nil policy", nil, 82 * time.Minute, 82 * time.Minute},
+		{"nearest 6", &Rounding{Increment: 6}, 82 * time.Minute, 84 * time.Minute},
+		{"nearest 15", &Rounding{Increment: 15, Mode: RoundNearest}, 82 * time.Minute, 75 * time.Minute},
+		{"up 15", &Rounding{Increment: 15, Mode: RoundUp}, 76 * time.Minute, 90 * time.Minute},
+		{"up exact", &Rounding{Increment: 15, Mode: RoundUp}, 75 * time.Minute, 75 * time.Minute},
+		{"down 15", &Rounding{Increment: 15, Mode: RoundDown}, 89 * time.Minute, 75 * time.Minute},
+		{"minimum only", &Rounding{DailyMinimum: 60}, 20 * time.Minute, 20 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rounding.Round(tt.in); got != tt.want {
+				t.Errorf("Round(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundingBilled(t *testing.T) {
+	entry := func(day, hour int, d time.Duration) Entry {
+		start := time.Date(2026, 3, day, hour, 0, 0, 0, time.Local)
+		end := start.Add(d)
+		return Entry{Segments: []TimeSegment{{Start: start, End: &end}}, Completed: true}
+	}
+	entries := []Entry{
+		entry(2, 14, 20*time.Minute), // last entry on the 2nd, listed first
+		entry(2, 9, 10*time.Minute),
+		entry(3, 9, 3*time.Hour),
+	}
+
+	r := &Rounding{Increment: 15, Mode: RoundUp, DailyMinimum: 60}
+	got := r.Billed(entries, nil)
+	// The 2nd rounds to 30m + 15m, topped up to an hour on its last entry
+	want := []time.Duration{45 * time.Minute, 15 * time.Minute, 3 * time.Hour}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Billed()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// One day in UTC is two in a timezone 14 hours ahead, each topped up
+	ahead := time.FixedZone("UTC+14", 14*60*60)
+	late := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	early := late.Add(2 * time.Hour)
+	lateEnd, earlyEnd := late.Add(20*time.Minute), early.Add(20*time.Minute)
+	entries = []Entry{
+		{Segments: []TimeSegment{{Start: late, End: &lateEnd}}, Completed: true},
+		{Segments: []TimeSegment{{Start: early, End: &earlyEnd}}, Completed: true},
+	}
+	minimum := &Rounding{DailyMinimum: 60}
+	for _, tt := range []struct {
+		loc  *time.Location
+		want []time.Duration
+	}{
+		{time.UTC, []time.Duration{20 * time.Minute, 40 * time.Minute}},
+		{ahead, []time.Duration{time.Hour, time.Hour}},
+	} {
+		got := minimum.Billed(entries, tt.loc)
+		if got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("Billed() in %v = %v, want %v", tt.loc, got, tt.want)
+		}
+	}
+
+	if s := r.String(); s != "up to 15 min, 1h daily minimum" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestBudgetUse(t *testing.T) {
+	entry := func(day, hour int, d time.Duration, rate *int64) Entry {
+		start := time.Date(2026, 3, day, hour, 0, 0, 0, time.Local)
+		end := start.Add(d)
+		return Entry{Segments: []TimeSegment{{Start: start, End: &end}}, Completed: true, Rate: rate}
+	}
+	override := int64(20000)
+	p := &Project{HourlyRate: 10000, Rounding: &Rounding{Increment: 60, Mode: RoundUp}}
+	entries := []Entry{
+		entry(2, 9, 90*time.Minute, nil),       // billed 2h at 100
+		entry(3, 9, 30*time.Minute, &override), // billed 1h at 200
+		{Segments: entry(4, 9, 5*time.Hour, nil).Segments, NonBillable: true},
+	}
+
+	b := &Budget{Hours: 4, Amount: 100000}
+	use := b.Use(p, entries, time.Local)
+	if use.Hours != 3 || use.Amount != 40000 {
+		t.Errorf("Use() = %.2fh %d, want 3h 40000", use.Hours, use.Amount)
+	}
+	if use.Fraction != 0.75 {
+		t.Errorf("Fraction = %v, want 0.75 from the hours", use.Fraction)
+	}
+	if w := use.Warning(); w != "" {
+		t.Errorf("Warning() at 75%% = %q, want none", w)
+	}
+
+	for _, tt := range []struct {
+		fraction float64
+		want     string
+	}{
+		{0.8, "80% of budget spent"},
+		{1, "budget used up (100% spent)"},
+		{1.25, "budget used up (125% spent)"},
+	} {
+		if got := (BudgetUse{Fraction: tt.fraction}).Warning(); got != tt.want {
+			t.Errorf("Warning() at %v = %q, want %q", tt.fraction, got, tt.want)
+		}
+	}
+
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.Local)
+	if since := b.Since(now, time.Local); !since.IsZero() {
+		t.Errorf("Since() of a total budget = %v, want zero", since)
+	}
+	b.Monthly = true
+	if since := b.Since(now, time.Local); !since.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Since() of a monthly budget = %v, want Mar 1", since)
+	}
+	// Late on Mar 31 in UTC is already April in a timezone ahead of it
+	ahead := time.FixedZone("UTC+14", 14*60*60)
+	if since := b.Since(time.Date(2026, 3, 31, 20, 0, 0, 0, time.UTC), ahead); !since.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, ahead)) {
+		t.Errorf("Since() in the project timezone = %v, want Apr 1", since)
+	}
+	if s := b.String("USD"); s != "4h and $1,000.00 a month" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestBillingRetainer(t *testing.T) {
+	b := &Billing{Model: BillingRetainer, Fee: 500000, Hours: 20, Rollover: true}
+	for _, tt := range []struct {
+		months                     int
+		carried, billed            float64
+		allowance, overage, unused float64
+	}{
+		{1, 0, 15, 20, 0, 5},
+		{1, 5, 28, 25, 3, 0},
+		{2, 0, 30, 40, 0, 10},
+	} {
+		allowance, overage, unused := b.Retainer(tt.months, tt.carried, tt.billed)
+		if allowance != tt.allowance || overage != tt.overage || unused != tt.unused {
+			t.Errorf("Retainer(%d, %v, %v) = %v, %v, %v, want %v, %v, %v", tt.months, tt.carried, tt.billed,
+				allowance, overage, unused, tt.allowance, tt.overage, tt.unused)
+		}
+	}
+	b.Rollover = false
+	if _, _, unused := b.Retainer(1, 0, 15); unused != 0 {
+		t.Errorf("unused without rollover = %v, want 0", unused)
+	}
+	if s := b.String("USD"); s != "retainer of $5,000.00 a month for 20h" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestBilledMilestonesAndRollover(t *testing.T) {
+	b := &Billing{Model: BillingFixed, Milestones: []Milestone{{Name: "Design", Amount: 300000}, {Name: "Build", Amount: 900000}}}
+	if m := b.Milestone("design"); m == nil || m.Name != "Design" {
+		t.Errorf("Milestone(design) = %v, want Design", m)
+	}
+	if s := b.String("USD"); s != "fixed price, 2 milestones totalling $12,000.00" {
+		t.Errorf("String() = %q", s)
+	}
+
+	march := time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local)
+	april := time.Date(2026, 4, 30, 0, 0, 0, 0, time.Local)
+	invoices := []Invoice{
+		{ID: "1", ProjectID: "p", Charges: []Charge{{Description: "Milestone: Design", Milestone: "Design"}}},
+		{ID: "2", ProjectID: "p", Status: InvoiceStatusVoid, Charges: []Charge{{Milestone: "Build"}}},
+		{ID: "3", ProjectID: "q", Charges: []Charge{{Milestone: "Build"}}},
+		{ID: "4", ProjectID: "p", BillingModel: BillingRetainer, PeriodEnd: april, RolloverHours: 2.5},
+		{ID: "5", ProjectID: "p", BillingModel: BillingRetainer, PeriodEnd: march, RolloverHours: 6},
+		{ID: "6", ProjectID: "p", BillingModel: BillingRetainer, PeriodEnd: april.AddDate(0, 1, 0), Status: InvoiceStatusVoid, RolloverHours: 9},
+		{ID: "7", ProjectID: "p", CreditFor: "4", PeriodEnd: april.AddDate(0, 2, 0), RolloverHours: 7},
+	}
+	billed := BilledMilestones(invoices, "p")
+	if !billed["design"] || billed["build"] {
+		t.Errorf("BilledMilestones() = %v, want design only", billed)
+	}
+	if got := RolloverHours(invoices, "p"); got != 2.5 {
+		t.Errorf("RolloverHours() = %v, want 2.5 from the latest invoice", got)
+	}
+	if got := RolloverHours(invoices, "q"); got != 0 {
+		t.Errorf("RolloverHours() with no retainer invoice = %v, want 0", got)
+	}
+
+	// The carry comes from the invoice issued last, even when its period
+	// overlaps another's
+	invoices = append(invoices, Invoice{ID: "8", ProjectID: "p", BillingModel: BillingRetainer,
+		PeriodStart: april.AddDate(0, 0, -14), PeriodEnd: april, CreatedAt: april.AddDate(0, 0, 2), RolloverHours: 1})
+	if got := RolloverHours(invoices, "p"); got != 1 {
+		t.Errorf("RolloverHours() = %v, want 1 from the invoice issued last", got)
+	}
+}
+
+func TestBilledRetainerMonths(t *testing.T) {
+	invoices := []Invoice{
+		{ProjectID: "p", BillingModel: BillingRetainer, FeeMonths: []string{"2026-03"}},
+		{ProjectID: "p", BillingModel: BillingRetainer, FeeMonths: []string{"2026-06"}, Status: InvoiceStatusVoid},
+		{ProjectID: "q", BillingModel: BillingRetainer, FeeMonths: []string{"2026-07"}},
+		// Saved before the months were recorded, so charged for each month
+		// of its period
+		{ProjectID: "p", BillingModel: BillingRetainer,
+			PeriodStart: time.Date(2026, 4, 16, 0, 0, 0, 0, time.Local), PeriodEnd: time.Date(2026, 5, 31, 23, 59, 0, 0, time.Local)},
+	}
+	billed := BilledRetainerMonths(invoices, "p")
+	if len(billed) != 3 || !billed["2026-03"] || !billed["2026-04"] || !billed["2026-05"] {
+		t.Errorf("BilledRetainerMonths() = %v, want March to May", billed)
+	}
+}
+
+func TestGoalsTarget(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	var none *Goals
+	if got := none.Target(monday, 7); got != 0 {
+		t.Errorf("nil Target() = %v, want 0", got)
+	}
+	daily := &Goals{DailyHours: 6}
+	if got := daily.Target(monday, 7); got != 30 {
+		t.Errorf("daily Target() over a week = %v, want 30 for five weekdays", got)
+	}
+	if got := daily.Target(monday.AddDate(0, 0, 5), 2); got != 0 {
+		t.Errorf("daily Target() over a weekend = %v, want 0", got)
+	}
+	weekly := &Goals{DailyHours: 6, WeeklyHours: 35}
+	if got := weekly.Target(monday, 14); got != 70 {
+		t.Errorf("weekly Target() over two weeks = %v, want 70", got)
+	}
+}
+
+func TestInvoicePayments(t *testing.T) {
+	issued := time.Date(2026, 1, 31, 12, 0, 0, 0, time.Local)
+	due := issued.AddDate(0, 0, 30)
+	inv := &Invoice{ID: "INV-1", Amount: 100000, Status: InvoiceStatusPending, CreatedAt: issued, DueDate: &due}
+
+	if got := inv.State(issued); got != InvoiceStatusPending {
+		t.Errorf("State() = %q, want pending", got)
+	}
+	if err := inv.AddPayment(Payment{Date: issued, Amount: 40000}); err != nil {
+		t.Fatalf("AddPayment() error = %v", err)
+	}
+	if got := inv.State(issued); got != InvoiceStatusPartial {
+		t.Errorf("State() = %q, want partial", got)
+	}
+	if got := inv.State(due.Add(time.Hour)); got != InvoiceStatusOverdue {
+		t.Errorf("State() after due date = %q, want overdue", got)
+	}
+	if err := inv.AddPayment(Payment{Date: issued, Amount: 60001}); err == nil {
+		t.Error("AddPayment() should refuse more than the balance")
+	}
+
+	paid := issued.AddDate(0, 0, 45)
+	if err := inv.AddPayment(Payment{Date: paid, Amount: 60000}); err != nil {
+		t.Fatalf("AddPayment() error = %v", err)
+	}
+	if inv.Status != InvoiceStatusPaid || inv.Balance() != 0 || !inv.PaidAt.Equal(paid) {
+		t.Errorf("Invoice not settled: status %q, balance %d, paid %v", inv.Status, inv.Balance(), inv.PaidAt)
+	}
+	if got := inv.State(paid.AddDate(1, 0, 0)); got != InvoiceStatusPaid {
+		t.Errorf("State() of a paid invoice = %q, want paid", got)
+	}
+
+	// Invoices marked paid before payments were recorded are paid in full
+	legacy := &Invoice{Amount: 5000, Status: InvoiceStatusPaid}
+	if legacy.AmountPaid() != 5000 || legacy.Balance() != 0 {
+		t.Errorf("Legacy paid invoice: paid %d, balance %d", legacy.AmountPaid(), legacy.Balance())
+	}
+}
+
+func TestNextInvoiceNumber(t *testing.T) {
+	settings := &Settings{Numbering: &Numbering{Template: "{project}-{year}-{seq}", Yearly: true, Digits: 3}}
+	taken := map[string]bool{"Big-Co-2026-002": true}
+	exists := func(id string) bool { return taken[id] }
+	dec := time.Date(2026, 12, 31, 12, 0, 0, 0, time.Local)
+	jan := time.Date(2027, 1, 1, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		when time.Time
+		want string
+	}{
+		{dec, "Big-Co-2026-001"},
+		{dec, "Big-Co-2026-003"}, // 002 is already taken
+		{jan, "Big-Co-2027-001"}, // yearly counters restart
+	}
+	for _, tt := range tests {
+		if got := settings.NextInvoiceNumber("Big Co", tt.when, exists); got != tt.want {
+			t.Errorf("NextInvoiceNumber() = %q, want %q", got, tt.want)
+		}
+	}
+
+	settings.Numbering = &Numbering{Template: "INV-{seq}"}
+	if got := settings.NextInvoiceNumber("Big Co", jan, exists); got != "INV-1" {
+		t.Errorf("Global NextInvoiceNumber() = %q, want INV-1", got)
+	}
+}
@@ -0,0 +1,138 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for projects and invoices without a currency
+const DefaultCurrency = "USD"
+
+// Currency describes how amounts in a currency are stored and written.
+// Amounts are integers in minor units, e.g. cents for USD.
+type Currency struct {
+	Code        string
+	Symbol      string
+	Digits      int    // number of minor unit digits (2 for cents, 0 for yen)
+	SymbolAfter bool   // write "12,50 €" rather than "€12.50"
+	Decimal     string // decimal separator
+	Thousands   string // thousands separator
+}
+
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", Symbol: "$", Digits: 2, Decimal: ".", Thousands: ","},
+	"CAD": {Code: "CAD", Symbol: "CA$", Digits: 2, Decimal: ".", Thousands: ","},
+	"AUD": {Code: "AUD", Symbol: "A$", Digits: 2, Decimal: ".", Thousands: ","},
+	"NZD": {Code: "NZD", Symbol: "NZ$", Digits: 2, Decimal: ".", Thousands: ","},
+	"GBP": {Code: "GBP", Symbol: "£", Digits: 2, Decimal: ".", Thousands: ","},
+	"EUR": {Code: "EUR", Symbol: "€", Digits: 2, SymbolAfter: true, Decimal: ",", Thousands: "."},
+	"CHF": {Code: "CHF", Symbol: "CHF", Digits: 2, SymbolAfter: true, Decimal: ".", Thousands: "'"},
+	"SEK": {Code: "SEK", Symbol: "kr", Digits: 2, SymbolAfter: true, Decimal: ",", Thousands: " "},
+	"NOK": {Code: "NOK", Symbol: "kr", Digits: 2, SymbolAfter: true, Decimal: ",", Thousands: " "},
+	"DKK": {Code: "DKK", Symbol: "kr.", Digits: 2, SymbolAfter: true, Decimal: ",", Thousands: "."},
+	"JPY": {Code: "JPY", Symbol: "¥", Digits: 0, Decimal: ".", Thousands: ","},
+	"INR": {Code: "INR", Symbol: "₹", Digits: 2, Decimal: ".", Thousands: ","},
+}
+
+// Lookup returns the currency for an ISO 4217 code. An empty code returns
+// the default currency.
+func Lookup(code string) (Currency, error) {
+	if code == "" {
+		code = DefaultCurrency
+	}
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("unsupported currency %q (supported: %s)", code, strings.Join(Codes(), ", "))
+	}
+	return c, nil
+}
+
+// Codes returns the supported currency codes in alphabetical order
+func Codes() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// currency returns the currency for code, falling back to a plain
+// two-digit format labelled with the code itself when it is unknown
+func currency(code string) Currency {
+	if c, err := Lookup(code); err == nil {
+		return c
+	}
+	return Currency{Code: code, Symbol: code, Digits: 2, SymbolAfter: true, Decimal: ".", Thousands: ","}
+}
+
+// FromMajor converts an amount in major units (e.g. dollars) to minor units
+func FromMajor(amount float64, code string) int64 {
+	return int64(math.Round(amount * math.Pow10(currency(code).Digits)))
+}
+
+// ToMajor converts an amount in minor units to major units
+func ToMajor(amount int64, code string) float64 {
+	return float64(amount) / math.Pow10(currency(code).Digits)
+}
+
+// Multiply returns amount times quantity, rounded to the nearest minor unit.
+// It is used for hours times an hourly rate.
+func Multiply(amount int64, quantity float64) int64 {
+	return int64(math.Round(float64(amount) * quantity))
+}
+
+// Parse converts a decimal string in major units such as "150" or "99.95"
+// to minor units. It accepts a point as the decimal separator.
+func Parse(s, code string) (int64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return FromMajor(f, code), nil
+}
+
+// FormatNumber writes amount using the currency's separators, without a symbol
+func FormatNumber(amount int64, code string) string {
+	c := currency(code)
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	unit := int64(math.Pow10(c.Digits))
+	major := strconv.FormatInt(amount/unit, 10)
+
+	// Group the major part in threes
+	var b strings.Builder
+	for i, d := range major {
+		if i > 0 && (len(major)-i)%3 == 0 {
+			b.WriteString(c.Thousands)
+		}
+		b.WriteRune(d)
+	}
+
+	s := sign + b.String()
+	if c.Digits > 0 {
+		s += c.Decimal + fmt.Sprintf("%0*d", c.Digits, amount%unit)
+	}
+	return s
+}
+
+// Format writes amount with the currency's symbol and separators,
+// e.g. "$1,234.50" or "1.234,50 €"
+func Format(amount int64, code string) string {
+	c := currency(code)
+	if c.SymbolAfter {
+		return FormatNumber(amount, code) + " " + c.Symbol
+	}
+	if amount < 0 {
+		return "-" + c.Symbol + FormatNumber(-amount, code)
+	}
+	return c.Symbol + FormatNumber(amount, code)
+}
@@ -0,0 +1,86 @@
+package money
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount int64
+		code   string
+		want   string
+	}{
+		{30000, "USD", "$300.00"},
+		{123456789, "USD", "$1,234,567.89"},
+		{5, "USD", "$0.05"},
+		{-150050, "USD", "-$1,500.50"},
+		{123450, "EUR", "1.234,50 €"},
+		{-99, "EUR", "-0,99 €"},
+		{1234500, "GBP", "£12,345.00"},
+		{150000, "JPY", "¥150,000"},
+		{100000, "CHF", "1'000.00 CHF"},
+		{2500, "", "$25.00"},
+		{2500, "XYZ", "25.00 XYZ"},
+	}
+
+	for _, tt := range tests {
+		if got := Format(tt.amount, tt.code); got != tt.want {
+			t.Errorf("Format(%d, %q) = %q, want %q", tt.amount, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestFromMajorAndToMajor(t *testing.T) {
+	tests := []struct {
+		major float64
+		code  string
+		minor int64
+	}{
+		{100, "USD", 10000},
+		{125.5, "USD", 12550},
+		{0.1 + 0.2, "USD", 30},
+		{99.995, "EUR", 10000},
+		{1500, "JPY", 1500},
+	}
+
+	for _, tt := range tests {
+		if got := FromMajor(tt.major, tt.code); got != tt.minor {
+			t.Errorf("FromMajor(%v, %q) = %d, want %d", tt.major, tt.code, got, tt.minor)
+		}
+	}
+	if got := ToMajor(12550, "USD"); got != 125.5 {
+		t.Errorf("ToMajor(12550, USD) = %v, want 125.5", got)
+	}
+	if got := ToMajor(1500, "JPY"); got != 1500 {
+		t.Errorf("ToMajor(1500, JPY) = %v, want 1500", got)
+	}
+}
+
+func TestMultiply(t *testing.T) {
+	if got := Multiply(10000, 1.37); got != 13700 {
+		t.Errorf("Multiply(10000, 1.37) = %d, want 13700", got)
+	}
+	if got := Multiply(12550, 1.0/3); got != 4183 {
+		t.Errorf("Multiply(12550, 1/3) = %d, want 4183", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if got, err := Parse("150.25", "USD"); err != nil || got != 15025 {
+		t.Errorf("Parse(150.25) = %d, %v", got, err)
+	}
+	if _, err := Parse("abc", "USD"); err == nil {
+		t.Error("Expected error for invalid amount")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c, err := Lookup("eur")
+	if err != nil || c.Code != "EUR" {
+		t.Errorf("Lookup(eur) = %+v, %v", c, err)
+	}
+	if c, _ := Lookup(""); c.Code != DefaultCurrency {
+		t.Errorf("Lookup(\"\") = %q, want default", c.Code)
+	}
+	if _, err := Lookup("XYZ"); err == nil {
+		t.Error("Expected error for unsupported currency")
+	}
+}
@@ -0,0 +1,147 @@
+// Package period turns the days and date ranges given to commands into
+// instants. Days are always counted in an explicit timezone, so an entry
+// lands on the same day whichever way its period was asked for.
+package period
+
+import (
+	"fmt"
+	"time"
+)
+
+// Layout is the format days are given in
+const Layout = "2006-01-02"
+
+// Location loads the IANA timezone name, or returns time.Local for an
+// empty name
+func Location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q, use a name such as Europe/London", name)
+	}
+	return loc, nil
+}
+
+// LocationOr loads the IANA timezone name, or returns fallback for an
+// empty or unknown name
+func LocationOr(name string, fallback *time.Location) *time.Location {
+	if name == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// ParseDay returns the midnight starting the day s, given as YYYY-MM-DD,
+// in loc
+func ParseDay(s string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation(Layout, s, loc)
+}
+
+// StartOfDay returns the midnight starting t's day in loc. On a day that
+// starts with a daylight saving change it is the first instant of the day.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// EndOfDay returns the last instant of t's day in loc
+func EndOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+}
+
+// StartOfWeek returns the midnight starting the Monday of t's week in loc
+func StartOfWeek(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return time.Date(t.Year(), t.Month(), t.Day()-weekday+1, 0, 0, 0, 0, loc)
+}
+
+// StartOfMonth returns the midnight starting the first of t's month in loc
+func StartOfMonth(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+}
+
+// SameDay returns the midnight starting the calendar day of day, a
+// midnight, in loc, e.g. to count a project's time in its own timezone
+func SameDay(day time.Time, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+}
+
+// Days counts the calendar days from the midnight from to the midnight
+// to, which need not be a whole number of 24 hours apart across a
+// daylight saving change
+func Days(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a) / (24 * time.Hour))
+}
+
+// Flags are the --since, --until, --week and --month options that pick
+// the period a command covers
+type Flags struct {
+	Since string // first day, YYYY-MM-DD
+	Until string // last day, YYYY-MM-DD
+	Week  bool   // this week so far
+	Month bool   // this month so far
+}
+
+// IsZero reports whether no period was given
+func (f Flags) IsZero() bool {
+	return f == Flags{}
+}
+
+// Range is a span of time from the start of its first day
+type Range struct {
+	From time.Time // midnight starting the first day, zero if unbounded
+	To   time.Time // last instant of the last day, or now; zero if unbounded
+}
+
+// Range resolves the flags in loc. The period runs from midnight starting
+// the first day to the end of the last, or to now when no last day is
+// given. With no flags the range is unbounded.
+func (f Flags) Range(loc *time.Location, now time.Time) (Range, error) {
+	var r Range
+	if f.Week && f.Month || (f.Week || f.Month) && (f.Since != "" || f.Until != "") {
+		return r, fmt.Errorf("--week and --month cannot be used with --since, --until or each other")
+	}
+	switch {
+	case f.Week:
+		return Range{From: StartOfWeek(now, loc), To: now}, nil
+	case f.Month:
+		return Range{From: StartOfMonth(now, loc), To: now}, nil
+	}
+	if f.Since != "" {
+		from, err := ParseDay(f.Since, loc)
+		if err != nil {
+			return r, fmt.Errorf("invalid date format for --since, use YYYY-MM-DD")
+		}
+		r.From, r.To = from, now
+	}
+	if f.Until != "" {
+		until, err := ParseDay(f.Until, loc)
+		if err != nil {
+			return r, fmt.Errorf("invalid date format for --until, use YYYY-MM-DD")
+		}
+		r.To = EndOfDay(until, loc)
+		if r.To.Before(r.From) {
+			return r, fmt.Errorf("--until is before --since")
+		}
+	}
+	return r, nil
+}
+
+// ThisMonth is the range from the start of now's month in loc until now
+func ThisMonth(loc *time.Location, now time.Time) Range {
+	return Range{From: StartOfMonth(now, loc), To: now}
+}
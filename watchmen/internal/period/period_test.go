@@ -0,0 +1,149 @@
+package period
+
+import (
+	"testing"
+	"time"
+)
+
+func newYork(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	return loc
+}
+
+func TestLocation(t *testing.T) {
+	if loc, err := Location(""); err != nil || loc != time.Local {
+		t.Errorf("Location(\"\") = %v, %v, want Local", loc, err)
+	}
+	if loc, err := Location("Europe/London"); err != nil || loc.String() != "Europe/London" {
+		t.Errorf("Location(Europe/London) = %v, %v", loc, err)
+	}
+	if _, err := Location("Mars/Olympus"); err == nil {
+		t.Error("Expected an error for an unknown timezone")
+	}
+	if loc := LocationOr("Mars/Olympus", time.UTC); loc != time.UTC {
+		t.Errorf("LocationOr() = %v, want the fallback", loc)
+	}
+}
+
+func TestParseDay(t *testing.T) {
+	ny := newYork(t)
+	day, err := ParseDay("2026-10-05", ny)
+	if err != nil {
+		t.Fatalf("ParseDay() error = %v", err)
+	}
+	if !day.Equal(time.Date(2026, 10, 5, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseDay() = %v, want midnight in New York", day)
+	}
+	if _, err := ParseDay("10/05/2026", ny); err == nil {
+		t.Error("Expected an error for a date not in YYYY-MM-DD")
+	}
+}
+
+func TestDaylightSaving(t *testing.T) {
+	ny := newYork(t)
+	tests := []struct {
+		name  string
+		day   time.Time
+		hours float64
+	}{
+		{"spring forward", time.Date(2026, 3, 8, 12, 0, 0, 0, ny), 23},
+		{"fall back", time.Date(2026, 11, 1, 12, 0, 0, 0, ny), 25},
+		{"ordinary", time.Date(2026, 10, 5, 12, 0, 0, 0, ny), 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := StartOfDay(tt.day, ny), EndOfDay(tt.day, ny)
+			if h, m, _ := start.Clock(); h != 0 || m != 0 || start.Day() != tt.day.Day() {
+				t.Errorf("StartOfDay() = %v", start)
+			}
+			if got := end.Add(time.Nanosecond).Sub(start).Hours(); got != tt.hours {
+				t.Errorf("day is %vh long, want %v", got, tt.hours)
+			}
+			if next := end.Add(time.Nanosecond); next.Day() == tt.day.Day() || next.Hour() != 0 {
+				t.Errorf("EndOfDay() + 1ns = %v, want the next midnight", next)
+			}
+		})
+	}
+
+	// The weeks holding each change still start at midnight on Monday
+	if got := StartOfWeek(time.Date(2026, 3, 8, 23, 0, 0, 0, ny), ny); !got.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, ny)) {
+		t.Errorf("StartOfWeek() = %v, want Mon Mar 2", got)
+	}
+	if got := StartOfWeek(time.Date(2026, 11, 2, 9, 0, 0, 0, ny), ny); !got.Equal(time.Date(2026, 11, 2, 0, 0, 0, 0, ny)) {
+		t.Errorf("StartOfWeek() = %v, want Mon Nov 2", got)
+	}
+
+	// A month holding a change is not a whole number of 24 hours
+	from, to := time.Date(2026, 3, 1, 0, 0, 0, 0, ny), time.Date(2026, 4, 1, 0, 0, 0, 0, ny)
+	if got := Days(from, to); got != 31 {
+		t.Errorf("Days() = %d, want 31", got)
+	}
+	if got := Days(time.Date(2026, 11, 1, 0, 0, 0, 0, ny), time.Date(2026, 11, 2, 0, 0, 0, 0, ny)); got != 1 {
+		t.Errorf("Days() = %d across fall back, want 1", got)
+	}
+
+	// Counting a day in another timezone keeps its date
+	london, _ := time.LoadLocation("Europe/London")
+	if got := SameDay(from, london); got.Format(Layout) != "2026-03-01" || got.Location() != london {
+		t.Errorf("SameDay() = %v", got)
+	}
+}
+
+func TestRange(t *testing.T) {
+	ny := newYork(t)
+	now := time.Date(2026, 10, 15, 14, 0, 0, 0, ny) // a Thursday
+
+	tests := []struct {
+		name     string
+		flags    Flags
+		from, to time.Time
+		wantErr  bool
+	}{
+		{"none", Flags{}, time.Time{}, time.Time{}, false},
+		{"since", Flags{Since: "2026-10-01"}, time.Date(2026, 10, 1, 0, 0, 0, 0, ny), now, false},
+		{"until includes the day", Flags{Since: "2026-10-01", Until: "2026-10-07"},
+			time.Date(2026, 10, 1, 0, 0, 0, 0, ny), time.Date(2026, 10, 8, 0, 0, 0, 0, ny).Add(-time.Nanosecond), false},
+		{"until only", Flags{Until: "2026-10-07"}, time.Time{}, time.Date(2026, 10, 8, 0, 0, 0, 0, ny).Add(-time.Nanosecond), false},
+		{"same day", Flags{Since: "2026-10-07", Until: "2026-10-07"},
+			time.Date(2026, 10, 7, 0, 0, 0, 0, ny), time.Date(2026, 10, 8, 0, 0, 0, 0, ny).Add(-time.Nanosecond), false},
+		{"week", Flags{Week: true}, time.Date(2026, 10, 12, 0, 0, 0, 0, ny), now, false},
+		{"month", Flags{Month: true}, time.Date(2026, 10, 1, 0, 0, 0, 0, ny), now, false},
+		{"week and month", Flags{Week: true, Month: true}, time.Time{}, time.Time{}, true},
+		{"week and since", Flags{Week: true, Since: "2026-10-01"}, time.Time{}, time.Time{}, true},
+		{"bad since", Flags{Since: "Oct 1"}, time.Time{}, time.Time{}, true},
+		{"bad until", Flags{Until: "2026-13-01"}, time.Time{}, time.Time{}, true},
+		{"until before since", Flags{Since: "2026-10-07", Until: "2026-10-06"}, time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.flags.Range(ny, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Range() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !r.From.Equal(tt.from) || !r.To.Equal(tt.to) {
+				t.Errorf("Range() = %v - %v, want %v - %v", r.From, r.To, tt.from, tt.to)
+			}
+		})
+	}
+
+	// An entry late in the evening is already the next day in UTC but
+	// belongs to the local day it started on
+	r, _ := Flags{Since: "2026-10-07", Until: "2026-10-07"}.Range(ny, now)
+	late := time.Date(2026, 10, 8, 3, 30, 0, 0, time.UTC) // 11:30pm in New York
+	if late.Before(r.From) || late.After(r.To) {
+		t.Errorf("%v is outside %v - %v", late, r.From, r.To)
+	}
+}
+
+func TestFlagsIsZero(t *testing.T) {
+	if !(Flags{}).IsZero() || (Flags{Week: true}).IsZero() {
+		t.Error("IsZero() wrong")
+	}
+}
@@ -0,0 +1,520 @@
+// Package server exposes a store over a small HTTP/JSON API on localhost,
+// for status bars and editor plugins, and pushes timer updates to clients
+// as Server-Sent Events.
+//
+// Every request is handled under one lock, so clients of the same server
+// never race on the data file. Changes made by the CLI in the meantime are
+// picked up by reopening a JSON store when its file changes.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"watchmen/internal/model"
+	"watchmen/internal/period"
+	"watchmen/internal/storage"
+)
+
+// Server serves the API for the store at a path
+type Server struct {
+	path     string
+	interval time.Duration // between timer updates on /events
+	now      func() time.Time
+
+	mu      sync.Mutex // held for every use of store
+	store   storage.Store
+	modTime time.Time // of a JSON data file when last read
+	size    int64
+
+	subsMu sync.Mutex
+	subs   map[chan []byte]bool
+	last   []byte // status most recently sent to subscribers
+}
+
+// New returns a server for store, opened from path, sending timer updates
+// every interval. The server owns the store from then on: it may reopen
+// it, and Store returns the one in use.
+func New(path string, store storage.Store, interval time.Duration) *Server {
+	s := &Server{
+		path:     path,
+		interval: interval,
+		now:      time.Now,
+		store:    store,
+		subs:     make(map[chan []byte]bool),
+	}
+	s.modTime, s.size = s.stat()
+	return s
+}
+
+// Store returns the store in use, for the caller to close
+func (s *Server) Store() storage.Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store
+}
+
+// Handler returns the API's routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("POST /start", s.handleStart)
+	mux.HandleFunc("POST /stop", s.handleStop)
+	mux.HandleFunc("POST /pause", s.handlePause)
+	mux.HandleFunc("POST /resume", s.handleResume)
+	mux.HandleFunc("GET /entries", s.handleEntries)
+	mux.HandleFunc("GET /projects", s.handleProjects)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	return localOnly(mux)
+}
+
+// localOnly refuses requests addressed to another host, so a web page
+// cannot reach the API through DNS rebinding, and POSTs that are not
+// JSON, which a browser could send cross-site without asking
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if host != "localhost" && !isLoopback(host) {
+			writeError(w, http.StatusForbidden, errors.New("only requests to localhost are served"))
+			return
+		}
+		if r.Method == http.MethodPost && !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("POST requests must be application/json"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopback(host string) bool {
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// CheckAddr returns an error unless addr listens on a loopback address
+func CheckAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host != "localhost" && !isLoopback(host) {
+		return fmt.Errorf("%s is not a localhost address", addr)
+	}
+	return nil
+}
+
+// Run sends timer updates to subscribers every interval, and whenever the
+// data file changes, until done is closed
+func (s *Server) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			err := s.refresh()
+			status := s.status()
+			s.mu.Unlock()
+			if err == nil {
+				s.publish(status, true)
+			}
+		}
+	}
+}
+
+// stat returns the modification time and size of a JSON data file, or
+// zeroes for SQLite, which is read afresh on every query
+func (s *Server) stat() (time.Time, int64) {
+	if storage.IsSQLitePath(s.path) {
+		return time.Time{}, 0
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// refresh reopens a JSON store whose file has changed since it was read,
+// as it keeps the data in memory. The caller must hold mu.
+func (s *Server) refresh() error {
+	modTime, size := s.stat()
+	if modTime.Equal(s.modTime) && size == s.size {
+		return nil
+	}
+	store, err := storage.Open(s.path)
+	if err != nil {
+		return err
+	}
+	s.store.Close()
+	s.store = store
+	s.modTime, s.size = modTime, size
+	return nil
+}
+
+// do runs fn with the store under the lock, after picking up any outside
+// changes, and notes the store's own writes so they are not read again
+func (s *Server) do(fn func(storage.Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	err := fn(s.store)
+	s.modTime, s.size = s.stat()
+	return err
+}
+
+// Status is the timer state, in the shape of 'watchmen status --json'
+type Status struct {
+	Running        bool          `json:"running"`
+	Paused         bool          `json:"paused"`
+	Status         string        `json:"status"` // running, paused or idle
+	ProjectID      string        `json:"project_id,omitempty"`
+	ProjectName    string        `json:"project_name,omitempty"`
+	StartedAt      *time.Time    `json:"started_at,omitempty"`
+	ElapsedSeconds int           `json:"elapsed_seconds"`
+	Hours          float64       `json:"hours"`
+	Segments       int           `json:"segments"`
+	Note           string        `json:"note,omitempty"`
+	Warnings       []string      `json:"warnings"`
+	AlsoPaused     []PausedEntry `json:"also_paused"`
+}
+
+// PausedEntry is another paused entry, when each project may have one
+type PausedEntry struct {
+	ProjectID   string  `json:"project_id"`
+	ProjectName string  `json:"project_name"`
+	Hours       float64 `json:"hours"`
+	Note        string  `json:"note,omitempty"`
+}
+
+// status returns the timer state. The caller must hold mu.
+func (s *Server) status() Status {
+	status := Status{Status: "idle", Warnings: []string{}, AlsoPaused: []PausedEntry{}}
+	entry := s.store.ActiveEntry()
+	if entry == nil {
+		return status
+	}
+	now := s.now()
+	duration := entryDuration(entry, now)
+	start := entry.StartTime()
+	status.Running = entry.IsRunning()
+	status.Paused = entry.IsPaused()
+	status.Status = "running"
+	if status.Paused {
+		status.Status = "paused"
+	}
+	status.ProjectID = entry.ProjectID
+	status.ProjectName = s.projectName(entry.ProjectID)
+	status.StartedAt = &start
+	status.ElapsedSeconds = int(duration.Seconds())
+	status.Hours = duration.Hours()
+	status.Segments = len(entry.Segments)
+	status.Note = entry.Note
+	if warnings := entry.TimerWarnings(now, s.store.GetSettings().Timer.WarnAfter(), homeLocation(s.store)); warnings != nil {
+		status.Warnings = warnings
+	}
+	for _, e := range s.store.PausedEntries() {
+		if e.ID != entry.ID {
+			status.AlsoPaused = append(status.AlsoPaused, PausedEntry{
+				ProjectID:   e.ProjectID,
+				ProjectName: s.projectName(e.ProjectID),
+				Hours:       entryDuration(&e, now).Hours(),
+				Note:        e.Note,
+			})
+		}
+	}
+	return status
+}
+
+func entryDuration(e *model.Entry, now time.Time) time.Duration {
+	var total time.Duration
+	for _, seg := range e.Segments {
+		total += seg.Between(seg.Start, now, now)
+	}
+	return total
+}
+
+func (s *Server) projectName(id string) string {
+	if p, err := s.store.GetProject(id); err == nil {
+		return p.Name
+	}
+	return id
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	var status Status
+	if err := s.do(func(storage.Store) error { status = s.status(); return nil }); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// timerRequest is the body of the start, stop and resume requests
+type timerRequest struct {
+	Project string `json:"project"`
+	Note    string `json:"note"`
+	Force   bool   `json:"force"` // stop a segment longer than max_segment_hours
+}
+
+// change runs a timer change and replies with the new status, sending it
+// to subscribers too
+func (s *Server) change(w http.ResponseWriter, r *http.Request, fn func(storage.Store, timerRequest) error) {
+	var req timerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+	var status Status
+	err := s.do(func(store storage.Store) error {
+		if err := fn(store, req); err != nil {
+			return err
+		}
+		status = s.status()
+		return nil
+	})
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	s.publish(status, false)
+	writeJSON(w, http.StatusOK, status)
+}
+
+// projectID resolves a project by name or ID
+func projectID(store storage.Store, idOrName string) (string, error) {
+	if idOrName == "" {
+		return "", nil
+	}
+	p, err := store.GetProject(idOrName)
+	if err != nil {
+		return "", fmt.Errorf("project %q: %w", idOrName, err)
+	}
+	return p.ID, nil
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, func(store storage.Store, req timerRequest) error {
+		if req.Project == "" {
+			return badRequest("project is required")
+		}
+		id, err := projectID(store, req.Project)
+		if err != nil {
+			return err
+		}
+		_, err = store.StartEntry(id, req.Note, nil)
+		return err
+	})
+}
+
+// errTooLong is returned when stopping would save a segment longer than
+// max_segment_hours without force
+var errTooLong = errors.New("segment is longer than max_segment_hours, send force to stop it anyway")
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, func(store storage.Store, req timerRequest) error {
+		active := store.ActiveEntry()
+		if active == nil {
+			return storage.ErrNoActiveEntry
+		}
+		if seg := active.OpenSegment(); seg != nil && !req.Force {
+			if limit := store.GetSettings().Timer.MaxSegment(); limit > 0 && s.now().Sub(seg.Start) > limit {
+				return errTooLong
+			}
+		}
+		_, err := store.StopEntry(req.Note)
+		return err
+	})
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, func(store storage.Store, req timerRequest) error {
+		_, err := store.PauseEntry()
+		return err
+	})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, func(store storage.Store, req timerRequest) error {
+		id, err := projectID(store, req.Project)
+		if err != nil {
+			return err
+		}
+		_, err = store.ResumeEntry(id)
+		return err
+	})
+}
+
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var entries []model.Entry
+	err := s.do(func(store storage.Store) error {
+		loc := homeLocation(store)
+		var from, to *time.Time
+		for _, p := range []struct {
+			name string
+			dst  **time.Time
+			end  bool
+		}{{"since", &from, false}, {"until", &to, true}} {
+			v := query.Get(p.name)
+			if v == "" {
+				continue
+			}
+			t, err := period.ParseDay(v, loc)
+			if err != nil {
+				return badRequest(fmt.Sprintf("invalid %s %q, use YYYY-MM-DD", p.name, v))
+			}
+			if p.end {
+				t = period.EndOfDay(t, loc)
+			}
+			*p.dst = &t
+		}
+
+		id, err := projectID(store, query.Get("project"))
+		if err != nil {
+			return err
+		}
+		entries = store.ListEntries(id, from, to)
+		return nil
+	})
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	if entries == nil {
+		entries = []model.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	var projects []model.Project
+	if err := s.do(func(store storage.Store) error { projects = store.ListProjects(); return nil }); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if projects == nil {
+		projects = []model.Project{}
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// handleEvents streams the status as a "status" event when a client
+// connects, whenever the timer changes and every interval while it runs
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	var status Status
+	if err := s.do(func(storage.Store) error { status = s.status(); return nil }); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ch := make(chan []byte, 8)
+	s.subsMu.Lock()
+	s.subs[ch] = true
+	s.subsMu.Unlock()
+	defer func() {
+		s.subsMu.Lock()
+		delete(s.subs, ch)
+		s.subsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for {
+		if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-r.Context().Done():
+			return
+		case data = <-ch:
+		}
+	}
+}
+
+// publish sends a status to every subscriber. On a tick it is only sent
+// if the timer is running, or the status has changed since last sent.
+func (s *Server) publish(status Status, tick bool) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return
+	}
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	if tick && !status.Running && string(data) == string(s.last) {
+		return
+	}
+	s.last = data
+	for ch := range s.subs {
+		select {
+		case ch <- data:
+		default: // a slow client misses an update rather than holding up the rest
+		}
+	}
+}
+
+// homeLocation returns the home timezone days are counted in. It is read
+// from the settings each time, as another process may have changed it.
+func homeLocation(store storage.Store) *time.Location {
+	return period.LocationOr(store.GetSettings().Timezone, time.Local)
+}
+
+// badRequest is an error in what the client sent
+type badRequest string
+
+func (e badRequest) Error() string { return string(e) }
+
+// errorStatus maps store errors to HTTP status codes
+func errorStatus(err error) int {
+	var bad badRequest
+	switch {
+	case errors.As(err, &bad):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrProjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrActiveEntry), errors.Is(err, storage.ErrNoActiveEntry),
+		errors.Is(err, storage.ErrNoPausedEntry), errors.Is(err, storage.ErrNotPaused),
+		errors.Is(err, storage.ErrAlreadyPaused), errors.Is(err, storage.ErrPausedOnProject),
+		errors.Is(err, storage.ErrSeveralPaused), errors.Is(err, errTooLong):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+	"watchmen/internal/storage"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.json")
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddProject("acme", 10000, ""); err != nil {
+		t.Fatal(err)
+	}
+	srv := New(path, store, time.Hour)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Store().Close()
+	})
+	return srv, ts, path
+}
+
+func post(t *testing.T, url, body string) (int, map[string]any) {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func TestTimerRequests(t *testing.T) {
+	_, ts, path := newTestServer(t)
+
+	code, out := post(t, ts.URL+"/start", `{"project":"acme","note":"api"}`)
+	if code != http.StatusOK || out["status"] != "running" || out["project_name"] != "acme" {
+		t.Fatalf("start = %d %v", code, out)
+	}
+	if code, out := post(t, ts.URL+"/start", `{"project":"acme"}`); code != http.StatusConflict {
+		t.Errorf("second start = %d %v, want 409", code, out)
+	}
+	if code, _ := post(t, ts.URL+"/start", `{"project":"nope"}`); code != http.StatusNotFound {
+		t.Errorf("start on a missing project = %d, want 404", code)
+	}
+	if code, out := post(t, ts.URL+"/pause", ``); code != http.StatusOK || out["status"] != "paused" {
+		t.Errorf("pause = %d %v", code, out)
+	}
+
+	// The CLI resumes the entry behind the server's back
+	cli, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.ResumeEntry(""); err != nil {
+		t.Fatal(err)
+	}
+	cli.Close()
+	resp, err := http.Get(ts.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status Status
+	json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if status.Status != "running" || status.Segments != 2 {
+		t.Errorf("status after the CLI resumed = %+v, want running with 2 segments", status)
+	}
+
+	if code, out := post(t, ts.URL+"/stop", `{"note":"done"}`); code != http.StatusOK || out["status"] != "idle" {
+		t.Errorf("stop = %d %v", code, out)
+	}
+	resp, err = http.Get(ts.URL + "/entries?project=acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]any
+	json.NewDecoder(resp.Body).Decode(&entries)
+	resp.Body.Close()
+	if len(entries) != 1 || entries[0]["note"] != "api | done" {
+		t.Errorf("entries = %v", entries)
+	}
+}
+
+func TestEntriesInHomeTimezone(t *testing.T) {
+	srv, ts, _ := newTestServer(t)
+	kiritimati, err := time.LoadLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	store := srv.Store()
+	if err := store.UpdateSettings(func(s *model.Settings) { s.Timezone = "Pacific/Kiritimati" }); err != nil {
+		t.Fatal(err)
+	}
+	// Late on the 5th in UTC, but already the 6th in the home timezone
+	start := time.Date(2026, 10, 6, 1, 0, 0, 0, kiritimati)
+	if _, err := store.LogEntry(store.ListProjects()[0].ID, "early", start, start.Add(time.Hour), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for day, want := range map[string]int{"2026-10-05": 0, "2026-10-06": 1} {
+		resp, err := http.Get(ts.URL + "/entries?since=" + day + "&until=" + day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entries []map[string]any
+		json.NewDecoder(resp.Body).Decode(&entries)
+		resp.Body.Close()
+		if len(entries) != want {
+			t.Errorf("entries on %s = %d, want %d", day, len(entries), want)
+		}
+	}
+}
+
+func TestRequestsAreLocal(t *testing.T) {
+	_, ts, _ := newTestServer(t)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/status", nil)
+	req.Host = "evil.example:7717"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("request for another host = %d, want 403", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL+"/start", "text/plain", strings.NewReader(`{"project":"acme"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("form-like POST = %d, want 415", resp.StatusCode)
+	}
+
+	for addr, ok := range map[string]bool{"127.0.0.1:7717": true, "localhost:80": true, "[::1]:7717": true, "0.0.0.0:7717": false, ":7717": false} {
+		if err := CheckAddr(addr); (err == nil) != ok {
+			t.Errorf("CheckAddr(%q) = %v", addr, err)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	_, ts, _ := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := make(chan Status)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var status Status
+				json.Unmarshal([]byte(data), &status)
+				events <- status
+			}
+		}
+		close(events)
+	}()
+
+	next := func() Status {
+		select {
+		case status := <-events:
+			return status
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+		}
+		return Status{}
+	}
+	if status := next(); status.Status != "idle" {
+		t.Errorf("first event = %+v, want idle", status)
+	}
+	post(t, ts.URL+"/start", `{"project":"acme"}`)
+	if status := next(); status.Status != "running" {
+		t.Errorf("event after start = %+v, want running", status)
+	}
+}
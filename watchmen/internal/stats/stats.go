@@ -0,0 +1,230 @@
+// Package stats works out utilisation and working patterns from tracked
+// time and the invoices that billed it
+package stats
+
+import (
+	"time"
+
+	"watchmen/internal/model"
+	"watchmen/internal/money"
+	"watchmen/internal/period"
+)
+
+// Stats summarises the time worked over a run of days
+type Stats struct {
+	From          time.Time
+	To            time.Time // midnight after the last day
+	Days          int
+	Hours         float64
+	BillableHours float64
+	DaysWorked    int
+	AverageDay    float64 // per day worked
+	LongestStreak int
+	StreakFrom    time.Time     // first day of the longest streak
+	Weekdays      []WeekdayStat // Monday first
+
+	// Utilisation is billable hours over GoalHours, or over all hours
+	// worked when there are no goals
+	GoalHours   float64
+	Utilisation float64
+	DailyGoal   float64
+	DaysMetGoal int // days with at least DailyGoal billable hours
+
+	Rates []EffectiveRate
+}
+
+// WeekdayStat is the time worked on one day of the week
+type WeekdayStat struct {
+	Day   string
+	Hours float64
+}
+
+// EffectiveRate is what the invoiced time earned per hour in one currency
+type EffectiveRate struct {
+	Currency string
+	Invoiced int64 // minor units
+	Hours    float64
+	Rate     int64 // minor units per hour
+}
+
+// Compute works out the stats for days days from the midnight starting
+// from. Time is counted on the day it was worked, in the project's
+// timezone when it has one, and open segments run until now.
+//
+// The effective rate spreads each invoice's labour, after its share of
+// any discount and before tax and expenses, over the entries it billed by
+// time worked. The share earned in the period is divided by the hours
+// behind it plus the period's non-billable hours. Billable time not yet
+// invoiced is left out of both. Voided invoices and credit notes are
+// ignored.
+func Compute(entries []model.Entry, projects []model.Project, invoices []model.Invoice, goals *model.Goals, from time.Time, days int, now time.Time) *Stats {
+	st := &Stats{From: from, To: from.AddDate(0, 0, days), Days: days}
+
+	daily := make([]time.Duration, days)
+	billable := make([]time.Duration, days)
+	weekdays := make([]time.Duration, 7)
+	worked := make(map[string]time.Duration) // by entry, within the period
+	locs := make(map[string]*time.Location)
+	for _, p := range projects {
+		locs[p.ID] = period.LocationOr(p.Timezone, from.Location())
+	}
+	for _, e := range entries {
+		loc := locs[e.ProjectID]
+		if loc == nil {
+			loc = from.Location()
+		}
+		for _, seg := range e.Segments {
+			for d := range days {
+				day := from.AddDate(0, 0, d)
+				dur := seg.Between(period.SameDay(day, loc), period.SameDay(day.AddDate(0, 0, 1), loc), now)
+				if dur == 0 {
+					continue
+				}
+				daily[d] += dur
+				if e.IsBillable() {
+					billable[d] += dur
+				}
+				weekdays[(int(day.Weekday())+6)%7] += dur
+				worked[e.ID] += dur
+			}
+		}
+	}
+
+	var total, totalBillable time.Duration
+	streak := 0
+	for d := range days {
+		total += daily[d]
+		totalBillable += billable[d]
+		if daily[d] == 0 {
+			streak = 0
+			continue
+		}
+		st.DaysWorked++
+		streak++
+		if streak > st.LongestStreak {
+			st.LongestStreak = streak
+			st.StreakFrom = from.AddDate(0, 0, d-streak+1)
+		}
+		if goals != nil && goals.DailyHours > 0 && billable[d].Hours() >= goals.DailyHours {
+			st.DaysMetGoal++
+		}
+	}
+	st.Hours = total.Hours()
+	st.BillableHours = totalBillable.Hours()
+	if st.DaysWorked > 0 {
+		st.AverageDay = st.Hours / float64(st.DaysWorked)
+	}
+	for i, dur := range weekdays {
+		st.Weekdays = append(st.Weekdays, WeekdayStat{
+			Day:   time.Weekday((i + 1) % 7).String(),
+			Hours: dur.Hours(),
+		})
+	}
+
+	if goals != nil {
+		st.DailyGoal = goals.DailyHours
+	}
+	st.GoalHours = goals.Target(from, days)
+	switch {
+	case st.GoalHours > 0:
+		st.Utilisation = st.BillableHours / st.GoalHours
+	case st.Hours > 0:
+		st.Utilisation = st.BillableHours / st.Hours
+	}
+
+	st.Rates = effectiveRates(entries, projects, invoices, worked)
+	return st
+}
+
+func effectiveRates(entries []model.Entry, projects []model.Project, invoices []model.Invoice, worked map[string]time.Duration) []EffectiveRate {
+	currencies := make(map[string]string) // by project
+	for _, p := range projects {
+		currencies[p.ID] = p.CurrencyCode()
+	}
+	billed := make(map[string]*model.Invoice)
+	for i := range invoices {
+		inv := &invoices[i]
+		if inv.VoidedAt != nil || inv.IsCreditNote() {
+			continue
+		}
+		billed[inv.ID] = inv
+	}
+
+	// Each invoice's labour is shared among its entries by time worked
+	onInvoice := make(map[string]time.Duration)
+	for _, e := range entries {
+		if billed[e.InvoiceID] != nil {
+			onInvoice[e.InvoiceID] += e.Duration()
+		}
+	}
+
+	earned := make(map[string]int64)
+	hours := make(map[string]time.Duration)
+	invoiced := make(map[string]bool) // currencies with invoiced time
+	var order []string
+	for _, e := range entries {
+		dur, ok := worked[e.ID]
+		if !ok {
+			continue
+		}
+		var currency string
+		var share int64
+		if inv := billed[e.InvoiceID]; inv != nil {
+			currency = inv.CurrencyCode()
+			invoiced[currency] = true
+			if onInvoice[inv.ID] > 0 {
+				share = money.Multiply(labour(inv), float64(dur)/float64(onInvoice[inv.ID]))
+			}
+		} else if !e.IsBillable() {
+			currency = currencies[e.ProjectID]
+			if currency == "" {
+				currency = money.DefaultCurrency
+			}
+		} else {
+			continue // not invoiced yet
+		}
+		if _, ok := hours[currency]; !ok {
+			order = append(order, currency)
+		}
+		earned[currency] += share
+		hours[currency] += dur
+	}
+
+	rates := []EffectiveRate{}
+	for _, currency := range order {
+		if !invoiced[currency] {
+			continue
+		}
+		r := EffectiveRate{
+			Currency: currency,
+			Invoiced: earned[currency],
+			Hours:    hours[currency].Hours(),
+		}
+		if r.Hours > 0 {
+			r.Rate = money.Multiply(r.Invoiced, 1/r.Hours)
+		}
+		rates = append(rates, r)
+	}
+	return rates
+}
+
+// labour returns what an invoice charged for time after its share of any
+// discount, working it out from the hours and rate on invoices saved
+// before the breakdown was recorded
+func labour(inv *model.Invoice) int64 {
+	if inv.Labor == 0 && inv.Expenses == 0 {
+		if len(inv.RateLines) > 0 {
+			var total int64
+			for _, l := range inv.RateLines {
+				total += l.Amount
+			}
+			return total
+		}
+		return money.Multiply(inv.Rate, inv.Hours)
+	}
+	discount := inv.Discount
+	if subtotal := inv.Subtotal(); subtotal > 0 {
+		discount = money.Multiply(inv.Discount, float64(inv.Labor)/float64(subtotal))
+	}
+	return max(inv.Labor-discount, 0)
+}
@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func TestCompute(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	seg := func(day, start, end int) model.TimeSegment {
+		s := monday.AddDate(0, 0, day).Add(time.Duration(start) * time.Hour)
+		e := monday.AddDate(0, 0, day).Add(time.Duration(end) * time.Hour)
+		return model.TimeSegment{Start: s, End: &e}
+	}
+
+	projects := []model.Project{{ID: "p1", Name: "acme"}}
+	entries := []model.Entry{
+		{ID: "a", ProjectID: "p1", Segments: []model.TimeSegment{seg(0, 9, 15)}, InvoiceID: "INV-1"},
+		{ID: "b", ProjectID: "p1", Segments: []model.TimeSegment{seg(1, 9, 11)}, InvoiceID: "INV-1"},
+		{ID: "c", ProjectID: "p1", Segments: []model.TimeSegment{seg(1, 22, 26)}}, // not invoiced, past midnight
+		{ID: "d", ProjectID: "p1", Segments: []model.TimeSegment{seg(4, 9, 11)}, NonBillable: true},
+		{ID: "e", ProjectID: "p1", Segments: []model.TimeSegment{seg(-3, 9, 17)}, InvoiceID: "INV-1"}, // before the period
+	}
+	invoices := []model.Invoice{
+		// 16h worked for 1,760.00 of labour less its share of a 100.00 discount
+		{ID: "INV-1", ProjectID: "p1", Labor: 176000, Expenses: 24000, Discount: 10000},
+		{ID: "INV-0", ProjectID: "p1", Labor: 99900, VoidedAt: &monday},
+	}
+
+	st := Compute(entries, projects, invoices, &model.Goals{DailyHours: 6}, monday, 7, monday.AddDate(0, 0, 7))
+	if st.Hours != 14 || st.BillableHours != 12 {
+		t.Errorf("hours = %v billable %v, want 14 and 12", st.Hours, st.BillableHours)
+	}
+	if st.DaysWorked != 4 || st.AverageDay != 3.5 {
+		t.Errorf("days worked = %d average %v, want 4 days of 3.5h", st.DaysWorked, st.AverageDay)
+	}
+	if st.LongestStreak != 3 || !st.StreakFrom.Equal(monday) {
+		t.Errorf("streak = %d from %v, want 3 from Monday", st.LongestStreak, st.StreakFrom)
+	}
+	if st.Weekdays[0].Day != "Monday" || st.Weekdays[0].Hours != 6 || st.Weekdays[2].Hours != 2 {
+		t.Errorf("weekdays = %v", st.Weekdays)
+	}
+	if st.GoalHours != 30 || st.Utilisation != 0.4 {
+		t.Errorf("goal %v utilisation %v, want 30 and 0.4", st.GoalHours, st.Utilisation)
+	}
+	if st.DaysMetGoal != 1 {
+		t.Errorf("days met goal = %d, want 1", st.DaysMetGoal)
+	}
+
+	// Of INV-1's 1,672.00 labour, the period's 8 of 16 hours earned half,
+	// over those 8 hours and 2 non-billable ones
+	if len(st.Rates) != 1 {
+		t.Fatalf("rates = %v, want one currency", st.Rates)
+	}
+	if r := st.Rates[0]; r.Currency != "USD" || r.Invoiced != 83600 || r.Hours != 10 || r.Rate != 8360 {
+		t.Errorf("rate = %+v, want 836.00 over 10h at 83.60", r)
+	}
+}
+
+func TestComputeNoGoals(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	end := monday.Add(4 * time.Hour)
+	entries := []model.Entry{
+		{ID: "a", Segments: []model.TimeSegment{{Start: monday.Add(time.Hour), End: &end}}},
+		{ID: "b", Segments: []model.TimeSegment{{Start: monday, End: &end}}, NonBillable: true},
+	}
+	st := Compute(entries, nil, nil, nil, monday, 1, monday)
+	if st.GoalHours != 0 || st.Utilisation != 3.0/7 {
+		t.Errorf("utilisation = %v, want billable share 3/7", st.Utilisation)
+	}
+	if len(st.Rates) != 0 {
+		t.Errorf("rates = %v, want none without invoices", st.Rates)
+	}
+}
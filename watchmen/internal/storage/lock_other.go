@@ -0,0 +1,9 @@
+//go:build !unix
+
+package storage
+
+// lockFile is a no-op on platforms without flock. Writes are still atomic,
+// but concurrent processes are not serialised.
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
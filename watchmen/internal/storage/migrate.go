@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"watchmen/internal/model"
+)
+
+// Migrate copies everything in src into dst, which must be empty, and then
+// checks that dst reads back exactly what src holds.
+func Migrate(src, dst Store) error {
+	data, err := src.Snapshot()
+	if err != nil {
+		return fmt.Errorf("reading source: %w", err)
+	}
+
+	existing, err := dst.Snapshot()
+	if err != nil {
+		return fmt.Errorf("reading destination: %w", err)
+	}
+	if len(existing.Clients) > 0 || len(existing.Projects) > 0 || len(existing.Entries) > 0 || len(existing.Invoices) > 0 || len(existing.Expenses) > 0 {
+		return ErrNotEmpty
+	}
+
+	if err := dst.Restore(data); err != nil {
+		return fmt.Errorf("writing destination: %w", err)
+	}
+
+	copied, err := dst.Snapshot()
+	if err != nil {
+		return fmt.Errorf("verifying destination: %w", err)
+	}
+	same, err := sameData(data, copied)
+	if err != nil {
+		return fmt.Errorf("verifying destination: %w", err)
+	}
+	if !same {
+		return fmt.Errorf("verifying destination: migrated data does not match source")
+	}
+	return nil
+}
+
+// sameData reports whether a and b serialise identically, treating nil and
+// empty lists as equal
+func sameData(a, b *model.Data) (bool, error) {
+	encode := func(d *model.Data) ([]byte, error) {
+		n := *d
+		if len(n.Clients) == 0 {
+			n.Clients = nil
+		}
+		if len(n.Projects) == 0 {
+			n.Projects = nil
+		}
+		if len(n.Entries) == 0 {
+			n.Entries = nil
+		}
+		if len(n.Invoices) == 0 {
+			n.Invoices = nil
+		}
+		if len(n.Expenses) == 0 {
+			n.Expenses = nil
+		}
+		return json.Marshal(n)
+	}
+	aj, err := encode(a)
+	if err != nil {
+		return false, err
+	}
+	bj, err := encode(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(aj, bj), nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func TestMigrateJSONToSQLite(t *testing.T) {
+	dir := t.TempDir()
+	src, _ := New(filepath.Join(dir, "data.json"))
+
+	c, _ := src.AddClient("Acme")
+	src.UpdateClient(c.ID, func(c *model.Client) {
+		c.Contact = &model.ContactInfo{Name: "Jane", Email: "jane@example.com"}
+		c.PaymentTerms = 30
+	})
+	p1, _ := src.AddProject("One", 12550, "first project")
+	src.UpdateProject(p1.ID, func(p *model.Project) {
+		p.PurchaseOrder = "PO-9"
+		p.ClientID = c.ID
+	})
+	p2, _ := src.AddProject("Two", 0, "")
+
+	start := time.Date(2026, 1, 5, 9, 0, 0, 123456789, time.FixedZone("EST", -5*3600))
+	src.LogEntry(p1.ID, "logged", start, start.Add(90*time.Minute), nil)
+	src.StartEntry(p2.ID, "multi", nil)
+	src.PauseEntry()
+	src.ResumeEntry("")
+	src.PauseEntry()
+	src.SetUserContact(&model.ContactInfo{Name: "Me", Company: "Me LLC"})
+	src.SaveInvoice(&model.Invoice{ID: "INV-1", ProjectID: p1.ID, ProjectName: "One", Hours: 1.5, Rate: 12550, Amount: 18825}, nil, nil)
+	// Data written before duplicate checks may repeat an invoice ID
+	data, _ := src.Snapshot()
+	data.Invoices = append(data.Invoices, model.Invoice{ID: "INV-1", ProjectID: p1.ID, ProjectName: "One", Description: "duplicate id", Status: model.InvoiceStatusPending})
+	src.Restore(data)
+	src.MarkInvoicePaid("INV-1")
+	src.AddExpense(model.Expense{ProjectID: p1.ID, Date: start, Description: "Mileage", Quantity: 12.5, UnitAmount: 67})
+
+	dst, err := NewSQLite(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite failed: %v", err)
+	}
+	defer dst.Close()
+
+	if err := Migrate(src, dst); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	want, _ := src.Snapshot()
+	got, _ := dst.Snapshot()
+	same, err := sameData(want, got)
+	if err != nil || !same {
+		t.Fatalf("Migrated data differs from source")
+	}
+
+	// Behaviour carries over, not just the data
+	if active := dst.ActiveEntry(); active == nil || !active.IsPaused() || len(active.Segments) != 2 {
+		t.Errorf("Paused entry not migrated: %+v", active)
+	}
+	if invoices := dst.ListInvoices("One", ""); len(invoices) != 2 {
+		t.Errorf("Expected 2 invoices, got %d", len(invoices))
+	}
+}
+
+func TestMigrateFromV1File(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	v1Data := `{
+		"version": 1,
+		"projects": [{"id": "proj1", "name": "Test", "hourly_rate": 100, "created_at": "2024-01-01T00:00:00Z"}],
+		"entries": [{"id": "entry1", "project_id": "proj1", "start_time": "2024-01-15T09:00:00Z", "end_time": "2024-01-15T11:00:00Z"}]
+	}`
+	if err := os.WriteFile(path, []byte(v1Data), 0644); err != nil {
+		t.Fatalf("Failed to write v1 data: %v", err)
+	}
+
+	src, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to load v1 data: %v", err)
+	}
+	dst, _ := NewSQLite(filepath.Join(dir, "data.db"))
+	defer dst.Close()
+
+	if err := Migrate(src, dst); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	entries := dst.ListEntries("Test", nil, nil)
+	if len(entries) != 1 || entries[0].Duration() != 2*time.Hour || !entries[0].Completed {
+		t.Errorf("Unexpected migrated entries: %+v", entries)
+	}
+}
+
+func TestMigrateRefusesNonEmptyDestination(t *testing.T) {
+	dir := t.TempDir()
+	src, _ := New(filepath.Join(dir, "data.json"))
+	src.AddProject("One", 100, "")
+
+	dst, _ := NewSQLite(filepath.Join(dir, "data.db"))
+	defer dst.Close()
+	dst.AddProject("Existing", 100, "")
+
+	if err := Migrate(src, dst); err != ErrNotEmpty {
+		t.Errorf("Expected ErrNotEmpty, got %v", err)
+	}
+}
@@ -0,0 +1,1630 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"watchmen/internal/model"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchemaVersion is recorded in PRAGMA user_version
+const sqliteSchemaVersion = 4
+
+// sqliteMigrations[i] upgrades a database from schema version i+1 to i+2.
+// A newly created database starts at the current version.
+var sqliteMigrations = []func(tx *sql.Tx) error{
+	migrateSQLiteFromV1,
+	migrateSQLiteFromV2,
+	migrateSQLiteFromV3,
+}
+
+const sqliteSchema = `
+	CREATE TABLE IF NOT EXISTS clients (
+		id   TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		data TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS projects (
+		id   TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		data TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS projects_name ON projects(name);
+
+	CREATE TABLE IF NOT EXISTS entries (
+		id         TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		start_time INTEGER NOT NULL,
+		completed  INTEGER NOT NULL DEFAULT 0,
+		invoice_id TEXT NOT NULL DEFAULT '',
+		data       TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS entries_project_start ON entries(project_id, start_time);
+	CREATE INDEX IF NOT EXISTS entries_start ON entries(start_time);
+	CREATE INDEX IF NOT EXISTS entries_completed ON entries(completed);
+
+	CREATE TABLE IF NOT EXISTS segments (
+		entry_id TEXT NOT NULL,
+		seq      INTEGER NOT NULL,
+		start_at TEXT NOT NULL,
+		end_at   TEXT,
+		PRIMARY KEY (entry_id, seq)
+	);
+
+	CREATE TABLE IF NOT EXISTS invoices (
+		id         TEXT NOT NULL,
+		project_id TEXT NOT NULL,
+		status     TEXT NOT NULL,
+		data       TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS invoices_id ON invoices(id);
+	CREATE INDEX IF NOT EXISTS invoices_project ON invoices(project_id);
+
+	CREATE TABLE IF NOT EXISTS expenses (
+		id         TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		date       INTEGER NOT NULL,
+		invoice_id TEXT NOT NULL DEFAULT '',
+		data       TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS expenses_project_date ON expenses(project_id, date);
+
+	CREATE TABLE IF NOT EXISTS settings (
+		id   INTEGER PRIMARY KEY CHECK (id = 1),
+		data TEXT NOT NULL
+	);
+`
+
+// sqliteIndexes covers columns added by migrations, so it runs after them
+const sqliteIndexes = `
+	CREATE INDEX IF NOT EXISTS entries_invoice ON entries(invoice_id);
+	CREATE INDEX IF NOT EXISTS expenses_invoice ON expenses(invoice_id);
+`
+
+// SQLiteStore keeps data in a SQLite database. Every record is stored whole
+// as JSON in a data column so model fields round-trip without schema changes;
+// the remaining columns copy the fields used for lookups and filtering.
+// Segments live in their own table. Rows are returned in insertion order.
+type SQLiteStore struct {
+	path string
+	db   *sql.DB
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// NewSQLite opens or creates the SQLite database at path
+func NewSQLite(path string) (*SQLiteStore, error) {
+	// Write transactions take the database lock up front so concurrent
+	// processes wait on busy_timeout instead of failing mid-transaction
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating tables: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > sqliteSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("database schema version %d is newer than supported version %d", version, sqliteSchemaVersion)
+	}
+	s := &SQLiteStore{path: path, db: db}
+	if version < sqliteSchemaVersion {
+		if err := s.migrate(version); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migrating database: %w", err)
+		}
+	}
+	if _, err := db.Exec(sqliteIndexes); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating indexes: %w", err)
+	}
+	return s, nil
+}
+
+// migrate brings the schema from version up to date in one transaction
+func (s *SQLiteStore) migrate(version int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if version > 0 {
+			for _, m := range sqliteMigrations[version-1:] {
+				if err := m(tx); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion))
+		return err
+	})
+}
+
+// migrateSQLiteFromV1 converts project rates and invoice amounts from
+// float dollars to integer minor units, as in JSON data version 3
+func migrateSQLiteFromV1(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT data FROM projects")
+	if err != nil {
+		return err
+	}
+	var projects []v2Project
+	for rows.Next() {
+		var data string
+		var p v2Project
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			rows.Close()
+			return err
+		}
+		projects = append(projects, p)
+	}
+	rows.Close()
+	for _, old := range projects {
+		p := convertV2Project(old)
+		if err := putProject(tx, &p); err != nil {
+			return err
+		}
+	}
+
+	rows, err = tx.Query("SELECT rowid, data FROM invoices")
+	if err != nil {
+		return err
+	}
+	invoices := make(map[int64]v2Invoice)
+	for rows.Next() {
+		var rowid int64
+		var data string
+		var inv v2Invoice
+		if err := rows.Scan(&rowid, &data); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(data), &inv); err != nil {
+			rows.Close()
+			return err
+		}
+		invoices[rowid] = inv
+	}
+	rows.Close()
+	for rowid, old := range invoices {
+		inv := convertV2Invoice(old)
+		if err := putInvoice(tx, rowid, &inv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSQLiteFromV2 adds the invoice_id columns linking entries and
+// expenses to the invoice that billed them. A table newer than the database
+// was just created by sqliteSchema and has the column already.
+func migrateSQLiteFromV2(tx *sql.Tx) error {
+	for _, table := range []string{"entries", "expenses"} {
+		exists, err := hasColumn(tx, table, "invoice_id")
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN invoice_id TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether table has the named column
+func hasColumn(q querier, table, column string) (bool, error) {
+	rows, err := q.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// migrateSQLiteFromV3 moves the billing contacts kept on projects into
+// clients, as in JSON data version 4
+func migrateSQLiteFromV3(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT data FROM projects ORDER BY rowid")
+	if err != nil {
+		return err
+	}
+	var projects []model.Project
+	var legacy []legacyProject
+	for rows.Next() {
+		var data string
+		var p model.Project
+		var lp legacyProject
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(data), &lp); err != nil {
+			rows.Close()
+			return err
+		}
+		projects = append(projects, p)
+		legacy = append(legacy, lp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range clientsFromContacts(projects, legacy, time.Now()) {
+		if err := putClient(tx, &c); err != nil {
+			return err
+		}
+	}
+	// Rewriting every project also drops the old contact from its data
+	for i := range projects {
+		if err := putProject(tx, &projects[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// withTx runs fn in a write transaction, rolling back if it fails
+func (s *SQLiteStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// Clients
+
+func getClient(q querier, idOrName string) (*model.Client, error) {
+	var data string
+	err := q.QueryRow("SELECT data FROM clients WHERE id = ? OR name = ? ORDER BY rowid LIMIT 1",
+		idOrName, idOrName).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c model.Client
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func listClients(q querier) ([]model.Client, error) {
+	rows, err := q.Query("SELECT data FROM clients ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Client
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var c model.Client
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+func putClient(q querier, c *model.Client) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT INTO clients (id, name, data) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, data = excluded.data`,
+		c.ID, c.Name, string(data))
+	return err
+}
+
+// Projects
+
+func getProject(q querier, idOrName string) (*model.Project, error) {
+	var data string
+	err := q.QueryRow("SELECT data FROM projects WHERE id = ? OR name = ? ORDER BY rowid LIMIT 1",
+		idOrName, idOrName).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p model.Project
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func listProjects(q querier) ([]model.Project, error) {
+	rows, err := q.Query("SELECT data FROM projects ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Project
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var p model.Project
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+func putProject(q querier, p *model.Project) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT INTO projects (id, name, data) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, data = excluded.data`,
+		p.ID, p.Name, string(data))
+	return err
+}
+
+// projectIDs returns the IDs an entry or invoice may carry to match the
+// project filter idOrName, mirroring the JSON store's lookup by ID or name
+func projectIDs(q querier, idOrName string) []any {
+	ids := []any{idOrName}
+	if p, err := getProject(q, idOrName); err == nil {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// Entries
+
+// queryEntries returns the entries matching the where clause, with their
+// segments, in insertion order
+func queryEntries(q querier, where string, args ...any) ([]model.Entry, error) {
+	rows, err := q.Query("SELECT data FROM entries"+where+" ORDER BY rowid", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Entry
+	index := make(map[string]int)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var e model.Entry
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, err
+		}
+		index[e.ID] = len(result)
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	segRows, err := q.Query("SELECT entry_id, start_at, end_at FROM segments WHERE entry_id IN (SELECT id FROM entries"+where+") ORDER BY entry_id, seq", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer segRows.Close()
+
+	for segRows.Next() {
+		var entryID, startStr string
+		var endStr sql.NullString
+		if err := segRows.Scan(&entryID, &startStr, &endStr); err != nil {
+			return nil, err
+		}
+		i, ok := index[entryID]
+		if !ok {
+			continue
+		}
+		start, err := parseTime(startStr)
+		if err != nil {
+			return nil, err
+		}
+		seg := model.TimeSegment{Start: start}
+		if endStr.Valid {
+			end, err := parseTime(endStr.String)
+			if err != nil {
+				return nil, err
+			}
+			seg.End = &end
+		}
+		result[i].Segments = append(result[i].Segments, seg)
+	}
+	return result, segRows.Err()
+}
+
+func getEntry(q querier, id string) (*model.Entry, error) {
+	entries, err := queryEntries(q, " WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrEntryNotFound
+	}
+	return &entries[0], nil
+}
+
+// activeEntry returns the running entry, or else the most recently paused,
+// or nil if there is neither
+func activeEntry(q querier) (*model.Entry, error) {
+	entries, err := unfinishedEntries(q)
+	if err != nil {
+		return nil, err
+	}
+	if i := activeIndex(entries); i >= 0 {
+		return &entries[i], nil
+	}
+	return nil, nil
+}
+
+// unfinishedEntries returns the entries that are running or paused
+func unfinishedEntries(q querier) ([]model.Entry, error) {
+	return queryEntries(q, " WHERE completed = 0")
+}
+
+func putEntry(q querier, e *model.Entry) error {
+	row := *e
+	row.Segments = nil
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT INTO entries (id, project_id, start_time, completed, invoice_id, data) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET project_id = excluded.project_id, start_time = excluded.start_time,
+			completed = excluded.completed, invoice_id = excluded.invoice_id, data = excluded.data`,
+		e.ID, e.ProjectID, e.StartTime().UnixMicro(), e.Completed, e.InvoiceID, string(data))
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.Exec("DELETE FROM segments WHERE entry_id = ?", e.ID); err != nil {
+		return err
+	}
+	for i, seg := range e.Segments {
+		var end any
+		if seg.End != nil {
+			end = formatTime(*seg.End)
+		}
+		if _, err := q.Exec("INSERT INTO segments (entry_id, seq, start_at, end_at) VALUES (?, ?, ?, ?)",
+			e.ID, i, formatTime(seg.Start), end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invoices
+
+// getInvoice returns the first invoice with the given ID and its rowid
+func getInvoice(q querier, id string) (int64, *model.Invoice, error) {
+	var rowid int64
+	var data string
+	err := q.QueryRow("SELECT rowid, data FROM invoices WHERE id = ? ORDER BY rowid LIMIT 1", id).Scan(&rowid, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	var inv model.Invoice
+	if err := json.Unmarshal([]byte(data), &inv); err != nil {
+		return 0, nil, err
+	}
+	return rowid, &inv, nil
+}
+
+func queryInvoices(q querier, where string, args ...any) ([]model.Invoice, error) {
+	rows, err := q.Query("SELECT data FROM invoices"+where+" ORDER BY rowid", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Invoice
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var inv model.Invoice
+		if err := json.Unmarshal([]byte(data), &inv); err != nil {
+			return nil, err
+		}
+		result = append(result, inv)
+	}
+	return result, rows.Err()
+}
+
+// putInvoice inserts inv, or updates the row with the given rowid if non-zero
+func putInvoice(q querier, rowid int64, inv *model.Invoice) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	if rowid == 0 {
+		_, err = q.Exec("INSERT INTO invoices (id, project_id, status, data) VALUES (?, ?, ?, ?)",
+			inv.ID, inv.ProjectID, string(inv.Status), string(data))
+		return err
+	}
+	_, err = q.Exec("UPDATE invoices SET id = ?, project_id = ?, status = ?, data = ? WHERE rowid = ?",
+		inv.ID, inv.ProjectID, string(inv.Status), string(data), rowid)
+	return err
+}
+
+// Expenses
+
+func queryExpenses(q querier, where string, args ...any) ([]model.Expense, error) {
+	rows, err := q.Query("SELECT data FROM expenses"+where+" ORDER BY rowid", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Expense
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var e model.Expense
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+func putExpense(q querier, e *model.Expense) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT INTO expenses (id, project_id, date, invoice_id, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET project_id = excluded.project_id, date = excluded.date,
+			invoice_id = excluded.invoice_id, data = excluded.data`,
+		e.ID, e.ProjectID, e.Date.UnixMicro(), e.InvoiceID, string(data))
+	return err
+}
+
+// Settings
+
+func getSettings(q querier) (*model.Settings, error) {
+	var data string
+	err := q.QueryRow("SELECT data FROM settings WHERE id = 1").Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var settings model.Settings
+	if err := json.Unmarshal([]byte(data), &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func putSettings(q querier, settings *model.Settings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT INTO settings (id, data) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data`, string(data))
+	return err
+}
+
+// AddProject creates a new project
+func (s *SQLiteStore) AddProject(name string, hourlyRate int64, description string) (*model.Project, error) {
+	p := model.Project{
+		ID:          generateID(),
+		Name:        name,
+		HourlyRate:  hourlyRate,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+	if err := putProject(s.db, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetProject returns a project by ID or name
+func (s *SQLiteStore) GetProject(idOrName string) (*model.Project, error) {
+	return getProject(s.db, idOrName)
+}
+
+// ListProjects returns all projects
+func (s *SQLiteStore) ListProjects() []model.Project {
+	projects, _ := listProjects(s.db)
+	return projects
+}
+
+// UpdateProject updates a project's fields
+func (s *SQLiteStore) UpdateProject(idOrName string, updates func(*model.Project)) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		p, err := getProject(tx, idOrName)
+		if err != nil {
+			return err
+		}
+		updates(p)
+		return putProject(tx, p)
+	})
+}
+
+// AddClient creates a new client
+func (s *SQLiteStore) AddClient(name string) (*model.Client, error) {
+	c := model.Client{
+		ID:        generateID(),
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	if err := putClient(s.db, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetClient returns a client by ID or name
+func (s *SQLiteStore) GetClient(idOrName string) (*model.Client, error) {
+	return getClient(s.db, idOrName)
+}
+
+// ListClients returns all clients
+func (s *SQLiteStore) ListClients() []model.Client {
+	clients, _ := listClients(s.db)
+	return clients
+}
+
+// UpdateClient updates a client's fields
+func (s *SQLiteStore) UpdateClient(idOrName string, updates func(*model.Client)) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		c, err := getClient(tx, idOrName)
+		if err != nil {
+			return err
+		}
+		updates(c)
+		return putClient(tx, c)
+	})
+}
+
+// SetBillingContact sets the contact of a project's client, creating the
+// client if the project has none
+func (s *SQLiteStore) SetBillingContact(projectIDOrName string, contact *model.ContactInfo, updates func(*model.Project)) (*model.Client, error) {
+	var client *model.Client
+	err := s.withTx(func(tx *sql.Tx) error {
+		p, err := getProject(tx, projectIDOrName)
+		if err != nil {
+			return err
+		}
+		if p.ClientID != "" {
+			client, err = getClient(tx, p.ClientID)
+			if err != nil && !errors.Is(err, ErrClientNotFound) {
+				return err
+			}
+		}
+		if client == nil {
+			if _, err := getClient(tx, p.Name); err == nil {
+				return ErrClientExists
+			} else if !errors.Is(err, ErrClientNotFound) {
+				return err
+			}
+			client = &model.Client{ID: generateID(), Name: p.Name, CreatedAt: time.Now()}
+		}
+		client.Contact = contact
+		if err := putClient(tx, client); err != nil {
+			return err
+		}
+		p.ClientID = client.ID
+		if updates != nil {
+			updates(p)
+		}
+		return putProject(tx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// StartEntry starts a new time entry
+func (s *SQLiteStore) StartEntry(projectID, note string, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		project, err := getProject(tx, projectID)
+		if err != nil {
+			return err
+		}
+		unfinished, err := unfinishedEntries(tx)
+		if err != nil {
+			return err
+		}
+		settings, err := getSettings(tx)
+		if err != nil {
+			return err
+		}
+		if err := checkStart(unfinished, project.ID, settings.PausePerProject()); err != nil {
+			return err
+		}
+
+		entry = model.Entry{
+			ID:        generateID(),
+			ProjectID: project.ID,
+			Note:      note,
+			Segments: []model.TimeSegment{
+				{Start: time.Now()},
+			},
+		}
+		if updates != nil {
+			updates(&entry)
+		}
+		return putEntry(tx, &entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// SwitchEntry stops or pauses the running entry and starts another
+func (s *SQLiteStore) SwitchEntry(projectID, note string, pause bool, updates func(*model.Entry)) (*model.Entry, *model.Entry, error) {
+	var previous *model.Entry
+	var entry model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		project, err := getProject(tx, projectID)
+		if err != nil {
+			return err
+		}
+		unfinished, err := unfinishedEntries(tx)
+		if err != nil {
+			return err
+		}
+		settings, err := getSettings(tx)
+		if err != nil {
+			return err
+		}
+		i, err := switchTarget(unfinished, project.ID, pause, settings.PausePerProject())
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if i >= 0 {
+			previous = &unfinished[i]
+			switchFrom(previous, now, pause)
+			if err := putEntry(tx, previous); err != nil {
+				return err
+			}
+		}
+
+		entry = model.Entry{
+			ID:        generateID(),
+			ProjectID: project.ID,
+			Note:      note,
+			Segments:  []model.TimeSegment{{Start: now}},
+		}
+		if updates != nil {
+			updates(&entry)
+		}
+		return putEntry(tx, &entry)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return previous, &entry, nil
+}
+
+// StopEntry stops the running entry, or else the most recently paused
+func (s *SQLiteStore) StopEntry(note string) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		entry, err = activeEntry(tx)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return ErrNoActiveEntry
+		}
+		stopEntry(entry, time.Now(), note)
+		return putEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// TrimEntry closes the open segment of the running entry at an earlier time
+func (s *SQLiteStore) TrimEntry(at time.Time, pause bool) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		entry, err = activeEntry(tx)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return ErrNoActiveEntry
+		}
+		if err := trimEntry(entry, at, time.Now(), pause); err != nil {
+			return err
+		}
+		return putEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// PauseEntry pauses the current running entry
+func (s *SQLiteStore) PauseEntry() (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		unfinished, err := unfinishedEntries(tx)
+		if err != nil {
+			return err
+		}
+		i := runningIndex(unfinished)
+		if i < 0 {
+			if activeIndex(unfinished) >= 0 {
+				return ErrAlreadyPaused
+			}
+			return ErrNoActiveEntry
+		}
+		now := time.Now()
+		entry = &unfinished[i]
+		entry.Segments[len(entry.Segments)-1].End = &now
+		return putEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ResumeEntry resumes the paused entry on a project, or the only one
+func (s *SQLiteStore) ResumeEntry(projectID string) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		if projectID != "" {
+			project, err := getProject(tx, projectID)
+			if err != nil {
+				return err
+			}
+			projectID = project.ID
+		}
+		unfinished, err := unfinishedEntries(tx)
+		if err != nil {
+			return err
+		}
+		i, err := pausedIndex(unfinished, projectID)
+		if err != nil {
+			return err
+		}
+		entry = &unfinished[i]
+		entry.Segments = append(entry.Segments, model.TimeSegment{Start: time.Now()})
+		return putEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// LogEntry creates a completed time entry
+func (s *SQLiteStore) LogEntry(projectID, note string, start, end time.Time, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		if _, err := getProject(tx, projectID); err != nil {
+			return err
+		}
+		entry = model.Entry{
+			ID:        generateID(),
+			ProjectID: projectID,
+			Note:      note,
+			Segments: []model.TimeSegment{
+				{Start: start, End: &end},
+			},
+			Completed: true,
+		}
+		if updates != nil {
+			updates(&entry)
+		}
+		entries, err := queryEntries(tx, "")
+		if err != nil {
+			return err
+		}
+		if err := checkSegments(&entry, entries, time.Now()); err != nil {
+			return err
+		}
+		return putEntry(tx, &entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ActiveEntry returns the running entry, or else the most recently paused
+func (s *SQLiteStore) ActiveEntry() *model.Entry {
+	entry, _ := activeEntry(s.db)
+	return entry
+}
+
+// PausedEntries returns the paused entries
+func (s *SQLiteStore) PausedEntries() []model.Entry {
+	unfinished, _ := unfinishedEntries(s.db)
+	var result []model.Entry
+	for _, e := range unfinished {
+		if e.IsPaused() {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// ListEntries returns entries, optionally filtered by project and date range
+func (s *SQLiteStore) ListEntries(projectID string, from, to *time.Time) []model.Entry {
+	var conds []string
+	var args []any
+	if projectID != "" {
+		ids := projectIDs(s.db, projectID)
+		conds = append(conds, "project_id IN ("+placeholders(len(ids))+")")
+		args = append(args, ids...)
+	}
+	if from != nil {
+		conds = append(conds, "start_time >= ?")
+		args = append(args, from.UnixMicro())
+	}
+	if to != nil {
+		conds = append(conds, "start_time <= ?")
+		args = append(args, to.UnixMicro())
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	entries, _ := queryEntries(s.db, where, args...)
+	return entries
+}
+
+// DeleteEntry removes an entry by ID
+func (s *SQLiteStore) DeleteEntry(id string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM entries WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrEntryNotFound
+		}
+		_, err = tx.Exec("DELETE FROM segments WHERE entry_id = ?", id)
+		return err
+	})
+}
+
+// AmendEntry updates the note on a completed entry by index (1=most recent)
+func (s *SQLiteStore) AmendEntry(index int, note string) (*model.Entry, error) {
+	if index <= 0 {
+		return nil, ErrInvalidIndex
+	}
+
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		var id string
+		err := tx.QueryRow("SELECT id FROM entries WHERE completed = 1 ORDER BY rowid DESC LIMIT 1 OFFSET ?",
+			index-1).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidIndex
+		}
+		if err != nil {
+			return err
+		}
+
+		entry, err = getEntry(tx, id)
+		if err != nil {
+			return err
+		}
+		entry.Note = note
+		return putEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// UpdateEntry applies updates to the entry with the given ID
+func (s *SQLiteStore) UpdateEntry(id string, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		entry, err = getEntry(tx, id)
+		if err != nil {
+			return err
+		}
+		updates(entry)
+		return putEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// EditEntry changes a completed entry's project, segments and other
+// fields in one write
+func (s *SQLiteStore) EditEntry(id, projectID string, segments []model.TimeSegment, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		entry, err = getEntry(tx, id)
+		if err != nil {
+			return err
+		}
+		if projectID != "" {
+			project, err := getProject(tx, projectID)
+			if err != nil {
+				return err
+			}
+			projectID = project.ID
+		}
+		entries, err := queryEntries(tx, "")
+		if err != nil {
+			return err
+		}
+		if err := editEntry(entry, projectID, segments, updates, entries, time.Now()); err != nil {
+			return err
+		}
+		return putEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// SplitEntry splits a completed entry in two at a time within it
+func (s *SQLiteStore) SplitEntry(id string, at time.Time, updates func(*model.Entry)) (*model.Entry, *model.Entry, error) {
+	var entry, rest *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		entry, err = getEntry(tx, id)
+		if err != nil {
+			return err
+		}
+		if rest, err = splitEntry(entry, at, updates); err != nil {
+			return err
+		}
+		if err := putEntry(tx, entry); err != nil {
+			return err
+		}
+		return putEntry(tx, rest)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, rest, nil
+}
+
+// MergeEntries merges two adjacent completed entries into the earlier one
+func (s *SQLiteStore) MergeEntries(id, otherID string, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		entry, err = getEntry(tx, id)
+		if err != nil {
+			return err
+		}
+		other, err := getEntry(tx, otherID)
+		if err != nil {
+			return err
+		}
+		if other.StartTime().Before(entry.StartTime()) {
+			entry, other = other, entry
+		}
+		entries, err := queryEntries(tx, "")
+		if err != nil {
+			return err
+		}
+		if err := mergeEntries(entry, other, entries, updates); err != nil {
+			return err
+		}
+		if err := putEntry(tx, entry); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM entries WHERE id = ?", other.ID); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM segments WHERE entry_id = ?", other.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetSettings returns the current settings
+func (s *SQLiteStore) GetSettings() *model.Settings {
+	settings, _ := getSettings(s.db)
+	if settings == nil {
+		return &model.Settings{}
+	}
+	return settings
+}
+
+// SetUserContact updates the user's contact info
+func (s *SQLiteStore) SetUserContact(contact *model.ContactInfo) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		settings, err := getSettings(tx)
+		if err != nil {
+			return err
+		}
+		if settings == nil {
+			settings = &model.Settings{}
+		}
+		settings.UserContact = contact
+		return putSettings(tx, settings)
+	})
+}
+
+// UpdateSettings applies updates to the settings
+func (s *SQLiteStore) UpdateSettings(updates func(*model.Settings)) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		settings, err := getSettings(tx)
+		if err != nil {
+			return err
+		}
+		if settings == nil {
+			settings = &model.Settings{}
+		}
+		updates(settings)
+		return putSettings(tx, settings)
+	})
+}
+
+// SaveInvoice saves a new invoice record and marks its entries and
+// expenses as billed
+func (s *SQLiteStore) SaveInvoice(inv *model.Invoice, entryIDs, expenseIDs []string) error {
+	inv.CreatedAt = time.Now()
+	if inv.Status == "" {
+		inv.Status = model.InvoiceStatusPending
+	}
+	return s.withTx(func(tx *sql.Tx) error {
+		settings, err := getSettings(tx)
+		if err != nil {
+			return err
+		}
+		if settings == nil {
+			settings = &model.Settings{}
+		}
+		var lookupErr error
+		exists := func(id string) bool {
+			_, _, err := getInvoice(tx, id)
+			if err != nil && !errors.Is(err, ErrInvoiceNotFound) {
+				lookupErr = err
+			}
+			return err == nil
+		}
+		allocate := inv.ID == ""
+		if err := assignInvoiceID(settings, inv, exists); err != nil {
+			return err
+		}
+		if lookupErr != nil {
+			return lookupErr
+		}
+		if allocate {
+			if err := putSettings(tx, settings); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range entryIDs {
+			e, err := getEntry(tx, id)
+			if err != nil {
+				return fmt.Errorf("entry %s: %w", id, err)
+			}
+			if e.IsBilled() {
+				return fmt.Errorf("entry %s: %w", id, ErrAlreadyBilled)
+			}
+			e.InvoiceID = inv.ID
+			if err := putEntry(tx, e); err != nil {
+				return err
+			}
+		}
+		for _, id := range expenseIDs {
+			expenses, err := queryExpenses(tx, " WHERE id = ?", id)
+			if err != nil {
+				return err
+			}
+			if len(expenses) == 0 {
+				return fmt.Errorf("expense %s: %w", id, ErrExpenseNotFound)
+			}
+			e := &expenses[0]
+			if e.InvoiceID != "" {
+				return fmt.Errorf("expense %s: %w", id, ErrAlreadyBilled)
+			}
+			e.InvoiceID = inv.ID
+			if err := putExpense(tx, e); err != nil {
+				return err
+			}
+		}
+		if inv.IsCreditNote() {
+			rowid, original, err := getInvoice(tx, inv.CreditFor)
+			if err != nil {
+				return fmt.Errorf("invoice %s: %w", inv.CreditFor, err)
+			}
+			if err := applyCreditNote(original, inv); err != nil {
+				return err
+			}
+			if err := putInvoice(tx, rowid, original); err != nil {
+				return err
+			}
+		}
+		return putInvoice(tx, 0, inv)
+	})
+}
+
+// GetInvoice returns an invoice by ID
+func (s *SQLiteStore) GetInvoice(id string) (*model.Invoice, error) {
+	_, inv, err := getInvoice(s.db, id)
+	return inv, err
+}
+
+// ListInvoices returns invoices, optionally filtered by project and/or status
+func (s *SQLiteStore) ListInvoices(projectID string, status model.InvoiceStatus) []model.Invoice {
+	var conds []string
+	var args []any
+	if projectID != "" {
+		ids := projectIDs(s.db, projectID)
+		conds = append(conds, "project_id IN ("+placeholders(len(ids))+")")
+		args = append(args, ids...)
+	}
+	if status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, string(status))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	invoices, _ := queryInvoices(s.db, where, args...)
+	return invoices
+}
+
+// updateInvoice applies fn to the invoice with the given ID in a
+// transaction and returns the result
+func (s *SQLiteStore) updateInvoice(id string, fn func(tx *sql.Tx, inv *model.Invoice) error) (*model.Invoice, error) {
+	var inv *model.Invoice
+	err := s.withTx(func(tx *sql.Tx) error {
+		rowid, found, err := getInvoice(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx, found); err != nil {
+			return err
+		}
+		inv = found
+		return putInvoice(tx, rowid, inv)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// releaseInvoice clears the invoice from the entries and expenses it billed
+func releaseInvoice(tx *sql.Tx, id string) error {
+	entries, err := queryEntries(tx, " WHERE invoice_id = ?", id)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].InvoiceID = ""
+		if err := putEntry(tx, &entries[i]); err != nil {
+			return err
+		}
+	}
+	expenses, err := queryExpenses(tx, " WHERE invoice_id = ?", id)
+	if err != nil {
+		return err
+	}
+	for i := range expenses {
+		expenses[i].InvoiceID = ""
+		if err := putExpense(tx, &expenses[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarkInvoicePaid records a payment of the whole balance today
+func (s *SQLiteStore) MarkInvoicePaid(id string) (*model.Invoice, error) {
+	return s.updateInvoice(id, func(_ *sql.Tx, inv *model.Invoice) error {
+		return inv.AddPayment(model.Payment{Date: time.Now(), Amount: inv.Balance()})
+	})
+}
+
+// RecordPayment records a payment against an invoice
+func (s *SQLiteStore) RecordPayment(id string, payment model.Payment) (*model.Invoice, error) {
+	return s.updateInvoice(id, func(_ *sql.Tx, inv *model.Invoice) error {
+		return inv.AddPayment(payment)
+	})
+}
+
+// VoidInvoice cancels an unpaid invoice and releases its entries and
+// expenses
+func (s *SQLiteStore) VoidInvoice(id string) (*model.Invoice, error) {
+	return s.updateInvoice(id, func(tx *sql.Tx, inv *model.Invoice) error {
+		if err := voidInvoice(inv, time.Now()); err != nil {
+			return err
+		}
+		return releaseInvoice(tx, id)
+	})
+}
+
+// DeleteInvoice removes an invoice by ID and releases its entries and
+// expenses. Deleting a credit note takes its credit off the invoice it
+// credited; an invoice with a credit note cannot be deleted.
+func (s *SQLiteStore) DeleteInvoice(id string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		rowid, inv, err := getInvoice(tx, id)
+		if err != nil {
+			return err
+		}
+		var notes int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM invoices WHERE json_extract(data, '$.credit_for') = ?", id).Scan(&notes); err != nil {
+			return err
+		}
+		if notes > 0 {
+			return fmt.Errorf("invoice %s: %w", id, ErrCredited)
+		}
+		if inv.IsCreditNote() {
+			origRowid, original, err := getInvoice(tx, inv.CreditFor)
+			if err != nil && !errors.Is(err, ErrInvoiceNotFound) {
+				return err
+			}
+			if original != nil {
+				revokeCreditNote(original, inv)
+				if err := putInvoice(tx, origRowid, original); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM invoices WHERE rowid = ?", rowid); err != nil {
+			return err
+		}
+		return releaseInvoice(tx, id)
+	})
+}
+
+// AddExpense records a billable expense against a project
+func (s *SQLiteStore) AddExpense(expense model.Expense) (*model.Expense, error) {
+	expense.ID = generateID()
+	expense.CreatedAt = time.Now()
+	if err := putExpense(s.db, &expense); err != nil {
+		return nil, err
+	}
+	return &expense, nil
+}
+
+// ListExpenses returns expenses, optionally filtered by project and date range
+func (s *SQLiteStore) ListExpenses(projectID string, from, to *time.Time) []model.Expense {
+	var conds []string
+	var args []any
+	if projectID != "" {
+		ids := projectIDs(s.db, projectID)
+		conds = append(conds, "project_id IN ("+placeholders(len(ids))+")")
+		args = append(args, ids...)
+	}
+	if from != nil {
+		conds = append(conds, "date >= ?")
+		args = append(args, from.UnixMicro())
+	}
+	if to != nil {
+		conds = append(conds, "date <= ?")
+		args = append(args, to.UnixMicro())
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	expenses, _ := queryExpenses(s.db, where, args...)
+	return expenses
+}
+
+// UpdateExpense applies updates to an expense by ID
+func (s *SQLiteStore) UpdateExpense(id string, updates func(*model.Expense)) (*model.Expense, error) {
+	var expense *model.Expense
+	err := s.withTx(func(tx *sql.Tx) error {
+		expenses, err := queryExpenses(tx, " WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		if len(expenses) == 0 {
+			return ErrExpenseNotFound
+		}
+		expense = &expenses[0]
+		updates(expense)
+		return putExpense(tx, expense)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// DeleteExpense removes an expense by ID
+func (s *SQLiteStore) DeleteExpense(id string) error {
+	res, err := s.db.Exec("DELETE FROM expenses WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrExpenseNotFound
+	}
+	return nil
+}
+
+// Snapshot returns a copy of all stored data
+func (s *SQLiteStore) Snapshot() (*model.Data, error) {
+	var data *model.Data
+	err := s.withTx(func(tx *sql.Tx) error {
+		clients, err := listClients(tx)
+		if err != nil {
+			return err
+		}
+		projects, err := listProjects(tx)
+		if err != nil {
+			return err
+		}
+		entries, err := queryEntries(tx, "")
+		if err != nil {
+			return err
+		}
+		invoices, err := queryInvoices(tx, "")
+		if err != nil {
+			return err
+		}
+		expenses, err := queryExpenses(tx, "")
+		if err != nil {
+			return err
+		}
+		settings, err := getSettings(tx)
+		if err != nil {
+			return err
+		}
+		data = &model.Data{
+			Version:  CurrentVersion,
+			Clients:  clients,
+			Projects: projects,
+			Entries:  entries,
+			Invoices: invoices,
+			Expenses: expenses,
+			Settings: settings,
+		}
+		return nil
+	})
+	return data, err
+}
+
+// Restore replaces all stored data with data
+func (s *SQLiteStore) Restore(data *model.Data) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		for _, table := range []string{"clients", "projects", "entries", "segments", "invoices", "expenses", "settings"} {
+			if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+				return err
+			}
+		}
+		for i := range data.Clients {
+			if err := putClient(tx, &data.Clients[i]); err != nil {
+				return err
+			}
+		}
+		for i := range data.Projects {
+			if err := putProject(tx, &data.Projects[i]); err != nil {
+				return err
+			}
+		}
+		for i := range data.Entries {
+			if err := putEntry(tx, &data.Entries[i]); err != nil {
+				return err
+			}
+		}
+		for i := range data.Invoices {
+			if err := putInvoice(tx, 0, &data.Invoices[i]); err != nil {
+				return err
+			}
+		}
+		for i := range data.Expenses {
+			if err := putExpense(tx, &data.Expenses[i]); err != nil {
+				return err
+			}
+		}
+		if data.Settings != nil {
+			return putSettings(tx, data.Settings)
+		}
+		return nil
+	})
+}
@@ -1,59 +1,45 @@
 package storage
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"time"
 
 	"watchmen/internal/model"
+	"watchmen/internal/money"
 )
 
-const CurrentVersion = 2
-
-var (
-	ErrProjectNotFound = errors.New("project not found")
-	ErrEntryNotFound   = errors.New("entry not found")
-	ErrInvoiceNotFound = errors.New("invoice not found")
-	ErrNoActiveEntry   = errors.New("no active time entry")
-	ErrActiveEntry     = errors.New("there is already an active time entry")
-	ErrNoPausedEntry   = errors.New("no paused time entry")
-	ErrNotPaused       = errors.New("entry is not paused")
-	ErrAlreadyPaused   = errors.New("entry is already paused")
-	ErrInvalidIndex    = errors.New("invalid entry index")
-)
+const CurrentVersion = 4
+
+// MaxBackups is the number of timestamped backups of the data file kept in
+// the backups directory next to it. Older backups are removed on save.
+const MaxBackups = 10
 
-// Store manages the JSON data file
-type Store struct {
+// JSONStore keeps all data in a single JSON file
+type JSONStore struct {
 	path string
 	data model.Data
 }
 
-// New creates a new Store, loading existing data if present
-func New(path string) (*Store, error) {
-	s := &Store{path: path}
+// New creates a new JSONStore, loading existing data if present
+func New(path string) (*JSONStore, error) {
+	s := &JSONStore{path: path}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	if err := s.load(); err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
-// DefaultPath returns the default data file path
-func DefaultPath() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	dir := filepath.Join(home, ".watchmen")
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", err
-	}
-	return filepath.Join(dir, "data.json"), nil
-}
-
 // v1Entry represents the old entry format for migration
 type v1Entry struct {
 	ID        string     `json:"id"`
@@ -66,12 +52,48 @@ type v1Entry struct {
 // v1Data represents the old data format for migration
 type v1Data struct {
 	Version  int             `json:"version"`
-	Projects []model.Project `json:"projects"`
+	Projects []v2Project     `json:"projects"`
 	Entries  []v1Entry       `json:"entries"`
 	Settings *model.Settings `json:"settings,omitempty"`
 }
 
-func (s *Store) load() error {
+// v2Project represents a project before money was stored in minor units
+type v2Project struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	HourlyRate    float64   `json:"hourly_rate"`
+	Description   string    `json:"description,omitempty"`
+	PurchaseOrder string    `json:"purchase_order,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// v2Invoice represents an invoice before money was stored in minor units
+type v2Invoice struct {
+	ID          string              `json:"id"`
+	ProjectID   string              `json:"project_id"`
+	ProjectName string              `json:"project_name"`
+	CreatedAt   time.Time           `json:"created_at"`
+	PeriodStart time.Time           `json:"period_start"`
+	PeriodEnd   time.Time           `json:"period_end"`
+	Hours       float64             `json:"hours"`
+	Rate        float64             `json:"rate"`
+	Amount      float64             `json:"amount"`
+	Status      model.InvoiceStatus `json:"status"`
+	PaidAt      *time.Time          `json:"paid_at,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Condensed   bool                `json:"condensed,omitempty"`
+}
+
+// v2Data represents the version 2 data format for migration
+type v2Data struct {
+	Version  int             `json:"version"`
+	Projects []v2Project     `json:"projects"`
+	Entries  []model.Entry   `json:"entries"`
+	Invoices []v2Invoice     `json:"invoices,omitempty"`
+	Settings *model.Settings `json:"settings,omitempty"`
+}
+
+func (s *JSONStore) load() error {
 	data, err := os.ReadFile(s.path)
 	if os.IsNotExist(err) {
 		s.data = model.Data{Version: CurrentVersion}
@@ -89,18 +111,56 @@ func (s *Store) load() error {
 		return err
 	}
 
-	// If version is 0 or 1, migrate from old format
+	// Older formats are migrated one version at a time
 	if versionCheck.Version < CurrentVersion {
-		return s.migrateFromV1(data)
+		return s.migrate(data, versionCheck.Version)
 	}
 
+	// Start from a clean value; Unmarshal would otherwise keep fields that
+	// are omitted from the file, such as an emptied invoice list
+	s.data = model.Data{}
 	return json.Unmarshal(data, &s.data)
 }
 
-func (s *Store) migrateFromV1(data []byte) error {
+func (s *JSONStore) migrate(data []byte, version int) error {
+	if version < 3 {
+		var v2 *v2Data
+		var err error
+		if version < 2 {
+			v2, err = migrateFromV1(data)
+		} else {
+			v2 = &v2Data{}
+			err = json.Unmarshal(data, v2)
+		}
+		if err != nil {
+			return err
+		}
+		s.data = *migrateFromV2(v2)
+	} else {
+		s.data = model.Data{}
+		if err := json.Unmarshal(data, &s.data); err != nil {
+			return err
+		}
+	}
+
+	// Version 4 moved billing contacts from projects to clients
+	var legacy struct {
+		Projects []legacyProject `json:"projects"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	s.data.Clients = append(s.data.Clients, clientsFromContacts(s.data.Projects, legacy.Projects, time.Now())...)
+	s.data.Version = CurrentVersion
+
+	// Save migrated data
+	return s.save()
+}
+
+func migrateFromV1(data []byte) (*v2Data, error) {
 	var old v1Data
 	if err := json.Unmarshal(data, &old); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Convert entries
@@ -119,34 +179,191 @@ func (s *Store) migrateFromV1(data []byte) error {
 		}
 	}
 
-	s.data = model.Data{
-		Version:  CurrentVersion,
+	return &v2Data{
+		Version:  2,
 		Projects: old.Projects,
 		Entries:  newEntries,
 		Settings: old.Settings,
+	}, nil
+}
+
+// migrateFromV2 converts float amounts to integer minor units. Before
+// version 3 every amount was in dollars. Billing contacts are moved to
+// clients afterwards.
+func migrateFromV2(old *v2Data) *model.Data {
+	data := &model.Data{
+		Version:  CurrentVersion,
+		Entries:  old.Entries,
+		Settings: old.Settings,
+	}
+	for _, p := range old.Projects {
+		data.Projects = append(data.Projects, convertV2Project(p))
+	}
+	for _, inv := range old.Invoices {
+		data.Invoices = append(data.Invoices, convertV2Invoice(inv))
 	}
+	return data
+}
 
-	// Save migrated data
+func convertV2Project(p v2Project) model.Project {
+	return model.Project{
+		ID:            p.ID,
+		Name:          p.Name,
+		HourlyRate:    money.FromMajor(p.HourlyRate, money.DefaultCurrency),
+		Description:   p.Description,
+		PurchaseOrder: p.PurchaseOrder,
+		CreatedAt:     p.CreatedAt,
+	}
+}
+
+func convertV2Invoice(inv v2Invoice) model.Invoice {
+	return model.Invoice{
+		ID:          inv.ID,
+		ProjectID:   inv.ProjectID,
+		ProjectName: inv.ProjectName,
+		CreatedAt:   inv.CreatedAt,
+		PeriodStart: inv.PeriodStart,
+		PeriodEnd:   inv.PeriodEnd,
+		Hours:       inv.Hours,
+		Rate:        money.FromMajor(inv.Rate, money.DefaultCurrency),
+		Amount:      money.FromMajor(inv.Amount, money.DefaultCurrency),
+		Status:      inv.Status,
+		PaidAt:      inv.PaidAt,
+		Description: inv.Description,
+		Condensed:   inv.Condensed,
+	}
+}
+
+// update runs fn as one load-modify-save cycle while holding the data file
+// lock. The data is reloaded first so that changes written by another process
+// since New are not overwritten.
+func (s *JSONStore) update(fn func() error) error {
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
 	return s.save()
 }
 
-func (s *Store) save() error {
+// save writes the data file atomically. The caller must hold the lock.
+func (s *JSONStore) save() error {
 	s.data.Version = CurrentVersion
 	data, err := json.MarshalIndent(s.data, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.path, data, 0644)
+	if err := s.backup(); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0644)
 }
 
-func generateID() string {
-	b := make([]byte, 8)
-	rand.Read(b)
-	return hex.EncodeToString(b)
+// BackupDir returns the directory holding backups of the data file at path
+func BackupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "backups")
+}
+
+// backup preserves the current data file as a timestamped backup and
+// removes all but the newest MaxBackups backups.
+func (s *JSONStore) backup() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	dir := BackupDir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	base := filepath.Base(s.path)
+	name := filepath.Join(dir, base+"."+time.Now().UTC().Format("20060102T150405.000000000"))
+	// The data file is always replaced by rename, never rewritten in place,
+	// so a hard link keeps the old contents intact.
+	if err := os.Link(s.path, name); err != nil {
+		if err := copyFile(s.path, name); err != nil {
+			return err
+		}
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, base+".*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(backups)
+	for len(backups) > MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and
+// renames it over path, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename itself survives a crash
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
 // AddProject creates a new project
-func (s *Store) AddProject(name string, hourlyRate float64, description string) (*model.Project, error) {
+func (s *JSONStore) AddProject(name string, hourlyRate int64, description string) (*model.Project, error) {
 	p := model.Project{
 		ID:          generateID(),
 		Name:        name,
@@ -154,12 +371,15 @@ func (s *Store) AddProject(name string, hourlyRate float64, description string)
 		Description: description,
 		CreatedAt:   time.Now(),
 	}
-	s.data.Projects = append(s.data.Projects, p)
-	return &p, s.save()
+	err := s.update(func() error {
+		s.data.Projects = append(s.data.Projects, p)
+		return nil
+	})
+	return &p, err
 }
 
 // GetProject returns a project by ID or name
-func (s *Store) GetProject(idOrName string) (*model.Project, error) {
+func (s *JSONStore) GetProject(idOrName string) (*model.Project, error) {
 	for i := range s.data.Projects {
 		if s.data.Projects[i].ID == idOrName || s.data.Projects[i].Name == idOrName {
 			return &s.data.Projects[i], nil
@@ -169,137 +389,295 @@ func (s *Store) GetProject(idOrName string) (*model.Project, error) {
 }
 
 // ListProjects returns all projects
-func (s *Store) ListProjects() []model.Project {
+func (s *JSONStore) ListProjects() []model.Project {
 	return s.data.Projects
 }
 
-// StartEntry starts a new time entry
-func (s *Store) StartEntry(projectID, note string) (*model.Entry, error) {
-	// Check for existing active or paused entry
-	for _, e := range s.data.Entries {
-		if e.IsRunning() || e.IsPaused() {
-			return nil, ErrActiveEntry
+// AddClient creates a new client
+func (s *JSONStore) AddClient(name string) (*model.Client, error) {
+	c := model.Client{
+		ID:        generateID(),
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	err := s.update(func() error {
+		s.data.Clients = append(s.data.Clients, c)
+		return nil
+	})
+	return &c, err
+}
+
+// GetClient returns a client by ID or name
+func (s *JSONStore) GetClient(idOrName string) (*model.Client, error) {
+	for i := range s.data.Clients {
+		if s.data.Clients[i].ID == idOrName || s.data.Clients[i].Name == idOrName {
+			return &s.data.Clients[i], nil
 		}
 	}
+	return nil, ErrClientNotFound
+}
+
+// ListClients returns all clients
+func (s *JSONStore) ListClients() []model.Client {
+	return s.data.Clients
+}
 
-	// Verify project exists
-	if _, err := s.GetProject(projectID); err != nil {
+// UpdateClient updates a client's fields
+func (s *JSONStore) UpdateClient(idOrName string, updates func(*model.Client)) error {
+	return s.update(func() error {
+		for i := range s.data.Clients {
+			if s.data.Clients[i].ID == idOrName || s.data.Clients[i].Name == idOrName {
+				updates(&s.data.Clients[i])
+				return nil
+			}
+		}
+		return ErrClientNotFound
+	})
+}
+
+// SetBillingContact sets the contact of a project's client, creating the
+// client if the project has none
+func (s *JSONStore) SetBillingContact(projectIDOrName string, contact *model.ContactInfo, updates func(*model.Project)) (*model.Client, error) {
+	var client model.Client
+	err := s.update(func() error {
+		i := slices.IndexFunc(s.data.Projects, func(p model.Project) bool {
+			return p.ID == projectIDOrName || p.Name == projectIDOrName
+		})
+		if i < 0 {
+			return ErrProjectNotFound
+		}
+		p := &s.data.Projects[i]
+		j := -1
+		if p.ClientID != "" {
+			j = slices.IndexFunc(s.data.Clients, func(c model.Client) bool { return c.ID == p.ClientID })
+		}
+		if j < 0 {
+			if slices.ContainsFunc(s.data.Clients, func(c model.Client) bool { return c.Name == p.Name }) {
+				return ErrClientExists
+			}
+			s.data.Clients = append(s.data.Clients, model.Client{ID: generateID(), Name: p.Name, CreatedAt: time.Now()})
+			j = len(s.data.Clients) - 1
+		}
+		s.data.Clients[j].Contact = contact
+		p.ClientID = s.data.Clients[j].ID
+		if updates != nil {
+			updates(p)
+		}
+		client = s.data.Clients[j]
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	return &client, nil
+}
 
-	now := time.Now()
-	entry := model.Entry{
-		ID:        generateID(),
-		ProjectID: projectID,
-		Note:      note,
-		Segments: []model.TimeSegment{
-			{Start: now},
-		},
-		Completed: false,
+// StartEntry starts a new time entry
+func (s *JSONStore) StartEntry(projectID, note string, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry model.Entry
+	err := s.update(func() error {
+		project, err := s.GetProject(projectID)
+		if err != nil {
+			return err
+		}
+		if err := checkStart(s.data.Entries, project.ID, s.data.Settings.PausePerProject()); err != nil {
+			return err
+		}
+
+		entry = model.Entry{
+			ID:        generateID(),
+			ProjectID: project.ID,
+			Note:      note,
+			Segments: []model.TimeSegment{
+				{Start: time.Now()},
+			},
+			Completed: false,
+		}
+		if updates != nil {
+			updates(&entry)
+		}
+		s.data.Entries = append(s.data.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	s.data.Entries = append(s.data.Entries, entry)
-	return &entry, s.save()
+	return &entry, nil
 }
 
-// StopEntry stops the current active or paused entry
-func (s *Store) StopEntry(note string) (*model.Entry, error) {
-	for i := range s.data.Entries {
-		if s.data.Entries[i].IsRunning() || s.data.Entries[i].IsPaused() {
-			// If running, close the current segment
-			if s.data.Entries[i].IsRunning() {
-				now := time.Now()
-				lastIdx := len(s.data.Entries[i].Segments) - 1
-				s.data.Entries[i].Segments[lastIdx].End = &now
-			}
+// SwitchEntry stops or pauses the running entry and starts another
+func (s *JSONStore) SwitchEntry(projectID, note string, pause bool, updates func(*model.Entry)) (*model.Entry, *model.Entry, error) {
+	var previous *model.Entry
+	var entry model.Entry
+	err := s.update(func() error {
+		project, err := s.GetProject(projectID)
+		if err != nil {
+			return err
+		}
+		i, err := switchTarget(s.data.Entries, project.ID, pause, s.data.Settings.PausePerProject())
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if i >= 0 {
+			switchFrom(&s.data.Entries[i], now, pause)
+			prev := s.data.Entries[i]
+			previous = &prev
+		}
 
-			s.data.Entries[i].Completed = true
+		entry = model.Entry{
+			ID:        generateID(),
+			ProjectID: project.ID,
+			Note:      note,
+			Segments:  []model.TimeSegment{{Start: now}},
+		}
+		if updates != nil {
+			updates(&entry)
+		}
+		s.data.Entries = append(s.data.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return previous, &entry, nil
+}
 
-			if note != "" {
-				if s.data.Entries[i].Note != "" {
-					s.data.Entries[i].Note += " | " + note
-				} else {
-					s.data.Entries[i].Note = note
-				}
-			}
-			if err := s.save(); err != nil {
-				return nil, err
-			}
-			return &s.data.Entries[i], nil
+// StopEntry stops the running entry, or else the most recently paused
+func (s *JSONStore) StopEntry(note string) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.update(func() error {
+		i := activeIndex(s.data.Entries)
+		if i < 0 {
+			return ErrNoActiveEntry
 		}
+		stopEntry(&s.data.Entries[i], time.Now(), note)
+		entry = &s.data.Entries[i]
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrNoActiveEntry
+	return entry, nil
 }
 
-// PauseEntry pauses the current running entry
-func (s *Store) PauseEntry() (*model.Entry, error) {
-	for i := range s.data.Entries {
-		if s.data.Entries[i].IsRunning() {
-			now := time.Now()
-			lastIdx := len(s.data.Entries[i].Segments) - 1
-			s.data.Entries[i].Segments[lastIdx].End = &now
+// TrimEntry closes the open segment of the running entry at an earlier time
+func (s *JSONStore) TrimEntry(at time.Time, pause bool) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.update(func() error {
+		i := activeIndex(s.data.Entries)
+		if i < 0 {
+			return ErrNoActiveEntry
+		}
+		entry = &s.data.Entries[i]
+		return trimEntry(entry, at, time.Now(), pause)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
 
-			if err := s.save(); err != nil {
-				return nil, err
+// PauseEntry pauses the current running entry
+func (s *JSONStore) PauseEntry() (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.update(func() error {
+		i := runningIndex(s.data.Entries)
+		if i < 0 {
+			if activeIndex(s.data.Entries) >= 0 {
+				return ErrAlreadyPaused
 			}
-			return &s.data.Entries[i], nil
-		}
-		if s.data.Entries[i].IsPaused() {
-			return nil, ErrAlreadyPaused
+			return ErrNoActiveEntry
 		}
+		now := time.Now()
+		entry = &s.data.Entries[i]
+		entry.Segments[len(entry.Segments)-1].End = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrNoActiveEntry
+	return entry, nil
 }
 
-// ResumeEntry resumes a paused entry
-func (s *Store) ResumeEntry() (*model.Entry, error) {
-	for i := range s.data.Entries {
-		if s.data.Entries[i].IsPaused() {
-			now := time.Now()
-			s.data.Entries[i].Segments = append(s.data.Entries[i].Segments, model.TimeSegment{Start: now})
-
-			if err := s.save(); err != nil {
-				return nil, err
+// ResumeEntry resumes the paused entry on a project, or the only one
+func (s *JSONStore) ResumeEntry(projectID string) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.update(func() error {
+		if projectID != "" {
+			project, err := s.GetProject(projectID)
+			if err != nil {
+				return err
 			}
-			return &s.data.Entries[i], nil
+			projectID = project.ID
 		}
-		if s.data.Entries[i].IsRunning() {
-			return nil, ErrActiveEntry
+		i, err := pausedIndex(s.data.Entries, projectID)
+		if err != nil {
+			return err
 		}
+		entry = &s.data.Entries[i]
+		entry.Segments = append(entry.Segments, model.TimeSegment{Start: time.Now()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrNoPausedEntry
+	return entry, nil
 }
 
 // LogEntry creates a completed time entry
-func (s *Store) LogEntry(projectID, note string, start, end time.Time) (*model.Entry, error) {
-	if _, err := s.GetProject(projectID); err != nil {
+func (s *JSONStore) LogEntry(projectID, note string, start, end time.Time, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry model.Entry
+	err := s.update(func() error {
+		if _, err := s.GetProject(projectID); err != nil {
+			return err
+		}
+
+		entry = model.Entry{
+			ID:        generateID(),
+			ProjectID: projectID,
+			Note:      note,
+			Segments: []model.TimeSegment{
+				{Start: start, End: &end},
+			},
+			Completed: true,
+		}
+		if updates != nil {
+			updates(&entry)
+		}
+		if err := checkSegments(&entry, s.data.Entries, time.Now()); err != nil {
+			return err
+		}
+		s.data.Entries = append(s.data.Entries, entry)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	return &entry, nil
+}
 
-	entry := model.Entry{
-		ID:        generateID(),
-		ProjectID: projectID,
-		Note:      note,
-		Segments: []model.TimeSegment{
-			{Start: start, End: &end},
-		},
-		Completed: true,
+// ActiveEntry returns the running entry, or else the most recently paused
+func (s *JSONStore) ActiveEntry() *model.Entry {
+	if i := activeIndex(s.data.Entries); i >= 0 {
+		return &s.data.Entries[i]
 	}
-	s.data.Entries = append(s.data.Entries, entry)
-	return &entry, s.save()
+	return nil
 }
 
-// ActiveEntry returns the current running or paused entry, if any
-func (s *Store) ActiveEntry() *model.Entry {
-	for i := range s.data.Entries {
-		if s.data.Entries[i].IsRunning() || s.data.Entries[i].IsPaused() {
-			return &s.data.Entries[i]
+// PausedEntries returns the paused entries
+func (s *JSONStore) PausedEntries() []model.Entry {
+	var result []model.Entry
+	for _, e := range s.data.Entries {
+		if e.IsPaused() {
+			result = append(result, e)
 		}
 	}
-	return nil
+	return result
 }
 
 // ListEntries returns entries, optionally filtered by project and date range
-func (s *Store) ListEntries(projectID string, from, to *time.Time) []model.Entry {
+func (s *JSONStore) ListEntries(projectID string, from, to *time.Time) []model.Entry {
 	var result []model.Entry
 	for _, e := range s.data.Entries {
 		if projectID != "" && e.ProjectID != projectID {
@@ -322,49 +700,158 @@ func (s *Store) ListEntries(projectID string, from, to *time.Time) []model.Entry
 }
 
 // DeleteEntry removes an entry by ID
-func (s *Store) DeleteEntry(id string) error {
-	for i, e := range s.data.Entries {
-		if e.ID == id {
-			s.data.Entries = append(s.data.Entries[:i], s.data.Entries[i+1:]...)
-			return s.save()
+func (s *JSONStore) DeleteEntry(id string) error {
+	return s.update(func() error {
+		for i, e := range s.data.Entries {
+			if e.ID == id {
+				s.data.Entries = append(s.data.Entries[:i], s.data.Entries[i+1:]...)
+				return nil
+			}
 		}
-	}
-	return ErrEntryNotFound
+		return ErrEntryNotFound
+	})
 }
 
 // AmendEntry updates the note on a completed entry by index (1=most recent)
-func (s *Store) AmendEntry(index int, note string) (*model.Entry, error) {
+func (s *JSONStore) AmendEntry(index int, note string) (*model.Entry, error) {
 	if index <= 0 {
 		return nil, ErrInvalidIndex
 	}
 
-	// Get all completed entries in reverse chronological order
-	var completed []int // indices into s.data.Entries
-	for i := len(s.data.Entries) - 1; i >= 0; i-- {
-		if s.data.Entries[i].Completed {
-			completed = append(completed, i)
+	var entry *model.Entry
+	err := s.update(func() error {
+		// Get all completed entries in reverse chronological order
+		var completed []int // indices into s.data.Entries
+		for i := len(s.data.Entries) - 1; i >= 0; i-- {
+			if s.data.Entries[i].Completed {
+				completed = append(completed, i)
+			}
+		}
+
+		if index > len(completed) {
+			return ErrInvalidIndex
 		}
-	}
 
-	if index > len(completed) {
-		return nil, ErrInvalidIndex
+		// Get the actual entry index
+		entryIdx := completed[index-1]
+
+		// Update the note
+		s.data.Entries[entryIdx].Note = note
+		entry = &s.data.Entries[entryIdx]
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return entry, nil
+}
 
-	// Get the actual entry index
-	entryIdx := completed[index-1]
+// EditEntry changes a completed entry's project, segments and other
+// fields in one write
+func (s *JSONStore) EditEntry(id, projectID string, segments []model.TimeSegment, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry model.Entry
+	err := s.update(func() error {
+		e := s.findEntry(id)
+		if e == nil {
+			return ErrEntryNotFound
+		}
+		if projectID != "" {
+			project, err := s.GetProject(projectID)
+			if err != nil {
+				return err
+			}
+			projectID = project.ID
+		}
+		if err := editEntry(e, projectID, segments, updates, s.data.Entries, time.Now()); err != nil {
+			return err
+		}
+		entry = *e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
 
-	// Update the note
-	s.data.Entries[entryIdx].Note = note
+// SplitEntry splits a completed entry in two at a time within it
+func (s *JSONStore) SplitEntry(id string, at time.Time, updates func(*model.Entry)) (*model.Entry, *model.Entry, error) {
+	var entry, rest model.Entry
+	err := s.update(func() error {
+		e := s.findEntry(id)
+		if e == nil {
+			return ErrEntryNotFound
+		}
+		split, err := splitEntry(e, at, updates)
+		if err != nil {
+			return err
+		}
+		entry, rest = *e, *split
+		s.data.Entries = append(s.data.Entries, rest)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &entry, &rest, nil
+}
 
-	if err := s.save(); err != nil {
+// MergeEntries merges two adjacent completed entries into the earlier one
+func (s *JSONStore) MergeEntries(id, otherID string, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry model.Entry
+	err := s.update(func() error {
+		e, other := s.findEntry(id), s.findEntry(otherID)
+		if e == nil || other == nil {
+			return ErrEntryNotFound
+		}
+		if other.StartTime().Before(e.StartTime()) {
+			e, other = other, e
+		}
+		if err := mergeEntries(e, other, s.data.Entries, updates); err != nil {
+			return err
+		}
+		entry = *e
+		laterID := other.ID
+		s.data.Entries = slices.DeleteFunc(s.data.Entries, func(e model.Entry) bool { return e.ID == laterID })
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	return &entry, nil
+}
+
+// findEntry returns the stored entry with the given ID, or nil
+func (s *JSONStore) findEntry(id string) *model.Entry {
+	for i := range s.data.Entries {
+		if s.data.Entries[i].ID == id {
+			return &s.data.Entries[i]
+		}
+	}
+	return nil
+}
 
-	return &s.data.Entries[entryIdx], nil
+// UpdateEntry applies updates to the entry with the given ID
+func (s *JSONStore) UpdateEntry(id string, updates func(*model.Entry)) (*model.Entry, error) {
+	var entry *model.Entry
+	err := s.update(func() error {
+		for i := range s.data.Entries {
+			if s.data.Entries[i].ID == id {
+				updates(&s.data.Entries[i])
+				entry = &s.data.Entries[i]
+				return nil
+			}
+		}
+		return ErrEntryNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
 }
 
 // GetSettings returns the current settings
-func (s *Store) GetSettings() *model.Settings {
+func (s *JSONStore) GetSettings() *model.Settings {
 	if s.data.Settings == nil {
 		return &model.Settings{}
 	}
@@ -372,37 +859,148 @@ func (s *Store) GetSettings() *model.Settings {
 }
 
 // SetUserContact updates the user's contact info
-func (s *Store) SetUserContact(contact *model.ContactInfo) error {
-	if s.data.Settings == nil {
-		s.data.Settings = &model.Settings{}
-	}
-	s.data.Settings.UserContact = contact
-	return s.save()
+func (s *JSONStore) SetUserContact(contact *model.ContactInfo) error {
+	return s.update(func() error {
+		if s.data.Settings == nil {
+			s.data.Settings = &model.Settings{}
+		}
+		s.data.Settings.UserContact = contact
+		return nil
+	})
+}
+
+// UpdateSettings applies updates to the settings
+func (s *JSONStore) UpdateSettings(updates func(*model.Settings)) error {
+	return s.update(func() error {
+		if s.data.Settings == nil {
+			s.data.Settings = &model.Settings{}
+		}
+		updates(s.data.Settings)
+		return nil
+	})
 }
 
 // UpdateProject updates a project's fields
-func (s *Store) UpdateProject(idOrName string, updates func(*model.Project)) error {
-	for i := range s.data.Projects {
-		if s.data.Projects[i].ID == idOrName || s.data.Projects[i].Name == idOrName {
-			updates(&s.data.Projects[i])
-			return s.save()
+func (s *JSONStore) UpdateProject(idOrName string, updates func(*model.Project)) error {
+	return s.update(func() error {
+		for i := range s.data.Projects {
+			if s.data.Projects[i].ID == idOrName || s.data.Projects[i].Name == idOrName {
+				updates(&s.data.Projects[i])
+				return nil
+			}
 		}
-	}
-	return ErrProjectNotFound
+		return ErrProjectNotFound
+	})
 }
 
-// SaveInvoice saves a new invoice record
-func (s *Store) SaveInvoice(inv *model.Invoice) error {
+// SaveInvoice saves a new invoice record and marks its entries and
+// expenses as billed
+func (s *JSONStore) SaveInvoice(inv *model.Invoice, entryIDs, expenseIDs []string) error {
 	inv.CreatedAt = time.Now()
 	if inv.Status == "" {
 		inv.Status = model.InvoiceStatusPending
 	}
-	s.data.Invoices = append(s.data.Invoices, *inv)
-	return s.save()
+	return s.update(func() error {
+		entries := make(map[string]*model.Entry)
+		for i := range s.data.Entries {
+			entries[s.data.Entries[i].ID] = &s.data.Entries[i]
+		}
+		for _, id := range entryIDs {
+			e, ok := entries[id]
+			if !ok {
+				return fmt.Errorf("entry %s: %w", id, ErrEntryNotFound)
+			}
+			if e.IsBilled() {
+				return fmt.Errorf("entry %s: %w", id, ErrAlreadyBilled)
+			}
+		}
+		expenses := make(map[string]*model.Expense)
+		for i := range s.data.Expenses {
+			expenses[s.data.Expenses[i].ID] = &s.data.Expenses[i]
+		}
+		for _, id := range expenseIDs {
+			e, ok := expenses[id]
+			if !ok {
+				return fmt.Errorf("expense %s: %w", id, ErrExpenseNotFound)
+			}
+			if e.InvoiceID != "" {
+				return fmt.Errorf("expense %s: %w", id, ErrAlreadyBilled)
+			}
+		}
+
+		exists := func(id string) bool { return s.findInvoice(id) != nil }
+		if err := assignInvoiceID(s.data.Settings, inv, exists); err != nil {
+			return err
+		}
+
+		if inv.IsCreditNote() {
+			original := s.findInvoice(inv.CreditFor)
+			if original == nil {
+				return fmt.Errorf("invoice %s: %w", inv.CreditFor, ErrInvoiceNotFound)
+			}
+			if err := applyCreditNote(original, inv); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range entryIDs {
+			entries[id].InvoiceID = inv.ID
+		}
+		for _, id := range expenseIDs {
+			expenses[id].InvoiceID = inv.ID
+		}
+		s.data.Invoices = append(s.data.Invoices, *inv)
+		return nil
+	})
+}
+
+// findInvoice returns the stored invoice with the given ID, or nil
+func (s *JSONStore) findInvoice(id string) *model.Invoice {
+	for i := range s.data.Invoices {
+		if s.data.Invoices[i].ID == id {
+			return &s.data.Invoices[i]
+		}
+	}
+	return nil
+}
+
+// updateInvoice applies fn to the stored invoice with the given ID and
+// returns a copy of the result
+func (s *JSONStore) updateInvoice(id string, fn func(*model.Invoice) error) (*model.Invoice, error) {
+	var result model.Invoice
+	err := s.update(func() error {
+		inv := s.findInvoice(id)
+		if inv == nil {
+			return ErrInvoiceNotFound
+		}
+		if err := fn(inv); err != nil {
+			return err
+		}
+		result = *inv
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// releaseInvoice clears the invoice from the entries and expenses it billed
+func (s *JSONStore) releaseInvoice(id string) {
+	for j := range s.data.Entries {
+		if s.data.Entries[j].InvoiceID == id {
+			s.data.Entries[j].InvoiceID = ""
+		}
+	}
+	for j := range s.data.Expenses {
+		if s.data.Expenses[j].InvoiceID == id {
+			s.data.Expenses[j].InvoiceID = ""
+		}
+	}
 }
 
 // GetInvoice returns an invoice by ID
-func (s *Store) GetInvoice(id string) (*model.Invoice, error) {
+func (s *JSONStore) GetInvoice(id string) (*model.Invoice, error) {
 	for i := range s.data.Invoices {
 		if s.data.Invoices[i].ID == id {
 			return &s.data.Invoices[i], nil
@@ -412,7 +1010,7 @@ func (s *Store) GetInvoice(id string) (*model.Invoice, error) {
 }
 
 // ListInvoices returns invoices, optionally filtered by project and/or status
-func (s *Store) ListInvoices(projectID string, status model.InvoiceStatus) []model.Invoice {
+func (s *JSONStore) ListInvoices(projectID string, status model.InvoiceStatus) []model.Invoice {
 	var result []model.Invoice
 	for _, inv := range s.data.Invoices {
 		if projectID != "" && inv.ProjectID != projectID {
@@ -430,29 +1028,147 @@ func (s *Store) ListInvoices(projectID string, status model.InvoiceStatus) []mod
 	return result
 }
 
-// MarkInvoicePaid marks an invoice as paid
-func (s *Store) MarkInvoicePaid(id string) (*model.Invoice, error) {
-	for i := range s.data.Invoices {
-		if s.data.Invoices[i].ID == id {
-			now := time.Now()
-			s.data.Invoices[i].Status = model.InvoiceStatusPaid
-			s.data.Invoices[i].PaidAt = &now
-			if err := s.save(); err != nil {
-				return nil, err
+// MarkInvoicePaid records a payment of the whole balance today
+func (s *JSONStore) MarkInvoicePaid(id string) (*model.Invoice, error) {
+	return s.updateInvoice(id, func(inv *model.Invoice) error {
+		return inv.AddPayment(model.Payment{Date: time.Now(), Amount: inv.Balance()})
+	})
+}
+
+// RecordPayment records a payment against an invoice
+func (s *JSONStore) RecordPayment(id string, payment model.Payment) (*model.Invoice, error) {
+	return s.updateInvoice(id, func(inv *model.Invoice) error {
+		return inv.AddPayment(payment)
+	})
+}
+
+// VoidInvoice cancels an unpaid invoice and releases its entries and
+// expenses
+func (s *JSONStore) VoidInvoice(id string) (*model.Invoice, error) {
+	return s.updateInvoice(id, func(inv *model.Invoice) error {
+		if err := voidInvoice(inv, time.Now()); err != nil {
+			return err
+		}
+		s.releaseInvoice(id)
+		return nil
+	})
+}
+
+// DeleteInvoice removes an invoice by ID and releases its entries and
+// expenses. Deleting a credit note takes its credit off the invoice it
+// credited; an invoice with a credit note cannot be deleted.
+func (s *JSONStore) DeleteInvoice(id string) error {
+	return s.update(func() error {
+		for _, inv := range s.data.Invoices {
+			if inv.CreditFor == id {
+				return fmt.Errorf("invoice %s: %w", id, ErrCredited)
+			}
+		}
+		for i, inv := range s.data.Invoices {
+			if inv.ID == id {
+				if original := s.findInvoice(inv.CreditFor); inv.IsCreditNote() && original != nil {
+					revokeCreditNote(original, &inv)
+				}
+				s.data.Invoices = append(s.data.Invoices[:i], s.data.Invoices[i+1:]...)
+				s.releaseInvoice(id)
+				return nil
 			}
-			return &s.data.Invoices[i], nil
 		}
+		return ErrInvoiceNotFound
+	})
+}
+
+// AddExpense records a billable expense against a project
+func (s *JSONStore) AddExpense(expense model.Expense) (*model.Expense, error) {
+	expense.ID = generateID()
+	expense.CreatedAt = time.Now()
+	err := s.update(func() error {
+		s.data.Expenses = append(s.data.Expenses, expense)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrInvoiceNotFound
+	return &expense, nil
 }
 
-// DeleteInvoice removes an invoice by ID
-func (s *Store) DeleteInvoice(id string) error {
-	for i, inv := range s.data.Invoices {
-		if inv.ID == id {
-			s.data.Invoices = append(s.data.Invoices[:i], s.data.Invoices[i+1:]...)
-			return s.save()
+// ListExpenses returns expenses, optionally filtered by project and date range
+func (s *JSONStore) ListExpenses(projectID string, from, to *time.Time) []model.Expense {
+	var result []model.Expense
+	for _, e := range s.data.Expenses {
+		if projectID != "" && e.ProjectID != projectID {
+			// Check if project name matches
+			p, _ := s.GetProject(projectID)
+			if p == nil || p.ID != e.ProjectID {
+				continue
+			}
+		}
+		if from != nil && e.Date.Before(*from) {
+			continue
+		}
+		if to != nil && e.Date.After(*to) {
+			continue
 		}
+		result = append(result, e)
 	}
-	return ErrInvoiceNotFound
+	return result
+}
+
+// UpdateExpense applies updates to an expense by ID
+func (s *JSONStore) UpdateExpense(id string, updates func(*model.Expense)) (*model.Expense, error) {
+	var expense *model.Expense
+	err := s.update(func() error {
+		for i := range s.data.Expenses {
+			if s.data.Expenses[i].ID == id {
+				updates(&s.data.Expenses[i])
+				expense = &s.data.Expenses[i]
+				return nil
+			}
+		}
+		return ErrExpenseNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// DeleteExpense removes an expense by ID
+func (s *JSONStore) DeleteExpense(id string) error {
+	return s.update(func() error {
+		for i, e := range s.data.Expenses {
+			if e.ID == id {
+				s.data.Expenses = append(s.data.Expenses[:i], s.data.Expenses[i+1:]...)
+				return nil
+			}
+		}
+		return ErrExpenseNotFound
+	})
+}
+
+// Snapshot returns a copy of all stored data
+func (s *JSONStore) Snapshot() (*model.Data, error) {
+	raw, err := json.Marshal(s.data)
+	if err != nil {
+		return nil, err
+	}
+	var data model.Data
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	data.Version = CurrentVersion
+	return &data, nil
+}
+
+// Restore replaces all stored data with data
+func (s *JSONStore) Restore(data *model.Data) error {
+	return s.update(func() error {
+		s.data = *data
+		return nil
+	})
+}
+
+// Close releases resources held by the store
+func (s *JSONStore) Close() error {
+	return nil
 }
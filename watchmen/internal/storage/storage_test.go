@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
-func setupTestStore(t *testing.T) (*Store, string) {
+func setupTestStore(t *testing.T) (*JSONStore, string) {
 	t.Helper()
 	tmpDir := t.TempDir()
 	path := filepath.Join(tmpDir, "test_data.json")
@@ -146,6 +148,144 @@ func TestMigrationFromV0(t *testing.T) {
 	}
 }
 
+func TestMigrationFromV2(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "test_data.json")
+
+	// v2 stored rates and amounts as floating point major units
+	v2Data := `{
+		"version": 2,
+		"projects": [
+			{
+				"id": "proj1",
+				"name": "Test Project",
+				"hourly_rate": 125.5,
+				"created_at": "2024-01-01T00:00:00Z"
+			}
+		],
+		"entries": [],
+		"invoices": [
+			{
+				"id": "INV-1",
+				"project_id": "proj1",
+				"project_name": "Test Project",
+				"hours": 1.5,
+				"rate": 125.5,
+				"amount": 188.25,
+				"status": "pending"
+			}
+		]
+	}`
+
+	if err := os.WriteFile(path, []byte(v2Data), 0644); err != nil {
+		t.Fatalf("Failed to write v2 data: %v", err)
+	}
+
+	store, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+
+	project, err := store.GetProject("Test Project")
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+	if project.HourlyRate != 12550 {
+		t.Errorf("HourlyRate = %d, want 12550", project.HourlyRate)
+	}
+	if project.CurrencyCode() != "USD" {
+		t.Errorf("CurrencyCode() = %q, want USD", project.CurrencyCode())
+	}
+
+	inv, err := store.GetInvoice("INV-1")
+	if err != nil {
+		t.Fatalf("GetInvoice() error = %v", err)
+	}
+	if inv.Rate != 12550 || inv.Amount != 18825 {
+		t.Errorf("invoice rate/amount = %d/%d, want 12550/18825", inv.Rate, inv.Amount)
+	}
+}
+
+func TestMigrationFromV3(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "test_data.json")
+
+	// v3 kept a billing contact on each project
+	v3Data := `{
+		"version": 3,
+		"projects": [
+			{
+				"id": "web",
+				"name": "Website",
+				"hourly_rate": 10000,
+				"billing_contact": {"name": "Jane", "company": "Acme Inc"},
+				"created_at": "2024-01-01T00:00:00Z"
+			},
+			{
+				"id": "app",
+				"name": "App",
+				"hourly_rate": 12000,
+				"billing_contact": {"name": "Jane", "company": "Acme Inc"},
+				"created_at": "2024-01-02T00:00:00Z"
+			},
+			{
+				"id": "own",
+				"name": "Own",
+				"hourly_rate": 0,
+				"created_at": "2024-01-03T00:00:00Z"
+			}
+		],
+		"entries": [],
+		"invoices": []
+	}`
+
+	if err := os.WriteFile(path, []byte(v3Data), 0644); err != nil {
+		t.Fatalf("Failed to write v3 data: %v", err)
+	}
+
+	store, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+
+	clients := store.ListClients()
+	if len(clients) != 1 {
+		t.Fatalf("Expected 1 client, got %d", len(clients))
+	}
+	client := clients[0]
+	if client.Name != "Acme Inc" || client.Contact == nil || client.Contact.Name != "Jane" {
+		t.Errorf("Client not migrated from billing contact: %+v", client)
+	}
+	for _, name := range []string{"Website", "App"} {
+		if p, _ := store.GetProject(name); p.ClientID != client.ID {
+			t.Errorf("%s client = %q, want %q", name, p.ClientID, client.ID)
+		}
+	}
+	if p, _ := store.GetProject("Own"); p.ClientID != "" {
+		t.Errorf("Project without a contact given client %q", p.ClientID)
+	}
+
+	// The old field is dropped when the file is saved
+	raw, _ := os.ReadFile(path)
+	var saved struct {
+		Version  int               `json:"version"`
+		Projects []json.RawMessage `json:"projects"`
+	}
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatalf("Failed to read saved data: %v", err)
+	}
+	if saved.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", saved.Version, CurrentVersion)
+	}
+	for _, p := range saved.Projects {
+		var fields map[string]json.RawMessage
+		json.Unmarshal(p, &fields)
+		if _, ok := fields["billing_contact"]; ok {
+			t.Errorf("billing_contact still saved: %s", p)
+		}
+	}
+}
+
 func TestPauseEntry(t *testing.T) {
 	store, _ := setupTestStore(t)
 
@@ -153,7 +293,7 @@ func TestPauseEntry(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Start an entry
-	_, err := store.StartEntry(project.ID, "test note")
+	_, err := store.StartEntry(project.ID, "test note", nil)
 	if err != nil {
 		t.Fatalf("Failed to start entry: %v", err)
 	}
@@ -195,7 +335,7 @@ func TestPauseEntryErrors(t *testing.T) {
 
 	// Add project and start entry
 	project, _ := store.AddProject("Test", 100, "")
-	store.StartEntry(project.ID, "")
+	store.StartEntry(project.ID, "", nil)
 
 	// Pause it
 	store.PauseEntry()
@@ -212,7 +352,7 @@ func TestResumeEntry(t *testing.T) {
 
 	// Add a project and start/pause an entry
 	project, _ := store.AddProject("Test", 100, "")
-	store.StartEntry(project.ID, "test note")
+	store.StartEntry(project.ID, "test note", nil)
 	store.PauseEntry()
 
 	// Verify it's paused
@@ -223,7 +363,7 @@ func TestResumeEntry(t *testing.T) {
 	initialSegments := len(active.Segments)
 
 	// Resume it
-	resumed, err := store.ResumeEntry()
+	resumed, err := store.ResumeEntry("")
 	if err != nil {
 		t.Fatalf("Failed to resume entry: %v", err)
 	}
@@ -249,17 +389,17 @@ func TestResumeEntryErrors(t *testing.T) {
 	store, _ := setupTestStore(t)
 
 	// Try to resume with no paused entry
-	_, err := store.ResumeEntry()
+	_, err := store.ResumeEntry("")
 	if err != ErrNoPausedEntry {
 		t.Errorf("Expected ErrNoPausedEntry, got %v", err)
 	}
 
 	// Add project and start entry (running, not paused)
 	project, _ := store.AddProject("Test", 100, "")
-	store.StartEntry(project.ID, "")
+	store.StartEntry(project.ID, "", nil)
 
 	// Try to resume a running entry
-	_, err = store.ResumeEntry()
+	_, err = store.ResumeEntry("")
 	if err != ErrActiveEntry {
 		t.Errorf("Expected ErrActiveEntry, got %v", err)
 	}
@@ -270,7 +410,7 @@ func TestStopPausedEntry(t *testing.T) {
 
 	// Add a project, start, and pause an entry
 	project, _ := store.AddProject("Test", 100, "")
-	store.StartEntry(project.ID, "initial note")
+	store.StartEntry(project.ID, "initial note", nil)
 	store.PauseEntry()
 
 	// Stop the paused entry
@@ -298,11 +438,11 @@ func TestStartEntryBlockedByPaused(t *testing.T) {
 	project2, _ := store.AddProject("Test2", 100, "")
 
 	// Start and pause on project1
-	store.StartEntry(project1.ID, "")
+	store.StartEntry(project1.ID, "", nil)
 	store.PauseEntry()
 
 	// Try to start on project2 - should fail
-	_, err := store.StartEntry(project2.ID, "")
+	_, err := store.StartEntry(project2.ID, "", nil)
 	if err != ErrActiveEntry {
 		t.Errorf("Expected ErrActiveEntry when starting with paused entry, got %v", err)
 	}
@@ -314,7 +454,7 @@ func TestPauseResumeDuration(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Start entry
-	entry, _ := store.StartEntry(project.ID, "")
+	entry, _ := store.StartEntry(project.ID, "", nil)
 	time.Sleep(100 * time.Millisecond)
 
 	// Pause
@@ -333,7 +473,7 @@ func TestPauseResumeDuration(t *testing.T) {
 	}
 
 	// Resume
-	entry, _ = store.ResumeEntry()
+	entry, _ = store.ResumeEntry("")
 	time.Sleep(100 * time.Millisecond)
 
 	// Stop
@@ -353,15 +493,15 @@ func TestAmendEntry(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Create three completed entries
-	store.StartEntry(project.ID, "first entry")
+	store.StartEntry(project.ID, "first entry", nil)
 	time.Sleep(10 * time.Millisecond)
 	store.StopEntry("")
 
-	store.StartEntry(project.ID, "second entry")
+	store.StartEntry(project.ID, "second entry", nil)
 	time.Sleep(10 * time.Millisecond)
 	store.StopEntry("")
 
-	store.StartEntry(project.ID, "third entry")
+	store.StartEntry(project.ID, "third entry", nil)
 	time.Sleep(10 * time.Millisecond)
 	store.StopEntry("")
 
@@ -398,7 +538,7 @@ func TestAmendEntryErrors(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Create one completed entry
-	store.StartEntry(project.ID, "test")
+	store.StartEntry(project.ID, "test", nil)
 	store.StopEntry("")
 
 	// Try to amend with invalid index
@@ -424,11 +564,11 @@ func TestAmendRunningEntry(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Create completed entry
-	store.StartEntry(project.ID, "completed")
+	store.StartEntry(project.ID, "completed", nil)
 	store.StopEntry("")
 
 	// Start a running entry
-	store.StartEntry(project.ID, "running")
+	store.StartEntry(project.ID, "running", nil)
 
 	// Try to amend - should only see completed entry
 	amended, err := store.AmendEntry(1, "updated")
@@ -453,7 +593,7 @@ func TestAmendClearNote(t *testing.T) {
 	project, _ := store.AddProject("Test", 100, "")
 
 	// Create entry with note
-	store.StartEntry(project.ID, "original note")
+	store.StartEntry(project.ID, "original note", nil)
 	store.StopEntry("")
 
 	// Clear the note
@@ -466,3 +606,167 @@ func TestAmendClearNote(t *testing.T) {
 		t.Errorf("Expected empty note, got %q", amended.Note)
 	}
 }
+
+func TestSaveIsAtomic(t *testing.T) {
+	store, path := setupTestStore(t)
+
+	project, _ := store.AddProject("Test", 100, "")
+	store.StartEntry(project.ID, "", nil)
+	store.StopEntry("")
+
+	// No temp files should be left behind next to the data file
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*.tmp-*"))
+	if len(matches) != 0 {
+		t.Errorf("Expected no leftover temp files, got %v", matches)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read data file: %v", err)
+	}
+	var check map[string]interface{}
+	if err := json.Unmarshal(data, &check); err != nil {
+		t.Errorf("Data file is not valid JSON: %v", err)
+	}
+}
+
+func TestBackupsAreRotated(t *testing.T) {
+	store, path := setupTestStore(t)
+
+	project, _ := store.AddProject("Test", 100, "")
+	start := time.Now().Add(-100 * time.Hour)
+	for i := 0; i < MaxBackups+5; i++ {
+		// Entries an hour apart, as overlapping ones are refused
+		store.LogEntry(project.ID, "", start.Add(time.Duration(i)*time.Hour), start.Add(time.Duration(i)*time.Hour+time.Minute), nil)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(BackupDir(path), filepath.Base(path)+".*"))
+	if len(backups) != MaxBackups {
+		t.Fatalf("Expected %d backups, got %d", MaxBackups, len(backups))
+	}
+
+	// The newest backup holds the state before the last save
+	newest := backups[len(backups)-1]
+	data, err := os.ReadFile(newest)
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	var backup struct {
+		Entries []json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &backup); err != nil {
+		t.Fatalf("Backup is not valid JSON: %v", err)
+	}
+	if len(backup.Entries) != MaxBackups+4 {
+		t.Errorf("Expected newest backup to have %d entries, got %d", MaxBackups+4, len(backup.Entries))
+	}
+}
+
+func TestUpdateSeesOtherStoresChanges(t *testing.T) {
+	store1, path := setupTestStore(t)
+	project, _ := store1.AddProject("Test", 100, "")
+
+	store2, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to open second store: %v", err)
+	}
+
+	store1.LogEntry(project.ID, "from store1", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), nil)
+	store2.LogEntry(project.ID, "from store2", time.Now().Add(-time.Hour), time.Now(), nil)
+
+	reloaded, _ := New(path)
+	if entries := reloaded.ListEntries("", nil, nil); len(entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d (lost update)", len(entries))
+	}
+}
+
+func TestConcurrentStartStop(t *testing.T) {
+	store, path := setupTestStore(t)
+	project, _ := store.AddProject("Test", 100, "")
+
+	const workers = 8
+	const rounds = 10
+	var starts, stops atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := New(path)
+			if err != nil {
+				t.Errorf("Failed to open store: %v", err)
+				return
+			}
+			for i := 0; i < rounds; i++ {
+				if _, err := s.StartEntry(project.ID, "", nil); err == nil {
+					starts.Add(1)
+				} else if err != ErrActiveEntry {
+					t.Errorf("Unexpected start error: %v", err)
+				}
+				if _, err := s.StopEntry(""); err == nil {
+					stops.Add(1)
+				} else if err != ErrNoActiveEntry {
+					t.Errorf("Unexpected stop error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	final, err := New(path)
+	if err != nil {
+		t.Fatalf("Data file corrupted: %v", err)
+	}
+	entries := final.ListEntries("", nil, nil)
+	if int64(len(entries)) != starts.Load() {
+		t.Errorf("Expected %d entries, got %d", starts.Load(), len(entries))
+	}
+	var completed int64
+	for _, e := range entries {
+		if e.Completed {
+			completed++
+		}
+	}
+	if completed != stops.Load() {
+		t.Errorf("Expected %d completed entries, got %d", stops.Load(), completed)
+	}
+	if int64(len(entries))-completed > 1 {
+		t.Errorf("Expected at most one active entry, got %d", int64(len(entries))-completed)
+	}
+}
+
+func TestConcurrentLogEntry(t *testing.T) {
+	store, path := setupTestStore(t)
+	project, _ := store.AddProject("Test", 100, "")
+
+	const workers = 8
+	const rounds = 10
+	base := time.Now().Add(-workers * rounds * time.Hour)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := New(path)
+			if err != nil {
+				t.Errorf("Failed to open store: %v", err)
+				return
+			}
+			for i := 0; i < rounds; i++ {
+				start := base.Add(time.Duration(w*rounds+i) * time.Hour)
+				if _, err := s.LogEntry(project.ID, "", start, start.Add(time.Hour), nil); err != nil {
+					t.Errorf("LogEntry failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	final, err := New(path)
+	if err != nil {
+		t.Fatalf("Data file corrupted: %v", err)
+	}
+	if entries := final.ListEntries("", nil, nil); len(entries) != workers*rounds {
+		t.Errorf("Expected %d entries, got %d", workers*rounds, len(entries))
+	}
+}
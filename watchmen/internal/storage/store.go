@@ -0,0 +1,596 @@
+package storage
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrClientNotFound  = errors.New("client not found")
+	ErrClientExists    = errors.New("client already exists")
+	ErrEntryNotFound   = errors.New("entry not found")
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	ErrExpenseNotFound = errors.New("expense not found")
+	ErrAlreadyBilled   = errors.New("already billed on another invoice")
+	ErrCredited        = errors.New("invoice has a credit note, delete the credit note first")
+	ErrDuplicateID     = errors.New("invoice number already in use")
+	ErrNoNumbering     = errors.New("invoice numbering is not configured")
+	ErrNoActiveEntry   = errors.New("no active time entry")
+	ErrActiveEntry     = errors.New("there is already an active time entry")
+	ErrNoPausedEntry   = errors.New("no paused time entry")
+	ErrNotPaused       = errors.New("entry is not paused")
+	ErrAlreadyPaused   = errors.New("entry is already paused")
+	ErrInvalidIndex    = errors.New("invalid entry index")
+	ErrNotEmpty        = errors.New("destination store is not empty")
+	ErrEntryActive     = errors.New("entry is still running or paused")
+	ErrOverlap         = errors.New("overlaps another entry")
+	ErrPausedOnProject = errors.New("project already has a paused entry, resume it instead")
+	ErrSeveralPaused   = errors.New("several entries are paused, name the project to resume")
+)
+
+// Store is the interface implemented by the storage backends
+type Store interface {
+	AddProject(name string, hourlyRate int64, description string) (*model.Project, error)
+	GetProject(idOrName string) (*model.Project, error)
+	ListProjects() []model.Project
+	UpdateProject(idOrName string, updates func(*model.Project)) error
+
+	AddClient(name string) (*model.Client, error)
+	GetClient(idOrName string) (*model.Client, error)
+	ListClients() []model.Client
+	UpdateClient(idOrName string, updates func(*model.Client)) error
+	// SetBillingContact sets the contact of a project's client in one
+	// write, first creating a client named after the project if it has
+	// none, failing with ErrClientExists if that name is taken. updates, if
+	// not nil, sets the project's other fields in the same write.
+	SetBillingContact(projectIDOrName string, contact *model.ContactInfo, updates func(*model.Project)) (*model.Client, error)
+
+	// StartEntry starts a new entry. Nothing else may be running, or
+	// paused unless Settings.PausePerProject allows a paused entry on each
+	// other project. updates, if not nil, sets the entry's other fields
+	// before it is saved.
+	StartEntry(projectID, note string, updates func(*model.Entry)) (*model.Entry, error)
+	// SwitchEntry stops the running entry, or pauses it if pause is set,
+	// and starts a new one on projectID at the same instant. It returns the
+	// entry switched from, nil if nothing was running, and the new entry.
+	// updates, if not nil, sets the new entry's other fields before it is
+	// saved.
+	SwitchEntry(projectID, note string, pause bool, updates func(*model.Entry)) (*model.Entry, *model.Entry, error)
+	// StopEntry stops the running entry, or else the most recently paused
+	StopEntry(note string) (*model.Entry, error)
+	PauseEntry() (*model.Entry, error)
+	// ResumeEntry resumes the paused entry on projectID, or the only paused
+	// entry if projectID is empty
+	ResumeEntry(projectID string) (*model.Entry, error)
+	// TrimEntry closes the open segment of the running entry at at, for a
+	// timer that was left running. The entry is paused if pause is set, and
+	// stopped otherwise.
+	TrimEntry(at time.Time, pause bool) (*model.Entry, error)
+	// LogEntry records a completed entry, failing with ErrOverlap if it
+	// overlaps another entry. updates, if not nil, sets the entry's other
+	// fields before it is saved.
+	LogEntry(projectID, note string, start, end time.Time, updates func(*model.Entry)) (*model.Entry, error)
+	// ActiveEntry returns the running entry, or else the most recently
+	// paused, or nil
+	ActiveEntry() *model.Entry
+	// PausedEntries returns the paused entries, not counting a running one
+	PausedEntries() []model.Entry
+	ListEntries(projectID string, from, to *time.Time) []model.Entry
+	DeleteEntry(id string) error
+	AmendEntry(index int, note string) (*model.Entry, error)
+	UpdateEntry(id string, updates func(*model.Entry)) (*model.Entry, error)
+	// EditEntry changes a completed entry in one write: it moves to
+	// projectID if not empty, its segments are replaced if segments is not
+	// nil, and updates, if not nil, sets its other fields. Segments must be
+	// in order, each end after it starts, and not overlap another entry,
+	// failing with ErrOverlap if they do. Nothing is saved unless every
+	// change is valid.
+	EditEntry(id, projectID string, segments []model.TimeSegment, updates func(*model.Entry)) (*model.Entry, error)
+	// SplitEntry splits a completed entry in two at a time within it,
+	// returning the entry cut short and a new entry with the rest. updates,
+	// if not nil, sets other fields of the entry cut short in the same write.
+	SplitEntry(id string, at time.Time, updates func(*model.Entry)) (*model.Entry, *model.Entry, error)
+	// MergeEntries merges two completed entries on the same project, with
+	// no other entry starting between them, into the earlier one and
+	// deletes the later one. updates, if not nil, sets other fields of the
+	// merged entry in the same write.
+	MergeEntries(id, otherID string, updates func(*model.Entry)) (*model.Entry, error)
+
+	GetSettings() *model.Settings
+	SetUserContact(contact *model.ContactInfo) error
+	UpdateSettings(updates func(*model.Settings)) error
+
+	// SaveInvoice saves a new invoice record and marks the given entries
+	// and expenses as billed by it, failing with ErrAlreadyBilled if any of
+	// them is on another invoice. An invoice without an ID is given the
+	// next number from the configured numbering; an ID already in use fails
+	// with ErrDuplicateID. A credit note is applied to the invoice it
+	// credits.
+	SaveInvoice(inv *model.Invoice, entryIDs, expenseIDs []string) error
+	GetInvoice(id string) (*model.Invoice, error)
+	ListInvoices(projectID string, status model.InvoiceStatus) []model.Invoice
+	// MarkInvoicePaid records a payment of the whole balance today
+	MarkInvoicePaid(id string) (*model.Invoice, error)
+	RecordPayment(id string, payment model.Payment) (*model.Invoice, error)
+	// VoidInvoice cancels an unpaid invoice, keeping the record but
+	// releasing the entries and expenses it billed
+	VoidInvoice(id string) (*model.Invoice, error)
+	// DeleteInvoice removes an invoice and releases the entries and
+	// expenses it billed
+	DeleteInvoice(id string) error
+
+	AddExpense(expense model.Expense) (*model.Expense, error)
+	ListExpenses(projectID string, from, to *time.Time) []model.Expense
+	UpdateExpense(id string, updates func(*model.Expense)) (*model.Expense, error)
+	DeleteExpense(id string) error
+
+	// Snapshot returns a copy of everything in the store
+	Snapshot() (*model.Data, error)
+	// Restore replaces everything in the store with data
+	Restore(data *model.Data) error
+	Close() error
+}
+
+// Open opens the store at path, choosing the backend from the file
+// extension: .db, .sqlite and .sqlite3 use SQLite, anything else JSON.
+func Open(path string) (Store, error) {
+	if IsSQLitePath(path) {
+		return NewSQLite(path)
+	}
+	return New(path)
+}
+
+// IsSQLitePath reports whether path names a SQLite database
+func IsSQLitePath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return true
+	}
+	return false
+}
+
+// DefaultPath returns the default data file path. The SQLite database is
+// used once it exists, otherwise the JSON file.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".watchmen")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	dbPath := filepath.Join(dir, "data.db")
+	if _, err := os.Stat(dbPath); err == nil {
+		return dbPath, nil
+	}
+	return filepath.Join(dir, "data.json"), nil
+}
+
+// legacyProject holds the billing contact kept on projects before clients
+// owned it, in data from version 3 and earlier
+type legacyProject struct {
+	ID             string             `json:"id"`
+	BillingContact *model.ContactInfo `json:"billing_contact,omitempty"`
+}
+
+// clientsFromContacts moves the billing contacts once kept on projects into
+// clients, one for each distinct contact, named after its company or
+// person. Projects with the same contact share a client.
+func clientsFromContacts(projects []model.Project, legacy []legacyProject, now time.Time) []model.Client {
+	contacts := make(map[string]*model.ContactInfo)
+	for _, lp := range legacy {
+		if lp.BillingContact != nil && *lp.BillingContact != (model.ContactInfo{}) {
+			contacts[lp.ID] = lp.BillingContact
+		}
+	}
+	var clients []model.Client
+	names := make(map[string]bool)
+	for i := range projects {
+		p := &projects[i]
+		contact := contacts[p.ID]
+		if contact == nil || p.ClientID != "" {
+			continue
+		}
+		j := slices.IndexFunc(clients, func(c model.Client) bool { return *c.Contact == *contact })
+		if j < 0 {
+			name := cmp.Or(contact.Company, contact.Name, p.Name)
+			for n, base := 2, name; names[name]; n++ {
+				name = fmt.Sprintf("%s %d", base, n)
+			}
+			names[name] = true
+			clients = append(clients, model.Client{
+				ID:        generateID(),
+				Name:      name,
+				Contact:   contact,
+				CreatedAt: now,
+			})
+			j = len(clients) - 1
+		}
+		p.ClientID = clients[j].ID
+	}
+	return clients
+}
+
+func generateID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// assignInvoiceID gives inv the next number from settings if it has no ID
+// yet, or checks that the ID it has is not taken. The caller persists
+// settings, whose counter may have moved.
+func assignInvoiceID(settings *model.Settings, inv *model.Invoice, exists func(string) bool) error {
+	if inv.ID != "" {
+		if exists(inv.ID) {
+			return fmt.Errorf("invoice %s: %w", inv.ID, ErrDuplicateID)
+		}
+		return nil
+	}
+	if settings == nil || settings.Numbering == nil {
+		return ErrNoNumbering
+	}
+	inv.ID = settings.NextInvoiceNumber(inv.ProjectName, inv.CreatedAt, exists)
+	return nil
+}
+
+// applyCreditNote records note against the invoice it credits and marks
+// the note itself settled
+func applyCreditNote(original, note *model.Invoice) error {
+	if original.ProjectID != note.ProjectID || original.CurrencyCode() != note.CurrencyCode() {
+		return fmt.Errorf("credit note %s does not match invoice %s", note.ID, original.ID)
+	}
+	err := original.AddPayment(model.Payment{
+		Date:      note.CreatedAt,
+		Amount:    -note.Amount,
+		Method:    "credit note",
+		Reference: note.ID,
+	})
+	if err != nil {
+		return err
+	}
+	note.Status = model.InvoiceStatusPaid
+	return nil
+}
+
+// revokeCreditNote removes the payment a deleted credit note made against
+// the invoice it credited, reopening the invoice if that settled it
+func revokeCreditNote(original, note *model.Invoice) {
+	for i, p := range original.Payments {
+		if p.Method == "credit note" && p.Reference == note.ID {
+			original.Payments = append(original.Payments[:i], original.Payments[i+1:]...)
+			if original.Status == model.InvoiceStatusPaid {
+				original.Status = model.InvoiceStatusPending
+				original.PaidAt = nil
+			}
+			return
+		}
+	}
+}
+
+// voidInvoice marks inv void, refusing once money has been received
+func voidInvoice(inv *model.Invoice, now time.Time) error {
+	if inv.Status != model.InvoiceStatusPending || inv.IsCreditNote() {
+		return fmt.Errorf("invoice %s is %s", inv.ID, inv.Status)
+	}
+	if len(inv.Payments) > 0 {
+		return fmt.Errorf("invoice %s has payments recorded, issue a credit note instead", inv.ID)
+	}
+	inv.Status = model.InvoiceStatusVoid
+	inv.VoidedAt = &now
+	return nil
+}
+
+// runningIndex returns the index of the running entry in entries, or -1
+func runningIndex(entries []model.Entry) int {
+	for i := range entries {
+		if entries[i].IsRunning() {
+			return i
+		}
+	}
+	return -1
+}
+
+// activeIndex returns the index of the running entry in entries, or else
+// of the most recently paused one, or -1 if there is neither
+func activeIndex(entries []model.Entry) int {
+	if i := runningIndex(entries); i >= 0 {
+		return i
+	}
+	active := -1
+	for i := range entries {
+		if entries[i].IsPaused() && (active < 0 || pausedAt(&entries[i]).After(pausedAt(&entries[active]))) {
+			active = i
+		}
+	}
+	return active
+}
+
+func pausedAt(e *model.Entry) time.Time {
+	return *e.Segments[len(e.Segments)-1].End
+}
+
+// pausedIndex returns the index of the paused entry on projectID in
+// entries, or of the only paused entry if projectID is empty
+func pausedIndex(entries []model.Entry, projectID string) (int, error) {
+	if runningIndex(entries) >= 0 {
+		return -1, ErrActiveEntry
+	}
+	found := -1
+	for i := range entries {
+		if !entries[i].IsPaused() || projectID != "" && entries[i].ProjectID != projectID {
+			continue
+		}
+		if found >= 0 {
+			return -1, ErrSeveralPaused
+		}
+		found = i
+	}
+	if found < 0 {
+		return -1, ErrNoPausedEntry
+	}
+	return found, nil
+}
+
+// checkStart checks that an entry on projectID may start alongside the
+// unfinished entries
+func checkStart(entries []model.Entry, projectID string, perProject bool) error {
+	for i := range entries {
+		e := &entries[i]
+		switch {
+		case e.IsRunning():
+			return ErrActiveEntry
+		case e.IsPaused() && !perProject:
+			return ErrActiveEntry
+		case e.IsPaused() && e.ProjectID == projectID:
+			return ErrPausedOnProject
+		}
+	}
+	return nil
+}
+
+// switchTarget returns the index in entries of the entry to stop, or
+// pause if pause is set, when switching to projectID, or -1 if there is
+// none. Unless each project may have a paused entry, a paused entry is
+// stopped too. Nothing is changed, so a switch that cannot happen leaves
+// the timer as it was.
+func switchTarget(entries []model.Entry, projectID string, pause, perProject bool) (int, error) {
+	if pause && !perProject {
+		return -1, errors.New("pausing on switch needs a paused entry per project to be allowed")
+	}
+	i := runningIndex(entries)
+	if i < 0 && !perProject {
+		i = activeIndex(entries)
+	}
+	others := entries
+	if i >= 0 {
+		if pause && entries[i].ProjectID == projectID {
+			return -1, ErrPausedOnProject
+		}
+		others = slices.Delete(slices.Clone(entries), i, i+1)
+	}
+	if err := checkStart(others, projectID, perProject); err != nil {
+		return -1, err
+	}
+	return i, nil
+}
+
+// switchFrom stops e at now, or pauses it if pause is set
+func switchFrom(e *model.Entry, now time.Time, pause bool) {
+	if pause {
+		e.Segments[len(e.Segments)-1].End = &now
+		return
+	}
+	stopEntry(e, now, "")
+}
+
+// trimEntry closes e's open segment at at, which must fall between its
+// start and now, then stops e unless pause is set
+func trimEntry(e *model.Entry, at, now time.Time, pause bool) error {
+	seg := e.OpenSegment()
+	if seg == nil {
+		return ErrAlreadyPaused
+	}
+	if !at.After(seg.Start) {
+		return fmt.Errorf("cannot trim to %s, the timer started at %s", at.Format("Jan 2 3:04 PM"), seg.Start.Format("Jan 2 3:04 PM"))
+	}
+	if at.After(now) {
+		return fmt.Errorf("cannot trim to %s, it is in the future", at.Format("Jan 2 3:04 PM"))
+	}
+	seg.End = &at
+	if !pause {
+		stopEntry(e, at, "")
+	}
+	return nil
+}
+
+// segmentEnd returns the end of seg, or now if it is still open
+func segmentEnd(seg model.TimeSegment, now time.Time) time.Time {
+	if seg.End == nil {
+		return now
+	}
+	return *seg.End
+}
+
+// checkSegments checks that e's segments each end after they start, come
+// in order, and do not overlap the segments of the other entries
+func checkSegments(e *model.Entry, entries []model.Entry, now time.Time) error {
+	if len(e.Segments) == 0 {
+		return errors.New("an entry needs at least one segment")
+	}
+	for i, seg := range e.Segments {
+		if !segmentEnd(seg, now).After(seg.Start) {
+			return fmt.Errorf("segment %d does not end after it starts", i+1)
+		}
+		if i > 0 && seg.Start.Before(segmentEnd(e.Segments[i-1], now)) {
+			return fmt.Errorf("segment %d starts before segment %d ends", i+1, i)
+		}
+	}
+	for _, other := range entries {
+		if other.ID == e.ID {
+			continue
+		}
+		for _, o := range other.Segments {
+			oEnd := segmentEnd(o, now)
+			for _, seg := range e.Segments {
+				if seg.Start.Before(oEnd) && o.Start.Before(segmentEnd(seg, now)) {
+					return fmt.Errorf("%w (%s - %s)", ErrOverlap, o.Start.Local().Format("Jan 2 3:04 PM"), oEnd.Local().Format("3:04 PM"))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setSegments replaces the segments of the completed entry e
+func setSegments(e *model.Entry, segments []model.TimeSegment, entries []model.Entry, now time.Time) error {
+	if !e.Completed {
+		return ErrEntryActive
+	}
+	for i, seg := range segments {
+		if seg.End == nil {
+			return fmt.Errorf("segment %d has no end", i+1)
+		}
+	}
+	edited := *e
+	edited.Segments = segments
+	if err := checkSegments(&edited, entries, now); err != nil {
+		return err
+	}
+	e.Segments = segments
+	return nil
+}
+
+// editEntry applies the changes of EditEntry to e, leaving it untouched
+// unless they are all valid. projectID must already be resolved.
+func editEntry(e *model.Entry, projectID string, segments []model.TimeSegment, updates func(*model.Entry), entries []model.Entry, now time.Time) error {
+	edited := *e
+	edited.Segments = slices.Clone(e.Segments)
+	if projectID != "" {
+		edited.ProjectID = projectID
+	}
+	if segments != nil {
+		if err := setSegments(&edited, segments, entries, now); err != nil {
+			return err
+		}
+	}
+	if updates != nil {
+		updates(&edited)
+	}
+	*e = edited
+	return nil
+}
+
+// splitEntry cuts e short at at, applying updates if not nil, and returns a
+// new entry with the time after it
+func splitEntry(e *model.Entry, at time.Time, updates func(*model.Entry)) (*model.Entry, error) {
+	if !e.Completed {
+		return nil, ErrEntryActive
+	}
+	var before, after []model.TimeSegment
+	for _, seg := range e.Segments {
+		if seg.End == nil {
+			return nil, ErrEntryActive
+		}
+		end := *seg.End
+		switch {
+		case !end.After(at):
+			before = append(before, seg)
+		case !seg.Start.Before(at):
+			after = append(after, seg)
+		default:
+			cut := at
+			before = append(before, model.TimeSegment{Start: seg.Start, End: &cut})
+			after = append(after, model.TimeSegment{Start: at, End: &end})
+		}
+	}
+	if len(before) == 0 || len(after) == 0 {
+		return nil, fmt.Errorf("%s is not within the entry", at.Local().Format("Jan 2 3:04 PM"))
+	}
+
+	rest := *e
+	rest.ID = generateID()
+	rest.Segments = after
+	rest.ExternalID = "" // the import source stays with the original
+	e.Segments = before
+	if updates != nil {
+		updates(e)
+	}
+	return &rest, nil
+}
+
+// mergeEntries moves the segments and note of later into e and applies
+// updates if not nil, checking that no other entry starts between them and
+// none overlaps the result
+func mergeEntries(e, later *model.Entry, entries []model.Entry, updates func(*model.Entry)) error {
+	if !e.Completed || !later.Completed {
+		return ErrEntryActive
+	}
+	if e.ID == later.ID {
+		return errors.New("cannot merge an entry with itself")
+	}
+	if e.ProjectID != later.ProjectID {
+		return errors.New("entries are on different projects")
+	}
+	if e.InvoiceID != later.InvoiceID {
+		return errors.New("entries are billed on different invoices")
+	}
+	sameRate := (e.Rate == nil) == (later.Rate == nil) && (e.Rate == nil || *e.Rate == *later.Rate)
+	if !sameRate || e.Category != later.Category || e.NonBillable != later.NonBillable {
+		return errors.New("entries have a different rate, category or billable status")
+	}
+	for _, other := range entries {
+		if other.ID != e.ID && other.ID != later.ID &&
+			other.StartTime().After(e.StartTime()) && other.StartTime().Before(later.StartTime()) {
+			return errors.New("entries are not adjacent, another entry starts between them")
+		}
+	}
+
+	merged := *e
+	merged.Segments = append(slices.Clone(e.Segments), later.Segments...)
+	switch {
+	case merged.Note == "":
+		merged.Note = later.Note
+	case later.Note != "" && later.Note != merged.Note:
+		merged.Note += " | " + later.Note
+	}
+	if updates != nil {
+		updates(&merged)
+	}
+	others := slices.DeleteFunc(slices.Clone(entries), func(o model.Entry) bool { return o.ID == later.ID })
+	if err := checkSegments(&merged, others, time.Now()); err != nil {
+		return err
+	}
+	*e = merged
+	return nil
+}
+
+// stopEntry closes e's open segment, if any, marks it completed and appends
+// note to its existing note
+func stopEntry(e *model.Entry, now time.Time, note string) {
+	if e.IsRunning() {
+		e.Segments[len(e.Segments)-1].End = &now
+	}
+
+	e.Completed = true
+
+	if note != "" {
+		if e.Note != "" {
+			e.Note += " | " + note
+		} else {
+			e.Note = note
+		}
+	}
+}
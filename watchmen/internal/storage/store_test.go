@@ -0,0 +1,913 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+// forEachBackend runs fn against a fresh, empty store of every backend
+func forEachBackend(t *testing.T, fn func(t *testing.T, s Store)) {
+	t.Helper()
+	for _, name := range []string{"data.json", "data.db"} {
+		t.Run(filepath.Ext(name)[1:], func(t *testing.T) {
+			s, err := Open(filepath.Join(t.TempDir(), name))
+			if err != nil {
+				t.Fatalf("Failed to open store: %v", err)
+			}
+			defer s.Close()
+			fn(t, s)
+		})
+	}
+}
+
+func TestOpenChoosesBackend(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(filepath.Join(dir, "data.json"))
+	if err != nil {
+		t.Fatalf("Open json failed: %v", err)
+	}
+	if _, ok := s.(*JSONStore); !ok {
+		t.Errorf("Expected *JSONStore, got %T", s)
+	}
+	s.Close()
+
+	s, err = Open(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatalf("Open db failed: %v", err)
+	}
+	if _, ok := s.(*SQLiteStore); !ok {
+		t.Errorf("Expected *SQLiteStore, got %T", s)
+	}
+	s.Close()
+}
+
+func TestBackendTimerLifecycle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+
+		if _, err := s.PauseEntry(); err != ErrNoActiveEntry {
+			t.Errorf("Expected ErrNoActiveEntry, got %v", err)
+		}
+		if _, err := s.StartEntry(project.ID, "first", nil); err != nil {
+			t.Fatalf("StartEntry failed: %v", err)
+		}
+		if _, err := s.StartEntry(project.ID, "", nil); err != ErrActiveEntry {
+			t.Errorf("Expected ErrActiveEntry, got %v", err)
+		}
+		if _, err := s.ResumeEntry(""); err != ErrActiveEntry {
+			t.Errorf("Expected ErrActiveEntry, got %v", err)
+		}
+
+		paused, err := s.PauseEntry()
+		if err != nil || !paused.IsPaused() {
+			t.Fatalf("PauseEntry failed: %v", err)
+		}
+		if _, err := s.PauseEntry(); err != ErrAlreadyPaused {
+			t.Errorf("Expected ErrAlreadyPaused, got %v", err)
+		}
+
+		resumed, err := s.ResumeEntry("")
+		if err != nil || !resumed.IsRunning() {
+			t.Fatalf("ResumeEntry failed: %v", err)
+		}
+		if len(resumed.Segments) != 2 {
+			t.Errorf("Expected 2 segments, got %d", len(resumed.Segments))
+		}
+
+		stopped, err := s.StopEntry("done")
+		if err != nil {
+			t.Fatalf("StopEntry failed: %v", err)
+		}
+		if !stopped.Completed || stopped.Note != "first | done" {
+			t.Errorf("Unexpected stopped entry: completed=%v note=%q", stopped.Completed, stopped.Note)
+		}
+		if s.ActiveEntry() != nil {
+			t.Error("Expected no active entry after stop")
+		}
+		if _, err := s.StopEntry(""); err != ErrNoActiveEntry {
+			t.Errorf("Expected ErrNoActiveEntry, got %v", err)
+		}
+	})
+}
+
+func TestBackendSwitchEntry(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		a, _ := s.AddProject("A", 100, "")
+		b, _ := s.AddProject("B", 100, "")
+
+		previous, started, err := s.SwitchEntry(a.ID, "first", false, nil)
+		if err != nil || previous != nil || !started.IsRunning() {
+			t.Fatalf("SwitchEntry with nothing running: previous=%v err=%v", previous, err)
+		}
+		previous, started, err = s.SwitchEntry(b.ID, "second", false, func(e *model.Entry) { e.Category = "review" })
+		if err != nil {
+			t.Fatalf("SwitchEntry failed: %v", err)
+		}
+		if previous.ProjectID != a.ID || !previous.Completed || started.ProjectID != b.ID || started.Category != "review" {
+			t.Errorf("Unexpected switch from %+v to %+v", previous, started)
+		}
+		if !previous.Segments[0].End.Equal(started.StartTime()) {
+			t.Errorf("Expected no gap, stopped at %v and started at %v", previous.Segments[0].End, started.StartTime())
+		}
+		if _, _, err := s.SwitchEntry(a.ID, "", true, nil); err == nil {
+			t.Error("Expected an error pausing on switch with one paused entry allowed")
+		}
+		if _, _, err := s.SwitchEntry("missing", "", false, nil); err != ErrProjectNotFound {
+			t.Errorf("Expected ErrProjectNotFound, got %v", err)
+		}
+		if active := s.ActiveEntry(); active == nil || active.ID != started.ID || active.Category != "review" {
+			t.Error("Expected the failed switches to leave the entry running, with its category")
+		}
+	})
+}
+
+func TestBackendPausePerProject(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		a, _ := s.AddProject("A", 100, "")
+		b, _ := s.AddProject("B", 100, "")
+		s.StartEntry(a.ID, "", nil)
+		s.PauseEntry()
+		if _, err := s.StartEntry(b.ID, "", nil); err != ErrActiveEntry {
+			t.Errorf("Expected ErrActiveEntry, got %v", err)
+		}
+
+		s.UpdateSettings(func(settings *model.Settings) {
+			settings.Timer = &model.TimerSettings{PausePerProject: true}
+		})
+		if _, err := s.StartEntry(a.ID, "", nil); err != ErrPausedOnProject {
+			t.Errorf("Expected ErrPausedOnProject, got %v", err)
+		}
+		running, err := s.StartEntry(b.ID, "", nil)
+		if err != nil {
+			t.Fatalf("StartEntry with A paused failed: %v", err)
+		}
+		if active := s.ActiveEntry(); active == nil || active.ID != running.ID {
+			t.Error("Expected the running entry to be the active one")
+		}
+		if _, err := s.ResumeEntry(a.ID); err != ErrActiveEntry {
+			t.Errorf("Expected ErrActiveEntry resuming while B runs, got %v", err)
+		}
+
+		if _, _, err := s.SwitchEntry(a.ID, "", true, nil); err != ErrPausedOnProject {
+			t.Errorf("Expected ErrPausedOnProject switching to paused A, got %v", err)
+		}
+		if _, err := s.PauseEntry(); err != nil {
+			t.Fatalf("Expected the failed switch to leave B running, got %v", err)
+		}
+		if got := s.PausedEntries(); len(got) != 2 {
+			t.Fatalf("Expected 2 paused entries, got %d", len(got))
+		}
+		if _, err := s.ResumeEntry(""); err != ErrSeveralPaused {
+			t.Errorf("Expected ErrSeveralPaused, got %v", err)
+		}
+		resumed, err := s.ResumeEntry(a.ID)
+		if err != nil || resumed.ProjectID != a.ID || !resumed.IsRunning() {
+			t.Fatalf("ResumeEntry(A) failed: %v", err)
+		}
+
+		if _, _, err := s.SwitchEntry(b.ID, "", true, nil); err != ErrPausedOnProject {
+			t.Errorf("Expected ErrPausedOnProject switching to paused B, got %v", err)
+		}
+		s.StopEntry("")
+		if got := s.PausedEntries(); len(got) != 1 || got[0].ProjectID != b.ID {
+			t.Fatalf("Expected B still paused, got %+v", got)
+		}
+		s.ResumeEntry(b.ID)
+		previous, started, err := s.SwitchEntry(a.ID, "", true, nil)
+		if err != nil || !previous.IsPaused() || started.ProjectID != a.ID {
+			t.Fatalf("SwitchEntry with pause failed: %v", err)
+		}
+	})
+}
+
+func TestBackendTrimEntry(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+		if _, err := s.TrimEntry(time.Now(), false); err != ErrNoActiveEntry {
+			t.Errorf("Expected ErrNoActiveEntry, got %v", err)
+		}
+
+		entry, _ := s.StartEntry(project.ID, "forgot", nil)
+		start := time.Now().Add(-10 * time.Hour).Truncate(time.Second)
+		s.UpdateEntry(entry.ID, func(e *model.Entry) { e.Segments[0].Start = start })
+
+		if _, err := s.TrimEntry(start.Add(-time.Minute), false); err == nil {
+			t.Error("Expected an error trimming before the start")
+		}
+		if _, err := s.TrimEntry(time.Now().Add(time.Hour), false); err == nil {
+			t.Error("Expected an error trimming to the future")
+		}
+
+		paused, err := s.TrimEntry(start.Add(2*time.Hour), true)
+		if err != nil {
+			t.Fatalf("TrimEntry failed: %v", err)
+		}
+		if !paused.IsPaused() || paused.Duration() != 2*time.Hour {
+			t.Errorf("Expected a paused 2h entry, got paused=%v %v", paused.IsPaused(), paused.Duration())
+		}
+		if _, err := s.TrimEntry(start.Add(time.Hour), false); err != ErrAlreadyPaused {
+			t.Errorf("Expected ErrAlreadyPaused, got %v", err)
+		}
+
+		s.ResumeEntry("")
+		s.UpdateEntry(entry.ID, func(e *model.Entry) { e.Segments[1].Start = time.Now().Add(-time.Hour) })
+		stopped, err := s.TrimEntry(time.Now().Add(-time.Minute), false)
+		if err != nil {
+			t.Fatalf("TrimEntry failed: %v", err)
+		}
+		if !stopped.Completed || s.ActiveEntry() != nil {
+			t.Error("Expected the entry to be stopped")
+		}
+	})
+}
+
+func TestBackendListEntriesFilters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		p1, _ := s.AddProject("One", 100, "")
+		p2, _ := s.AddProject("Two", 100, "")
+
+		day := func(d int) time.Time { return time.Date(2026, 1, d, 9, 0, 0, 0, time.Local) }
+		s.LogEntry(p1.ID, "a", day(1), day(1).Add(time.Hour), nil)
+		s.LogEntry(p2.ID, "b", day(2), day(2).Add(time.Hour), nil)
+		s.LogEntry(p1.ID, "c", day(3), day(3).Add(time.Hour), nil)
+
+		if got := s.ListEntries("", nil, nil); len(got) != 3 {
+			t.Errorf("Expected 3 entries, got %d", len(got))
+		}
+		byName := s.ListEntries("One", nil, nil)
+		if len(byName) != 2 || byName[0].Note != "a" || byName[1].Note != "c" {
+			t.Errorf("Unexpected entries filtered by project name: %+v", byName)
+		}
+		if got := s.ListEntries(p2.ID, nil, nil); len(got) != 1 {
+			t.Errorf("Expected 1 entry for project ID, got %d", len(got))
+		}
+
+		from, to := day(2), day(3)
+		ranged := s.ListEntries("", &from, &to)
+		if len(ranged) != 2 || ranged[0].Note != "b" {
+			t.Errorf("Unexpected entries in range: %+v", ranged)
+		}
+		if len(ranged[0].Segments) != 1 || ranged[0].Duration() != time.Hour {
+			t.Errorf("Segments not loaded correctly: %+v", ranged[0].Segments)
+		}
+	})
+}
+
+func TestBackendAmendAndDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+		now := time.Now()
+		s.LogEntry(project.ID, "first", now.Add(-3*time.Hour), now.Add(-2*time.Hour), nil)
+		s.LogEntry(project.ID, "second", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+		s.StartEntry(project.ID, "running", nil)
+
+		amended, err := s.AmendEntry(2, "updated")
+		if err != nil {
+			t.Fatalf("AmendEntry failed: %v", err)
+		}
+		if amended.Note != "updated" {
+			t.Errorf("Expected amended note, got %q", amended.Note)
+		}
+		if _, err := s.AmendEntry(3, "x"); err != ErrInvalidIndex {
+			t.Errorf("Expected ErrInvalidIndex, got %v", err)
+		}
+
+		entries := s.ListEntries("", nil, nil)
+		if entries[0].Note != "updated" || entries[1].Note != "second" {
+			t.Errorf("Wrong entry amended: %q, %q", entries[0].Note, entries[1].Note)
+		}
+
+		if err := s.DeleteEntry(entries[0].ID); err != nil {
+			t.Fatalf("DeleteEntry failed: %v", err)
+		}
+		if err := s.DeleteEntry(entries[0].ID); err != ErrEntryNotFound {
+			t.Errorf("Expected ErrEntryNotFound, got %v", err)
+		}
+		if got := s.ListEntries("", nil, nil); len(got) != 2 {
+			t.Errorf("Expected 2 entries after delete, got %d", len(got))
+		}
+	})
+}
+
+func TestBackendUpdateEntry(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 10000, "")
+		start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+		entry, _ := s.LogEntry(project.ID, "work", start, start.Add(time.Hour), nil)
+
+		rate := int64(7500)
+		_, err := s.UpdateEntry(entry.ID, func(e *model.Entry) {
+			e.Rate = &rate
+			e.Category = "meetings"
+			e.NonBillable = true
+		})
+		if err != nil {
+			t.Fatalf("UpdateEntry failed: %v", err)
+		}
+		got := s.ListEntries("", nil, nil)[0]
+		if got.Rate == nil || *got.Rate != 7500 || got.Category != "meetings" || got.IsBillable() {
+			t.Errorf("Entry not updated: %+v", got)
+		}
+		if len(got.Segments) != 1 || got.Note != "work" {
+			t.Errorf("UpdateEntry changed other fields: %+v", got)
+		}
+
+		if _, err := s.UpdateEntry(entry.ID, func(e *model.Entry) { e.Rate = nil }); err != nil {
+			t.Fatalf("UpdateEntry failed: %v", err)
+		}
+		if got := s.ListEntries("", nil, nil)[0]; got.Rate != nil {
+			t.Errorf("Expected no rate override, got %d", *got.Rate)
+		}
+
+		if _, err := s.UpdateEntry("missing", func(e *model.Entry) {}); err != ErrEntryNotFound {
+			t.Errorf("Expected ErrEntryNotFound, got %v", err)
+		}
+	})
+}
+
+func TestBackendLogEntryRejectsOverlap(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+		start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+		if _, err := s.LogEntry(project.ID, "", start, start.Add(2*time.Hour), nil); err != nil {
+			t.Fatalf("LogEntry failed: %v", err)
+		}
+
+		if _, err := s.LogEntry(project.ID, "", start.Add(time.Hour), start.Add(3*time.Hour), nil); !errors.Is(err, ErrOverlap) {
+			t.Errorf("Expected ErrOverlap, got %v", err)
+		}
+		if _, err := s.LogEntry(project.ID, "", start, start.Add(-time.Hour), nil); err == nil {
+			t.Error("Expected an error for an entry ending before it starts")
+		}
+		if _, err := s.LogEntry(project.ID, "", start.Add(2*time.Hour), start.Add(3*time.Hour), nil); err != nil {
+			t.Errorf("Expected an entry starting as another ends to be logged, got %v", err)
+		}
+		if got := s.ListEntries("", nil, nil); len(got) != 2 {
+			t.Errorf("Expected 2 entries, got %d", len(got))
+		}
+	})
+}
+
+func TestBackendLogEntryWithFields(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+		start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+		_, err := s.LogEntry(project.ID, "standup", start, start.Add(time.Hour), func(e *model.Entry) {
+			e.ExternalID = "uid-1"
+			e.Category = "meetings"
+			e.NonBillable = true
+		})
+		if err != nil {
+			t.Fatalf("LogEntry failed: %v", err)
+		}
+		// The fields are saved with the entry rather than after it
+		got := s.ListEntries("", nil, nil)
+		if len(got) != 1 || got[0].ExternalID != "uid-1" || got[0].Category != "meetings" || got[0].IsBillable() {
+			t.Errorf("Entry = %+v", got)
+		}
+	})
+}
+
+func TestBackendStartEntryWithFields(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+		rate := int64(20000)
+		if _, err := s.StartEntry(project.ID, "review", func(e *model.Entry) {
+			e.Rate = &rate
+			e.Category = "review"
+		}); err != nil {
+			t.Fatalf("StartEntry failed: %v", err)
+		}
+		active := s.ActiveEntry()
+		if active == nil || active.Rate == nil || *active.Rate != rate || active.Category != "review" {
+			t.Errorf("Active entry = %+v", active)
+		}
+	})
+}
+
+func TestBackendEditSegments(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 10000, "")
+		other, _ := s.AddProject("Other", 10000, "")
+		day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local)
+		at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+		seg := func(start, end time.Time) model.TimeSegment { return model.TimeSegment{Start: start, End: &end} }
+
+		morning, _ := s.LogEntry(project.ID, "morning", at(9, 0), at(11, 0), nil)
+		afternoon, _ := s.LogEntry(project.ID, "afternoon", at(13, 0), at(15, 0), nil)
+
+		if _, err := s.EditEntry(morning.ID, "", []model.TimeSegment{seg(at(10, 0), at(9, 30))}, nil); err == nil {
+			t.Error("Expected an error for a segment ending before it starts")
+		}
+		if _, err := s.EditEntry(morning.ID, "", []model.TimeSegment{seg(at(9, 0), at(13, 30))}, nil); !errors.Is(err, ErrOverlap) {
+			t.Errorf("Expected ErrOverlap, got %v", err)
+		}
+		shifted, err := s.EditEntry(morning.ID, "", []model.TimeSegment{seg(at(8, 30), at(11, 30))}, nil)
+		if err != nil {
+			t.Fatalf("EditEntry failed: %v", err)
+		}
+		if shifted.Duration() != 3*time.Hour || !shifted.StartTime().Equal(at(8, 30)) {
+			t.Errorf("Unexpected segments: %+v", shifted.Segments)
+		}
+
+		moved, err := s.EditEntry(afternoon.ID, "Other", nil, nil)
+		if err != nil || moved.ProjectID != other.ID {
+			t.Fatalf("EditEntry(move) failed: %v", err)
+		}
+		if _, err := s.EditEntry(afternoon.ID, "missing", nil, nil); err != ErrProjectNotFound {
+			t.Errorf("Expected ErrProjectNotFound, got %v", err)
+		}
+		// A move is not saved when the new times are refused
+		if _, err := s.EditEntry(afternoon.ID, project.ID, []model.TimeSegment{seg(at(11, 0), at(14, 0))}, nil); !errors.Is(err, ErrOverlap) {
+			t.Errorf("Expected ErrOverlap, got %v", err)
+		}
+		if got := s.ListEntries("Other", nil, nil); len(got) != 1 || got[0].ID != afternoon.ID || !got[0].StartTime().Equal(at(13, 0)) {
+			t.Errorf("Rejected edit changed the entry: %+v", got)
+		}
+		if _, err := s.MergeEntries(morning.ID, afternoon.ID, nil); err == nil {
+			t.Error("Expected an error merging entries on different projects")
+		}
+		s.EditEntry(afternoon.ID, project.ID, nil, nil)
+
+		if _, _, err := s.SplitEntry(morning.ID, at(12, 0), nil); err == nil {
+			t.Error("Expected an error splitting outside the entry")
+		}
+		first, rest, err := s.SplitEntry(morning.ID, at(10, 0), func(e *model.Entry) { e.Note = "standup" })
+		if err != nil {
+			t.Fatalf("SplitEntry failed: %v", err)
+		}
+		if first.Duration() != 90*time.Minute || rest.Duration() != 90*time.Minute || first.Note != "standup" || rest.Note != "morning" {
+			t.Errorf("Unexpected split: %v %q and %v %q", first.Duration(), first.Note, rest.Duration(), rest.Note)
+		}
+		if got := s.ListEntries("", nil, nil); len(got) != 3 {
+			t.Fatalf("Expected 3 entries after split, got %d", len(got))
+		}
+
+		if _, err := s.MergeEntries(first.ID, afternoon.ID, nil); err == nil {
+			t.Error("Expected an error merging entries with another between them")
+		}
+		merged, err := s.MergeEntries(rest.ID, first.ID, func(e *model.Entry) { e.Note = "morning" })
+		if err != nil {
+			t.Fatalf("MergeEntries failed: %v", err)
+		}
+		if merged.ID != first.ID || merged.Duration() != 3*time.Hour || len(merged.Segments) != 2 || merged.Note != "morning" {
+			t.Errorf("Unexpected merge: %+v", merged)
+		}
+		got := s.ListEntries("", nil, nil)
+		if len(got) != 2 {
+			t.Errorf("Expected 2 entries after merge, got %d", len(got))
+		}
+		if got[0].ID != first.ID || got[0].Note != "morning" {
+			t.Errorf("Merged entry saved as %+v", got[0])
+		}
+
+		if _, err := s.StartEntry(project.ID, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.SplitEntry(s.ActiveEntry().ID, time.Now(), nil); err != ErrEntryActive {
+			t.Errorf("Expected ErrEntryActive, got %v", err)
+		}
+	})
+}
+
+func TestSplitEntryOpenSegment(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+	end := start.Add(time.Hour)
+	e := model.Entry{Completed: true, Segments: []model.TimeSegment{{Start: start, End: &end}, {Start: start.Add(2 * time.Hour)}}}
+	if _, err := splitEntry(&e, start.Add(30*time.Minute), nil); !errors.Is(err, ErrEntryActive) {
+		t.Errorf("splitEntry() error = %v, want ErrEntryActive", err)
+	}
+}
+
+func TestBackendProjectsAndSettings(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		s.AddProject("Test", 100, "desc")
+
+		err := s.UpdateProject("Test", func(p *model.Project) {
+			p.PurchaseOrder = "PO-1"
+		})
+		if err != nil {
+			t.Fatalf("UpdateProject failed: %v", err)
+		}
+		p, err := s.GetProject("Test")
+		if err != nil || p.PurchaseOrder != "PO-1" {
+			t.Errorf("Project not updated: %+v, %v", p, err)
+		}
+		if _, err := s.GetProject("missing"); err != ErrProjectNotFound {
+			t.Errorf("Expected ErrProjectNotFound, got %v", err)
+		}
+		if err := s.UpdateProject("missing", func(*model.Project) {}); err != ErrProjectNotFound {
+			t.Errorf("Expected ErrProjectNotFound, got %v", err)
+		}
+
+		if s.GetSettings().UserContact != nil {
+			t.Error("Expected no user contact")
+		}
+		s.SetUserContact(&model.ContactInfo{Name: "Me"})
+		if got := s.GetSettings().UserContact; got == nil || got.Name != "Me" {
+			t.Errorf("User contact not saved: %+v", got)
+		}
+	})
+}
+
+func TestBackendClients(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		if len(s.ListClients()) != 0 {
+			t.Fatal("Expected no clients")
+		}
+		c, err := s.AddClient("Acme")
+		if err != nil {
+			t.Fatalf("AddClient failed: %v", err)
+		}
+		s.AddClient("Globex")
+
+		err = s.UpdateClient("Acme", func(c *model.Client) {
+			c.Contact = &model.ContactInfo{Company: "Acme Inc"}
+			c.PaymentTerms = 14
+			c.Currency = "EUR"
+		})
+		if err != nil {
+			t.Fatalf("UpdateClient failed: %v", err)
+		}
+		got, err := s.GetClient(c.ID)
+		if err != nil || got.Contact == nil || got.Contact.Company != "Acme Inc" || got.PaymentTerms != 14 || got.CurrencyCode() != "EUR" {
+			t.Errorf("Client not updated: %+v, %v", got, err)
+		}
+		if got, err := s.GetClient("Acme"); err != nil || got.ID != c.ID {
+			t.Errorf("GetClient by name = %+v, %v", got, err)
+		}
+		if clients := s.ListClients(); len(clients) != 2 || clients[0].Name != "Acme" {
+			t.Errorf("ListClients = %+v", clients)
+		}
+		if _, err := s.GetClient("missing"); err != ErrClientNotFound {
+			t.Errorf("Expected ErrClientNotFound, got %v", err)
+		}
+		if err := s.UpdateClient("missing", func(*model.Client) {}); err != ErrClientNotFound {
+			t.Errorf("Expected ErrClientNotFound, got %v", err)
+		}
+	})
+}
+
+func TestBackendSetBillingContact(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		s.AddProject("web", 100, "")
+		s.AddProject("Acme", 100, "")
+		s.AddClient("Acme")
+
+		setPO := func(p *model.Project) { p.PurchaseOrder = "PO-1" }
+		c, err := s.SetBillingContact("web", &model.ContactInfo{Name: "Jane"}, setPO)
+		if err != nil || c.Name != "web" || c.Contact.Name != "Jane" {
+			t.Fatalf("SetBillingContact = %+v, %v", c, err)
+		}
+		p, _ := s.GetProject("web")
+		if p.ClientID != c.ID || p.PurchaseOrder != "PO-1" {
+			t.Errorf("Project not linked: %+v", p)
+		}
+
+		// A second contact updates the same client
+		again, err := s.SetBillingContact("web", &model.ContactInfo{Name: "John"}, nil)
+		if err != nil || again.ID != c.ID || len(s.ListClients()) != 2 {
+			t.Errorf("SetBillingContact again = %+v, %v with %d clients", again, err, len(s.ListClients()))
+		}
+		if got, _ := s.GetClient(c.ID); got.Contact.Name != "John" {
+			t.Errorf("Contact not updated: %+v", got.Contact)
+		}
+
+		// Nothing is saved when the client's name is taken
+		if _, err := s.SetBillingContact("Acme", &model.ContactInfo{Name: "Bob"}, setPO); err != ErrClientExists {
+			t.Errorf("Expected ErrClientExists, got %v", err)
+		}
+		if p, _ := s.GetProject("Acme"); p.ClientID != "" || p.PurchaseOrder != "" {
+			t.Errorf("Project changed by a failed SetBillingContact: %+v", p)
+		}
+		if _, err := s.SetBillingContact("missing", &model.ContactInfo{}, nil); err != ErrProjectNotFound {
+			t.Errorf("Expected ErrProjectNotFound, got %v", err)
+		}
+	})
+}
+
+func TestBackendInvoices(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+
+		s.SaveInvoice(&model.Invoice{ID: "INV-1", ProjectID: project.ID, Amount: 100}, nil, nil)
+		s.SaveInvoice(&model.Invoice{ID: "INV-2", ProjectID: project.ID, Amount: 200}, nil, nil)
+
+		inv, err := s.GetInvoice("INV-1")
+		if err != nil || inv.Status != model.InvoiceStatusPending {
+			t.Fatalf("GetInvoice failed: %+v, %v", inv, err)
+		}
+		if _, err := s.MarkInvoicePaid("INV-2"); err != nil {
+			t.Fatalf("MarkInvoicePaid failed: %v", err)
+		}
+		if got := s.ListInvoices("Test", model.InvoiceStatusPending); len(got) != 1 || got[0].ID != "INV-1" {
+			t.Errorf("Unexpected pending invoices: %+v", got)
+		}
+		if got := s.ListInvoices("", model.InvoiceStatusPaid); len(got) != 1 || got[0].PaidAt == nil {
+			t.Errorf("Unexpected paid invoices: %+v", got)
+		}
+
+		if err := s.DeleteInvoice("INV-1"); err != nil {
+			t.Fatalf("DeleteInvoice failed: %v", err)
+		}
+		if _, err := s.GetInvoice("INV-1"); err != ErrInvoiceNotFound {
+			t.Errorf("Expected ErrInvoiceNotFound, got %v", err)
+		}
+	})
+}
+
+func TestBackendInvoiceBillsEntries(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+		start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+		first, _ := s.LogEntry(project.ID, "first", start, start.Add(time.Hour), nil)
+		second, _ := s.LogEntry(project.ID, "second", start.Add(2*time.Hour), start.Add(3*time.Hour), nil)
+		expense, _ := s.AddExpense(model.Expense{ProjectID: project.ID, Date: start, Description: "Licence", UnitAmount: 4999})
+
+		err := s.SaveInvoice(&model.Invoice{ID: "INV-1", ProjectID: project.ID}, []string{first.ID}, []string{expense.ID})
+		if err != nil {
+			t.Fatalf("SaveInvoice failed: %v", err)
+		}
+		for _, e := range s.ListEntries("", nil, nil) {
+			want := ""
+			if e.ID == first.ID {
+				want = "INV-1"
+			}
+			if e.InvoiceID != want {
+				t.Errorf("Entry %q billed on %q, want %q", e.Note, e.InvoiceID, want)
+			}
+		}
+		if got := s.ListExpenses("", nil, nil); got[0].InvoiceID != "INV-1" {
+			t.Errorf("Expense billed on %q, want INV-1", got[0].InvoiceID)
+		}
+
+		// Billing an entry twice fails and leaves nothing half-saved
+		err = s.SaveInvoice(&model.Invoice{ID: "INV-2", ProjectID: project.ID}, []string{second.ID, first.ID}, nil)
+		if !errors.Is(err, ErrAlreadyBilled) {
+			t.Fatalf("Expected ErrAlreadyBilled, got %v", err)
+		}
+		if _, err := s.GetInvoice("INV-2"); err != ErrInvoiceNotFound {
+			t.Errorf("Rejected invoice was saved: %v", err)
+		}
+		for _, e := range s.ListEntries("", nil, nil) {
+			if e.ID == second.ID && e.IsBilled() {
+				t.Error("Rejected invoice billed the second entry")
+			}
+		}
+
+		// Deleting the invoice releases its entries and expenses
+		if err := s.DeleteInvoice("INV-1"); err != nil {
+			t.Fatalf("DeleteInvoice failed: %v", err)
+		}
+		for _, e := range s.ListEntries("", nil, nil) {
+			if e.IsBilled() {
+				t.Errorf("Entry %q still billed on %q", e.Note, e.InvoiceID)
+			}
+		}
+		if got := s.ListExpenses("", nil, nil); got[0].InvoiceID != "" {
+			t.Errorf("Expense still billed on %q", got[0].InvoiceID)
+		}
+	})
+}
+
+func TestBackendInvoiceLifecycle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+		start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+		entry, _ := s.LogEntry(project.ID, "work", start, start.Add(time.Hour), nil)
+
+		s.SaveInvoice(&model.Invoice{ID: "INV-1", ProjectID: project.ID, Amount: 30000}, nil, nil)
+		s.SaveInvoice(&model.Invoice{ID: "INV-2", ProjectID: project.ID, Amount: 10000}, []string{entry.ID}, nil)
+
+		inv, err := s.RecordPayment("INV-1", model.Payment{Date: start, Amount: 10000, Method: "card"})
+		if err != nil || inv.Balance() != 20000 || inv.Status != model.InvoiceStatusPending {
+			t.Fatalf("RecordPayment() = %+v, %v", inv, err)
+		}
+
+		// A credit note is saved on its own and reduces the original's balance
+		err = s.SaveInvoice(&model.Invoice{ID: "CN-1", ProjectID: project.ID, Amount: -5000, CreditFor: "INV-1"}, nil, nil)
+		if err != nil {
+			t.Fatalf("SaveInvoice(credit note) error = %v", err)
+		}
+		inv, _ = s.GetInvoice("INV-1")
+		if inv.Balance() != 15000 || len(inv.Payments) != 2 || inv.Payments[1].Reference != "CN-1" {
+			t.Errorf("Credit note not applied: %+v", inv)
+		}
+		if note, _ := s.GetInvoice("CN-1"); note.Balance() != 0 {
+			t.Errorf("Credit note balance = %d, want 0", note.Balance())
+		}
+
+		inv, err = s.MarkInvoicePaid("INV-1")
+		if err != nil || inv.Status != model.InvoiceStatusPaid || inv.Payments[2].Amount != 15000 {
+			t.Errorf("MarkInvoicePaid() = %+v, %v", inv, err)
+		}
+
+		// Voiding keeps the record and releases its entries
+		if _, err := s.VoidInvoice("INV-1"); err == nil {
+			t.Error("VoidInvoice() should refuse a paid invoice")
+		}
+		inv, err = s.VoidInvoice("INV-2")
+		if err != nil || inv.Status != model.InvoiceStatusVoid || inv.VoidedAt == nil {
+			t.Fatalf("VoidInvoice() = %+v, %v", inv, err)
+		}
+		if entries := s.ListEntries("", nil, nil); entries[0].IsBilled() {
+			t.Error("Voided invoice did not release its entry")
+		}
+		if got := s.ListInvoices("", model.InvoiceStatusVoid); len(got) != 1 || got[0].ID != "INV-2" {
+			t.Errorf("Unexpected void invoices: %+v", got)
+		}
+
+		// An invoice with a credit note stays until the note is deleted,
+		// which takes the credit back off it
+		if err := s.DeleteInvoice("INV-1"); !errors.Is(err, ErrCredited) {
+			t.Errorf("DeleteInvoice(credited) error = %v, want ErrCredited", err)
+		}
+		if err := s.DeleteInvoice("CN-1"); err != nil {
+			t.Fatalf("DeleteInvoice(credit note) error = %v", err)
+		}
+		inv, _ = s.GetInvoice("INV-1")
+		if inv.Status != model.InvoiceStatusPending || inv.PaidAt != nil || inv.Balance() != 5000 || len(inv.Payments) != 2 {
+			t.Errorf("Credit note not reversed: %+v", inv)
+		}
+		if err := s.DeleteInvoice("INV-1"); err != nil {
+			t.Errorf("DeleteInvoice() error = %v", err)
+		}
+	})
+}
+
+func TestBackendInvoiceNumbering(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		project, _ := s.AddProject("Test", 100, "")
+
+		if err := s.SaveInvoice(&model.Invoice{ProjectID: project.ID}, nil, nil); !errors.Is(err, ErrNoNumbering) {
+			t.Errorf("SaveInvoice() without numbering error = %v, want ErrNoNumbering", err)
+		}
+
+		s.UpdateSettings(func(settings *model.Settings) {
+			settings.Numbering = &model.Numbering{Template: "INV-{seq}", Digits: 4}
+		})
+		s.SaveInvoice(&model.Invoice{ID: "INV-0002", ProjectID: project.ID}, nil, nil)
+		var ids []string
+		for range 2 {
+			inv := &model.Invoice{ProjectID: project.ID}
+			if err := s.SaveInvoice(inv, nil, nil); err != nil {
+				t.Fatalf("SaveInvoice() error = %v", err)
+			}
+			ids = append(ids, inv.ID)
+		}
+		if ids[0] != "INV-0001" || ids[1] != "INV-0003" {
+			t.Errorf("Allocated %v, want [INV-0001 INV-0003]", ids)
+		}
+		if seq := s.GetSettings().InvoiceSeq["all"]; seq != 3 {
+			t.Errorf("Counter = %d, want 3", seq)
+		}
+
+		err := s.SaveInvoice(&model.Invoice{ID: "INV-0003", ProjectID: project.ID}, nil, nil)
+		if !errors.Is(err, ErrDuplicateID) {
+			t.Errorf("SaveInvoice() with a used ID error = %v, want ErrDuplicateID", err)
+		}
+		if got := s.ListInvoices("", ""); len(got) != 3 {
+			t.Errorf("Expected 3 invoices, got %d", len(got))
+		}
+	})
+}
+
+func TestBackendExpenses(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		p1, _ := s.AddProject("One", 100, "")
+		p2, _ := s.AddProject("Two", 100, "")
+
+		jan := time.Date(2026, 1, 10, 0, 0, 0, 0, time.Local)
+		feb := time.Date(2026, 2, 10, 0, 0, 0, 0, time.Local)
+		mileage, err := s.AddExpense(model.Expense{ProjectID: p1.ID, Date: jan, Description: "Mileage", Quantity: 120, UnitAmount: 50})
+		if err != nil || mileage.ID == "" {
+			t.Fatalf("AddExpense failed: %+v, %v", mileage, err)
+		}
+		s.AddExpense(model.Expense{ProjectID: p1.ID, Date: feb, Description: "Licence", UnitAmount: 4999})
+		s.AddExpense(model.Expense{ProjectID: p2.ID, Date: jan, Description: "Other", UnitAmount: 100})
+
+		if got := s.ListExpenses("", nil, nil); len(got) != 3 {
+			t.Errorf("Expected 3 expenses, got %d", len(got))
+		}
+		from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+		to := time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)
+		got := s.ListExpenses("One", &from, &to)
+		if len(got) != 1 || got[0].ID != mileage.ID || got[0].Total() != 6000 {
+			t.Errorf("Unexpected filtered expenses: %+v", got)
+		}
+
+		updated, err := s.UpdateExpense(mileage.ID, func(e *model.Expense) { e.InvoiceID = "INV-9" })
+		if err != nil || updated.InvoiceID != "INV-9" {
+			t.Fatalf("UpdateExpense = %+v, %v", updated, err)
+		}
+		if got := s.ListExpenses(p1.ID, &from, &to); got[0].InvoiceID != "INV-9" {
+			t.Errorf("Updated expense not saved: %+v", got[0])
+		}
+		if _, err := s.UpdateExpense("missing", func(e *model.Expense) {}); err != ErrExpenseNotFound {
+			t.Errorf("Expected ErrExpenseNotFound, got %v", err)
+		}
+
+		if err := s.DeleteExpense(mileage.ID); err != nil {
+			t.Fatalf("DeleteExpense failed: %v", err)
+		}
+		if err := s.DeleteExpense(mileage.ID); err != ErrExpenseNotFound {
+			t.Errorf("Expected ErrExpenseNotFound, got %v", err)
+		}
+		if got := s.ListExpenses(p1.ID, nil, nil); len(got) != 1 {
+			t.Errorf("Expected 1 expense left on One, got %d", len(got))
+		}
+	})
+}
+
+func TestSQLiteStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	s, err := NewSQLite(path)
+	if err != nil {
+		t.Fatalf("NewSQLite failed: %v", err)
+	}
+	project, _ := s.AddProject("Test", 100, "")
+	s.StartEntry(project.ID, "note", nil)
+	s.PauseEntry()
+	s.Close()
+
+	s, err = NewSQLite(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer s.Close()
+	active := s.ActiveEntry()
+	if active == nil || !active.IsPaused() || active.Note != "note" {
+		t.Errorf("Paused entry not persisted: %+v", active)
+	}
+}
+
+func TestSQLiteMigrationFromV1(t *testing.T) {
+	// A database as first written by the SQLite backend, before clients,
+	// expenses and invoice links
+	path := filepath.Join(t.TempDir(), "data.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE projects (id TEXT PRIMARY KEY, name TEXT NOT NULL, data TEXT NOT NULL);
+		CREATE TABLE entries (
+			id TEXT PRIMARY KEY, project_id TEXT NOT NULL, start_time INTEGER NOT NULL,
+			completed INTEGER NOT NULL DEFAULT 0, data TEXT NOT NULL
+		);
+		CREATE TABLE segments (
+			entry_id TEXT NOT NULL, seq INTEGER NOT NULL, start_at TEXT NOT NULL, end_at TEXT,
+			PRIMARY KEY (entry_id, seq)
+		);
+		CREATE TABLE invoices (id TEXT NOT NULL, project_id TEXT NOT NULL, status TEXT NOT NULL, data TEXT NOT NULL);
+		CREATE TABLE settings (id INTEGER PRIMARY KEY CHECK (id = 1), data TEXT NOT NULL);
+		INSERT INTO projects VALUES ('p1', 'acme', '{"id":"p1","name":"acme","hourly_rate":150.5,"created_at":"2026-01-01T00:00:00Z"}');
+		INSERT INTO entries VALUES ('e1', 'p1', 1767258000, 1, '{"id":"e1","project_id":"p1","completed":true}');
+		INSERT INTO segments VALUES ('e1', 0, '2026-01-01T09:00:00Z', '2026-01-01T10:00:00Z');
+		PRAGMA user_version = 1;
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSQLite(path)
+	if err != nil {
+		t.Fatalf("NewSQLite failed: %v", err)
+	}
+	defer s.Close()
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil || version != sqliteSchemaVersion {
+		t.Errorf("user_version = %d, %v, want %d", version, err, sqliteSchemaVersion)
+	}
+	project, err := s.GetProject("acme")
+	if err != nil || project.HourlyRate != 15050 {
+		t.Fatalf("project = %+v, %v, want a rate of 15050", project, err)
+	}
+
+	// The migrated tables can link entries and expenses to an invoice
+	entries := s.ListEntries("", nil, nil)
+	if len(entries) != 1 || entries[0].Duration() != time.Hour {
+		t.Fatalf("entries = %+v, want the one-hour entry", entries)
+	}
+	expense, err := s.AddExpense(model.Expense{ProjectID: "p1", Date: time.Now(), Description: "Train", UnitAmount: 2500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv := &model.Invoice{ID: "INV-1", ProjectID: "p1", ProjectName: "acme", CreatedAt: time.Now()}
+	if err := s.SaveInvoice(inv, []string{"e1"}, []string{expense.ID}); err != nil {
+		t.Fatalf("SaveInvoice failed: %v", err)
+	}
+	if entries := s.ListEntries("", nil, nil); entries[0].InvoiceID != inv.ID {
+		t.Errorf("entry invoice = %q, want %s", entries[0].InvoiceID, inv.ID)
+	}
+	if expenses := s.ListExpenses("", nil, nil); len(expenses) != 1 || expenses[0].InvoiceID != inv.ID {
+		t.Errorf("expenses = %+v, want billed on %s", expenses, inv.ID)
+	}
+}
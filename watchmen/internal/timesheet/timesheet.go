@@ -0,0 +1,236 @@
+// Package timesheet lays out tracked time as a grid of projects by day
+package timesheet
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"watchmen/internal/model"
+	"watchmen/internal/period"
+)
+
+// Sheet is the time on each project for each day of a period
+type Sheet struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"` // midnight after the last day
+	Days      []string  `json:"days"`
+	Rows      []Row     `json:"projects"`
+	DayTotals []float64 `json:"day_totals"`
+	Total     float64   `json:"total"`
+}
+
+// Row is one project's hours on each day of the sheet
+type Row struct {
+	ProjectID string    `json:"project_id"`
+	Project   string    `json:"project"`
+	Hours     []float64 `json:"hours"`
+	Total     float64   `json:"total"`
+	Budget    *Budget   `json:"budget,omitempty"`
+}
+
+// Budget is how a project stands against its budget
+type Budget struct {
+	Description string  `json:"description"` // e.g. "40h a month"
+	Remaining   string  `json:"remaining"`   // e.g. "6.00h left"
+	HoursSpent  float64 `json:"hours_spent"`
+	AmountSpent float64 `json:"amount_spent"` // major units of the project currency
+	Fraction    float64 `json:"fraction"`
+	Warning     string  `json:"warning,omitempty"`
+}
+
+// New builds the sheet for days days from the midnight starting from.
+// Segments are split at midnight so time is counted on the day it was
+// worked, and running segments count until now. A project with a timezone
+// has its days counted in that timezone rather than from's. Projects
+// appear in the order of projects, skipping those with no time.
+func New(entries []model.Entry, projects []model.Project, from time.Time, days int, now time.Time) *Sheet {
+	sheet := &Sheet{
+		From:      from,
+		To:        from.AddDate(0, 0, days),
+		DayTotals: make([]float64, days),
+	}
+	for d := range days {
+		sheet.Days = append(sheet.Days, from.AddDate(0, 0, d).Format("2006-01-02"))
+	}
+
+	locs := make(map[string]*time.Location)
+	for _, p := range projects {
+		locs[p.ID] = period.LocationOr(p.Timezone, from.Location())
+	}
+
+	byProject := make(map[string][]time.Duration)
+	for _, e := range entries {
+		loc := locs[e.ProjectID]
+		if loc == nil {
+			loc = from.Location()
+		}
+		for _, seg := range e.Segments {
+			for d := range days {
+				day := period.SameDay(from.AddDate(0, 0, d), loc)
+				worked := seg.Between(day, period.SameDay(from.AddDate(0, 0, d+1), loc), now)
+				if worked == 0 {
+					continue
+				}
+				if byProject[e.ProjectID] == nil {
+					byProject[e.ProjectID] = make([]time.Duration, days)
+				}
+				byProject[e.ProjectID][d] += worked
+			}
+		}
+	}
+
+	add := func(id, name string) {
+		durations, ok := byProject[id]
+		if !ok {
+			return
+		}
+		delete(byProject, id)
+		row := Row{ProjectID: id, Project: name, Hours: make([]float64, days)}
+		var total time.Duration
+		for d, dur := range durations {
+			row.Hours[d] = dur.Hours()
+			sheet.DayTotals[d] += dur.Hours()
+			total += dur
+		}
+		row.Total = total.Hours()
+		sheet.Total += row.Total
+		sheet.Rows = append(sheet.Rows, row)
+	}
+	for _, p := range projects {
+		add(p.ID, p.Name)
+	}
+	// Time on projects that no longer exist still counts
+	for _, e := range entries {
+		add(e.ProjectID, e.ProjectID)
+	}
+	return sheet
+}
+
+// Title describes the period, e.g. "Oct 12 - Oct 18, 2026"
+func (s *Sheet) Title() string {
+	last := s.To.AddDate(0, 0, -1)
+	if s.From.Year() != last.Year() {
+		return s.From.Format("Jan 2, 2006") + " - " + last.Format("Jan 2, 2006")
+	}
+	return s.From.Format("Jan 2") + " - " + last.Format("Jan 2, 2006")
+}
+
+func (s *Sheet) dayLabel(d int) string {
+	return s.From.AddDate(0, 0, d).Format("Mon 2")
+}
+
+// WriteText writes the sheet as an aligned table, with budgets below
+func (s *Sheet) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Timesheet %s\n\n", s.Title())
+	if len(s.Rows) == 0 {
+		fmt.Fprintln(w, "No time tracked")
+		return
+	}
+
+	fmt.Fprintf(w, "%-20s", "PROJECT")
+	for d := range s.Days {
+		fmt.Fprintf(w, " %7s", s.dayLabel(d))
+	}
+	fmt.Fprintf(w, " %8s\n", "TOTAL")
+	rule := strings.Repeat("-", 20+8*len(s.Days)+9)
+	fmt.Fprintln(w, rule)
+	for _, row := range s.Rows {
+		fmt.Fprintf(w, "%-20s", truncate(row.Project, 20))
+		for _, h := range row.Hours {
+			fmt.Fprintf(w, " %7s", hours(h))
+		}
+		fmt.Fprintf(w, " %8.2f\n", row.Total)
+	}
+	fmt.Fprintln(w, rule)
+	fmt.Fprintf(w, "%-20s", "TOTAL")
+	for _, h := range s.DayTotals {
+		fmt.Fprintf(w, " %7s", hours(h))
+	}
+	fmt.Fprintf(w, " %8.2f\n", s.Total)
+
+	budgets := s.budgetRows()
+	if len(budgets) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nBudgets:")
+	for _, row := range budgets {
+		fmt.Fprintf(w, "  %-20s %3.0f%% of %s, %s", truncate(row.Project, 20), row.Budget.Fraction*100, row.Budget.Description, row.Budget.Remaining)
+		if row.Budget.Warning != "" {
+			fmt.Fprintf(w, "  Warning: %s", row.Budget.Warning)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// WriteMarkdown writes the sheet as a markdown table, with budgets below
+func (s *Sheet) WriteMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "# Timesheet: %s\n\n", s.Title())
+	if len(s.Rows) == 0 {
+		fmt.Fprintln(w, "No time tracked.")
+		return
+	}
+
+	fmt.Fprint(w, "| Project |")
+	for d := range s.Days {
+		fmt.Fprintf(w, " %s |", s.dayLabel(d))
+	}
+	fmt.Fprintln(w, " Total |")
+	fmt.Fprint(w, "|---|")
+	for range s.Days {
+		fmt.Fprint(w, "---:|")
+	}
+	fmt.Fprintln(w, "---:|")
+	for _, row := range s.Rows {
+		fmt.Fprintf(w, "| %s |", strings.ReplaceAll(row.Project, "|", `\|`))
+		for _, h := range row.Hours {
+			fmt.Fprintf(w, " %s |", hours(h))
+		}
+		fmt.Fprintf(w, " %.2f |\n", row.Total)
+	}
+	fmt.Fprint(w, "| **Total** |")
+	for _, h := range s.DayTotals {
+		fmt.Fprintf(w, " %s |", hours(h))
+	}
+	fmt.Fprintf(w, " **%.2f** |\n", s.Total)
+
+	budgets := s.budgetRows()
+	if len(budgets) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n## Budgets")
+	fmt.Fprintln(w)
+	for _, row := range budgets {
+		fmt.Fprintf(w, "- **%s**: %.0f%% of %s, %s", row.Project, row.Budget.Fraction*100, row.Budget.Description, row.Budget.Remaining)
+		if row.Budget.Warning != "" {
+			fmt.Fprintf(w, " (%s)", row.Budget.Warning)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func (s *Sheet) budgetRows() []Row {
+	var rows []Row
+	for _, row := range s.Rows {
+		if row.Budget != nil {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// hours formats a cell, leaving days without time as a dash
+func hours(h float64) string {
+	if h == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", h)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n-3] + "..."
+	}
+	return s
+}
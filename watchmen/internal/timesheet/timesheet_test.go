@@ -0,0 +1,93 @@
+package timesheet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func TestNew(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	at := func(day, hour int) time.Time { return monday.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour) }
+	seg := func(day, start, end int) model.TimeSegment {
+		e := at(day, end)
+		return model.TimeSegment{Start: at(day, start), End: &e}
+	}
+
+	projects := []model.Project{{ID: "p1", Name: "acme"}, {ID: "p2", Name: "beta"}, {ID: "p3", Name: "idle"}}
+	entries := []model.Entry{
+		{ProjectID: "p2", Segments: []model.TimeSegment{seg(0, 9, 11), seg(0, 13, 14)}},
+		{ProjectID: "p1", Segments: []model.TimeSegment{seg(1, 22, 26)}},     // runs past midnight
+		{ProjectID: "p1", Segments: []model.TimeSegment{seg(-1, 20, 22)}},    // before the week
+		{ProjectID: "p1", Segments: []model.TimeSegment{{Start: at(6, 20)}}}, // running
+		{ProjectID: "gone", Segments: []model.TimeSegment{seg(3, 9, 10)}},
+	}
+
+	sheet := New(entries, projects, monday, 7, at(6, 21))
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("got %d rows, want acme, beta and gone", len(sheet.Rows))
+	}
+	acme, beta, gone := sheet.Rows[0], sheet.Rows[1], sheet.Rows[2]
+	if acme.Project != "acme" || beta.Project != "beta" || gone.Project != "gone" {
+		t.Errorf("rows = %s, %s, %s", acme.Project, beta.Project, gone.Project)
+	}
+	if acme.Hours[1] != 2 || acme.Hours[2] != 2 || acme.Hours[6] != 1 || acme.Total != 5 {
+		t.Errorf("acme hours = %v total %v, want 2h Tue, 2h Wed, 1h Sun", acme.Hours, acme.Total)
+	}
+	if beta.Hours[0] != 3 {
+		t.Errorf("beta Monday = %v, want 3", beta.Hours[0])
+	}
+	if sheet.DayTotals[0] != 3 || sheet.Total != 9 {
+		t.Errorf("totals = %v, %v", sheet.DayTotals, sheet.Total)
+	}
+
+	sheet.Rows[0].Budget = &Budget{Description: "10h in total", Remaining: "4.00h left", Fraction: 0.85, Warning: "85% of budget spent"}
+	var text, md bytes.Buffer
+	sheet.WriteText(&text)
+	sheet.WriteMarkdown(&md)
+	for _, want := range []string{"Oct 12 - Oct 18, 2026", "Tue 13", "acme", "85% of 10h in total, 4.00h left  Warning: 85% of budget spent"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text missing %q:\n%s", want, text.String())
+		}
+	}
+	for _, want := range []string{"| Project | Mon 12 |", "| beta | 3.00 | - |", "| **Total** |", "- **acme**: 85% of 10h in total"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+}
+
+func TestNewTimezones(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	london, _ := time.LoadLocation("Europe/London")
+	span := func(start, end time.Time) model.TimeSegment { return model.TimeSegment{Start: start, End: &end} }
+
+	// The week New York falls back from daylight saving on Sunday
+	monday := time.Date(2026, 10, 26, 0, 0, 0, 0, ny)
+	projects := []model.Project{{ID: "home", Name: "home"}, {ID: "away", Name: "away", Timezone: "Europe/London"}}
+	entries := []model.Entry{
+		// 1am-3am Tuesday in London is still Monday evening in New York
+		{ProjectID: "away", Segments: []model.TimeSegment{span(time.Date(2026, 10, 27, 1, 0, 0, 0, london), time.Date(2026, 10, 27, 3, 0, 0, 0, london))}},
+		{ProjectID: "home", Segments: []model.TimeSegment{span(time.Date(2026, 10, 27, 1, 0, 0, 0, london), time.Date(2026, 10, 27, 3, 0, 0, 0, london))}},
+		// Sunday is 25 hours long
+		{ProjectID: "home", Segments: []model.TimeSegment{span(time.Date(2026, 11, 1, 0, 0, 0, 0, ny), time.Date(2026, 11, 2, 0, 0, 0, 0, ny))}},
+	}
+
+	sheet := New(entries, projects, monday, 7, time.Date(2026, 11, 3, 0, 0, 0, 0, ny))
+	home, away := sheet.Rows[0], sheet.Rows[1]
+	if home.Hours[0] != 2 || home.Hours[1] != 0 {
+		t.Errorf("home Mon/Tue = %v/%v, want 2/0", home.Hours[0], home.Hours[1])
+	}
+	if away.Hours[0] != 0 || away.Hours[1] != 2 {
+		t.Errorf("away Mon/Tue = %v/%v, want 0/2 counted in London", away.Hours[0], away.Hours[1])
+	}
+	if home.Hours[6] != 25 {
+		t.Errorf("home Sunday = %v, want 25 across the clock change", home.Hours[6])
+	}
+}
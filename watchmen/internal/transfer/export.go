@@ -0,0 +1,169 @@
+// Package transfer moves entries, projects and invoices in and out of
+// watchmen as CSV or newline-delimited JSON, and reads the CSV exports of
+// other time trackers.
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"watchmen/internal/model"
+	"watchmen/internal/money"
+)
+
+// Entry is a time entry as exported, and as read back by ReadWatchmen
+type Entry struct {
+	ID        string    `json:"id"`
+	Project   string    `json:"project"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Hours     float64   `json:"hours"` // less than End - Start if the entry was paused
+	Note      string    `json:"note,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Billable  bool      `json:"billable"`
+	Rate      string    `json:"rate,omitempty"` // decimal, set when it overrides the project rate
+	Currency  string    `json:"currency"`
+	InvoiceID string    `json:"invoice_id,omitempty"`
+}
+
+// NewEntry converts a completed entry for export
+func NewEntry(e *model.Entry, project *model.Project) Entry {
+	out := Entry{
+		ID:        e.ID,
+		Project:   project.Name,
+		Start:     e.StartTime(),
+		Hours:     round2(e.Duration().Hours()),
+		Note:      e.Note,
+		Category:  e.Category,
+		Billable:  e.IsBillable(),
+		Currency:  project.CurrencyCode(),
+		InvoiceID: e.InvoiceID,
+	}
+	if n := len(e.Segments); n > 0 && e.Segments[n-1].End != nil {
+		out.End = *e.Segments[n-1].End
+	}
+	if e.Rate != nil {
+		out.Rate = decimal(*e.Rate, out.Currency)
+	}
+	return out
+}
+
+// Table is data ready to export: rows for CSV, and the records they were
+// made from for NDJSON
+type Table struct {
+	Header  []string
+	Rows    [][]string
+	Records []any
+}
+
+// WriteCSV writes the table as CSV with a header row
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(t.Header)
+	cw.WriteAll(t.Rows)
+	return cw.Error()
+}
+
+// WriteNDJSON writes one JSON object per line
+func (t *Table) WriteNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, r := range t.Records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryHeader is the header of the entries CSV, read back by ReadWatchmen
+var entryHeader = []string{"id", "project", "start", "end", "hours", "note", "category", "billable", "rate", "currency", "invoice_id"}
+
+// EntriesTable builds the export of entries
+func EntriesTable(entries []Entry) *Table {
+	t := &Table{Header: entryHeader}
+	for _, e := range entries {
+		t.Rows = append(t.Rows, []string{
+			e.ID,
+			e.Project,
+			e.Start.Format(time.RFC3339),
+			e.End.Format(time.RFC3339),
+			strconv.FormatFloat(e.Hours, 'f', 2, 64),
+			e.Note,
+			e.Category,
+			strconv.FormatBool(e.Billable),
+			e.Rate,
+			e.Currency,
+			e.InvoiceID,
+		})
+		t.Records = append(t.Records, e)
+	}
+	return t
+}
+
+// ProjectsTable builds the export of projects
+func ProjectsTable(projects []model.Project) *Table {
+	t := &Table{Header: []string{"id", "name", "hourly_rate", "currency", "description", "purchase_order", "payment_terms", "created_at"}}
+	for _, p := range projects {
+		terms := ""
+		if p.PaymentTerms > 0 {
+			terms = strconv.Itoa(p.PaymentTerms)
+		}
+		t.Rows = append(t.Rows, []string{
+			p.ID,
+			p.Name,
+			decimal(p.RateAt(time.Now()), p.CurrencyCode()),
+			p.CurrencyCode(),
+			p.Description,
+			p.PurchaseOrder,
+			terms,
+			p.CreatedAt.Format(time.RFC3339),
+		})
+		t.Records = append(t.Records, p)
+	}
+	return t
+}
+
+// InvoicesTable builds the export of invoices, with their status as of now
+func InvoicesTable(invoices []model.Invoice, now time.Time) *Table {
+	t := &Table{Header: []string{"id", "project", "created_at", "period_start", "period_end", "hours", "amount", "balance", "currency", "status", "due_date", "credit_for"}}
+	for _, inv := range invoices {
+		due := ""
+		if inv.DueDate != nil {
+			due = inv.DueDate.Format("2006-01-02")
+		}
+		t.Rows = append(t.Rows, []string{
+			inv.ID,
+			inv.ProjectName,
+			inv.CreatedAt.Format(time.RFC3339),
+			inv.PeriodStart.Format("2006-01-02"),
+			inv.PeriodEnd.Format("2006-01-02"),
+			strconv.FormatFloat(inv.Hours, 'f', 2, 64),
+			decimal(inv.Amount, inv.CurrencyCode()),
+			decimal(inv.Balance(), inv.CurrencyCode()),
+			inv.CurrencyCode(),
+			string(inv.State(now)),
+			due,
+			inv.CreditFor,
+		})
+		t.Records = append(t.Records, inv)
+	}
+	return t
+}
+
+// decimal writes an amount in major units with a plain decimal point, as
+// spreadsheets expect
+func decimal(amount int64, code string) string {
+	c, err := money.Lookup(code)
+	if err != nil {
+		c.Digits = 2
+	}
+	return strconv.FormatFloat(money.ToMajor(amount, code), 'f', c.Digits, 64)
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,213 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a time entry read from an export
+type Record struct {
+	Line     int // row or line in the source, for messages
+	Project  string
+	Start    time.Time
+	End      time.Time
+	Note     string
+	Category string
+	Billable bool
+	Rate     string // decimal hourly rate overriding the project's, if any
+}
+
+// Duration returns the length of the record
+func (r *Record) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// ReadWatchmen reads entries exported by watchmen, as CSV or NDJSON. A
+// paused entry is read back as one stretch of its worked hours.
+func ReadWatchmen(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first[0] != '{' {
+		return readWatchmenCSV(br)
+	}
+
+	var records []Record
+	dec := json.NewDecoder(br)
+	for line := 1; ; line++ {
+		var e Entry
+		if err := dec.Decode(&e); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", line, err)
+		}
+		records = append(records, entryRecord(line, e))
+	}
+}
+
+func readWatchmenCSV(r io.Reader) ([]Record, error) {
+	rows, err := readCSV(r, "project", "start", "hours")
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, row := range rows {
+		e := Entry{
+			Project:  row.get("project"),
+			Note:     row.get("note"),
+			Category: row.get("category"),
+			Rate:     row.get("rate"),
+			Billable: row.get("billable") != "false",
+		}
+		if e.Start, err = time.Parse(time.RFC3339, row.get("start")); err != nil {
+			return nil, fmt.Errorf("row %d: invalid start %q", row.line, row.get("start"))
+		}
+		if e.Hours, err = strconv.ParseFloat(row.get("hours"), 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid hours %q", row.line, row.get("hours"))
+		}
+		records = append(records, entryRecord(row.line, e))
+	}
+	return records, nil
+}
+
+func entryRecord(line int, e Entry) Record {
+	return Record{
+		Line:     line,
+		Project:  e.Project,
+		Start:    e.Start,
+		End:      e.Start.Add(time.Duration(e.Hours * float64(time.Hour))).Round(time.Second),
+		Note:     e.Note,
+		Category: e.Category,
+		Billable: e.Billable,
+		Rate:     e.Rate,
+	}
+}
+
+// ReadToggl reads a Toggl Track detailed report exported as CSV, with times
+// in loc. The first tag becomes the category.
+func ReadToggl(r io.Reader, loc *time.Location) ([]Record, error) {
+	return readTracker(r, loc, "Start date", "Start time", "End date", "End time")
+}
+
+// ReadClockify reads a Clockify detailed report exported as CSV, with times
+// in loc. The first tag becomes the category.
+func ReadClockify(r io.Reader, loc *time.Location) ([]Record, error) {
+	return readTracker(r, loc, "Start Date", "Start Time", "End Date", "End Time")
+}
+
+// readTracker reads the detailed CSV reports Toggl and Clockify share the
+// shape of: project, description, tags, billable and separate date and time
+// columns, in loc
+func readTracker(r io.Reader, loc *time.Location, startDate, startTime, endDate, endTime string) ([]Record, error) {
+	rows, err := readCSV(r, "Project", "Description", startDate, startTime, endDate, endTime)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, row := range rows {
+		rec := Record{
+			Line:     row.line,
+			Project:  row.get("Project"),
+			Note:     row.get("Description"),
+			Billable: !strings.EqualFold(row.get("Billable"), "No"),
+		}
+		if tags := row.get("Tags"); tags != "" {
+			first, _, _ := strings.Cut(tags, ",")
+			rec.Category = strings.TrimSpace(first)
+		}
+		if rec.Start, err = parseDateTime(row.get(startDate), row.get(startTime), loc); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row.line, err)
+		}
+		if rec.End, err = parseDateTime(row.get(endDate), row.get(endTime), loc); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row.line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+var (
+	dateLayouts = []string{"2006-01-02", "01/02/2006", "02.01.2006", "2006/01/02"}
+	timeLayouts = []string{"15:04:05", "15:04", "03:04:05 PM", "3:04:05 PM", "03:04 PM", "3:04 PM"}
+)
+
+// parseDateTime reads the separate date and time columns of a tracker's
+// export, in loc. Slash dates are read month first, as both
+// trackers write them by default.
+func parseDateTime(date, clock string, loc *time.Location) (time.Time, error) {
+	for _, dl := range dateLayouts {
+		for _, tl := range timeLayouts {
+			if t, err := time.ParseInLocation(dl+" "+tl, date+" "+strings.ToUpper(clock), loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date and time %q %q", date, clock)
+}
+
+// csvRow is a CSV row with its columns looked up by header name
+type csvRow struct {
+	line   int
+	fields []string
+	index  map[string]int
+}
+
+func (r csvRow) get(column string) string {
+	i, ok := r.index[strings.ToLower(column)]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[i])
+}
+
+// readCSV reads a CSV with a header row, checking the required columns are
+// present. Header names are matched ignoring case.
+func readCSV(r io.Reader, required ...string) ([]csvRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheets often start the file with a UTF-8 byte order mark
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, column := range required {
+		if _, ok := index[strings.ToLower(column)]; !ok {
+			return nil, fmt.Errorf("missing column %q, is this the right format?", column)
+		}
+	}
+
+	var rows []csvRow
+	for line := 2; ; line++ {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, csvRow{line: line, fields: fields, index: index})
+	}
+}
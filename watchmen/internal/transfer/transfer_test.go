@@ -0,0 +1,117 @@
+package transfer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"watchmen/internal/model"
+)
+
+func TestEntriesRoundTrip(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	rate := int64(9000)
+	entry := &model.Entry{
+		ID:        "e1",
+		ProjectID: "p1",
+		Segments:  []model.TimeSegment{{Start: start, End: &end}},
+		Note:      "API work, \"v2\"",
+		Category:  "dev",
+		Rate:      &rate,
+	}
+	project := &model.Project{ID: "p1", Name: "acme", HourlyRate: 15000}
+	table := EntriesTable([]Entry{NewEntry(entry, project)})
+
+	for _, format := range []string{"csv", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			var err error
+			if format == "csv" {
+				err = table.WriteCSV(&buf)
+			} else {
+				err = table.WriteNDJSON(&buf)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := ReadWatchmen(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("got %d records, want 1", len(records))
+			}
+			r := records[0]
+			if r.Project != "acme" || r.Note != entry.Note || r.Category != "dev" || !r.Billable {
+				t.Errorf("got %+v", r)
+			}
+			if !r.Start.Equal(start) || !r.End.Equal(end) {
+				t.Errorf("got %v - %v, want %v - %v", r.Start, r.End, start, end)
+			}
+			if r.Rate != "90.00" {
+				t.Errorf("rate = %q, want 90.00", r.Rate)
+			}
+		})
+	}
+}
+
+func TestReadToggl(t *testing.T) {
+	csv := "\xef\xbb\xbfUser,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags\n" +
+		"Me,me@example.com,Acme,Website,,Fix header,Yes,2026-10-15,09:00:00,2026-10-15,10:30:00,01:30:00,\"design, urgent\"\n" +
+		"Me,me@example.com,,Internal,,Admin,No,2026-10-15,23:30:00,2026-10-16,00:15:00,00:45:00,\n"
+
+	records, err := ReadToggl(strings.NewReader(csv), time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	r := records[0]
+	if r.Project != "Website" || r.Note != "Fix header" || r.Category != "design" || !r.Billable {
+		t.Errorf("got %+v", r)
+	}
+	if r.Duration() != 90*time.Minute {
+		t.Errorf("duration = %v, want 1h30m", r.Duration())
+	}
+	r = records[1]
+	if r.Billable || r.Category != "" || r.Line != 3 {
+		t.Errorf("got %+v", r)
+	}
+	if r.Duration() != 45*time.Minute {
+		t.Errorf("duration across midnight = %v, want 45m", r.Duration())
+	}
+}
+
+func TestReadClockify(t *testing.T) {
+	csv := "Project,Client,Description,Task,User,Tags,Billable,Start Date,Start Time,End Date,End Time,Duration (h)\n" +
+		"Website,Acme,Review,,Me,,Yes,10/02/2026,01:15:00 PM,10/02/2026,02:00:00 PM,00:45:00\n"
+
+	records, err := ReadClockify(strings.NewReader(csv), time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	want := time.Date(2026, 10, 2, 13, 15, 0, 0, time.Local)
+	if !records[0].Start.Equal(want) {
+		t.Errorf("start = %v, want %v (month first)", records[0].Start, want)
+	}
+	if records[0].Duration() != 45*time.Minute {
+		t.Errorf("duration = %v, want 45m", records[0].Duration())
+	}
+}
+
+func TestReadWrongFormat(t *testing.T) {
+	csv := "Project,Description,Start Date,Start Time,End Date,End Time\n"
+	if _, err := ReadWatchmen(strings.NewReader(csv)); err == nil {
+		t.Error("expected an error reading a Clockify export as watchmen")
+	}
+	if _, err := ReadToggl(strings.NewReader("Project,Start\nacme,today\n"), time.Local); err == nil {
+		t.Error("expected an error for missing columns")
+	}
+}